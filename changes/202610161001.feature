@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `Tar` and `Untar` to create and extract tar archives, optionally compressed using gzip or zstd
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `UntarFromReaderWithContextAndLimits` and `UnzipFromReaderWithContextAndLimits` to extract archives directly from streams whilst enforcing limits
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `OverlayFS` copy-on-write filesystem type layering a writable in-memory or scratch directory layer over a read-only base filesystem
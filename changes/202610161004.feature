@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `NewBasePathFileSystem` to restrict a filesystem to a root directory, rejecting any path traversal or symbolic link escaping it
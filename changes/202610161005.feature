@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `Watch` to observe changes (creation, modification, deletion, renaming) in a file tree, using inotify on the standard filesystem and polling on other filesystems
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `Sync` to incrementally synchronise file trees between filesystems (size/modification time or content hash comparison, optional removal of extraneous items, exclusion patterns and limits) and report the changes made
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `GenerateManifest` to describe a file tree (path, size, mode, modification time and content hash) in a serialisable manifest, and `DiffManifests`/`DiffDirectories` to determine what changed between two trees
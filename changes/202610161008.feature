@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `WriteFileAtomically` and `WriteToFileAtomically` to `FS` so that files are either fully written or left untouched (temporary file, fsync and rename on the standard filesystem, staged writes otherwise), preserving permissions and ownership
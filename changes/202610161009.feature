@@ -0,0 +1 @@
+:sparkles: `[cas]` Added a content-addressable blob store built on the filesystem abstraction, with a sharded layout, verification on read and garbage collection of unreferenced blobs
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `NewQuotaFileSystem` wrapping a filesystem so that writes exceeding a size or file-count quota fail with `ErrTooLarge`, optionally seeded with the existing usage under some roots
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `NewFaultInjectionFileSystem` and `FaultInjector` for injecting errors, short writes, latency or context cancellation into filesystem operations matching rules (path glob, operation, probability or Nth call)
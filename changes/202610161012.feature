@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `NewAuditFileSystem` recording filesystem operations (operation, path, size, duration, error, tag) to a sink such as an in-memory `AuditJournal` or loggers, and `ReplayAuditRecords` for turning a journal into a diff report
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `CopyWithContextAndOptions`, `RemoveWithContextAndOptions`, `ChmodRecursivelyWithOptions` and `FileHashesWithContext` processing file trees concurrently with bounded workers (`WithWorkers`), exclusion patterns, limits and deterministic error collation
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `CalculateDirectory` to `IFileHash` computing a deterministic Merkle hash of a directory tree (relative paths, contents and optionally modes and symbolic link targets) with exclusion patterns, limits and a per-subtree breakdown
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Files of the standard filesystem are now copied by the kernel on Linux: reflinks (`FICLONE`) are used where supported, otherwise holes of sparse files are preserved (`SEEK_DATA`/`SEEK_HOLE`) and data is copied using `copy_file_range`, falling back to user space copies transparently
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added extended attribute support (`GetXattr`, `SetXattr`, `ListXattr`, `RemoveXattr` and the optional `IXattrer` interface) so that xattrs and ACLs can be read and written, preserved by `CopyBetweenFSWithOptions` (`WithXattrPreservation`) and recorded in tar and zip archives (`WithXattrs`)
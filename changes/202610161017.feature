@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added pluggable lock backends (`ILockBackend`) with OS file locks (`flock`/`fcntl`) for local files, and `IFencedLock` locks issuing monotonically increasing fencing tokens and exposing their owner (holder ID, host, PID, acquisition time) for diagnostics
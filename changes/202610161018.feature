@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added reader/writer locks (`RemoteRWLockFile`) and counting semaphores (`RemoteSemaphoreFile`) relying on the same heart beat mechanism as `RemoteLockFile`
@@ -0,0 +1 @@
+:sparkles: `[filecache]` Added optional capacity limits (`MaxSize` and `MaxEntries`) to `FileCacheConfig` with LRU or LFU eviction policies
@@ -0,0 +1 @@
+:sparkles: `[filecache]` Added `FileCacheConfig.PersistIndex` to persist the cache index on the cache filesystem so that entries are kept and lazily verified across restarts
@@ -0,0 +1 @@
+:sparkles: `[filecache]` Added `GetOrFetch` to read through the cache whilst coalescing concurrent retrievals of the same missing entry
@@ -0,0 +1 @@
+:sparkles: `[filecache]` Added `HTTPEntryRetriever` and `NewHTTPFileCache` to populate a cache from HTTP servers with resumable downloads, checksum verification, size limits and `Retry-After`-aware retries
@@ -0,0 +1 @@
+:sparkles: `[metrics]` Added cache observers (`ICacheObserver`) and `InMemoryObserver` tracking hits, misses, stores, evictions and fetch latency, with snapshot export and summary logging
@@ -0,0 +1 @@
+:sparkles: `[tieredcache]` Added `TieredCache` composing a local `filecache` in front of a remote `sharedcache` with per-tier read/write policies, write-through stores and population of the local tier on remote hits
@@ -0,0 +1 @@
+:sparkles: `[filesystem]` Added `WithCompressionLevel` archive option to set the compression level of zip and compressed tar archives
@@ -0,0 +1 @@
+:sparkles: `[filecache]` `[sharedcache]` Added `WithObserver` option so that cache operations can be monitored
@@ -0,0 +1 @@
+:sparkles: `[sharedcache]` Added `PackageFormat` and `CompressionLevel` to the configuration so that entries can be packaged as zip, tar, tar.gz or tar.zst archives, or plain directories preserving file modes and symbolic links
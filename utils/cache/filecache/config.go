@@ -3,16 +3,38 @@ package filecache
 import (
 	"time"
 
+	"github.com/go-logr/logr"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
 	configUtils "github.com/ARM-software/golang-utils/utils/config"
+	"github.com/ARM-software/golang-utils/utils/logs/logrimp"
 	validationRules "github.com/ARM-software/golang-utils/utils/validation"
 )
 
+// EvictionPolicy defines which entries are evicted first when the cache exceeds its capacity.
+type EvictionPolicy string
+
+const (
+	// LRUEvictionPolicy evicts the least recently used entries first.
+	LRUEvictionPolicy EvictionPolicy = "lru"
+	// LFUEvictionPolicy evicts the least frequently used entries first. Entries used as frequently are evicted in least recently used order.
+	LFUEvictionPolicy EvictionPolicy = "lfu"
+)
+
 type FileCacheConfig struct {
 	CachePath               string        `mapstructure:"cache_path"`
 	GarbageCollectionPeriod time.Duration `mapstructure:"gc_period"`
 	TTL                     time.Duration `mapstructure:"ttl"`
+	// MaxSize is the maximum total size (in bytes) of the cache entries. No limit is applied if zero.
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxEntries is the maximum number of entries in the cache. No limit is applied if zero.
+	MaxEntries int64 `mapstructure:"max_entries"`
+	// EvictionPolicy defines which entries are evicted when a capacity limit is reached. LRUEvictionPolicy is used if not set.
+	EvictionPolicy EvictionPolicy `mapstructure:"eviction_policy"`
+	// PersistIndex states whether the cache index should be persisted in the cache directory so that entries are kept across restarts.
+	// If set, closing the cache keeps the entries on the filesystem and creating a cache on the same directory reloads them.
+	PersistIndex bool `mapstructure:"persist_index"`
 }
 
 func (cfg *FileCacheConfig) Validate() error {
@@ -27,6 +49,9 @@ func (cfg *FileCacheConfig) Validate() error {
 		validation.Field(&cfg.CachePath, validationRules.Required),
 		validation.Field(&cfg.GarbageCollectionPeriod, validationRules.Required),
 		validation.Field(&cfg.TTL, validationRules.Required),
+		validation.Field(&cfg.MaxSize, validation.Min(int64(0))),
+		validation.Field(&cfg.MaxEntries, validation.Min(int64(0))),
+		validation.Field(&cfg.EvictionPolicy, validation.In(LRUEvictionPolicy, LFUEvictionPolicy)),
 	)
 }
 
@@ -34,5 +59,59 @@ func DefaultFileCacheConfig() *FileCacheConfig {
 	return &FileCacheConfig{
 		GarbageCollectionPeriod: 10 * time.Minute,
 		TTL:                     2 * time.Hour,
+		EvictionPolicy:          LRUEvictionPolicy,
+	}
+}
+
+// FileCacheOptions defines the settings of a file cache which cannot be set via configuration.
+type FileCacheOptions struct {
+	observer metrics.ICacheObserver
+	logger   logr.Logger
+}
+
+// FileCacheOption configures FileCacheOptions.
+type FileCacheOption func(*FileCacheOptions) *FileCacheOptions
+
+// DefaultFileCacheOptions returns the default options i.e. the cache is not observed and nothing is logged.
+func DefaultFileCacheOptions() *FileCacheOptions {
+	return &FileCacheOptions{
+		observer: metrics.NewNoOpObserver(),
+		logger:   logrimp.NewNoopLogger(),
+	}
+}
+
+// WithFileCacheOptions returns the options resulting from applying options to the defaults.
+func WithFileCacheOptions(options ...FileCacheOption) (opts *FileCacheOptions) {
+	opts = DefaultFileCacheOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithObserver sets the observer notified of the cache hits, misses, stores, evictions and fetches e.g. metrics.NewInMemoryObserver().
+func WithObserver(observer metrics.ICacheObserver) FileCacheOption {
+	return func(o *FileCacheOptions) *FileCacheOptions {
+		if o == nil {
+			o = DefaultFileCacheOptions()
+		}
+		o.observer = metrics.ObserverOrNoOp(observer)
+		return o
+	}
+}
+
+// WithLogger sets the logger used to report problems the cache recovers from e.g. a persisted index which cannot be read.
+func WithLogger(logger logr.Logger) FileCacheOption {
+	return func(o *FileCacheOptions) *FileCacheOptions {
+		if o == nil {
+			o = DefaultFileCacheOptions()
+		}
+		if logger.GetSink() == nil {
+			logger = logrimp.NewNoopLogger()
+		}
+		o.logger = logger
+		return o
 	}
 }
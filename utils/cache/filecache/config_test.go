@@ -22,4 +22,27 @@ func TestFileCacheConfig_Validate(t *testing.T) {
 		cfg.CachePath = t.TempDir()
 		require.NoError(t, cfg.Validate())
 	})
+
+	t.Run("capacity limits cannot be negative", func(t *testing.T) {
+		cfg := DefaultFileCacheConfig()
+		cfg.CachePath = t.TempDir()
+		cfg.MaxSize = -1
+		require.Error(t, cfg.Validate())
+		cfg.MaxSize = 1024
+		cfg.MaxEntries = -1
+		require.Error(t, cfg.Validate())
+		cfg.MaxEntries = 10
+		require.NoError(t, cfg.Validate())
+	})
+
+	t.Run("eviction policy must be supported", func(t *testing.T) {
+		cfg := DefaultFileCacheConfig()
+		cfg.CachePath = t.TempDir()
+		cfg.EvictionPolicy = EvictionPolicy("fifo")
+		require.Error(t, cfg.Validate())
+		cfg.EvictionPolicy = LFUEvictionPolicy
+		require.NoError(t, cfg.Validate())
+		cfg.EvictionPolicy = ""
+		require.NoError(t, cfg.Validate())
+	})
 }
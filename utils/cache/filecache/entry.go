@@ -2,16 +2,62 @@ package filecache
 
 import (
 	"context"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/ARM-software/golang-utils/utils/filesystem"
 )
 
+// iAccountedEntry is implemented by entries keeping track of their size and accesses so that capacity limits and eviction policies can apply.
+// Other entries are considered empty and never accessed.
+type iAccountedEntry interface {
+	// RecordAccess records that the entry has just been accessed.
+	RecordAccess()
+	// GetSize returns the size in bytes of the underlying data.
+	GetSize() int64
+	// GetLastAccess returns when the entry was last accessed (or stored if it was never accessed).
+	GetLastAccess() time.Time
+	// GetAccessCount returns the number of times the entry was accessed.
+	GetAccessCount() uint64
+}
+
+func recordEntryAccess(entry ICacheEntry) {
+	if accounted, ok := entry.(iAccountedEntry); ok {
+		accounted.RecordAccess()
+	}
+}
+
+func determineSize(entry ICacheEntry) int64 {
+	if accounted, ok := entry.(iAccountedEntry); ok {
+		return accounted.GetSize()
+	}
+	return 0
+}
+
+func determineLastAccess(entry ICacheEntry) (lastAccess time.Time) {
+	if accounted, ok := entry.(iAccountedEntry); ok {
+		lastAccess = accounted.GetLastAccess()
+	}
+	return
+}
+
+func determineAccessCount(entry ICacheEntry) uint64 {
+	if accounted, ok := entry.(iAccountedEntry); ok {
+		return accounted.GetAccessCount()
+	}
+	return 0
+}
+
 type CacheEntry struct {
-	cachePath  string
-	cacheFs    filesystem.FS
-	ttl        time.Duration
-	expiration time.Time
+	cachePath   string
+	cacheFs     filesystem.FS
+	ttl         time.Duration
+	expiration  time.Time
+	size        int64
+	checksum    string
+	lastAccess  atomic.Int64
+	accessCount atomic.Uint64
 }
 
 func (e *CacheEntry) Copy(ctx context.Context, destFs filesystem.FS, destPath string) error {
@@ -51,11 +97,78 @@ func (e *CacheEntry) ExtendLifetime() {
 	e.expiration = time.Now().Add(e.ttl)
 }
 
+func (e *CacheEntry) RecordAccess() {
+	e.lastAccess.Store(time.Now().UnixNano())
+	e.accessCount.Add(1)
+}
+
+func (e *CacheEntry) GetSize() int64 {
+	return e.size
+}
+
+func (e *CacheEntry) GetLastAccess() time.Time {
+	return time.Unix(0, e.lastAccess.Load())
+}
+
+func (e *CacheEntry) GetAccessCount() uint64 {
+	return e.accessCount.Load()
+}
+
 func NewCacheEntry(cacheFilesystem filesystem.FS, path string, ttl time.Duration) ICacheEntry {
-	return &CacheEntry{
+	return newCacheEntry(cacheFilesystem, path, ttl, 0)
+}
+
+// newCacheEntry returns a cache entry whose underlying data is `size` bytes large.
+func newCacheEntry(cacheFilesystem filesystem.FS, path string, ttl time.Duration, size int64) *CacheEntry {
+	now := time.Now()
+	entry := &CacheEntry{
 		cachePath:  path,
 		cacheFs:    cacheFilesystem,
 		ttl:        ttl,
-		expiration: time.Now().Add(ttl),
+		expiration: now.Add(ttl),
+		size:       size,
+	}
+	entry.lastAccess.Store(now.UnixNano())
+	return entry
+}
+
+func newCacheEntryFromIndexRecord(cacheFilesystem filesystem.FS, record *indexRecord) *CacheEntry {
+	entry := &CacheEntry{
+		cachePath:  record.Path,
+		cacheFs:    cacheFilesystem,
+		ttl:        record.TTL,
+		expiration: record.Expiration,
+		size:       record.Size,
+		checksum:   record.Checksum,
+	}
+	entry.lastAccess.Store(record.LastAccess.UnixNano())
+	entry.accessCount.Store(record.AccessCount)
+	return entry
+}
+
+func (e *CacheEntry) toIndexRecord(key string) indexRecord {
+	return indexRecord{
+		Key:         key,
+		Path:        e.cachePath,
+		TTL:         e.ttl,
+		Expiration:  e.expiration,
+		LastAccess:  e.GetLastAccess(),
+		AccessCount: e.GetAccessCount(),
+		Size:        e.size,
+		Checksum:    e.checksum,
 	}
 }
+
+// determineEntrySize returns the total size of the files making up the entry at `path`.
+func determineEntrySize(ctx context.Context, fs filesystem.FS, path string) (size int64, err error) {
+	err = fs.WalkWithContext(ctx, path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return
+}
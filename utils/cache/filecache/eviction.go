@@ -0,0 +1,89 @@
+package filecache
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+type evictionCandidate struct {
+	key   string
+	entry ICacheEntry
+}
+
+// evictsFirst returns whether entry `a` should be evicted before entry `b` according to the policy.
+func (p EvictionPolicy) evictsFirst(a, b ICacheEntry) bool {
+	if p == LFUEvictionPolicy {
+		countA, countB := determineAccessCount(a), determineAccessCount(b)
+		if countA != countB {
+			return countA < countB
+		}
+	}
+	return determineLastAccess(a).Before(determineLastAccess(b))
+}
+
+// exceedsCapacity returns whether adding `extraEntries` entries totalling `extraSize` bytes would exceed the cache capacity limits.
+func (c *Cache) exceedsCapacity(extraSize int64, extraEntries int64) bool {
+	if c.cfg.MaxSize > 0 && c.size.Load()+extraSize > c.cfg.MaxSize {
+		return true
+	}
+	return c.cfg.MaxEntries > 0 && c.entryCount.Load()+extraEntries > c.cfg.MaxEntries
+}
+
+// makeRoom evicts entries, according to the eviction policy, until an entry of `size` bytes can be added without exceeding the cache capacity.
+// Entries currently in use are not evicted. It must be called whilst holding the eviction lock.
+func (c *Cache) makeRoom(ctx context.Context, size int64) error {
+	return c.makeRoomFor(ctx, size, 1)
+}
+
+// makeRoomFor is similar to makeRoom but for `count` entries totalling `size` bytes. In particular, if `count` is zero, entries are evicted until the cache capacity is no longer exceeded.
+func (c *Cache) makeRoomFor(ctx context.Context, size int64, count int64) error {
+	if !c.exceedsCapacity(size, count) {
+		return nil
+	}
+
+	var candidates []evictionCandidate
+	c.entries.Range(func(key string, entry ICacheEntry) {
+		candidates = append(candidates, evictionCandidate{key: key, entry: entry})
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return c.cfg.EvictionPolicy.evictsFirst(candidates[i].entry, candidates[j].entry)
+	})
+
+	for i := range candidates {
+		if !c.exceedsCapacity(size, count) {
+			break
+		}
+		if err := parallelisation.DetermineContextError(ctx); err != nil {
+			return err
+		}
+		c.evictIfPossible(ctx, candidates[i].key)
+	}
+
+	if c.exceedsCapacity(size, count) {
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "not enough room in the cache for an entry of %v bytes", size)
+	}
+	return nil
+}
+
+// evictIfPossible evicts the entry identified by `key` unless it is currently in use.
+func (c *Cache) evictIfPossible(ctx context.Context, key string) {
+	if !c.entriesLM.TryLock(key) {
+		return
+	}
+	entry := c.entries.Load(key)
+	if entry == nil {
+		// the entry was removed in the meantime
+		c.entriesLM.Unlock(key)
+		return
+	}
+	if err := entry.Delete(ctx); err != nil {
+		c.entriesLM.Unlock(key)
+		return
+	}
+	c.evictEntry(key, entry)
+	c.entriesLM.Unlock(key)
+	c.entriesLM.Delete(key)
+}
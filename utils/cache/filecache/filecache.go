@@ -3,9 +3,13 @@ package filecache
 import (
 	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/go-logr/logr"
+
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
 	"github.com/ARM-software/golang-utils/utils/commonerrors"
 	"github.com/ARM-software/golang-utils/utils/filesystem"
 	"github.com/ARM-software/golang-utils/utils/logs/logrimp"
@@ -23,6 +27,100 @@ type Cache struct {
 	cfg           *FileCacheConfig
 	cancelStore   *parallelisation.CancelFunctionStore
 	closed        atomic.Bool
+	// evictionMu serialises the capacity checks and evictions performed when storing entries.
+	evictionMu sync.Mutex
+	size       atomic.Int64
+	entryCount atomic.Int64
+	// index persists the cache index if FileCacheConfig.PersistIndex is set.
+	index *entryIndex
+	// retrievals coalesces concurrent retrievals of missing entries (see GetOrFetch). They are cancelled when the cache is closed.
+	retrievals *flightGroup
+	observer   metrics.ICacheObserver
+}
+
+func (c *Cache) addEntry(key string, entry ICacheEntry) {
+	if previous := c.entries.Load(key); previous != nil {
+		c.size.Add(-determineSize(previous))
+		c.entryCount.Add(-1)
+	}
+	c.entries.Store(key, entry)
+	c.entriesLM.Store(key)
+	c.size.Add(determineSize(entry))
+	c.entryCount.Add(1)
+}
+
+func (c *Cache) removeEntry(key string, entry ICacheEntry) {
+	c.entries.Delete(key)
+	c.size.Add(-determineSize(entry))
+	c.entryCount.Add(-1)
+	if c.index != nil {
+		c.index.forget(key)
+	}
+}
+
+// evictEntry removes an entry which has been evicted from the cache.
+func (c *Cache) evictEntry(key string, entry ICacheEntry) {
+	c.removeEntry(key, entry)
+	c.observer.OnEviction(key, determineSize(entry))
+}
+
+// observeFetch notifies the observer of the outcome of a request for an entry started at `start`. Requests which failed for other reasons than the entry missing are neither hits nor misses.
+func (c *Cache) observeFetch(key string, start time.Time, missed bool, err error) {
+	switch {
+	case missed:
+		c.observer.OnMiss(key)
+	case err == nil:
+		c.observer.OnHit(key)
+	}
+	c.observer.OnFetch(key, time.Since(start), err)
+}
+
+// loadIndex reloads the entries described by the persisted index.
+// An index which cannot be read (e.g. corrupted or of a different version) is discarded, along with the content of the cache directory it described, so that the cache remains usable.
+// As the cache capacity may have been lowered since, entries are evicted until it is no longer exceeded.
+func (c *Cache) loadIndex(ctx context.Context, logger logr.Logger) error {
+	entries, err := c.index.load(c.fs)
+	if commonerrors.Any(err, commonerrors.ErrMarshalling, commonerrors.ErrUnsupported) {
+		logger.Error(err, "discarding the cache index and the entries it described", "cache", c.cfg.CachePath)
+		entries = nil
+		err = c.fs.CleanDir(c.cfg.CachePath)
+	}
+	if err != nil {
+		return err
+	}
+	for key, entry := range entries {
+		c.addEntry(key, entry)
+	}
+
+	c.evictionMu.Lock()
+	defer c.evictionMu.Unlock()
+	err = c.makeRoomFor(ctx, 0, 0)
+	if err != nil {
+		return err
+	}
+	return c.saveIndex(ctx)
+}
+
+func (c *Cache) saveIndex(ctx context.Context) error {
+	if c.index == nil {
+		return nil
+	}
+	return c.index.save(ctx, c.entries)
+}
+
+// verifyEntry checks that an entry reloaded from a persisted index is still valid and evicts it if not. It must be called whilst holding the entry lock.
+func (c *Cache) verifyEntry(ctx context.Context, key string, entry ICacheEntry) error {
+	if c.index == nil {
+		return nil
+	}
+	err := c.index.verify(ctx, key, entry)
+	if err == nil || commonerrors.Any(err, commonerrors.ErrCancelled, commonerrors.ErrTimeout) {
+		return err
+	}
+	_ = entry.Delete(ctx)
+	c.evictEntry(key, entry)
+	_ = c.saveIndex(ctx)
+	return commonerrors.WrapErrorf(commonerrors.ErrNotFound, err, "cache entry for '%s' is no longer valid", key)
 }
 
 func (c *Cache) gc(ctx context.Context, _ time.Time) {
@@ -30,16 +128,21 @@ func (c *Cache) gc(ctx context.Context, _ time.Time) {
 		return
 	}
 
+	removed := false
 	c.entries.Range(func(key string, entry ICacheEntry) {
 		if entry.IsExpired() && c.entriesLM.TryLock(key) {
 			if err := entry.Delete(ctx); err == nil {
-				c.entries.Delete(key)
+				c.evictEntry(key, entry)
+				removed = true
 				defer c.entriesLM.Delete(key)
 			}
 			// defer Unlock after defer Delete so that Unlock() runs first, then Delete()
 			defer c.entriesLM.Unlock(key)
 		}
 	})
+	if removed {
+		_ = c.saveIndex(ctx)
+	}
 }
 
 func (c *Cache) Has(ctx context.Context, key string) (bool, error) {
@@ -47,7 +150,26 @@ func (c *Cache) Has(ctx context.Context, key string) (bool, error) {
 		return false, closedErr
 	}
 
-	return c.entries.Exists(key), nil
+	if c.index == nil || !c.entries.Exists(key) {
+		return c.entries.Exists(key), nil
+	}
+
+	c.entriesLM.Lock(key)
+	defer c.entriesLM.Unlock(key)
+
+	entry := c.entries.Load(key)
+	if entry == nil {
+		return false, nil
+	}
+
+	if err := c.verifyEntry(ctx, key, entry); err != nil {
+		if commonerrors.Any(err, commonerrors.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
 }
 
 func (c *Cache) Store(ctx context.Context, key string) error {
@@ -72,17 +194,50 @@ func (c *Cache) StoreWithTTL(ctx context.Context, key string, ttl time.Duration)
 		return err
 	}
 
-	c.entries.Store(key, NewCacheEntry(c.fs, entryPath, ttl))
-	c.entriesLM.Store(key)
+	size, err := determineEntrySize(ctx, c.fs, entryPath)
+	if err != nil {
+		return err
+	}
+	entry := newCacheEntry(c.fs, entryPath, ttl, size)
+	if c.index != nil {
+		entry.checksum, err = determineEntryChecksum(ctx, c.fs, entryPath)
+		if err != nil {
+			return err
+		}
+	}
 
-	return nil
+	c.evictionMu.Lock()
+	defer c.evictionMu.Unlock()
+
+	if c.cfg.MaxSize > 0 && size > c.cfg.MaxSize {
+		_ = entry.Delete(ctx)
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "cache entry '%s' (%v bytes) is larger than the cache capacity (%v bytes)", key, size, c.cfg.MaxSize)
+	}
+
+	if err := c.makeRoom(ctx, size); err != nil {
+		_ = entry.Delete(ctx)
+		return err
+	}
+
+	c.addEntry(key, entry)
+	c.observer.OnStore(key, size)
+
+	return c.saveIndex(ctx)
 }
 
-func (c *Cache) Fetch(ctx context.Context, key string, destFilesystem filesystem.FS, destPath string) error {
+func (c *Cache) Fetch(ctx context.Context, key string, destFilesystem filesystem.FS, destPath string) (err error) {
 	if c.closed.Load() {
 		return closedErr
 	}
 
+	start := time.Now()
+	err = c.fetch(ctx, key, destFilesystem, destPath)
+	c.observeFetch(key, start, commonerrors.Any(err, commonerrors.ErrNotFound), err)
+	return
+}
+
+// fetch copies the entry to its destination if present in the cache.
+func (c *Cache) fetch(ctx context.Context, key string, destFilesystem filesystem.FS, destPath string) error {
 	c.entriesLM.Lock(key)
 	defer c.entriesLM.Unlock(key)
 
@@ -98,15 +253,53 @@ func (c *Cache) Fetch(ctx context.Context, key string, destFilesystem filesystem
 		return commonerrors.Newf(commonerrors.ErrUnexpected, "cache entry for '%s' could not be loaded", key)
 	}
 
+	if err := c.verifyEntry(cpCtx, key, entry); err != nil {
+		return err
+	}
+
 	if err := entry.Copy(cpCtx, destFilesystem, destPath); err != nil {
 		return err
 	}
 
 	entry.ExtendLifetime()
+	recordEntryAccess(entry)
 
 	return nil
 }
 
+func (c *Cache) GetOrFetch(ctx context.Context, key string, destFilesystem filesystem.FS, destPath string) (err error) {
+	start := time.Now()
+	exists, err := c.Has(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		err = c.fetch(ctx, key, destFilesystem, destPath)
+		if !commonerrors.Any(err, commonerrors.ErrNotFound) {
+			c.observeFetch(key, start, false, err)
+			return err
+		}
+		// the entry was evicted in the meantime.
+	}
+
+	defer func() { c.observeFetch(key, start, true, err) }()
+
+	err = c.retrievals.do(ctx, key, func(fetchCtx context.Context) error {
+		err := c.Store(fetchCtx, key)
+		if commonerrors.Any(err, commonerrors.ErrExists) {
+			// the entry was stored in the meantime.
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.fetch(ctx, key, destFilesystem, destPath)
+}
+
 func (c *Cache) Evict(ctx context.Context, key string) error {
 	if c.closed.Load() {
 		return closedErr
@@ -126,7 +319,9 @@ func (c *Cache) Evict(ctx context.Context, key string) error {
 			return err
 		}
 
-		c.entries.Delete(key)
+		c.evictEntry(key, entry)
+
+		return c.saveIndex(ctx)
 	}
 
 	return nil
@@ -141,6 +336,13 @@ func (c *Cache) Close(ctx context.Context) error {
 	c.entriesLM.Clear()
 	c.cancelStore.Cancel()
 
+	if c.index != nil {
+		// Entries are kept so that they can be reloaded by the next cache created on the same directory.
+		err := c.saveIndex(ctx)
+		c.entries.Clear()
+		return err
+	}
+
 	retryPolicy := retry.DefaultExponentialBackoffRetryPolicyConfiguration()
 	logger := logrimp.NewNoopLogger()
 
@@ -151,7 +353,7 @@ func (c *Cache) Close(ctx context.Context) error {
 				return commonerrors.WrapErrorf(commonerrors.ErrUnexpected, err, "could not delete '%s'", key)
 			}
 
-			c.entries.Delete(key)
+			c.removeEntry(key, entry)
 			return nil
 		}
 
@@ -168,7 +370,7 @@ func (c *Cache) Close(ctx context.Context) error {
 	return nil
 }
 
-func NewGenericFileCache(ctx context.Context, cacheFilesystem filesystem.FS, entryRetriever IEntryRetriever, config *FileCacheConfig) (IFileCache, error) {
+func NewGenericFileCache(ctx context.Context, cacheFilesystem filesystem.FS, entryRetriever IEntryRetriever, config *FileCacheConfig, options ...FileCacheOption) (IFileCache, error) {
 	if err := config.Validate(); err != nil {
 		return nil, commonerrors.WrapError(commonerrors.ErrInvalid, err, "invalid configuration")
 
@@ -194,6 +396,7 @@ func NewGenericFileCache(ctx context.Context, cacheFilesystem filesystem.FS, ent
 		return nil, err
 	}
 
+	opts := WithFileCacheOptions(options...)
 	cache := &Cache{
 		entries:       newEntryMap(),
 		entriesLM:     newLockMap(),
@@ -201,6 +404,16 @@ func NewGenericFileCache(ctx context.Context, cacheFilesystem filesystem.FS, ent
 		fs:            cacheFilesystem,
 		cfg:           config,
 		cancelStore:   cancelStore,
+		retrievals:    newFlightGroup(gcCtx),
+		observer:      opts.observer,
+	}
+
+	if config.PersistIndex {
+		cache.index = newEntryIndex(cacheFilesystem, config.CachePath)
+		if err := cache.loadIndex(ctx, opts.logger); err != nil {
+			stop()
+			return nil, err
+		}
 	}
 
 	parallelisation.SafeSchedule(gcCtx, cache.cfg.GarbageCollectionPeriod, 0, cache.gc)
@@ -2,17 +2,22 @@ package filecache
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-faker/faker/v4"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
 	"github.com/ARM-software/golang-utils/utils/commonerrors"
 	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
 	"github.com/ARM-software/golang-utils/utils/filesystem"
@@ -493,6 +498,466 @@ func TestFileCache_Cancel(t *testing.T) {
 	})
 }
 
+func TestFileCache_Eviction(t *testing.T) {
+	newCache := func(t *testing.T, configure func(config *FileCacheConfig), sizes ...int) (filesystem.FS, string, IFileCache) {
+		t.Helper()
+		fs := filesystem.NewFs(filesystem.InMemoryFS)
+		tmpDir := t.TempDir()
+		tmpSrcDir := filesystem.FilePathJoin(fs, tmpDir, "test-cache-src")
+		require.NoError(t, fs.MkDirAll(tmpSrcDir, 0755))
+		for i, size := range sizes {
+			require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, tmpSrcDir, fmt.Sprintf("entry-%v", i)), make([]byte, size), 0644))
+		}
+		tmpCacheDir := filesystem.FilePathJoin(fs, tmpDir, "test-cache")
+		require.NoError(t, fs.MkDirAll(tmpCacheDir, 0755))
+
+		config := DefaultFileCacheConfig()
+		config.CachePath = tmpCacheDir
+		configure(config)
+		cache, err := NewFsFileCache(context.Background(), fs, fs, tmpSrcDir, config)
+		require.NoError(t, err)
+		return fs, tmpCacheDir, cache
+	}
+	assertEntries := func(t *testing.T, ctx context.Context, cache IFileCache, present []string, absent []string) {
+		t.Helper()
+		for _, key := range present {
+			exists, err := cache.Has(ctx, key)
+			require.NoError(t, err)
+			require.True(t, exists, "entry %v should be in the cache", key)
+		}
+		for _, key := range absent {
+			exists, err := cache.Has(ctx, key)
+			require.NoError(t, err)
+			require.False(t, exists, "entry %v should have been evicted", key)
+		}
+	}
+
+	tests := []struct {
+		policy  EvictionPolicy
+		evicted string
+		kept    string
+	}{
+		{policy: LRUEvictionPolicy, evicted: "entry-0", kept: "entry-1"},
+		{policy: LFUEvictionPolicy, evicted: "entry-1", kept: "entry-0"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("max entries with %v policy", test.policy), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			ctx := context.Background()
+			fs, tmpCacheDir, cache := newCache(t, func(config *FileCacheConfig) {
+				config.MaxEntries = 2
+				config.EvictionPolicy = test.policy
+			}, 10, 10, 10)
+			defer func() { require.NoError(t, cache.Close(ctx)) }()
+			destDir := t.TempDir()
+
+			require.NoError(t, cache.Store(ctx, "entry-0"))
+			require.NoError(t, cache.Store(ctx, "entry-1"))
+			// entry-0 is used more often but entry-1 more recently.
+			require.NoError(t, cache.Fetch(ctx, "entry-0", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())))
+			require.NoError(t, cache.Fetch(ctx, "entry-0", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())))
+			require.NoError(t, cache.Fetch(ctx, "entry-1", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())))
+
+			require.NoError(t, cache.Store(ctx, "entry-2"))
+			assertEntries(t, ctx, cache, []string{"entry-2", test.kept}, []string{test.evicted})
+			require.False(t, fs.Exists(filesystem.FilePathJoin(fs, tmpCacheDir, test.evicted)))
+		})
+	}
+
+	t.Run("max size", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		_, _, cache := newCache(t, func(config *FileCacheConfig) {
+			config.MaxSize = 250
+		}, 100, 100, 100, 300)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+
+		require.NoError(t, cache.Store(ctx, "entry-0"))
+		require.NoError(t, cache.Store(ctx, "entry-1"))
+		require.NoError(t, cache.Store(ctx, "entry-2"))
+		assertEntries(t, ctx, cache, []string{"entry-1", "entry-2"}, []string{"entry-0"})
+
+		err := cache.Store(ctx, "entry-3")
+		errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+		assertEntries(t, ctx, cache, []string{"entry-1", "entry-2"}, []string{"entry-3"})
+	})
+
+	t.Run("directories are accounted for", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs := filesystem.NewFs(filesystem.InMemoryFS)
+		size, err := determineEntrySize(ctx, fs, "/does/not/exist")
+		errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		assert.Zero(t, size)
+
+		dir := filesystem.FilePathJoin(fs, t.TempDir(), "entry")
+		require.NoError(t, fs.MkDirAll(filesystem.FilePathJoin(fs, dir, "subdir"), 0755))
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, dir, "file"), make([]byte, 12), 0644))
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, dir, "subdir", "file"), make([]byte, 30), 0644))
+		size, err = determineEntrySize(ctx, fs, dir)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), size)
+	})
+}
+
+func TestFileCache_PersistentIndex(t *testing.T) {
+	setUp := func(t *testing.T) (fs filesystem.FS, srcDir, cacheDir string, config *FileCacheConfig) {
+		t.Helper()
+		fs = filesystem.NewStandardFileSystem()
+		tmpDir := t.TempDir()
+		srcDir = filesystem.FilePathJoin(fs, tmpDir, "test-cache-src")
+		require.NoError(t, fs.MkDir(srcDir))
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, srcDir, "file"), []byte(faker.Sentence()), 0644))
+		require.NoError(t, fs.MkDir(filesystem.FilePathJoin(fs, srcDir, "dir")))
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, srcDir, "dir", "file"), []byte(faker.Sentence()), 0644))
+		cacheDir = filesystem.FilePathJoin(fs, tmpDir, "test-cache")
+		require.NoError(t, fs.MkDir(cacheDir))
+		config = DefaultFileCacheConfig()
+		config.CachePath = cacheDir
+		config.PersistIndex = true
+		return
+	}
+	populate := func(t *testing.T, ctx context.Context, fs filesystem.FS, srcDir string, config *FileCacheConfig) {
+		t.Helper()
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		require.NoError(t, cache.Store(ctx, "file"))
+		require.NoError(t, cache.StoreWithTTL(ctx, "dir", time.Hour))
+		require.NoError(t, cache.Fetch(ctx, "file", fs, filesystem.FilePathJoin(fs, t.TempDir(), "file")))
+		require.NoError(t, cache.Close(ctx))
+		require.True(t, fs.Exists(filesystem.FilePathJoin(fs, config.CachePath, IndexFileName)))
+		require.True(t, fs.Exists(filesystem.FilePathJoin(fs, config.CachePath, "file")))
+		require.True(t, fs.Exists(filesystem.FilePathJoin(fs, config.CachePath, "dir", "file")))
+	}
+
+	t.Run("Entries survive restarts", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, srcDir, cacheDir, config := setUp(t)
+		populate(t, ctx, fs, srcDir, config)
+		require.NoError(t, fs.Rm(srcDir))
+
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+
+		for _, key := range []string{"file", "dir"} {
+			exists, err := cache.Has(ctx, key)
+			require.NoError(t, err)
+			require.True(t, exists)
+			require.NoError(t, cache.Fetch(ctx, key, fs, filesystem.FilePathJoin(fs, t.TempDir(), key)))
+		}
+		entry := cache.(*Cache).entries.Load("file")
+		require.NotNil(t, entry)
+		assert.Equal(t, uint64(2), determineAccessCount(entry))
+		assert.Positive(t, determineSize(entry))
+
+		require.NoError(t, cache.Evict(ctx, "dir"))
+		require.False(t, fs.Exists(filesystem.FilePathJoin(fs, cacheDir, "dir")))
+	})
+
+	t.Run("Modified entries are discarded", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, srcDir, cacheDir, config := setUp(t)
+		populate(t, ctx, fs, srcDir, config)
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, cacheDir, "dir", "file"), []byte("corrupted"), 0644))
+		require.NoError(t, fs.Rm(filesystem.FilePathJoin(fs, cacheDir, "file")))
+
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+
+		exists, err := cache.Has(ctx, "dir")
+		require.NoError(t, err)
+		assert.False(t, exists)
+		assert.False(t, fs.Exists(filesystem.FilePathJoin(fs, cacheDir, "dir")))
+		err = cache.Fetch(ctx, "file", fs, filesystem.FilePathJoin(fs, t.TempDir(), "file"))
+		errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		exists, err = cache.Has(ctx, "file")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		// entries can be stored again
+		require.NoError(t, cache.Store(ctx, "file"))
+	})
+
+	t.Run("Entries outside the cache are discarded", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, srcDir, cacheDir, config := setUp(t)
+		populate(t, ctx, fs, srcDir, config)
+		indexPath := filesystem.FilePathJoin(fs, cacheDir, IndexFileName)
+		content, err := fs.ReadFile(indexPath)
+		require.NoError(t, err)
+		var index persistedIndex
+		require.NoError(t, json.Unmarshal(content, &index))
+		victims := []string{srcDir, filesystem.FilePathJoin(fs, cacheDir, "..", "test-cache-src", "file")}
+		for i := range index.Entries {
+			index.Entries[i].Path = victims[i%len(victims)]
+			index.Entries[i].Expiration = time.Now().Add(-time.Hour)
+		}
+		content, err = json.Marshal(&index)
+		require.NoError(t, err)
+		require.NoError(t, fs.WriteFile(indexPath, content, 0644))
+
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		for _, key := range []string{"file", "dir"} {
+			exists, err := cache.Has(ctx, key)
+			require.NoError(t, err)
+			assert.False(t, exists)
+		}
+		require.NoError(t, cache.Close(ctx))
+		assert.True(t, fs.Exists(filesystem.FilePathJoin(fs, srcDir, "file")))
+	})
+
+	t.Run("Invalid index", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, srcDir, cacheDir, config := setUp(t)
+		populate(t, ctx, fs, srcDir, config)
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, cacheDir, IndexFileName), []byte(faker.Sentence()), 0644))
+
+		// The cache starts afresh and the entries it can no longer account for are discarded.
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config, WithLogger(logr.Discard()))
+		require.NoError(t, err)
+		exists, err := cache.Has(ctx, "file")
+		require.NoError(t, err)
+		assert.False(t, exists)
+		assert.False(t, fs.Exists(filesystem.FilePathJoin(fs, cacheDir, "file")))
+		require.NoError(t, cache.Store(ctx, "file"))
+		require.NoError(t, cache.Close(ctx))
+	})
+
+	t.Run("Index of a different version", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, srcDir, cacheDir, config := setUp(t)
+		populate(t, ctx, fs, srcDir, config)
+		content, err := json.Marshal(&persistedIndex{Version: indexVersion + 1})
+		require.NoError(t, err)
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, cacheDir, IndexFileName), content, 0644))
+
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		exists, err := cache.Has(ctx, "dir")
+		require.NoError(t, err)
+		assert.False(t, exists)
+		assert.False(t, fs.Exists(filesystem.FilePathJoin(fs, cacheDir, "dir")))
+		require.NoError(t, cache.Close(ctx))
+	})
+
+	t.Run("Capacity limits apply to reloaded entries", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, srcDir, _, config := setUp(t)
+		populate(t, ctx, fs, srcDir, config)
+
+		config.MaxEntries = 1
+		cache, err := NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), cache.(*Cache).entryCount.Load())
+		require.NoError(t, cache.Close(ctx))
+
+		config.MaxEntries = 0
+		config.MaxSize = 1
+		cache, err = NewFsFileCache(ctx, fs, fs, srcDir, config)
+		require.NoError(t, err)
+		assert.Zero(t, cache.(*Cache).entryCount.Load())
+		require.NoError(t, cache.Close(ctx))
+	})
+}
+
+type blockingEntryRetriever struct {
+	FsEntryRetriever
+	calls   atomic.Int64
+	release chan struct{}
+	err     error
+	// ignoreCancellation makes retrievals wait for the release even if cancelled.
+	ignoreCancellation bool
+	running            atomic.Int64
+	overlapped         atomic.Bool
+}
+
+func (r *blockingEntryRetriever) FetchEntry(ctx context.Context, key string) (string, error) {
+	if r.running.Add(1) > 1 {
+		r.overlapped.Store(true)
+	}
+	defer r.running.Add(-1)
+	r.calls.Add(1)
+	if r.ignoreCancellation {
+		<-r.release
+	} else {
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+			return "", commonerrors.ErrCancelled
+		}
+	}
+	if r.err != nil {
+		return "", r.err
+	}
+	return r.FsEntryRetriever.FetchEntry(ctx, key)
+}
+
+func TestFileCache_GetOrFetch(t *testing.T) {
+	newCache := func(t *testing.T, retrieverErr error) (filesystem.FS, *blockingEntryRetriever, IFileCache) {
+		t.Helper()
+		fs := filesystem.NewStandardFileSystem()
+		tmpDir := t.TempDir()
+		srcDir := filesystem.FilePathJoin(fs, tmpDir, "test-cache-src")
+		require.NoError(t, fs.MkDir(srcDir))
+		require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, srcDir, "file"), []byte(faker.Sentence()), 0644))
+		cacheDir := filesystem.FilePathJoin(fs, tmpDir, "test-cache")
+		require.NoError(t, fs.MkDir(cacheDir))
+		retriever := &blockingEntryRetriever{
+			FsEntryRetriever: FsEntryRetriever{fs: fs, basePath: srcDir},
+			release:          make(chan struct{}),
+			err:              retrieverErr,
+		}
+		config := DefaultFileCacheConfig()
+		config.CachePath = cacheDir
+		cache, err := NewGenericFileCache(context.Background(), fs, retriever, config)
+		require.NoError(t, err)
+		return fs, retriever, cache
+	}
+	waitForRetrieval := func(t *testing.T, retriever *blockingEntryRetriever) {
+		t.Helper()
+		require.Eventually(t, func() bool { return retriever.calls.Load() > 0 }, 5*time.Second, time.Millisecond)
+	}
+
+	t.Run("Concurrent misses are coalesced", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, retriever, cache := newCache(t, nil)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+		destDir := t.TempDir()
+
+		g, gCtx := errgroup.WithContext(ctx)
+		for i := 0; i < 20; i++ {
+			dest := filesystem.FilePathJoin(fs, destDir, fmt.Sprintf("file-%v", i))
+			g.Go(func() error {
+				return cache.GetOrFetch(gCtx, "file", fs, dest)
+			})
+		}
+		waitForRetrieval(t, retriever)
+		time.Sleep(10 * time.Millisecond)
+		close(retriever.release)
+		require.NoError(t, g.Wait())
+		assert.Equal(t, int64(1), retriever.calls.Load())
+		files, err := fs.Ls(destDir)
+		require.NoError(t, err)
+		assert.Len(t, files, 20)
+
+		// Hits do not trigger any retrieval.
+		require.NoError(t, cache.GetOrFetch(ctx, "file", fs, filesystem.FilePathJoin(fs, destDir, "hit")))
+		assert.Equal(t, int64(1), retriever.calls.Load())
+	})
+
+	t.Run("Errors are shared", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, retriever, cache := newCache(t, commonerrors.ErrUnavailable)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+		destDir := t.TempDir()
+
+		errs := make(chan error, 5)
+		for i := 0; i < 5; i++ {
+			go func() {
+				errs <- cache.GetOrFetch(ctx, "file", fs, filesystem.FilePathJoin(fs, destDir, faker.Word()))
+			}()
+		}
+		waitForRetrieval(t, retriever)
+		time.Sleep(10 * time.Millisecond)
+		close(retriever.release)
+		for i := 0; i < 5; i++ {
+			errortest.AssertError(t, <-errs, commonerrors.ErrUnavailable)
+		}
+		assert.Equal(t, int64(1), retriever.calls.Load())
+		exists, err := cache.Has(ctx, "file")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Callers' contexts are independent", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, retriever, cache := newCache(t, nil)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+		dest := filesystem.FilePathJoin(fs, t.TempDir(), "file")
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancelledErr := make(chan error, 1)
+		go func() {
+			cancelledErr <- cache.GetOrFetch(cancelledCtx, "file", fs, filesystem.FilePathJoin(fs, t.TempDir(), "file"))
+		}()
+		waitForRetrieval(t, retriever)
+		fetchErr := make(chan error, 1)
+		go func() {
+			fetchErr <- cache.GetOrFetch(ctx, "file", fs, dest)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		errortest.AssertError(t, <-cancelledErr, commonerrors.ErrCancelled)
+		close(retriever.release)
+		require.NoError(t, <-fetchErr)
+		assert.True(t, fs.Exists(dest))
+		assert.Equal(t, int64(1), retriever.calls.Load())
+	})
+
+	t.Run("Retrievals are cancelled when nobody waits", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, retriever, cache := newCache(t, nil)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancelledErr := make(chan error, 1)
+		go func() {
+			cancelledErr <- cache.GetOrFetch(cancelledCtx, "file", fs, filesystem.FilePathJoin(fs, t.TempDir(), "file"))
+		}()
+		waitForRetrieval(t, retriever)
+		cancel()
+		errortest.AssertError(t, <-cancelledErr, commonerrors.ErrCancelled)
+
+		// A new retrieval is started by subsequent callers.
+		close(retriever.release)
+		require.NoError(t, cache.GetOrFetch(ctx, "file", fs, filesystem.FilePathJoin(fs, t.TempDir(), "file")))
+		assert.Equal(t, int64(2), retriever.calls.Load())
+	})
+
+	t.Run("Abandoned retrievals do not overlap with new ones", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		fs, retriever, cache := newCache(t, nil)
+		defer func() { require.NoError(t, cache.Close(ctx)) }()
+		retriever.ignoreCancellation = true
+
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancelledErr := make(chan error, 1)
+		go func() {
+			cancelledErr <- cache.GetOrFetch(cancelledCtx, "file", fs, filesystem.FilePathJoin(fs, t.TempDir(), "file"))
+		}()
+		waitForRetrieval(t, retriever)
+		cancel()
+		errortest.AssertError(t, <-cancelledErr, commonerrors.ErrCancelled)
+
+		dest := filesystem.FilePathJoin(fs, t.TempDir(), "file")
+		fetchErr := make(chan error, 1)
+		go func() {
+			fetchErr <- cache.GetOrFetch(ctx, "file", fs, dest)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		// The abandoned retrieval is still in progress.
+		assert.Equal(t, int64(1), retriever.calls.Load())
+		close(retriever.release)
+		require.NoError(t, <-fetchErr)
+		assert.True(t, fs.Exists(dest))
+		assert.False(t, retriever.overlapped.Load())
+	})
+}
+
 func TestFileCache_Concurent_Caches(t *testing.T) {
 	t.Run("Caches", func(t *testing.T) {
 		defer goleak.VerifyNone(t)
@@ -724,3 +1189,42 @@ func TestFileCache_Concurent_Caches(t *testing.T) {
 		require.Equal(t, actualHash, expectedHash, "the copied file got corrupted")
 	})
 }
+
+func TestFileCache_Metrics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	fs := filesystem.NewFs(filesystem.InMemoryFS)
+	tmpDir := t.TempDir()
+	tmpSrcDir := filesystem.FilePathJoin(fs, tmpDir, "test-cache-src")
+	require.NoError(t, fs.MkDirAll(tmpSrcDir, 0755))
+	require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, tmpSrcDir, "entry-0"), make([]byte, 10), 0644))
+	require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, tmpSrcDir, "entry-1"), make([]byte, 20), 0644))
+	config := DefaultFileCacheConfig()
+	config.CachePath = filesystem.FilePathJoin(fs, tmpDir, "test-cache")
+	require.NoError(t, fs.MkDirAll(config.CachePath, 0755))
+	config.MaxEntries = 1
+	observer := metrics.NewInMemoryObserver()
+	cache, err := NewFsFileCache(ctx, fs, fs, tmpSrcDir, config, WithObserver(observer))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, cache.Close(ctx)) }()
+	destDir := t.TempDir()
+
+	errortest.AssertError(t, cache.Fetch(ctx, "entry-0", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())), commonerrors.ErrNotFound)
+	require.NoError(t, cache.Store(ctx, "entry-0"))
+	require.NoError(t, cache.Fetch(ctx, "entry-0", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())))
+	// retrieving entry-1 evicts entry-0
+	require.NoError(t, cache.GetOrFetch(ctx, "entry-1", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())))
+	require.NoError(t, cache.GetOrFetch(ctx, "entry-1", fs, filesystem.FilePathJoin(fs, destDir, faker.Word())))
+	require.NoError(t, cache.Evict(ctx, "entry-1"))
+
+	snapshot := observer.Snapshot()
+	assert.Equal(t, uint64(2), snapshot.Hits)
+	assert.Equal(t, uint64(2), snapshot.Misses)
+	assert.Equal(t, uint64(2), snapshot.Stores)
+	assert.Equal(t, int64(30), snapshot.BytesStored)
+	assert.Equal(t, uint64(2), snapshot.Evictions)
+	assert.Equal(t, int64(30), snapshot.BytesEvicted)
+	assert.Equal(t, uint64(4), snapshot.Fetches)
+	assert.Equal(t, uint64(1), snapshot.FailedFetches)
+	assert.Positive(t, snapshot.TotalFetchLatency)
+}
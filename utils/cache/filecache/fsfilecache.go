@@ -56,7 +56,7 @@ func (p *FsEntryRetriever) SetCacheDir(cacheFs filesystem.FS, cacheDir string) e
 	return nil
 }
 
-func NewFsFileCache(ctx context.Context, srcFilesystem, cacheFilesystem filesystem.FS, basePath string, config *FileCacheConfig) (IFileCache, error) {
+func NewFsFileCache(ctx context.Context, srcFilesystem, cacheFilesystem filesystem.FS, basePath string, config *FileCacheConfig, options ...FileCacheOption) (IFileCache, error) {
 	fsProvider := &FsEntryRetriever{
 		fs:       srcFilesystem,
 		basePath: basePath,
@@ -66,5 +66,5 @@ func NewFsFileCache(ctx context.Context, srcFilesystem, cacheFilesystem filesyst
 		return nil, err
 	}
 
-	return NewGenericFileCache(ctx, cacheFilesystem, fsProvider, config)
+	return NewGenericFileCache(ctx, cacheFilesystem, fsProvider, config, options...)
 }
@@ -0,0 +1,434 @@
+package filecache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	httpUtils "github.com/ARM-software/golang-utils/utils/http"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+	"github.com/ARM-software/golang-utils/utils/safeio"
+)
+
+const (
+	// KeyPlaceholder is the placeholder which is replaced by the entry key in the URL template of an HTTPEntryRetriever.
+	KeyPlaceholder        = "{key}"
+	partialDownloadSuffix = ".part"
+	maxSidecarSize        = 1024
+)
+
+// HTTPEntryRetrieverOptions defines how entries are downloaded by an HTTPEntryRetriever.
+type HTTPEntryRetrieverOptions struct {
+	// checksumSidecarSuffix is the suffix to append to an entry URL to retrieve its checksum.
+	checksumSidecarSuffix string
+	// checksumHeader is the response header holding the checksum of an entry.
+	checksumHeader    string
+	checksumAlgorithm string
+	limits            filesystem.ILimits
+	retryPolicy       *httpUtils.RetryPolicyConfiguration
+}
+
+// HTTPEntryRetrieverOption configures HTTPEntryRetrieverOptions.
+type HTTPEntryRetrieverOption func(*HTTPEntryRetrieverOptions) *HTTPEntryRetrieverOptions
+
+// DefaultHTTPEntryRetrieverOptions returns the default options i.e. no limits apply, checksums are not verified and failed downloads are retried considering any `Retry-After` header returned by the server.
+func DefaultHTTPEntryRetrieverOptions() *HTTPEntryRetrieverOptions {
+	return &HTTPEntryRetrieverOptions{
+		checksumAlgorithm: hashing.HashSha256,
+		limits:            filesystem.NoLimits(),
+		retryPolicy:       httpUtils.DefaultRobustRetryPolicyConfiguration(),
+	}
+}
+
+// WithHTTPEntryRetrieverOptions returns the options resulting from applying options to the defaults.
+func WithHTTPEntryRetrieverOptions(options ...HTTPEntryRetrieverOption) (opts *HTTPEntryRetrieverOptions) {
+	opts = DefaultHTTPEntryRetrieverOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithChecksumSidecar verifies downloaded entries against the checksum found at the entry URL followed by `suffix` (e.g. `.sha256`).
+// The sidecar file is expected to follow the format of tools such as `sha256sum` i.e. to start with the checksum.
+func WithChecksumSidecar(suffix string) HTTPEntryRetrieverOption {
+	return func(o *HTTPEntryRetrieverOptions) *HTTPEntryRetrieverOptions {
+		if o == nil {
+			o = DefaultHTTPEntryRetrieverOptions()
+		}
+		o.checksumSidecarSuffix = suffix
+		return o
+	}
+}
+
+// WithChecksumHeader verifies downloaded entries against the checksum returned by the server in the `header` response header. It takes precedence over any sidecar file.
+func WithChecksumHeader(header string) HTTPEntryRetrieverOption {
+	return func(o *HTTPEntryRetrieverOptions) *HTTPEntryRetrieverOptions {
+		if o == nil {
+			o = DefaultHTTPEntryRetrieverOptions()
+		}
+		o.checksumHeader = header
+		return o
+	}
+}
+
+// WithChecksumAlgorithm sets the hashing algorithm (see hashing.SupportedHashingAlgorithms) used for the checksums. SHA256 is used by default.
+func WithChecksumAlgorithm(algorithm string) HTTPEntryRetrieverOption {
+	return func(o *HTTPEntryRetrieverOptions) *HTTPEntryRetrieverOptions {
+		if o == nil {
+			o = DefaultHTTPEntryRetrieverOptions()
+		}
+		o.checksumAlgorithm = algorithm
+		return o
+	}
+}
+
+// WithDownloadLimits sets the limits to enforce on downloads. Only the maximum file size is considered.
+func WithDownloadLimits(limits filesystem.ILimits) HTTPEntryRetrieverOption {
+	return func(o *HTTPEntryRetrieverOptions) *HTTPEntryRetrieverOptions {
+		if o == nil {
+			o = DefaultHTTPEntryRetrieverOptions()
+		}
+		o.limits = limits
+		return o
+	}
+}
+
+// WithDownloadRetryPolicy sets the policy applied when downloads fail because of the server being unavailable or the connection being interrupted.
+func WithDownloadRetryPolicy(policy *httpUtils.RetryPolicyConfiguration) HTTPEntryRetrieverOption {
+	return func(o *HTTPEntryRetrieverOptions) *HTTPEntryRetrieverOptions {
+		if o == nil {
+			o = DefaultHTTPEntryRetrieverOptions()
+		}
+		o.retryPolicy = policy
+		return o
+	}
+}
+
+// HTTPEntryRetriever implements IEntryRetriever by downloading files from a server, the URL of an entry being determined by replacing KeyPlaceholder in a URL template with the entry key.
+// Interrupted downloads are resumed using range requests when retried.
+type HTTPEntryRetriever struct {
+	client        httpUtils.IClient
+	urlTemplate   string
+	options       *HTTPEntryRetrieverOptions
+	cachefs       filesystem.FS
+	cacheBasePath string
+}
+
+// download holds the state of an entry download across attempts.
+type download struct {
+	url  string
+	path string
+	// validator is the ETag or last modification date of the entry, used to ensure a download is resumed only if the entry has not changed.
+	validator        string
+	expectedChecksum string
+}
+
+// NewHTTPEntryRetriever returns an entry retriever downloading entries using `client` from URLs following `urlTemplate` e.g. `https://example.com/artefacts/{key}`.
+func NewHTTPEntryRetriever(client httpUtils.IClient, urlTemplate string, options ...HTTPEntryRetrieverOption) (*HTTPEntryRetriever, error) {
+	if client == nil {
+		return nil, commonerrors.UndefinedVariable("HTTP client")
+	}
+	if !strings.Contains(urlTemplate, KeyPlaceholder) {
+		return nil, commonerrors.Newf(commonerrors.ErrInvalid, "URL template '%s' does not contain the key placeholder %v", urlTemplate, KeyPlaceholder)
+	}
+	opts := WithHTTPEntryRetrieverOptions(options...)
+	if _, err := filesystem.NewFileHash(opts.checksumAlgorithm); err != nil {
+		return nil, err
+	}
+	return &HTTPEntryRetriever{
+		client:      client,
+		urlTemplate: urlTemplate,
+		options:     opts,
+	}, nil
+}
+
+// FetchEntry downloads the entry identified by `key` into the cache directory and returns its absolute path.
+func (r *HTTPEntryRetriever) FetchEntry(ctx context.Context, key string) (string, error) {
+	if r.cachefs == nil {
+		return "", commonerrors.New(commonerrors.ErrUndefined, "the cache directory has not been set")
+	}
+
+	destPath := filesystem.FilePathJoin(r.cachefs, r.cacheBasePath, key)
+	d := &download{
+		url:  r.entryURL(key),
+		path: fmt.Sprintf("%v%v", destPath, partialDownloadSuffix),
+	}
+	if err := r.cachefs.MkDirAll(filesystem.FilePathDir(r.cachefs, destPath), 0755); err != nil {
+		return "", err
+	}
+	// Any leftover from a previous download cannot be trusted.
+	if err := r.cachefs.Rm(d.path); err != nil {
+		return "", err
+	}
+
+	err := r.retry(ctx, func() (*http.Response, error) {
+		return r.downloadChunk(ctx, d)
+	})
+	if err == nil {
+		err = r.verifyChecksum(ctx, d)
+	}
+	if err == nil {
+		err = r.cachefs.Move(d.path, destPath)
+	}
+	if err != nil {
+		_ = r.cachefs.Rm(d.path)
+		return "", err
+	}
+
+	return destPath, nil
+}
+
+func (r *HTTPEntryRetriever) SetCacheDir(cacheFs filesystem.FS, cacheDir string) error {
+	if cacheFs == nil {
+		return commonerrors.New(commonerrors.ErrUndefined, "the cache filesystem cannot be nil")
+	}
+
+	if !cacheFs.Exists(cacheDir) {
+		return commonerrors.Newf(commonerrors.ErrNotFound, "cannot access '%s', No such file or directory", cacheDir)
+	}
+
+	r.cachefs = cacheFs
+	r.cacheBasePath = cacheDir
+
+	return nil
+}
+
+func (r *HTTPEntryRetriever) entryURL(key string) string {
+	segments := strings.Split(strings.ReplaceAll(key, "\\", "/"), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.ReplaceAll(r.urlTemplate, KeyPlaceholder, strings.Join(segments, "/"))
+}
+
+// retry performs `action` until it succeeds, fails with an error which is not transient or the retry policy is exhausted.
+func (r *HTTPEntryRetriever) retry(ctx context.Context, action func() (*http.Response, error)) (err error) {
+	policy := r.options.retryPolicy
+	attempts := 1
+	if policy != nil && policy.Enabled && policy.RetryMax > 1 {
+		attempts = policy.RetryMax
+	}
+	waitPolicy := httpUtils.BackOffPolicyFactory(policy)
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		resp, err = action()
+		if err == nil || attempt+1 >= attempts || !commonerrors.Any(err, commonerrors.ErrUnavailable) {
+			return
+		}
+		parallelisation.SleepWithContext(ctx, waitPolicy.Apply(policy.RetryWaitMin, policy.RetryWaitMax, attempt, resp))
+		if ctxErr := parallelisation.DetermineContextError(ctx); ctxErr != nil {
+			err = ctxErr
+			return
+		}
+	}
+}
+
+func (r *HTTPEntryRetriever) get(ctx context.Context, location string, header http.Header) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrInvalid, err, "invalid URL '%s'", location)
+		return
+	}
+	for name := range header {
+		req.Header.Set(name, header.Get(name))
+	}
+	resp, err = r.client.Do(req) //nolint:bodyclose // the body is closed by the callers
+	if err != nil {
+		if ctxErr := parallelisation.DetermineContextError(ctx); ctxErr != nil {
+			err = ctxErr
+			return
+		}
+		err = commonerrors.WrapErrorf(commonerrors.ErrUnavailable, err, "could not download '%s'", location)
+	}
+	return
+}
+
+// checkResponseStatus converts error statuses into errors. Statuses for which a later attempt may succeed are converted to commonerrors.ErrUnavailable.
+func checkResponseStatus(resp *http.Response, location string) error {
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return commonerrors.Newf(commonerrors.ErrNotFound, "'%s' could not be found (%v)", location, resp.Status)
+	case resp.StatusCode == http.StatusUnauthorized:
+		return commonerrors.Newf(commonerrors.ErrUnauthorised, "not authorised to download '%s' (%v)", location, resp.Status)
+	case resp.StatusCode == http.StatusForbidden:
+		return commonerrors.Newf(commonerrors.ErrForbidden, "not allowed to download '%s' (%v)", location, resp.Status)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= http.StatusInternalServerError:
+		return commonerrors.Newf(commonerrors.ErrUnavailable, "could not download '%s' (%v)", location, resp.Status)
+	default:
+		return commonerrors.Newf(commonerrors.ErrUnexpected, "could not download '%s' (%v)", location, resp.Status)
+	}
+}
+
+// downloadChunk downloads the entry, or the rest of it if it was partially downloaded already.
+func (r *HTTPEntryRetriever) downloadChunk(ctx context.Context, d *download) (resp *http.Response, err error) {
+	offset := int64(0)
+	if r.cachefs.Exists(d.path) {
+		offset, err = r.cachefs.GetFileSize(d.path)
+		if err != nil {
+			return
+		}
+	}
+	header := http.Header{}
+	if offset > 0 {
+		header.Set(headers.Range, fmt.Sprintf("bytes=%d-", offset))
+		if d.validator != "" {
+			header.Set(headers.IfRange, d.validator)
+		}
+	}
+	resp, err = r.get(ctx, d.url, header)
+	if err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the entry must have changed: starting again.
+		err = commonerrors.Join(commonerrors.ErrUnavailable, r.cachefs.Rm(d.path))
+		return
+	case resp.StatusCode == http.StatusPartialContent && !strings.HasPrefix(resp.Header.Get(headers.ContentRange), fmt.Sprintf("bytes %d-", offset)):
+		err = commonerrors.Join(commonerrors.Newf(commonerrors.ErrUnavailable, "unexpected range '%v' returned for '%s'", resp.Header.Get(headers.ContentRange), d.url), r.cachefs.Rm(d.path))
+		return
+	case resp.StatusCode == http.StatusOK:
+		// the whole entry is returned (e.g. range requests are not supported or the entry changed).
+		offset = 0
+	}
+	err = checkResponseStatus(resp, d.url)
+	if err != nil {
+		return
+	}
+
+	d.recordResponse(resp, r.options.checksumHeader)
+
+	maxSize := int64(-1)
+	if r.options.limits != nil && r.options.limits.Apply() && r.options.limits.GetMaxFileSize() >= 0 {
+		maxSize = r.options.limits.GetMaxFileSize()
+	}
+	if maxSize >= 0 && resp.ContentLength >= 0 && offset+resp.ContentLength > maxSize {
+		err = commonerrors.Newf(commonerrors.ErrTooLarge, "'%s' is larger (%v bytes) than the maximum size allowed (%v bytes)", d.url, offset+resp.ContentLength, maxSize)
+		return
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if offset == 0 {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := r.cachefs.OpenFile(d.path, flags, 0644)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	var body io.Reader = resp.Body
+	if maxSize >= 0 {
+		body = io.LimitReader(resp.Body, maxSize-offset+1)
+	}
+	written, err := safeio.CopyDataWithContext(ctx, body, f)
+	if maxSize >= 0 && offset+written > maxSize {
+		err = commonerrors.Newf(commonerrors.ErrTooLarge, "'%s' is larger than the maximum size allowed (%v bytes)", d.url, maxSize)
+		return
+	}
+	if err != nil {
+		if commonerrors.Any(err, commonerrors.ErrCancelled, commonerrors.ErrTimeout) {
+			return
+		}
+		err = commonerrors.WrapErrorf(commonerrors.ErrUnavailable, err, "download of '%s' was interrupted", d.url)
+		return
+	}
+	if resp.ContentLength >= 0 && written < resp.ContentLength {
+		err = commonerrors.Newf(commonerrors.ErrUnavailable, "download of '%s' was interrupted after %v bytes out of %v", d.url, written, resp.ContentLength)
+	}
+	return
+}
+
+func (d *download) recordResponse(resp *http.Response, checksumHeader string) {
+	if validator := resp.Header.Get(headers.ETag); validator != "" {
+		d.validator = validator
+	} else if validator := resp.Header.Get(headers.LastModified); validator != "" {
+		d.validator = validator
+	}
+	if checksumHeader != "" {
+		if checksum := strings.TrimSpace(resp.Header.Get(checksumHeader)); checksum != "" {
+			d.expectedChecksum = checksum
+		}
+	}
+}
+
+func (r *HTTPEntryRetriever) fetchSidecarChecksum(ctx context.Context, location string) (resp *http.Response, checksum string, err error) {
+	resp, err = r.get(ctx, location, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	err = checkResponseStatus(resp, location)
+	if err != nil {
+		return
+	}
+	content, err := safeio.ReadAtMost(ctx, resp.Body, maxSidecarSize, -1)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrUnavailable, err, "could not read '%s'", location)
+		return
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "'%s' does not contain any checksum", location)
+		return
+	}
+	checksum = fields[0]
+	return
+}
+
+// verifyChecksum checks the downloaded entry against its expected checksum, if any can be determined.
+func (r *HTTPEntryRetriever) verifyChecksum(ctx context.Context, d *download) (err error) {
+	expected := d.expectedChecksum
+	if expected == "" && r.options.checksumSidecarSuffix != "" {
+		sidecarURL := fmt.Sprintf("%v%v", d.url, r.options.checksumSidecarSuffix)
+		err = r.retry(ctx, func() (resp *http.Response, err error) {
+			resp, expected, err = r.fetchSidecarChecksum(ctx, sidecarURL)
+			return
+		})
+		if err != nil {
+			return
+		}
+	}
+	if expected == "" {
+		return
+	}
+	hasher, err := filesystem.NewFileHash(r.options.checksumAlgorithm)
+	if err != nil {
+		return
+	}
+	actual, err := hasher.CalculateFileWithContext(ctx, r.cachefs, d.path)
+	if err != nil {
+		return
+	}
+	if !strings.EqualFold(actual, expected) {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "checksum of '%s' (%v) does not match the expected checksum (%v)", d.url, actual, expected)
+	}
+	return
+}
+
+// NewHTTPFileCache returns a file cache whose entries are downloaded from URLs following `urlTemplate` (see HTTPEntryRetriever).
+// `retrieverOptions` configure how entries are downloaded whereas `options` configure the cache itself.
+func NewHTTPFileCache(ctx context.Context, client httpUtils.IClient, urlTemplate string, cacheFilesystem filesystem.FS, config *FileCacheConfig, retrieverOptions []HTTPEntryRetrieverOption, options ...FileCacheOption) (IFileCache, error) {
+	retriever, err := NewHTTPEntryRetriever(client, urlTemplate, retrieverOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGenericFileCache(ctx, cacheFilesystem, retriever, config, options...)
+}
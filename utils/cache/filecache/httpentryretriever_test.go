@@ -0,0 +1,243 @@
+package filecache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	httpUtils "github.com/ARM-software/golang-utils/utils/http"
+)
+
+func newTestHTTPEntryRetriever(t *testing.T, client httpUtils.IClient, server *httptest.Server, options ...HTTPEntryRetrieverOption) (*HTTPEntryRetriever, filesystem.FS, string) {
+	t.Helper()
+	retriever, err := NewHTTPEntryRetriever(client, fmt.Sprintf("%v/files/%v", server.URL, KeyPlaceholder), options...)
+	require.NoError(t, err)
+	cacheFs := filesystem.NewStandardFileSystem()
+	cacheDir := t.TempDir()
+	require.NoError(t, retriever.SetCacheDir(cacheFs, cacheDir))
+	return retriever, cacheFs, cacheDir
+}
+
+func fastRetryPolicy() *httpUtils.RetryPolicyConfiguration {
+	policy := httpUtils.DefaultRobustRetryPolicyConfiguration()
+	policy.RetryWaitMin = time.Millisecond
+	policy.RetryWaitMax = 10 * time.Millisecond
+	return policy
+}
+
+func TestNewHTTPEntryRetriever(t *testing.T) {
+	client := httpUtils.NewPlainHTTPClient()
+	defer func() { _ = client.Close() }()
+
+	_, err := NewHTTPEntryRetriever(nil, "http://example.com/{key}")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewHTTPEntryRetriever(client, "http://example.com/files")
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = NewHTTPEntryRetriever(client, "http://example.com/{key}", WithChecksumAlgorithm(faker.Word()))
+	assert.Error(t, err)
+
+	retriever, err := NewHTTPEntryRetriever(client, "http://example.com/files/{key}?version=1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/files/a%20b/c?version=1", retriever.entryURL("a b/c"))
+	_, err = retriever.FetchEntry(context.Background(), faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+}
+
+func TestHTTPEntryRetriever_FetchEntry(t *testing.T) {
+	content := []byte(strings.Repeat(faker.Paragraph(), 10))
+	sha, err := hashing.NewHashingAlgorithm(hashing.HashSha256)
+	require.NoError(t, err)
+	checksum, err := sha.Calculate(bytes.NewReader(content))
+	require.NoError(t, err)
+
+	t.Run("download with checksum sidecar", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/files/dir/test.bin":
+				http.ServeContent(w, r, "test.bin", time.Now(), bytes.NewReader(content))
+			case "/files/dir/test.bin.sha256":
+				_, _ = fmt.Fprintf(w, "%v  test.bin\n", checksum)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+		client := httpUtils.NewPlainHTTPClient()
+		defer func() { _ = client.Close() }()
+		retriever, cacheFs, cacheDir := newTestHTTPEntryRetriever(t, client, server, WithChecksumSidecar(".sha256"))
+
+		path, err := retriever.FetchEntry(context.Background(), "dir/test.bin")
+		require.NoError(t, err)
+		assert.Equal(t, filesystem.FilePathJoin(cacheFs, cacheDir, "dir", "test.bin"), path)
+		actual, err := cacheFs.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, actual)
+		assert.False(t, cacheFs.Exists(path+partialDownloadSuffix))
+
+		_, err = retriever.FetchEntry(context.Background(), "missing.bin")
+		errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Checksum-Sha256", strings.Repeat("0", len(checksum)))
+			http.ServeContent(w, r, "test.bin", time.Now(), bytes.NewReader(content))
+		}))
+		defer server.Close()
+		client := httpUtils.NewPlainHTTPClient()
+		defer func() { _ = client.Close() }()
+		retriever, cacheFs, cacheDir := newTestHTTPEntryRetriever(t, client, server, WithChecksumHeader("X-Checksum-Sha256"))
+
+		_, err := retriever.FetchEntry(context.Background(), "test.bin")
+		errortest.AssertError(t, err, commonerrors.ErrInvalid)
+		empty, err := cacheFs.IsEmpty(cacheDir)
+		require.NoError(t, err)
+		assert.True(t, empty)
+	})
+
+	t.Run("retry when unavailable", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.Header().Set(headers.RetryAfter, "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("X-Checksum-Sha256", checksum)
+			http.ServeContent(w, r, "test.bin", time.Now(), bytes.NewReader(content))
+		}))
+		defer server.Close()
+		client := httpUtils.NewPlainHTTPClient()
+		defer func() { _ = client.Close() }()
+		retriever, cacheFs, _ := newTestHTTPEntryRetriever(t, client, server, WithChecksumHeader("X-Checksum-Sha256"), WithDownloadRetryPolicy(fastRetryPolicy()))
+
+		path, err := retriever.FetchEntry(context.Background(), "test.bin")
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+		actual, err := cacheFs.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, actual)
+
+		calls.Store(0)
+		retriever.options.retryPolicy = httpUtils.DefaultNoRetryPolicyConfiguration()
+		_, err = retriever.FetchEntry(context.Background(), "test.bin")
+		errortest.AssertError(t, err, commonerrors.ErrUnavailable)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("resume interrupted download", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		var (
+			calls        atomic.Int32
+			rangeResumed atomic.Bool
+		)
+		modTime := time.Now().Add(-time.Hour)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Checksum-Sha256", checksum)
+			if calls.Add(1) == 1 {
+				w.Header().Set(headers.LastModified, modTime.UTC().Format(http.TimeFormat))
+				w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", len(content)))
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(content[:len(content)/2])
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+				panic(http.ErrAbortHandler)
+			}
+			rangeResumed.Store(r.Header.Get(headers.Range) == fmt.Sprintf("bytes=%d-", len(content)/2))
+			http.ServeContent(w, r, "test.bin", modTime, bytes.NewReader(content))
+		}))
+		defer server.Close()
+		client := httpUtils.NewPlainHTTPClient()
+		defer func() { _ = client.Close() }()
+		retriever, cacheFs, _ := newTestHTTPEntryRetriever(t, client, server, WithChecksumHeader("X-Checksum-Sha256"), WithDownloadRetryPolicy(fastRetryPolicy()))
+
+		path, err := retriever.FetchEntry(context.Background(), "test.bin")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+		assert.True(t, rangeResumed.Load())
+		actual, err := cacheFs.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, actual)
+	})
+
+	t.Run("download limits", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Has("chunked") {
+				// no content length is advertised
+				w.Header().Set(headers.TransferEncoding, "chunked")
+				_, _ = w.Write(content)
+				return
+			}
+			http.ServeContent(w, r, "test.bin", time.Now(), bytes.NewReader(content))
+		}))
+		defer server.Close()
+		client := httpUtils.NewPlainHTTPClient()
+		defer func() { _ = client.Close() }()
+		limits := filesystem.NewLimits(int64(len(content)-1), 1, 1, -1, true)
+		retriever, cacheFs, cacheDir := newTestHTTPEntryRetriever(t, client, server, WithDownloadLimits(limits))
+
+		_, err := retriever.FetchEntry(context.Background(), "test.bin")
+		errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+		retriever.urlTemplate = fmt.Sprintf("%v/files/%v?chunked", server.URL, KeyPlaceholder)
+		_, err = retriever.FetchEntry(context.Background(), "test.bin")
+		errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+		empty, err := cacheFs.IsEmpty(cacheDir)
+		require.NoError(t, err)
+		assert.True(t, empty)
+
+		retriever.options.limits = filesystem.NewLimits(int64(len(content)), 1, 1, -1, true)
+		_, err = retriever.FetchEntry(context.Background(), "test.bin")
+		require.NoError(t, err)
+	})
+}
+
+func TestHTTPFileCache(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	content := []byte(faker.Paragraph())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.txt", time.Now(), bytes.NewReader(content))
+	}))
+	defer server.Close()
+	client := httpUtils.NewPlainHTTPClient()
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	cacheFs := filesystem.NewStandardFileSystem()
+	config := DefaultFileCacheConfig()
+	config.CachePath = t.TempDir()
+	observer := metrics.NewInMemoryObserver()
+	cache, err := NewHTTPFileCache(ctx, client, fmt.Sprintf("%v/%v", server.URL, KeyPlaceholder), cacheFs, config, []HTTPEntryRetrieverOption{WithChecksumAlgorithm(hashing.HashSha256)}, WithObserver(observer))
+	require.NoError(t, err)
+	defer func() { _ = cache.Close(ctx) }()
+
+	destDir := t.TempDir()
+	require.NoError(t, cache.GetOrFetch(ctx, "test.txt", cacheFs, destDir))
+	actual, err := cacheFs.ReadFile(filesystem.FilePathJoin(cacheFs, destDir, "test.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, actual)
+	snapshot := observer.Snapshot()
+	assert.Equal(t, uint64(1), snapshot.Misses)
+	assert.Equal(t, uint64(1), snapshot.Stores)
+}
@@ -0,0 +1,173 @@
+package filecache
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+)
+
+const (
+	// IndexFileName is the name of the file, in the cache directory, in which the cache index is persisted (see FileCacheConfig.PersistIndex).
+	IndexFileName = ".filecache-index.json"
+	indexVersion  = 1
+	checksumType  = hashing.HashSha256
+)
+
+// indexRecord describes a cache entry in the persisted index.
+type indexRecord struct {
+	Key         string        `json:"key"`
+	Path        string        `json:"path"`
+	TTL         time.Duration `json:"ttl"`
+	Expiration  time.Time     `json:"expiration"`
+	LastAccess  time.Time     `json:"last_access"`
+	AccessCount uint64        `json:"access_count"`
+	Size        int64         `json:"size"`
+	Checksum    string        `json:"checksum"`
+}
+
+type persistedIndex struct {
+	Version int           `json:"version"`
+	Entries []indexRecord `json:"entries"`
+}
+
+// iPersistableEntry is implemented by entries which can be recorded in the persisted index.
+type iPersistableEntry interface {
+	toIndexRecord(key string) indexRecord
+}
+
+// entryIndex persists the cache index on the cache filesystem.
+type entryIndex struct {
+	mu       sync.Mutex
+	fs       filesystem.FS
+	cacheDir string
+	path     string
+	// unverified holds the keys of the entries which were reloaded from the index and have not been checked yet.
+	unverified sync.Map
+}
+
+func newEntryIndex(fs filesystem.FS, cacheDir string) *entryIndex {
+	return &entryIndex{
+		fs:       fs,
+		cacheDir: cacheDir,
+		path:     filesystem.FilePathJoin(fs, cacheDir, IndexFileName),
+	}
+}
+
+// isWithinCacheDirectory states whether `path` lies within the cache directory.
+func (i *entryIndex) isWithinCacheDirectory(path string) bool {
+	if strings.TrimSpace(path) == "" {
+		return false
+	}
+	cacheDir := filesystem.FilePathClean(i.fs, i.cacheDir)
+	return strings.HasPrefix(filesystem.FilePathClean(i.fs, path), cacheDir+string(i.fs.PathSeparator()))
+}
+
+// load reads the persisted index and returns the entries it describes. Entries are marked as requiring verification.
+// As entries are eventually deleted with privileges, records pointing outside the cache directory are ignored.
+func (i *entryIndex) load(cacheFs filesystem.FS) (entries map[string]ICacheEntry, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	entries = map[string]ICacheEntry{}
+	if !i.fs.Exists(i.path) {
+		return
+	}
+	content, err := i.fs.ReadFile(i.path)
+	if err != nil {
+		return
+	}
+	var index persistedIndex
+	err = json.Unmarshal(content, &index)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrMarshalling, err, "could not read the cache index '%s'", i.path)
+		return
+	}
+	if index.Version != indexVersion {
+		err = commonerrors.Newf(commonerrors.ErrUnsupported, "unsupported version %v of the cache index '%s'", index.Version, i.path)
+		return
+	}
+	for j := range index.Entries {
+		record := index.Entries[j]
+		if !i.isWithinCacheDirectory(record.Path) {
+			continue
+		}
+		record.Path = filesystem.FilePathClean(i.fs, record.Path)
+		entries[record.Key] = newCacheEntryFromIndexRecord(cacheFs, &record)
+		i.unverified.Store(record.Key, record.Checksum)
+	}
+	return
+}
+
+// save persists the index describing `entries`.
+func (i *entryIndex) save(ctx context.Context, entries iEntryMap) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	index := persistedIndex{Version: indexVersion}
+	entries.Range(func(key string, entry ICacheEntry) {
+		if persistable, ok := entry.(iPersistableEntry); ok {
+			index.Entries = append(index.Entries, persistable.toIndexRecord(key))
+		}
+	})
+	content, err := json.Marshal(&index)
+	if err != nil {
+		return commonerrors.WrapError(commonerrors.ErrMarshalling, err, "could not serialise the cache index")
+	}
+	return i.fs.WriteFileAtomically(ctx, i.path, content, 0644)
+}
+
+// verify checks, if not done already, that the content of an entry reloaded from the index has not changed since it was stored.
+func (i *entryIndex) verify(ctx context.Context, key string, entry ICacheEntry) error {
+	expected, ok := i.unverified.Load(key)
+	if !ok {
+		return nil
+	}
+	persistable, ok := entry.(iPersistableEntry)
+	if !ok {
+		i.unverified.Delete(key)
+		return nil
+	}
+	record := persistable.toIndexRecord(key)
+	if !i.fs.Exists(record.Path) {
+		return commonerrors.Newf(commonerrors.ErrNotFound, "content of cache entry '%s' could not be found", key)
+	}
+	actual, err := determineEntryChecksum(ctx, i.fs, record.Path)
+	if err != nil {
+		return err
+	}
+	if checksum, _ := expected.(string); actual != checksum {
+		return commonerrors.Newf(commonerrors.ErrInvalid, "content of cache entry '%s' has changed since it was stored", key)
+	}
+	i.unverified.Delete(key)
+	return nil
+}
+
+func (i *entryIndex) forget(key string) {
+	i.unverified.Delete(key)
+}
+
+// determineEntryChecksum returns a checksum of the entry at `path`, whether it is a file or a directory.
+func determineEntryChecksum(ctx context.Context, fs filesystem.FS, path string) (checksum string, err error) {
+	hasher, err := filesystem.NewFileHash(checksumType)
+	if err != nil {
+		return
+	}
+	isDir, err := fs.IsDir(path)
+	if err != nil {
+		return
+	}
+	if !isDir {
+		checksum, err = hasher.CalculateFileWithContext(ctx, fs, path)
+		return
+	}
+	hash, err := hasher.CalculateDirectoryWithContext(ctx, fs, path)
+	if err != nil {
+		return
+	}
+	checksum = hash.Hash
+	return
+}
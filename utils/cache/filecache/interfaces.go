@@ -35,6 +35,12 @@ type IFileCache interface {
 	// Fetch returns an error if the key does not exist in the cache, the copying process fails or the cache is closed.
 	Fetch(ctx context.Context, key string, destFilesystem filesystem.FS, destPath string) error
 
+	// GetOrFetch copies the cached data for the provided `key` to the destination `destPath` in `destFilesystem`, similarly to Fetch.
+	// If the entry is not in the cache, it is first retrieved and stored (see Store). Concurrent calls for the same missing key are coalesced
+	// so that the entry is only retrieved once and the result of the retrieval (including any error) shared with all of them.
+	// Each caller only waits for the retrieval until its own context is cancelled; the retrieval itself is only cancelled if all callers stop waiting or the cache is closed.
+	GetOrFetch(ctx context.Context, key string, destFilesystem filesystem.FS, destPath string) error
+
 	// Close close the cache by stopping the GC and cleans up any entries that are still cached
 	Close(ctx context.Context) error
 }
@@ -0,0 +1,91 @@
+package filecache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+type flight struct {
+	done    chan struct{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+	// abandoned states whether the call was cancelled because nobody was waiting for its result anymore.
+	abandoned bool
+}
+
+// flightGroup de-duplicates concurrent calls made for the same key so that only one of them is actually performed and its result shared with all the callers.
+type flightGroup struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	flights map[string]*flight
+}
+
+// newFlightGroup returns a group whose calls are performed using contexts derived from `ctx`.
+func newFlightGroup(ctx context.Context) *flightGroup {
+	return &flightGroup{ctx: ctx, flights: map[string]*flight{}}
+}
+
+// do calls `fn` for `key` unless a call is already in progress for that key, in which case its result is awaited instead.
+// The call is performed using a context derived from the group's context and so, is not affected by the callers' contexts.
+// Nonetheless, each caller only waits for the result until its own context is done, and the call is cancelled once no caller is waiting for it anymore.
+// Calls for the same key never overlap: an abandoned call must return before a new one is started.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) error) error {
+	if err := parallelisation.DetermineContextError(ctx); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	f, inProgress := g.flights[key]
+	for inProgress && f.abandoned {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return parallelisation.DetermineContextError(ctx)
+		}
+		g.mu.Lock()
+		f, inProgress = g.flights[key]
+	}
+	if !inProgress {
+		callCtx, cancel := context.WithCancel(g.ctx)
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go g.call(callCtx, key, f, fn)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// Nobody is interested in the result anymore: the call is abandoned and any subsequent caller will start a new one once it has returned.
+			f.abandoned = true
+			f.cancel()
+		}
+		g.mu.Unlock()
+		return parallelisation.DetermineContextError(ctx)
+	}
+}
+
+func (g *flightGroup) call(ctx context.Context, key string, f *flight, fn func(context.Context) error) {
+	defer f.cancel()
+	f.err = fn(ctx)
+	g.mu.Lock()
+	g.forget(key, f)
+	g.mu.Unlock()
+	close(f.done)
+}
+
+// forget removes the flight from the group. It must be called whilst holding the group lock.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
@@ -0,0 +1,9 @@
+// Package metrics provides hooks for observing caches (such as [filecache] or [sharedcache]) so that their efficiency can be monitored.
+//
+// Caches notify an [ICacheObserver] of every hit, miss, store, eviction and fetch. [InMemoryObserver] aggregates these
+// notifications into a [Snapshot] which can be exported (e.g. to a dashboard) or written to a [summary.ISummaryLogger].
+//
+// [filecache]: https://pkg.go.dev/github.com/ARM-software/golang-utils/utils/cache/filecache
+// [sharedcache]: https://pkg.go.dev/github.com/ARM-software/golang-utils/utils/sharedcache
+// [summary.ISummaryLogger]: https://pkg.go.dev/github.com/ARM-software/golang-utils/utils/logs/summary#ISummaryLogger
+package metrics
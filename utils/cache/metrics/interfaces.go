@@ -0,0 +1,30 @@
+package metrics
+
+import "time"
+
+// Mocks are generated using `go generate ./...`
+// Add interfaces to the following command for a mock to be generated
+//go:generate go tool mockgen -destination=../../mocks/mock_$GOPACKAGE.go -package=mocks github.com/ARM-software/golang-utils/utils/cache/$GOPACKAGE ICacheObserver,ICacheMetrics
+
+// ICacheObserver is notified of the operations performed on a cache. Implementations must be safe for concurrent use and should return quickly as they are called on every cache operation.
+type ICacheObserver interface {
+	// OnHit is called when an entry requested is served from the cache.
+	OnHit(key string)
+	// OnMiss is called when an entry requested is not present in the cache.
+	OnMiss(key string)
+	// OnStore is called when an entry of `size` bytes has been stored in the cache. A negative size means the size is unknown.
+	OnStore(key string, size int64)
+	// OnEviction is called when an entry of `size` bytes has been evicted from the cache. A negative size means the size is unknown.
+	OnEviction(key string, size int64)
+	// OnFetch is called when a request for an entry completes, `latency` being the time it took to serve it and `err` its outcome.
+	OnFetch(key string, latency time.Duration, err error)
+}
+
+// ICacheMetrics is an observer aggregating the notifications it receives into metrics.
+type ICacheMetrics interface {
+	ICacheObserver
+	// Snapshot returns the current value of the metrics.
+	Snapshot() Snapshot
+	// Reset resets all the metrics.
+	Reset()
+}
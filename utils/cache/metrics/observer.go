@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/logs/summary"
+	"github.com/ARM-software/golang-utils/utils/units/size"
+)
+
+var (
+	_ ICacheObserver = &NoOpObserver{}
+	_ ICacheMetrics  = &InMemoryObserver{}
+)
+
+// NoOpObserver is an observer which ignores all notifications. It is used by caches when no observer is specified.
+type NoOpObserver struct{}
+
+func (o *NoOpObserver) OnHit(_ string)                             {}
+func (o *NoOpObserver) OnMiss(_ string)                            {}
+func (o *NoOpObserver) OnStore(_ string, _ int64)                  {}
+func (o *NoOpObserver) OnEviction(_ string, _ int64)               {}
+func (o *NoOpObserver) OnFetch(_ string, _ time.Duration, _ error) {}
+
+// NewNoOpObserver returns an observer which does nothing.
+func NewNoOpObserver() ICacheObserver {
+	return &NoOpObserver{}
+}
+
+// ObserverOrNoOp returns `observer` or a NoOpObserver if it is not set.
+func ObserverOrNoOp(observer ICacheObserver) ICacheObserver {
+	if observer == nil {
+		return NewNoOpObserver()
+	}
+	return observer
+}
+
+// Snapshot describes the metrics of a cache at a point in time.
+type Snapshot struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Stores    uint64 `json:"stores"`
+	Evictions uint64 `json:"evictions"`
+	// BytesStored is the total amount of bytes stored in the cache, ignoring entries of unknown size.
+	BytesStored int64 `json:"bytes_stored"`
+	// BytesEvicted is the total amount of bytes evicted from the cache, ignoring entries of unknown size.
+	BytesEvicted      int64         `json:"bytes_evicted"`
+	Fetches           uint64        `json:"fetches"`
+	FailedFetches     uint64        `json:"failed_fetches"`
+	TotalFetchLatency time.Duration `json:"total_fetch_latency"`
+	MaxFetchLatency   time.Duration `json:"max_fetch_latency"`
+}
+
+// HitRatio returns the proportion of requests served from the cache, or 0 if no request was made.
+func (s *Snapshot) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// MeanFetchLatency returns the average time taken to serve a request.
+func (s *Snapshot) MeanFetchLatency() time.Duration {
+	if s.Fetches == 0 {
+		return 0
+	}
+	return s.TotalFetchLatency / time.Duration(s.Fetches) //nolint:gosec // the number of fetches cannot realistically overflow
+}
+
+// Export returns the metrics as a flat map, e.g. for publishing them to a monitoring system. Durations are expressed in seconds.
+func (s *Snapshot) Export() map[string]any {
+	return map[string]any{
+		"hits":                       s.Hits,
+		"misses":                     s.Misses,
+		"hit_ratio":                  s.HitRatio(),
+		"stores":                     s.Stores,
+		"bytes_stored":               s.BytesStored,
+		"evictions":                  s.Evictions,
+		"bytes_evicted":              s.BytesEvicted,
+		"fetches":                    s.Fetches,
+		"failed_fetches":             s.FailedFetches,
+		"mean_fetch_latency_seconds": s.MeanFetchLatency().Seconds(),
+		"max_fetch_latency_seconds":  s.MaxFetchLatency.Seconds(),
+	}
+}
+
+// WriteSummary writes a human-readable summary of the metrics of the cache called `name` using `logger`.
+func (s *Snapshot) WriteSummary(logger summary.ISummaryLogger, name string) (err error) {
+	if logger == nil {
+		err = commonerrors.UndefinedVariable("summary logger")
+		return
+	}
+	stored, err := size.FormatSizeAsBinarySI(float64(s.BytesStored), 2)
+	if err != nil {
+		return
+	}
+	evicted, err := size.FormatSizeAsBinarySI(float64(s.BytesEvicted), 2)
+	if err != nil {
+		return
+	}
+	lines := []struct {
+		format string
+		values []any
+	}{
+		{"### Cache metrics: %v", []any{name}},
+		{"- Hits: %d (%.2f%% hit ratio)", []any{s.Hits, 100 * s.HitRatio()}},
+		{"- Misses: %d", []any{s.Misses}},
+		{"- Stores: %d (%v)", []any{s.Stores, stored}},
+		{"- Evictions: %d (%v)", []any{s.Evictions, evicted}},
+		{"- Fetches: %d (%d failed, mean latency %v, max latency %v)", []any{s.Fetches, s.FailedFetches, s.MeanFetchLatency(), s.MaxFetchLatency}},
+	}
+	for i := range lines {
+		err = logger.WriteStringF(lines[i].format, lines[i].values...)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// InMemoryObserver aggregates the notifications it receives into metrics held in memory.
+type InMemoryObserver struct {
+	mu      sync.Mutex
+	metrics Snapshot
+}
+
+// NewInMemoryObserver returns an observer keeping track of the cache metrics in memory.
+func NewInMemoryObserver() *InMemoryObserver {
+	return &InMemoryObserver{}
+}
+
+func (o *InMemoryObserver) OnHit(_ string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics.Hits++
+}
+
+func (o *InMemoryObserver) OnMiss(_ string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics.Misses++
+}
+
+func (o *InMemoryObserver) OnStore(_ string, entrySize int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics.Stores++
+	if entrySize > 0 {
+		o.metrics.BytesStored += entrySize
+	}
+}
+
+func (o *InMemoryObserver) OnEviction(_ string, entrySize int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics.Evictions++
+	if entrySize > 0 {
+		o.metrics.BytesEvicted += entrySize
+	}
+}
+
+func (o *InMemoryObserver) OnFetch(_ string, latency time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics.Fetches++
+	if err != nil {
+		o.metrics.FailedFetches++
+	}
+	o.metrics.TotalFetchLatency += latency
+	o.metrics.MaxFetchLatency = max(o.metrics.MaxFetchLatency, latency)
+}
+
+func (o *InMemoryObserver) Snapshot() Snapshot {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.metrics
+}
+
+func (o *InMemoryObserver) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.metrics = Snapshot{}
+}
+
+// WriteSummary writes a human-readable summary of the current metrics of the cache called `name` using `logger`.
+func (o *InMemoryObserver) WriteSummary(logger summary.ISummaryLogger, name string) error {
+	snapshot := o.Snapshot()
+	return snapshot.WriteSummary(logger, name)
+}
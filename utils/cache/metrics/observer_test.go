@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/logs/summary"
+)
+
+func TestNoOpObserver(t *testing.T) {
+	assert.IsType(t, &NoOpObserver{}, ObserverOrNoOp(nil))
+	observer := NewInMemoryObserver()
+	assert.Equal(t, observer, ObserverOrNoOp(observer))
+	noop := NewNoOpObserver()
+	assert.NotPanics(t, func() {
+		noop.OnHit(faker.Word())
+		noop.OnMiss(faker.Word())
+		noop.OnStore(faker.Word(), 1)
+		noop.OnEviction(faker.Word(), 1)
+		noop.OnFetch(faker.Word(), time.Second, nil)
+	})
+}
+
+func TestInMemoryObserver(t *testing.T) {
+	observer := NewInMemoryObserver()
+	snapshot := observer.Snapshot()
+	assert.Zero(t, snapshot.HitRatio())
+	assert.Zero(t, snapshot.MeanFetchLatency())
+
+	key := faker.Word()
+	observer.OnHit(key)
+	observer.OnHit(key)
+	observer.OnHit(key)
+	observer.OnMiss(key)
+	observer.OnStore(key, 2048)
+	observer.OnStore(key, -1)
+	observer.OnEviction(key, 1024)
+	observer.OnFetch(key, time.Second, nil)
+	observer.OnFetch(key, 3*time.Second, commonerrors.ErrUnexpected)
+
+	snapshot = observer.Snapshot()
+	assert.Equal(t, Snapshot{
+		Hits:              3,
+		Misses:            1,
+		Stores:            2,
+		Evictions:         1,
+		BytesStored:       2048,
+		BytesEvicted:      1024,
+		Fetches:           2,
+		FailedFetches:     1,
+		TotalFetchLatency: 4 * time.Second,
+		MaxFetchLatency:   3 * time.Second,
+	}, snapshot)
+	assert.InDelta(t, 0.75, snapshot.HitRatio(), 0.001)
+	assert.Equal(t, 2*time.Second, snapshot.MeanFetchLatency())
+
+	exported := snapshot.Export()
+	assert.Equal(t, uint64(3), exported["hits"])
+	assert.InDelta(t, 0.75, exported["hit_ratio"], 0.001)
+	assert.InDelta(t, 2, exported["mean_fetch_latency_seconds"], 0.001)
+	serialised, err := json.Marshal(&snapshot)
+	require.NoError(t, err)
+	var deserialised Snapshot
+	require.NoError(t, json.Unmarshal(serialised, &deserialised))
+	assert.Equal(t, snapshot, deserialised)
+
+	observer.Reset()
+	assert.Equal(t, Snapshot{}, observer.Snapshot())
+}
+
+func TestInMemoryObserver_Concurrency(t *testing.T) {
+	observer := NewInMemoryObserver()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				observer.OnHit(faker.Word())
+				observer.OnFetch(faker.Word(), time.Duration(j)*time.Millisecond, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	snapshot := observer.Snapshot()
+	assert.Equal(t, uint64(1000), snapshot.Hits)
+	assert.Equal(t, uint64(1000), snapshot.Fetches)
+	assert.Equal(t, 99*time.Millisecond, snapshot.MaxFetchLatency)
+}
+
+func TestInMemoryObserver_WriteSummary(t *testing.T) {
+	observer := NewInMemoryObserver()
+	errortest.AssertError(t, observer.WriteSummary(nil, faker.Word()), commonerrors.ErrUndefined)
+
+	observer.OnHit(faker.Word())
+	observer.OnMiss(faker.Word())
+	observer.OnStore(faker.Word(), 2048)
+	observer.OnFetch(faker.Word(), time.Second, nil)
+	logger, err := summary.NewInMemorySummaryLogger("test")
+	require.NoError(t, err)
+	name := faker.Word()
+	require.NoError(t, observer.WriteSummary(logger, name))
+	content := logger.GetSummary()
+	assert.Contains(t, content, "Cache metrics: "+name)
+	assert.Contains(t, content, "Hits: 1 (50.00% hit ratio)")
+	assert.Contains(t, content, "Stores: 1 (2.00KiB)")
+	assert.Contains(t, content, "Fetches: 1 (0 failed, mean latency 1s, max latency 1s)")
+}
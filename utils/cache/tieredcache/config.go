@@ -0,0 +1,61 @@
+package tieredcache
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/ARM-software/golang-utils/utils/cache/filecache"
+	configUtils "github.com/ARM-software/golang-utils/utils/config"
+)
+
+// TierPolicy defines how a cache tier is used.
+type TierPolicy struct {
+	// Read states whether entries are looked up in the tier when fetched.
+	Read bool `mapstructure:"read"`
+	// Write states whether entries stored (or evicted) are written through to the tier.
+	Write bool `mapstructure:"write"`
+	// IgnoreErrors states whether failures of the tier should be ignored, the other tier being used instead. Missing entries are not considered as failures.
+	IgnoreErrors bool `mapstructure:"ignore_errors"`
+}
+
+// LocalTierConfiguration defines the local tier i.e. a file cache (see filecache.FileCacheConfig) in front of the remote tier.
+type LocalTierConfiguration struct {
+	filecache.FileCacheConfig `mapstructure:",squash"`
+	TierPolicy                `mapstructure:",squash"`
+	// Populate states whether entries found in the remote tier should be stored in the local tier so that subsequent fetches are served locally.
+	Populate bool `mapstructure:"populate"`
+}
+
+func (cfg *LocalTierConfiguration) Validate() error {
+	// Validate Embedded Structs
+	err := configUtils.ValidateEmbedded(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	return validation.ValidateStruct(cfg,
+		validation.Field(&cfg.Populate, validation.When(!cfg.Read, validation.Empty.Error("cannot populate the local tier if it is not read"))),
+	)
+}
+
+type Configuration struct {
+	Local  LocalTierConfiguration `mapstructure:"local"`
+	Remote TierPolicy             `mapstructure:"remote"`
+}
+
+func (cfg *Configuration) Validate() error {
+	// Validate Embedded Structs
+	return configUtils.ValidateEmbedded(cfg)
+}
+
+// DefaultTieredCacheConfiguration returns a configuration where both tiers are read and written, and entries found remotely are cached locally.
+func DefaultTieredCacheConfiguration() *Configuration {
+	return &Configuration{
+		Local: LocalTierConfiguration{
+			FileCacheConfig: *filecache.DefaultFileCacheConfig(),
+			TierPolicy:      TierPolicy{Read: true, Write: true},
+			Populate:        true,
+		},
+		Remote: TierPolicy{Read: true, Write: true},
+	}
+}
@@ -0,0 +1,18 @@
+package tieredcache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTieredCacheConfiguration(t *testing.T) {
+	cfg := DefaultTieredCacheConfiguration()
+	require.Error(t, cfg.Validate())
+	cfg.Local.CachePath = t.TempDir()
+	require.NoError(t, cfg.Validate())
+	cfg.Local.Read = false
+	require.Error(t, cfg.Validate())
+	cfg.Local.Populate = false
+	require.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,21 @@
+package tieredcache
+
+import "context"
+
+// Mocks are generated using `go generate ./...`
+// Add interfaces to the following command for a mock to be generated
+//go:generate go tool mockgen -destination=../../mocks/mock_$GOPACKAGE.go -package=mocks github.com/ARM-software/golang-utils/utils/cache/$GOPACKAGE ITieredCache
+
+// ITieredCache defines a cache made of a local tier (see filecache.IFileCache), e.g. on a node-local disk, in front of a remote tier (see sharedcache.ISharedCacheRepository), e.g. on a network share.
+// How each tier is used is defined by its policy (see TierPolicy).
+type ITieredCache interface {
+	// Fetch installs the files of cache[`key`] into the `dest` directory. The local tier is looked up first, and then the remote tier.
+	// Entries found in the remote tier are stored in the local tier if configured to be populated.
+	Fetch(ctx context.Context, key, dest string) error
+	// Store stores the files of the `src` directory to cache[`key`] in every tier written to. The remote tier is written to first.
+	Store(ctx context.Context, key, src string) error
+	// Evict removes cache[`key`] from every tier written to.
+	Evict(ctx context.Context, key string) error
+	// Close closes the local tier. Remote entries are left untouched.
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,333 @@
+package tieredcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ARM-software/golang-utils/utils/cache/filecache"
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+	"github.com/ARM-software/golang-utils/utils/sharedcache"
+)
+
+var _ ITieredCache = &TieredCache{}
+
+// remoteTierError describes a failure of the remote tier whilst populating the local tier so that it can be told apart from local tier failures.
+type remoteTierError struct {
+	err error
+}
+
+func (e *remoteTierError) Error() string {
+	return e.err.Error()
+}
+
+func (e *remoteTierError) Unwrap() error {
+	return e.err
+}
+
+// entryRetriever retrieves the entries of the local tier either from the directory staged for them or, otherwise, from the remote tier.
+type entryRetriever struct {
+	remote   sharedcache.ISharedCacheRepository
+	cacheFs  filesystem.FS
+	cacheDir string
+	mu       sync.Mutex
+	staged   map[string]string
+}
+
+func newEntryRetriever(remote sharedcache.ISharedCacheRepository) *entryRetriever {
+	return &entryRetriever{
+		remote: remote,
+		staged: map[string]string{},
+	}
+}
+
+// stage makes the entry corresponding to `key` be copied from the `src` directory rather than retrieved from the remote tier.
+func (r *entryRetriever) stage(key, src string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.staged[key] = src
+}
+
+// unstage reverts stage, unless `key` has been staged again with a different source since.
+func (r *entryRetriever) unstage(key, src string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.staged[key] == src {
+		delete(r.staged, key)
+	}
+}
+
+func (r *entryRetriever) stagedSource(key string) (src string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	src, ok = r.staged[key]
+	return
+}
+
+func (r *entryRetriever) SetCacheDir(cacheFs filesystem.FS, cacheDir string) error {
+	if cacheFs == nil {
+		return commonerrors.New(commonerrors.ErrUndefined, "the cache filesystem cannot be nil")
+	}
+
+	if !cacheFs.Exists(cacheDir) {
+		return commonerrors.Newf(commonerrors.ErrNotFound, "cannot access '%s', No such file or directory", cacheDir)
+	}
+
+	r.cacheFs = cacheFs
+	r.cacheDir = cacheDir
+
+	return nil
+}
+
+func (r *entryRetriever) FetchEntry(ctx context.Context, key string) (string, error) {
+	tmpPath := filesystem.FilePathJoin(r.cacheFs, r.cacheDir, fmt.Sprintf("%v-tmp", key))
+	if err := r.cacheFs.Rm(tmpPath); err != nil {
+		return "", err
+	}
+
+	var err error
+	if src, ok := r.stagedSource(key); ok {
+		err = filesystem.CopyBetweenFS(ctx, r.cacheFs, src, r.cacheFs, tmpPath)
+	} else if fetchErr := r.remote.Fetch(ctx, key, tmpPath); fetchErr != nil {
+		err = &remoteTierError{err: fetchErr}
+	}
+	if err != nil {
+		_ = r.cacheFs.Rm(tmpPath)
+		return "", err
+	}
+
+	destPath := filesystem.FilePathJoin(r.cacheFs, r.cacheDir, key)
+	if err := r.cacheFs.Move(tmpPath, destPath); err != nil {
+		_ = r.cacheFs.Rm(tmpPath)
+		return "", err
+	}
+
+	return destPath, nil
+}
+
+// TieredCache implements ITieredCache using a file cache as local tier and a shared cache as remote tier.
+type TieredCache struct {
+	fs        filesystem.FS
+	local     filecache.IFileCache
+	retriever *entryRetriever
+	remote    sharedcache.ISharedCacheRepository
+	cfg       *Configuration
+}
+
+// isMissing returns whether an error corresponds to an entry missing from a tier.
+func isMissing(err error) bool {
+	return commonerrors.Any(err, commonerrors.ErrNotFound, commonerrors.ErrEmpty)
+}
+
+// isIgnorable returns whether a tier error can be ignored according to the tier policy.
+func isIgnorable(err error, policy *TierPolicy) bool {
+	return policy.IgnoreErrors && !commonerrors.Any(err, commonerrors.ErrCancelled, commonerrors.ErrTimeout)
+}
+
+func (c *TieredCache) setUpDestination(dest string) error {
+	if err := c.fs.MkDir(dest); err != nil {
+		return err
+	}
+	return c.fs.CleanDir(dest)
+}
+
+func (c *TieredCache) Fetch(ctx context.Context, key, dest string) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	err = c.setUpDestination(dest)
+	if err != nil {
+		return
+	}
+
+	localPolicy := &c.cfg.Local.TierPolicy
+	populate := c.cfg.Local.Populate && c.cfg.Remote.Read
+	if localPolicy.Read {
+		if populate {
+			// Entries missing locally are retrieved from the remote tier and stored in the local tier.
+			err = c.local.GetOrFetch(ctx, key, c.fs, dest)
+		} else {
+			err = c.local.Fetch(ctx, key, c.fs, dest)
+		}
+		var remoteErr *remoteTierError
+		switch {
+		case err == nil:
+			return
+		case errors.As(err, &remoteErr):
+			// the entry could not be retrieved from the remote tier in order to populate the local tier.
+			err = remoteErr.err
+			if !isMissing(err) && isIgnorable(err, &c.cfg.Remote) {
+				err = commonerrors.WrapErrorf(commonerrors.ErrNotFound, err, "no cache entry for key [%v]", key)
+			}
+			return
+		case isMissing(err):
+			if populate {
+				// the entry is not in the remote tier either.
+				return
+			}
+		case !isIgnorable(err, localPolicy) && !(populate && c.isMissingLocally(ctx, key)):
+			return
+		default:
+			// The local tier failure is ignored, or the local tier could not be populated (e.g. the entry is larger than its capacity), and the entry is directly fetched from the remote tier instead.
+			err = c.setUpDestination(dest)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	if !c.cfg.Remote.Read {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "no cache entry for key [%v]", key)
+		return
+	}
+
+	err = c.remote.Fetch(ctx, key, dest)
+	return
+}
+
+// isMissingLocally returns whether an entry is missing from the local tier e.g. because the local tier could not be populated with it.
+func (c *TieredCache) isMissingLocally(ctx context.Context, key string) bool {
+	exists, err := c.local.Has(ctx, key)
+	return err == nil && !exists
+}
+
+func (c *TieredCache) Store(ctx context.Context, key, src string) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if !c.fs.Exists(src) {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "source path does not exist [%v]", src)
+		return
+	}
+
+	var ignored []error
+	stored := false
+	if c.cfg.Remote.Write {
+		subErr := c.remote.Store(ctx, key, src)
+		switch {
+		case subErr == nil:
+			stored = true
+		case isIgnorable(subErr, &c.cfg.Remote):
+			ignored = append(ignored, subErr)
+		default:
+			err = subErr
+			return
+		}
+	}
+
+	if c.cfg.Local.Write {
+		subErr := c.storeLocally(ctx, key, src)
+		switch {
+		case subErr == nil:
+			stored = true
+		case isIgnorable(subErr, &c.cfg.Local.TierPolicy):
+			ignored = append(ignored, subErr)
+		default:
+			err = subErr
+			return
+		}
+	}
+
+	if !stored && len(ignored) > 0 {
+		// Tier errors can only be ignored if the entry was stored in at least one tier.
+		err = commonerrors.Join(ignored...)
+	}
+	return
+}
+
+// storeLocally stores the content of `src` into the local tier, replacing any existing entry.
+func (c *TieredCache) storeLocally(ctx context.Context, key, src string) error {
+	if err := c.local.Evict(ctx, key); err != nil {
+		return err
+	}
+	c.retriever.stage(key, src)
+	defer c.retriever.unstage(key, src)
+	err := c.local.Store(ctx, key)
+	if commonerrors.Any(err, commonerrors.ErrExists) {
+		// the entry was stored concurrently, e.g. populated from the remote tier which has just been written to.
+		return nil
+	}
+	return err
+}
+
+func (c *TieredCache) Evict(ctx context.Context, key string) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+
+	var ignored []error
+	evicted := false
+	if c.cfg.Local.Write {
+		subErr := c.local.Evict(ctx, key)
+		switch {
+		case subErr == nil:
+			evicted = true
+		case isIgnorable(subErr, &c.cfg.Local.TierPolicy):
+			ignored = append(ignored, subErr)
+		default:
+			err = subErr
+			return
+		}
+	}
+
+	if c.cfg.Remote.Write {
+		subErr := c.remote.RemoveEntry(ctx, key)
+		switch {
+		case subErr == nil:
+			evicted = true
+		case isIgnorable(subErr, &c.cfg.Remote):
+			ignored = append(ignored, subErr)
+		default:
+			err = subErr
+			return
+		}
+	}
+
+	if !evicted && len(ignored) > 0 {
+		// Tier errors can only be ignored if the entry was evicted from at least one tier.
+		err = commonerrors.Join(ignored...)
+	}
+	return
+}
+
+func (c *TieredCache) Close(ctx context.Context) error {
+	return c.local.Close(ctx)
+}
+
+// NewTieredCache returns a cache using a file cache on `localFs` as local tier in front of the `remote` shared cache.
+// `localFs` is also the filesystem on which entries are fetched to and stored from. As a result, it must be the filesystem the shared cache unpacks entries onto (e.g. filesystem.NewStandardFileSystem()).
+// `options` apply to the local file cache.
+func NewTieredCache(ctx context.Context, localFs filesystem.FS, remote sharedcache.ISharedCacheRepository, cfg *Configuration, options ...filecache.FileCacheOption) (*TieredCache, error) {
+	if cfg == nil {
+		return nil, commonerrors.New(commonerrors.ErrUndefined, "missing configuration")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, commonerrors.WrapError(commonerrors.ErrInvalid, err, "invalid configuration")
+	}
+	if localFs == nil {
+		return nil, commonerrors.New(commonerrors.ErrUndefined, "the local filesystem cannot be nil")
+	}
+	if remote == nil {
+		return nil, commonerrors.New(commonerrors.ErrUndefined, "the remote cache cannot be nil")
+	}
+
+	retriever := newEntryRetriever(remote)
+	local, err := filecache.NewGenericFileCache(ctx, localFs, retriever, &cfg.Local.FileCacheConfig, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TieredCache{
+		fs:        localFs,
+		local:     local,
+		retriever: retriever,
+		remote:    remote,
+		cfg:       cfg,
+	}, nil
+}
@@ -0,0 +1,302 @@
+package tieredcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ARM-software/golang-utils/utils/cache/filecache"
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/filesystem/filesystemtest"
+	"github.com/ARM-software/golang-utils/utils/sharedcache"
+)
+
+type testTiers struct {
+	fs             filesystem.FS
+	cache          *TieredCache
+	remote         sharedcache.ISharedCacheRepository
+	localObserver  *metrics.InMemoryObserver
+	remoteObserver *metrics.InMemoryObserver
+	src            string
+}
+
+func newTestTiers(t *testing.T, cacheType sharedcache.CacheType, configure func(cfg *Configuration)) *testTiers {
+	t.Helper()
+	ctx := context.Background()
+	tiers := &testTiers{
+		fs:             filesystem.NewStandardFileSystem(),
+		localObserver:  metrics.NewInMemoryObserver(),
+		remoteObserver: metrics.NewInMemoryObserver(),
+		src:            t.TempDir(),
+	}
+	_ = filesystemtest.CreateTestFileTree(t, tiers.fs, tiers.src, time.Now(), time.Now())
+
+	remote, err := sharedcache.NewCache(cacheType, tiers.fs, &sharedcache.Configuration{
+		RemoteStoragePath: t.TempDir(),
+		Timeout:           time.Second,
+	}, sharedcache.WithObserver(tiers.remoteObserver))
+	require.NoError(t, err)
+	tiers.remote = remote
+
+	cfg := DefaultTieredCacheConfiguration()
+	cfg.Local.CachePath = t.TempDir()
+	if configure != nil {
+		configure(cfg)
+	}
+	tiers.cache, err = NewTieredCache(ctx, tiers.fs, remote, cfg, filecache.WithObserver(tiers.localObserver))
+	require.NoError(t, err)
+	return tiers
+}
+
+func (tiers *testTiers) assertFetched(t *testing.T, ctx context.Context, key string) {
+	t.Helper()
+	dest := filesystem.FilePathJoin(tiers.fs, t.TempDir(), "dest")
+	require.NoError(t, tiers.cache.Fetch(ctx, key, dest))
+	expected, err := tiers.fs.Ls(tiers.src)
+	require.NoError(t, err)
+	actual, err := tiers.fs.Ls(dest)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, expected, actual)
+}
+
+func TestTieredCache(t *testing.T) {
+	for i := range sharedcache.CacheTypes {
+		cacheType := sharedcache.CacheTypes[i]
+		t.Run(fmt.Sprintf("write through for cache %v", cacheType), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			ctx := context.Background()
+			tiers := newTestTiers(t, cacheType, nil)
+			defer func() { require.NoError(t, tiers.cache.Close(ctx)) }()
+			key := faker.Word()
+
+			errortest.AssertError(t, tiers.cache.Fetch(ctx, key, t.TempDir()), commonerrors.ErrNotFound, commonerrors.ErrEmpty)
+			require.NoError(t, tiers.cache.Store(ctx, key, tiers.src))
+			assert.Equal(t, uint64(1), tiers.remoteObserver.Snapshot().Stores)
+			assert.Equal(t, uint64(1), tiers.localObserver.Snapshot().Stores)
+
+			tiers.remoteObserver.Reset()
+			tiers.assertFetched(t, ctx, key)
+			tiers.assertFetched(t, ctx, key)
+			assert.Zero(t, tiers.remoteObserver.Snapshot().Fetches)
+			assert.Equal(t, uint64(2), tiers.localObserver.Snapshot().Hits)
+
+			// storing again replaces the entries
+			require.NoError(t, tiers.cache.Store(ctx, key, tiers.src))
+			tiers.assertFetched(t, ctx, key)
+
+			require.NoError(t, tiers.cache.Evict(ctx, key))
+			errortest.AssertError(t, tiers.cache.Fetch(ctx, key, t.TempDir()), commonerrors.ErrNotFound, commonerrors.ErrEmpty)
+			errortest.AssertError(t, tiers.remote.Fetch(ctx, key, t.TempDir()), commonerrors.ErrNotFound)
+		})
+		t.Run(fmt.Sprintf("remote hits populate the local tier for cache %v", cacheType), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			ctx := context.Background()
+			tiers := newTestTiers(t, cacheType, nil)
+			defer func() { require.NoError(t, tiers.cache.Close(ctx)) }()
+			key := faker.Word()
+			require.NoError(t, tiers.remote.Store(ctx, key, tiers.src))
+
+			tiers.assertFetched(t, ctx, key)
+			tiers.assertFetched(t, ctx, key)
+			assert.Equal(t, uint64(1), tiers.remoteObserver.Snapshot().Hits)
+			snapshot := tiers.localObserver.Snapshot()
+			assert.Equal(t, uint64(1), snapshot.Stores)
+			assert.Equal(t, uint64(1), snapshot.Misses)
+			assert.Equal(t, uint64(1), snapshot.Hits)
+		})
+	}
+}
+
+func TestTieredCache_Policies(t *testing.T) {
+	t.Run("local tier not populated", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		tiers := newTestTiers(t, sharedcache.CacheImmutable, func(cfg *Configuration) {
+			cfg.Local.Populate = false
+		})
+		defer func() { require.NoError(t, tiers.cache.Close(ctx)) }()
+		key := faker.Word()
+		require.NoError(t, tiers.remote.Store(ctx, key, tiers.src))
+
+		tiers.assertFetched(t, ctx, key)
+		tiers.assertFetched(t, ctx, key)
+		assert.Equal(t, uint64(2), tiers.remoteObserver.Snapshot().Hits)
+		assert.Zero(t, tiers.localObserver.Snapshot().Stores)
+	})
+	t.Run("local tier only", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		tiers := newTestTiers(t, sharedcache.CacheImmutable, func(cfg *Configuration) {
+			cfg.Remote = TierPolicy{}
+		})
+		defer func() { require.NoError(t, tiers.cache.Close(ctx)) }()
+		key := faker.Word()
+		require.NoError(t, tiers.cache.Store(ctx, key, tiers.src))
+		tiers.assertFetched(t, ctx, key)
+		assert.Zero(t, tiers.remoteObserver.Snapshot().Stores)
+		errortest.AssertError(t, tiers.remote.Fetch(ctx, key, t.TempDir()), commonerrors.ErrNotFound)
+
+		require.NoError(t, tiers.cache.Evict(ctx, key))
+		errortest.AssertError(t, tiers.cache.Fetch(ctx, key, t.TempDir()), commonerrors.ErrNotFound)
+	})
+	t.Run("remote tier only", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		ctx := context.Background()
+		tiers := newTestTiers(t, sharedcache.CacheImmutable, func(cfg *Configuration) {
+			cfg.Local.TierPolicy = TierPolicy{}
+			cfg.Local.Populate = false
+		})
+		defer func() { require.NoError(t, tiers.cache.Close(ctx)) }()
+		key := faker.Word()
+		require.NoError(t, tiers.cache.Store(ctx, key, tiers.src))
+		tiers.assertFetched(t, ctx, key)
+		assert.Equal(t, uint64(1), tiers.remoteObserver.Snapshot().Hits)
+		assert.Zero(t, tiers.localObserver.Snapshot().Stores)
+		assert.Zero(t, tiers.localObserver.Snapshot().Fetches)
+	})
+}
+
+// unavailableRemote is a remote tier which cannot be written to.
+type unavailableRemote struct {
+	sharedcache.ISharedCacheRepository
+}
+
+func (r *unavailableRemote) Store(_ context.Context, _, _ string) error {
+	return commonerrors.ErrUnavailable
+}
+
+func (r *unavailableRemote) Fetch(_ context.Context, _, _ string) error {
+	return commonerrors.ErrUnavailable
+}
+
+func (r *unavailableRemote) RemoveEntry(_ context.Context, _ string) error {
+	return commonerrors.ErrUnavailable
+}
+
+func TestTieredCache_RemoteFailures(t *testing.T) {
+	ctx := context.Background()
+	fs := filesystem.NewStandardFileSystem()
+	src := t.TempDir()
+	_ = filesystemtest.CreateTestFileTree(t, fs, src, time.Now(), time.Now())
+	key := faker.Word()
+
+	for _, ignoreErrors := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ignore errors %v", ignoreErrors), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			remote := &unavailableRemote{}
+
+			cfg := DefaultTieredCacheConfiguration()
+			cfg.Local.CachePath = t.TempDir()
+			cfg.Remote.IgnoreErrors = ignoreErrors
+			cache, err := NewTieredCache(ctx, fs, remote, cfg)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, cache.Close(ctx)) }()
+
+			if !ignoreErrors {
+				errortest.AssertError(t, cache.Store(ctx, key, src), commonerrors.ErrUnavailable)
+				return
+			}
+			require.NoError(t, cache.Store(ctx, key, src))
+			require.NoError(t, cache.Fetch(ctx, key, filesystem.FilePathJoin(fs, t.TempDir(), "dest")))
+			require.NoError(t, cache.Evict(ctx, key))
+		})
+	}
+}
+
+func TestTieredCache_LocalTierNotPopulated(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	tiers := newTestTiers(t, sharedcache.CacheImmutable, func(cfg *Configuration) {
+		// Entries are too large for the local tier.
+		cfg.Local.MaxSize = 1
+	})
+	defer func() { require.NoError(t, tiers.cache.Close(ctx)) }()
+	key := faker.Word()
+	require.NoError(t, tiers.remote.Store(ctx, key, tiers.src))
+
+	tiers.assertFetched(t, ctx, key)
+	assert.Zero(t, tiers.localObserver.Snapshot().Stores)
+	assert.Equal(t, uint64(2), tiers.remoteObserver.Snapshot().Hits)
+}
+
+func TestTieredCache_RemoteFetchFailures(t *testing.T) {
+	ctx := context.Background()
+	fs := filesystem.NewStandardFileSystem()
+	key := faker.Word()
+
+	for _, ignoreErrors := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ignore errors %v", ignoreErrors), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			cfg := DefaultTieredCacheConfiguration()
+			cfg.Local.CachePath = t.TempDir()
+			// Remote failures are judged according to the remote tier policy.
+			cfg.Local.IgnoreErrors = !ignoreErrors
+			cfg.Remote.IgnoreErrors = ignoreErrors
+			cache, err := NewTieredCache(ctx, fs, &unavailableRemote{}, cfg)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, cache.Close(ctx)) }()
+
+			err = cache.Fetch(ctx, key, t.TempDir())
+			if ignoreErrors {
+				errortest.AssertError(t, err, commonerrors.ErrNotFound)
+			} else {
+				errortest.AssertError(t, err, commonerrors.ErrUnavailable)
+			}
+		})
+	}
+}
+
+func TestTieredCache_AllTiersFailing(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	fs := filesystem.NewStandardFileSystem()
+	src := t.TempDir()
+	_ = filesystemtest.CreateTestFileTree(t, fs, src, time.Now(), time.Now())
+	key := faker.Word()
+
+	cfg := DefaultTieredCacheConfiguration()
+	cfg.Local.CachePath = t.TempDir()
+	cfg.Local.IgnoreErrors = true
+	cfg.Remote.IgnoreErrors = true
+	cache, err := NewTieredCache(ctx, fs, &unavailableRemote{}, cfg)
+	require.NoError(t, err)
+	// The local tier fails once closed.
+	require.NoError(t, cache.Close(ctx))
+
+	errortest.AssertError(t, cache.Store(ctx, key, src), commonerrors.ErrUnavailable)
+	errortest.AssertError(t, cache.Evict(ctx, key), commonerrors.ErrUnavailable)
+}
+
+func TestNewTieredCache(t *testing.T) {
+	ctx := context.Background()
+	fs := filesystem.NewStandardFileSystem()
+	remote, err := sharedcache.NewCache(sharedcache.CacheImmutable, fs, &sharedcache.Configuration{RemoteStoragePath: t.TempDir()})
+	require.NoError(t, err)
+
+	_, err = NewTieredCache(ctx, fs, remote, nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	cfg := DefaultTieredCacheConfiguration()
+	_, err = NewTieredCache(ctx, fs, remote, cfg)
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	cfg.Local.CachePath = t.TempDir()
+	cfg.Local.Read = false
+	_, err = NewTieredCache(ctx, fs, remote, cfg)
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	cfg.Local.Populate = false
+	_, err = NewTieredCache(ctx, nil, remote, cfg)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewTieredCache(ctx, fs, nil, cfg)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	cache, err := NewTieredCache(ctx, fs, remote, cfg)
+	require.NoError(t, err)
+	require.NoError(t, cache.Close(ctx))
+}
@@ -0,0 +1,32 @@
+// Package cas provides a content-addressable store on top of a filesystem: blobs are stored under the digest of their content and are therefore deduplicated.
+package cas
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Mocks are generated using `go generate ./...`
+// Add interfaces to the following command for a mock to be generated
+//go:generate go tool mockgen -destination=../mocks/mock_$GOPACKAGE.go -package=mocks github.com/ARM-software/golang-utils/utils/$GOPACKAGE IStore
+
+// IStore defines a content-addressable blob store.
+type IStore interface {
+	// Put stores the content of `reader` and returns its digest. A blob with the same content is only ever stored once.
+	Put(ctx context.Context, reader io.Reader) (digest string, err error)
+	// Get returns a reader over the blob identified by `digest`. The content is verified while being read and reading returns a commonerrors.ErrInvalid error if it does not match the digest.
+	// The reader must be closed after use. Get returns a commonerrors.ErrNotFound error if the blob is not in the store.
+	Get(ctx context.Context, digest string) (io.ReadCloser, error)
+	// Has states whether the blob identified by `digest` is in the store.
+	Has(ctx context.Context, digest string) (bool, error)
+	// Delete removes the blob identified by `digest` from the store. It returns a commonerrors.ErrNotFound error if the blob is not in the store.
+	Delete(ctx context.Context, digest string) error
+	// Walk calls `fn` for each blob of the store.
+	Walk(ctx context.Context, fn func(digest string, size int64) error) error
+	// GarbageCollect removes all the blobs which are not referenced according to `isReferenced` and which have been stored for longer than `gracePeriod`.
+	// The grace period prevents blobs which have just been stored, and so are not referenced yet, from being collected. It returns the digests of the removed blobs.
+	GarbageCollect(ctx context.Context, isReferenced func(digest string) bool, gracePeriod time.Duration) (removed []string, err error)
+	// GetHashAlgorithm returns the hashing algorithm used for determining blob digests (see hashing package).
+	GetHashAlgorithm() string
+}
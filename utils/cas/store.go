@@ -0,0 +1,330 @@
+package cas
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+const (
+	// DefaultHashAlgorithm is the hashing algorithm used by default for determining blob digests.
+	DefaultHashAlgorithm = hashing.HashSha256
+	// shardLength is the number of characters of the digest used for each level of the sharded directory layout.
+	shardLength     = 2
+	tempDirectory   = ".tmp"
+	tempFilePattern = "blob-*"
+)
+
+// Store is a content-addressable blob store persisting blobs on a filesystem.
+// Blobs are stored using a sharded directory layout (i.e. `<root>/ab/cd/abcd...`) so that directories do not grow too large.
+type Store struct {
+	fs            filesystem.FS
+	root          string
+	hashAlgorithm string
+	digestLength  int
+}
+
+// NewStore returns a blob store persisting blobs in `root` on filesystem `fs` and using `hashAlgorithm` for determining digests (see hashing package).
+// If `hashAlgorithm` is empty, DefaultHashAlgorithm is used.
+func NewStore(fs filesystem.FS, root string, hashAlgorithm string) (store IStore, err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	if strings.TrimSpace(root) == "" {
+		err = commonerrors.UndefinedVariable("store root")
+		return
+	}
+	if hashAlgorithm == "" {
+		hashAlgorithm = DefaultHashAlgorithm
+	}
+	algorithm, err := hashing.DetermineHashingAlgorithmCanonicalReference(hashAlgorithm)
+	if err != nil {
+		return
+	}
+	hasher, err := hashing.NewHashingAlgorithm(algorithm)
+	if err != nil {
+		return
+	}
+	// All digests have the same length, which can therefore be determined using any content.
+	emptyDigest, err := hasher.Calculate(strings.NewReader(""))
+	if err != nil {
+		return
+	}
+	if len(emptyDigest) <= 2*shardLength {
+		err = commonerrors.Newf(commonerrors.ErrUnsupported, "hashing algorithm [%v] produces digests which are too short", algorithm)
+		return
+	}
+	err = fs.MkDir(filesystem.FilePathJoin(fs, root, tempDirectory))
+	if err != nil {
+		return
+	}
+	store = &Store{
+		fs:            fs,
+		root:          root,
+		hashAlgorithm: algorithm,
+		digestLength:  len(emptyDigest),
+	}
+	return
+}
+
+func (s *Store) GetHashAlgorithm() string {
+	return s.hashAlgorithm
+}
+
+func (s *Store) Put(ctx context.Context, reader io.Reader) (digest string, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if reader == nil {
+		err = commonerrors.UndefinedVariable("reader")
+		return
+	}
+	hasher, err := hashing.NewHashingAlgorithm(s.hashAlgorithm)
+	if err != nil {
+		return
+	}
+	tmp, err := s.fs.TempFile(s.tempPath(), tempFilePattern)
+	if err != nil {
+		return
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = tmp.Close()
+		if s.fs.Exists(tmpName) {
+			_ = s.fs.Rm(tmpName)
+		}
+	}()
+	d, err := hasher.CalculateWithContext(ctx, io.TeeReader(reader, tmp))
+	if err != nil {
+		return
+	}
+	err = filesystem.ConvertFileSystemError(tmp.Close())
+	if err != nil {
+		return
+	}
+	blobPath := s.blobPath(d)
+	if s.fs.Exists(blobPath) {
+		// The blob is stored again as far as garbage collection is concerned so that it benefits from the grace period.
+		now := time.Now()
+		err = s.fs.Chtimes(blobPath, now, now)
+	} else {
+		// Blobs are only ever moved into place once complete so that readers never see partially written blobs.
+		err = s.fs.Move(tmpName, blobPath)
+	}
+	if err != nil {
+		return
+	}
+	digest = d
+	return
+}
+
+func (s *Store) Get(ctx context.Context, digest string) (reader io.ReadCloser, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	err = s.validateDigest(digest)
+	if err != nil {
+		return
+	}
+	hasher, err := hashing.NewHashingAlgorithm(s.hashAlgorithm)
+	if err != nil {
+		return
+	}
+	f, err := s.fs.GenericOpen(s.blobPath(digest))
+	if err != nil {
+		if commonerrors.Any(err, commonerrors.ErrNotFound) || !s.fs.Exists(s.blobPath(digest)) {
+			err = commonerrors.WrapErrorf(commonerrors.ErrNotFound, err, "blob [%v] could not be found", digest)
+		}
+		return
+	}
+	reader = newVerifyingReader(ctx, f, hasher, digest)
+	return
+}
+
+func (s *Store) Has(ctx context.Context, digest string) (found bool, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	err = s.validateDigest(digest)
+	if err != nil {
+		return
+	}
+	found = s.fs.Exists(s.blobPath(digest))
+	return
+}
+
+func (s *Store) Delete(ctx context.Context, digest string) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	err = s.validateDigest(digest)
+	if err != nil {
+		return
+	}
+	blobPath := s.blobPath(digest)
+	if !s.fs.Exists(blobPath) {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "blob [%v] could not be found", digest)
+		return
+	}
+	err = s.fs.Rm(blobPath)
+	return
+}
+
+func (s *Store) Walk(ctx context.Context, fn func(digest string, size int64) error) (err error) {
+	if fn == nil {
+		err = commonerrors.UndefinedVariable("walk function")
+		return
+	}
+	err = s.walk(ctx, func(digest string, info os.FileInfo) error {
+		return fn(digest, info.Size())
+	})
+	return
+}
+
+func (s *Store) GarbageCollect(ctx context.Context, isReferenced func(digest string) bool, gracePeriod time.Duration) (removed []string, err error) {
+	if isReferenced == nil {
+		err = commonerrors.UndefinedVariable("reference function")
+		return
+	}
+	threshold := time.Now().Add(-gracePeriod)
+	err = s.walk(ctx, func(digest string, info os.FileInfo) error {
+		if isReferenced(digest) || info.ModTime().After(threshold) {
+			return nil
+		}
+		subErr := s.fs.Rm(s.blobPath(digest))
+		if subErr != nil {
+			return subErr
+		}
+		removed = append(removed, digest)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	// Temporary files older than the grace period were left behind by interrupted writes.
+	err = s.fs.WalkWithContext(ctx, s.tempPath(), func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if info.IsDir() || info.ModTime().After(threshold) {
+			return nil
+		}
+		return s.fs.Rm(path)
+	})
+	err = filesystem.ConvertFileSystemError(err)
+	return
+}
+
+func (s *Store) walk(ctx context.Context, fn func(digest string, info os.FileInfo) error) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	err = s.fs.WalkWithContextAndExclusionPatterns(ctx, s.root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if info.IsDir() {
+			return nil
+		}
+		digest := info.Name()
+		// Any file not following the store layout is ignored.
+		if s.validateDigest(digest) != nil || filesystem.FilePathClean(s.fs, path) != filesystem.FilePathClean(s.fs, s.blobPath(digest)) {
+			return nil
+		}
+		return fn(digest, info)
+	}, "^"+strings.ReplaceAll(tempDirectory, ".", "[.]")+"$")
+	err = filesystem.ConvertFileSystemError(err)
+	return
+}
+
+func (s *Store) validateDigest(digest string) error {
+	if len(digest) != s.digestLength {
+		return commonerrors.Newf(commonerrors.ErrInvalid, "[%v] is not a valid %v digest", digest, s.hashAlgorithm)
+	}
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return commonerrors.Newf(commonerrors.ErrInvalid, "[%v] is not a valid %v digest", digest, s.hashAlgorithm)
+		}
+	}
+	return nil
+}
+
+func (s *Store) blobPath(digest string) string {
+	return filesystem.FilePathJoin(s.fs, s.root, digest[:shardLength], digest[shardLength:2*shardLength], digest)
+}
+
+func (s *Store) tempPath() string {
+	return filesystem.FilePathJoin(s.fs, s.root, tempDirectory)
+}
+
+// verifyingReader hashes the content of a blob while it is being read and checks it matches the expected digest once the end of the blob is reached.
+type verifyingReader struct {
+	source    io.ReadCloser
+	reader    io.Reader
+	pipe      *io.PipeWriter
+	digest    string
+	result    chan hashResult
+	closeOnce sync.Once
+}
+
+type hashResult struct {
+	digest string
+	err    error
+}
+
+func newVerifyingReader(ctx context.Context, source io.ReadCloser, hasher hashing.IHash, digest string) *verifyingReader {
+	pipeReader, pipeWriter := io.Pipe()
+	result := make(chan hashResult, 1)
+	go func() {
+		d, err := hasher.CalculateWithContext(ctx, pipeReader)
+		_ = pipeReader.CloseWithError(err)
+		result <- hashResult{digest: d, err: err}
+	}()
+	return &verifyingReader{
+		source: source,
+		reader: io.TeeReader(source, pipeWriter),
+		pipe:   pipeWriter,
+		digest: digest,
+		result: result,
+	}
+}
+
+func (r *verifyingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if err != io.EOF {
+		err = filesystem.ConvertFileSystemError(err)
+		return
+	}
+	_ = r.pipe.Close()
+	res := <-r.result
+	r.result <- res
+	switch {
+	case res.err != nil:
+		err = res.err
+	case res.digest != r.digest:
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "blob is corrupted: content digest [%v] does not match [%v]", res.digest, r.digest)
+	}
+	return
+}
+
+func (r *verifyingReader) Close() (err error) {
+	r.closeOnce.Do(func() {
+		_ = r.pipe.CloseWithError(io.ErrClosedPipe)
+		err = filesystem.ConvertFileSystemError(r.source.Close())
+	})
+	return
+}
@@ -0,0 +1,184 @@
+package cas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	"github.com/ARM-software/golang-utils/utils/safeio"
+)
+
+func newTestStore(t *testing.T, fs filesystem.FS) (store IStore, root string) {
+	t.Helper()
+	root, err := fs.TempDirInTempDir("test-cas-")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = fs.Rm(root) })
+	store, err = NewStore(fs, root, "")
+	require.NoError(t, err)
+	return
+}
+
+func TestStore_PutGet(t *testing.T) {
+	for _, fsType := range filesystem.FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := filesystem.NewFs(fsType)
+			store, root := newTestStore(t, fs)
+			assert.Equal(t, hashing.HashSha256, store.GetHashAlgorithm())
+			content := faker.Paragraph()
+
+			digest, err := store.Put(context.Background(), strings.NewReader(content))
+			require.NoError(t, err)
+			assert.Equal(t, hashing.CalculateHash(content, hashing.HashSha256), digest)
+			assert.True(t, fs.Exists(filesystem.FilePathJoin(fs, root, digest[:2], digest[2:4], digest)))
+			found, err := store.Has(context.Background(), digest)
+			require.NoError(t, err)
+			assert.True(t, found)
+
+			reader, err := store.Get(context.Background(), digest)
+			require.NoError(t, err)
+			actual, err := safeio.ReadAll(context.Background(), reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			assert.Equal(t, content, string(actual))
+
+			// Identical content is only stored once.
+			digest2, err := store.Put(context.Background(), strings.NewReader(content))
+			require.NoError(t, err)
+			assert.Equal(t, digest, digest2)
+			count := 0
+			require.NoError(t, store.Walk(context.Background(), func(d string, size int64) error {
+				count++
+				assert.Equal(t, digest, d)
+				assert.Equal(t, int64(len(content)), size)
+				return nil
+			}))
+			assert.Equal(t, 1, count)
+			empty, err := fs.IsEmpty(filesystem.FilePathJoin(fs, root, tempDirectory))
+			require.NoError(t, err)
+			assert.True(t, empty)
+
+			require.NoError(t, store.Delete(context.Background(), digest))
+			found, err = store.Has(context.Background(), digest)
+			require.NoError(t, err)
+			assert.False(t, found)
+			errortest.AssertError(t, store.Delete(context.Background(), digest), commonerrors.ErrNotFound)
+			_, err = store.Get(context.Background(), digest)
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		})
+	}
+}
+
+func TestStore_Corruption(t *testing.T) {
+	for _, fsType := range filesystem.FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := filesystem.NewFs(fsType)
+			store, root := newTestStore(t, fs)
+			digest, err := store.Put(context.Background(), strings.NewReader(faker.Paragraph()))
+			require.NoError(t, err)
+			require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, root, digest[:2], digest[2:4], digest), []byte(faker.Sentence()), 0o644))
+
+			reader, err := store.Get(context.Background(), digest)
+			require.NoError(t, err)
+			defer func() { _ = reader.Close() }()
+			_, err = io.Copy(io.Discard, reader)
+			errortest.AssertError(t, err, commonerrors.ErrInvalid)
+		})
+	}
+}
+
+func TestStore_Failures(t *testing.T) {
+	fs := filesystem.NewInMemoryFileSystem()
+	store, _ := newTestStore(t, fs)
+	_, err := NewStore(nil, faker.Word(), "")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewStore(fs, "", "")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewStore(fs, faker.Word(), faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrNotFound, commonerrors.ErrUnsupported, commonerrors.ErrInvalid)
+
+	for _, digest := range []string{"", faker.Word(), strings.Repeat("z", 64), hashing.CalculateMD5Hash(faker.Word()), strings.ToUpper(hashing.CalculateHash(faker.Word(), hashing.HashSha256))} {
+		_, err = store.Get(context.Background(), digest)
+		errortest.AssertError(t, err, commonerrors.ErrInvalid)
+		_, err = store.Has(context.Background(), digest)
+		errortest.AssertError(t, err, commonerrors.ErrInvalid)
+		errortest.AssertError(t, store.Delete(context.Background(), digest), commonerrors.ErrInvalid)
+	}
+	_, err = store.Put(context.Background(), nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.Put(ctx, bytes.NewReader([]byte(faker.Paragraph())))
+	errortest.AssertError(t, err, commonerrors.ErrCancelled)
+}
+
+func TestStore_GarbageCollectAfterDuplicatePut(t *testing.T) {
+	fs := filesystem.NewInMemoryFileSystem()
+	store, _ := newTestStore(t, fs)
+	content := faker.Paragraph()
+	digest, err := store.Put(context.Background(), strings.NewReader(content))
+	require.NoError(t, err)
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, fs.Chtimes(store.(*Store).blobPath(digest), old, old))
+
+	duplicate, err := store.Put(context.Background(), strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, digest, duplicate)
+	removed, err := store.GarbageCollect(context.Background(), func(string) bool { return false }, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	exists, err := store.Has(context.Background(), digest)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestStore_GarbageCollect(t *testing.T) {
+	for _, fsType := range filesystem.FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := filesystem.NewFs(fsType)
+			store, root := newTestStore(t, fs)
+			var digests []string
+			for i := 0; i < 4; i++ {
+				digest, err := store.Put(context.Background(), strings.NewReader(fmt.Sprintf("%v %v", i, faker.Paragraph())))
+				require.NoError(t, err)
+				digests = append(digests, digest)
+			}
+			staleTemp, err := fs.TouchTempFile(filesystem.FilePathJoin(fs, root, tempDirectory), tempFilePattern)
+			require.NoError(t, err)
+			referenced := map[string]bool{digests[0]: true, digests[2]: true}
+			isReferenced := func(d string) bool { return referenced[d] }
+
+			// Recent blobs are protected by the grace period.
+			removed, err := store.GarbageCollect(context.Background(), isReferenced, time.Hour)
+			require.NoError(t, err)
+			assert.Empty(t, removed)
+			assert.True(t, fs.Exists(staleTemp))
+
+			removed, err = store.GarbageCollect(context.Background(), isReferenced, -time.Second)
+			require.NoError(t, err)
+			sort.Strings(removed)
+			expected := []string{digests[1], digests[3]}
+			sort.Strings(expected)
+			assert.Equal(t, expected, removed)
+			assert.False(t, fs.Exists(staleTemp))
+			var remaining []string
+			require.NoError(t, store.Walk(context.Background(), func(d string, _ int64) error {
+				remaining = append(remaining, d)
+				return nil
+			}))
+			assert.ElementsMatch(t, []string{digests[0], digests[2]}, remaining)
+		})
+	}
+}
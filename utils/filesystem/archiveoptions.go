@@ -0,0 +1,95 @@
+package filesystem
+
+import "github.com/ARM-software/golang-utils/utils/commonerrors"
+
+const (
+	// DefaultCompressionLevel uses the default level of the compression algorithm.
+	DefaultCompressionLevel = 0
+	// BestSpeedCompressionLevel favours compression speed over archive size.
+	BestSpeedCompressionLevel = 1
+	// BestCompressionLevel favours archive size over compression speed.
+	BestCompressionLevel = 9
+)
+
+// ArchiveOptions defines how archives (zip, tar) are created or extracted.
+type ArchiveOptions struct {
+	limits ILimits
+	// exclusionPatterns lists the patterns of items which should not be archived.
+	exclusionPatterns []string
+	// preserveXattrs states whether extended attributes should be recorded in archives and restored on extraction.
+	preserveXattrs bool
+	// compressionLevel is the level of compression applied when creating compressed archives.
+	compressionLevel int
+}
+
+// ArchiveOption configures ArchiveOptions.
+type ArchiveOption func(*ArchiveOptions) *ArchiveOptions
+
+// DefaultArchiveOptions returns the default archive options i.e. no limits apply, nothing is excluded, extended attributes are ignored and the default compression level is used.
+func DefaultArchiveOptions() *ArchiveOptions {
+	return &ArchiveOptions{limits: NoLimits()}
+}
+
+// WithArchiveOptions returns the archive options resulting from applying options to the defaults.
+func WithArchiveOptions(options ...ArchiveOption) (opts *ArchiveOptions) {
+	opts = DefaultArchiveOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithArchiveLimits sets the limits to enforce when creating or extracting archives.
+func WithArchiveLimits(limits ILimits) ArchiveOption {
+	return func(o *ArchiveOptions) *ArchiveOptions {
+		if o == nil {
+			o = DefaultArchiveOptions()
+		}
+		o.limits = limits
+		return o
+	}
+}
+
+// WithArchiveExclusionPatterns ignores any item matching an exclusion pattern when creating archives.
+func WithArchiveExclusionPatterns(exclusionPatterns ...string) ArchiveOption {
+	return func(o *ArchiveOptions) *ArchiveOptions {
+		if o == nil {
+			o = DefaultArchiveOptions()
+		}
+		o.exclusionPatterns = append(o.exclusionPatterns, exclusionPatterns...)
+		return o
+	}
+}
+
+// WithXattrs records extended attributes (and hence, ACLs) of items in archives and restores them on extraction.
+// Tar archives store them as PAX records (`SCHILY.xattr.*`) as done by GNU tar and bsdtar whereas zip archives store them in an extra field.
+func WithXattrs() ArchiveOption {
+	return func(o *ArchiveOptions) *ArchiveOptions {
+		if o == nil {
+			o = DefaultArchiveOptions()
+		}
+		o.preserveXattrs = true
+		return o
+	}
+}
+
+// WithCompressionLevel sets the level of compression applied when creating zip or compressed tar archives, from BestSpeedCompressionLevel to BestCompressionLevel.
+// DefaultCompressionLevel uses the default level of the compression algorithm.
+func WithCompressionLevel(level int) ArchiveOption {
+	return func(o *ArchiveOptions) *ArchiveOptions {
+		if o == nil {
+			o = DefaultArchiveOptions()
+		}
+		o.compressionLevel = level
+		return o
+	}
+}
+
+func (o *ArchiveOptions) checkCompressionLevel() error {
+	if o.compressionLevel < DefaultCompressionLevel || o.compressionLevel > BestCompressionLevel {
+		return commonerrors.Newf(commonerrors.ErrInvalid, "compression level %v is not between %v and %v", o.compressionLevel, DefaultCompressionLevel, BestCompressionLevel)
+	}
+	return nil
+}
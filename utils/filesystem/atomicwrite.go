@@ -0,0 +1,145 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+	"github.com/ARM-software/golang-utils/utils/safeio"
+)
+
+// WriteFileAtomically writes data to a file atomically. See FS.WriteFileAtomically.
+func WriteFileAtomically(ctx context.Context, filename string, data []byte, perm os.FileMode) error {
+	return globalFileSystem.WriteFileAtomically(ctx, filename, data, perm)
+}
+
+func (fs *VFS) WriteFileAtomically(ctx context.Context, filename string, data []byte, perm os.FileMode) (err error) {
+	n, err := fs.WriteToFileAtomically(ctx, filename, bytes.NewReader(data), perm)
+	if err != nil {
+		return
+	}
+	if int(n) < len(data) {
+		err = io.ErrShortWrite
+	}
+	return
+}
+
+func (fs *VFS) WriteToFileAtomically(ctx context.Context, filename string, reader io.Reader, perm os.FileMode) (written int64, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if reader == nil {
+		err = commonerrors.UndefinedVariable("reader")
+		return
+	}
+	mode := perm
+	uid, gid, ownersErr := -1, -1, error(nil)
+	info, statErr := fs.Stat(filename)
+	if statErr == nil {
+		if info.IsDir() {
+			err = commonerrors.Newf(commonerrors.ErrInvalid, "path [%v] is a directory", filename)
+			return
+		}
+		// The permissions and ownership of the file being replaced are preserved.
+		mode = info.Mode().Perm()
+		uid, gid, ownersErr = fs.FetchOwners(filename)
+		if ownersErr != nil {
+			uid, gid = -1, -1
+		}
+	}
+	if fs.GetType() == StandardFS {
+		written, err = fs.writeToFileUsingRename(ctx, filename, reader, mode, uid, gid)
+	} else {
+		written, err = fs.writeToFileUsingStaging(ctx, filename, reader, mode)
+	}
+	return
+}
+
+// writeToFileUsingRename writes data to a temporary file in the same directory as the target, flushes it to storage and then renames it over the target.
+// As renaming is atomic on POSIX filesystems, consumers either see the previous content or the new content but never a partially written file.
+func (fs *VFS) writeToFileUsingRename(ctx context.Context, filename string, reader io.Reader, mode os.FileMode, uid, gid int) (written int64, err error) {
+	dir := FilePathDir(fs, filename)
+	tmp, err := fs.TempFile(dir, "."+FilePathBase(fs, filename)+".tmp-*")
+	if err != nil {
+		return
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = tmp.Close()
+		if err != nil {
+			_ = fs.vfs.Remove(tmpName)
+		}
+	}()
+	written, err = safeio.CopyDataWithContext(ctx, reader, tmp)
+	if err != nil {
+		return
+	}
+	if written == 0 {
+		err = commonerrors.New(commonerrors.ErrEmpty, "no bytes were written")
+		return
+	}
+	err = ConvertFileSystemError(tmp.Sync())
+	if err != nil {
+		return
+	}
+	err = ConvertFileSystemError(tmp.Close())
+	if err != nil {
+		return
+	}
+	err = fs.Chmod(tmpName, mode)
+	if err != nil {
+		return
+	}
+	if uid >= 0 && gid >= 0 {
+		// Ownership can only be changed by privileged users and so, it is preserved on a best effort basis.
+		_ = fs.Chown(tmpName, uid, gid)
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	err = ConvertFileSystemError(fs.vfs.Rename(tmpName, filename))
+	if err != nil {
+		return
+	}
+	// Flushing the directory so that the rename persists in case of a crash.
+	if d, subErr := fs.vfs.Open(dir); subErr == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return
+}
+
+// writeToFileUsingStaging is the fallback strategy for filesystems (e.g. in-memory) which do not provide atomic renames or durable storage:
+// all the data is read before the target is modified so that a reading failure or a cancellation never leaves a partially written file.
+func (fs *VFS) writeToFileUsingStaging(ctx context.Context, filename string, reader io.Reader, mode os.FileMode) (written int64, err error) {
+	data, err := safeio.ReadAll(ctx, reader)
+	if err != nil {
+		return
+	}
+	if len(data) == 0 {
+		err = commonerrors.New(commonerrors.ErrEmpty, "no bytes were written")
+		return
+	}
+	f, err := fs.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	n, err := f.Write(data)
+	written = int64(n)
+	err = ConvertFileSystemError(err)
+	if err != nil {
+		return
+	}
+	err = ConvertFileSystemError(f.Close())
+	return
+}
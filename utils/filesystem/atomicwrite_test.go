@@ -0,0 +1,101 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/platform"
+)
+
+func TestWriteFileAtomically(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			dir, err := fs.TempDirInTempDir("test-atomic-write-")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(dir) }()
+			file := FilePathJoin(fs, dir, "config.json")
+
+			content := faker.Paragraph()
+			require.NoError(t, fs.WriteFileAtomically(context.Background(), file, []byte(content), 0o600))
+			actual, err := fs.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(actual))
+
+			newContent := faker.Paragraph()
+			written, err := fs.WriteToFileAtomically(context.Background(), file, bytes.NewReader([]byte(newContent)), 0o644)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(newContent)), written)
+			actual, err = fs.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, newContent, string(actual))
+			if !platform.IsWindows() {
+				// The permissions of the file being replaced are preserved.
+				info, err := fs.Stat(file)
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+			}
+			items, err := fs.Ls(dir)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"config.json"}, items)
+		})
+	}
+}
+
+type failingReader struct {
+	data io.Reader
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, errors.New("failure")
+	}
+	return n, err
+}
+
+func TestWriteToFileAtomically_Failures(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			dir, err := fs.TempDirInTempDir("test-atomic-write-")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(dir) }()
+			file := FilePathJoin(fs, dir, "config.json")
+			content := faker.Paragraph()
+			require.NoError(t, fs.WriteFile(file, []byte(content), 0o644))
+
+			_, err = fs.WriteToFileAtomically(context.Background(), file, &failingReader{data: bytes.NewReader([]byte(faker.Paragraph()))}, 0o644)
+			require.Error(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err = fs.WriteFileAtomically(ctx, file, []byte(faker.Paragraph()), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrCancelled)
+			_, err = fs.WriteToFileAtomically(context.Background(), file, bytes.NewReader(nil), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrEmpty)
+			_, err = fs.WriteToFileAtomically(context.Background(), file, nil, 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrUndefined)
+			err = fs.WriteFileAtomically(context.Background(), dir, []byte(faker.Paragraph()), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrInvalid)
+
+			// The original file is left untouched.
+			actual, err := fs.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(actual))
+			items, err := fs.Ls(dir)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"config.json"}, items)
+		})
+	}
+}
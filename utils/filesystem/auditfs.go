@@ -0,0 +1,669 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// AuditRecord describes an operation performed on an audited filesystem.
+type AuditRecord struct {
+	Operation FileOperation `json:"operation"`
+	Path      string        `json:"path"`
+	// Target is the new path of an item when it is renamed or the destination of a link when it is created.
+	Target string `json:"target,omitempty"`
+	// Size is the number of bytes read or written, or the size a file was truncated to.
+	Size     int64         `json:"size,omitempty"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Error describes why the operation failed, if it did.
+	Error string `json:"error,omitempty"`
+	// Tag is the tag of the audited filesystem (see WithAuditTag) e.g. to identify the component performing the operation.
+	Tag string `json:"tag,omitempty"`
+}
+
+// Failed states whether the operation failed.
+func (r *AuditRecord) Failed() bool {
+	return r.Error != ""
+}
+
+func (r AuditRecord) String() string {
+	b := strings.Builder{}
+	if r.Tag != "" {
+		_, _ = fmt.Fprintf(&b, "[%v] ", r.Tag)
+	}
+	_, _ = fmt.Fprintf(&b, "%v %v", r.Operation, r.Path)
+	if r.Target != "" {
+		_, _ = fmt.Fprintf(&b, " -> %v", r.Target)
+	}
+	if r.Size > 0 {
+		_, _ = fmt.Fprintf(&b, " (%v bytes)", r.Size)
+	}
+	_, _ = fmt.Fprintf(&b, " in %v", r.Duration)
+	if r.Failed() {
+		_, _ = fmt.Fprintf(&b, ": %v", r.Error)
+	}
+	return b.String()
+}
+
+// AuditOptions defines how operations on an audited filesystem are recorded.
+type AuditOptions struct {
+	// recordReads states whether operations which do not modify the filesystem (e.g. open, read, stat, list) should be recorded too.
+	recordReads bool
+	tag         string
+}
+
+// AuditOption configures AuditOptions.
+type AuditOption func(*AuditOptions) *AuditOptions
+
+// DefaultAuditOptions returns the default audit options i.e. only operations modifying the filesystem are recorded.
+func DefaultAuditOptions() *AuditOptions {
+	return &AuditOptions{}
+}
+
+// WithAuditOptions returns the audit options resulting from applying options to the defaults.
+func WithAuditOptions(options ...AuditOption) (opts *AuditOptions) {
+	opts = DefaultAuditOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithReadOperationsAudit records operations which do not modify the filesystem too.
+func WithReadOperationsAudit() AuditOption {
+	return func(o *AuditOptions) *AuditOptions {
+		if o == nil {
+			o = DefaultAuditOptions()
+		}
+		o.recordReads = true
+		return o
+	}
+}
+
+// WithAuditTag tags all the records of the filesystem e.g. with the name of the component using it.
+func WithAuditTag(tag string) AuditOption {
+	return func(o *AuditOptions) *AuditOptions {
+		if o == nil {
+			o = DefaultAuditOptions()
+		}
+		o.tag = tag
+		return o
+	}
+}
+
+// AuditJournal is an audit sink keeping records in memory. It can be serialised (e.g. using the serialization packages) and replayed into a report of the changes made (see GetDiffReport).
+type AuditJournal struct {
+	mu      sync.RWMutex
+	records []AuditRecord
+}
+
+// NewAuditJournal returns an empty audit journal.
+func NewAuditJournal() *AuditJournal {
+	return &AuditJournal{}
+}
+
+func (j *AuditJournal) Record(record AuditRecord) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.records = append(j.records, record)
+}
+
+// GetRecords returns all the records in the order they were made.
+func (j *AuditJournal) GetRecords() []AuditRecord {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	records := make([]AuditRecord, len(j.records))
+	copy(records, j.records)
+	return records
+}
+
+// Clear removes all the records.
+func (j *AuditJournal) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.records = nil
+}
+
+// GetDiffReport replays the records to determine what was changed on the filesystem. See ReplayAuditRecords.
+func (j *AuditJournal) GetDiffReport() *ManifestDiff {
+	return ReplayAuditRecords(j.GetRecords())
+}
+
+func (j *AuditJournal) MarshalJSON() ([]byte, error) {
+	records := j.GetRecords()
+	if records == nil {
+		records = []AuditRecord{}
+	}
+	return json.Marshal(records)
+}
+
+func (j *AuditJournal) UnmarshalJSON(data []byte) error {
+	var records []AuditRecord
+	err := json.Unmarshal(data, &records)
+	if err != nil {
+		return commonerrors.WrapError(commonerrors.ErrMarshalling, err, "could not unmarshal audit journal")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.records = records
+	return nil
+}
+
+// IAuditLogger describes loggers (e.g. logs.Loggers) audit records can be written to.
+type IAuditLogger interface {
+	Log(output ...interface{})
+	LogError(err ...interface{})
+}
+
+type loggerAuditSink struct {
+	logger IAuditLogger
+}
+
+func (s *loggerAuditSink) Record(record AuditRecord) {
+	if record.Failed() {
+		s.logger.LogError(record.String())
+	} else {
+		s.logger.Log(record.String())
+	}
+}
+
+// NewLoggerAuditSink returns an audit sink logging records: failed operations are logged as errors.
+func NewLoggerAuditSink(logger IAuditLogger) (IAuditSink, error) {
+	if logger == nil {
+		return nil, commonerrors.UndefinedVariable("logger")
+	}
+	return &loggerAuditSink{logger: logger}, nil
+}
+
+type auditStatus int
+
+const (
+	auditStatusAdded auditStatus = iota
+	auditStatusModified
+	auditStatusRemoved
+)
+
+type auditReplay map[string]auditStatus
+
+func isWithinAuditedPath(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+"/") || strings.HasPrefix(path, parent+string(filepath.Separator))
+}
+
+func (r auditReplay) added(path string) {
+	status, found := r[path]
+	switch {
+	case !found:
+		r[path] = auditStatusAdded
+	case status == auditStatusRemoved:
+		// The item was recreated.
+		r[path] = auditStatusModified
+	}
+}
+
+func (r auditReplay) modified(path string) {
+	if _, found := r[path]; !found {
+		r[path] = auditStatusModified
+	}
+}
+
+// removed marks an item and all its descendants as removed. Items which were added are simply forgotten.
+func (r auditReplay) removed(path string) {
+	_, found := r[path]
+	for p, status := range r {
+		if !isWithinAuditedPath(p, path) {
+			continue
+		}
+		if status == auditStatusAdded {
+			delete(r, p)
+		} else {
+			r[p] = auditStatusRemoved
+		}
+	}
+	if !found {
+		r[path] = auditStatusRemoved
+	}
+}
+
+// renamed moves an item to a new path: it is considered removed from its previous path and added at the new one, along with any descendant known to exist.
+func (r auditReplay) renamed(oldPath, newPath string) {
+	var moved []string
+	for p, status := range r {
+		if p != oldPath && isWithinAuditedPath(p, oldPath) && status != auditStatusRemoved {
+			moved = append(moved, newPath+p[len(oldPath):])
+		}
+	}
+	r.removed(oldPath)
+	r.added(newPath)
+	for i := range moved {
+		r.added(moved[i])
+	}
+}
+
+// ReplayAuditRecords determines which items were added, removed or modified by the successful operations described by records.
+// Operations cancelling each other out (e.g. a file created and then removed) are not reported.
+func ReplayAuditRecords(records []AuditRecord) *ManifestDiff {
+	replay := auditReplay{}
+	for i := range records {
+		record := &records[i]
+		if record.Failed() {
+			continue
+		}
+		switch record.Operation {
+		case FileOperationCreate, FileOperationMkdir, FileOperationLink:
+			replay.added(record.Path)
+		case FileOperationWrite, FileOperationTruncate, FileOperationChmod, FileOperationChown, FileOperationChtimes, FileOperationSetXattr:
+			replay.modified(record.Path)
+		case FileOperationRemove:
+			replay.removed(record.Path)
+		case FileOperationRename:
+			replay.renamed(record.Path, record.Target)
+		default:
+		}
+	}
+	diff := &ManifestDiff{}
+	for path, status := range replay {
+		switch status {
+		case auditStatusAdded:
+			diff.Added = append(diff.Added, path)
+		case auditStatusModified:
+			diff.Modified = append(diff.Modified, path)
+		case auditStatusRemoved:
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff
+}
+
+// auditFs is an afero.Fs recording the operations performed on a source filesystem.
+type auditFs struct {
+	source afero.Fs
+	sink   IAuditSink
+	opts   AuditOptions
+}
+
+func (a *auditFs) record(op FileOperation, path, target string, size int64, start time.Time, err error) {
+	if !a.opts.recordReads && !isMutatingFileOperation(op) {
+		return
+	}
+	record := AuditRecord{
+		Operation: op,
+		Path:      path,
+		Target:    target,
+		Size:      size,
+		Time:      start.UTC(),
+		Duration:  time.Since(start),
+		Tag:       a.opts.tag,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	a.sink.Record(record)
+}
+
+func isMutatingFileOperation(op FileOperation) bool {
+	switch op {
+	case FileOperationOpen, FileOperationRead, FileOperationList, FileOperationStat, FileOperationReadlink, FileOperationGetXattr, FileOperationClose, FileOperationSync:
+		return false
+	default:
+		return true
+	}
+}
+
+func (a *auditFs) Name() string {
+	return "AuditFs"
+}
+
+func (a *auditFs) Create(name string) (afero.File, error) {
+	return a.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func (a *auditFs) Mkdir(name string, perm os.FileMode) error {
+	start := time.Now()
+	err := a.source.Mkdir(name, perm)
+	a.record(FileOperationMkdir, name, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) MkdirAll(path string, perm os.FileMode) error {
+	if fi, err := a.source.Stat(path); err == nil && fi.IsDir() {
+		return nil
+	}
+	start := time.Now()
+	err := a.source.MkdirAll(path, perm)
+	a.record(FileOperationMkdir, path, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) Open(name string) (afero.File, error) {
+	return a.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (a *auditFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	op := FileOperationOpen
+	if flag&os.O_CREATE != 0 {
+		if _, err := a.source.Stat(name); err != nil {
+			op = FileOperationCreate
+		}
+	}
+	if op == FileOperationOpen && flag&os.O_TRUNC != 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		op = FileOperationTruncate
+	}
+	start := time.Now()
+	f, err := a.source.OpenFile(name, flag, perm)
+	a.record(op, name, "", 0, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &auditFile{File: f, fs: a, openedAt: start}, nil
+}
+
+func (a *auditFs) Remove(name string) error {
+	start := time.Now()
+	err := a.source.Remove(name)
+	a.record(FileOperationRemove, name, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) RemoveAll(path string) error {
+	if _, err := a.lstat(path); err != nil {
+		// Nothing to remove.
+		return a.source.RemoveAll(path)
+	}
+	start := time.Now()
+	err := a.source.RemoveAll(path)
+	a.record(FileOperationRemove, path, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) Rename(oldname, newname string) error {
+	start := time.Now()
+	err := a.source.Rename(oldname, newname)
+	a.record(FileOperationRename, oldname, newname, 0, start, err)
+	return err
+}
+
+func (a *auditFs) Stat(name string) (os.FileInfo, error) {
+	start := time.Now()
+	fi, err := a.source.Stat(name)
+	a.record(FileOperationStat, name, "", 0, start, err)
+	return fi, err
+}
+
+func (a *auditFs) lstat(name string) (os.FileInfo, error) {
+	if lstater, ok := a.source.(afero.Lstater); ok {
+		fi, _, err := lstater.LstatIfPossible(name)
+		return fi, err
+	}
+	return a.source.Stat(name)
+}
+
+func (a *auditFs) LstatIfPossible(name string) (fi os.FileInfo, lstatCalled bool, err error) {
+	start := time.Now()
+	if lstater, ok := a.source.(afero.Lstater); ok {
+		fi, lstatCalled, err = lstater.LstatIfPossible(name)
+	} else {
+		fi, err = a.source.Stat(name)
+	}
+	a.record(FileOperationStat, name, "", 0, start, err)
+	return
+}
+
+func (a *auditFs) SymlinkIfPossible(oldname, newname string) error {
+	linker, ok := a.source.(afero.Linker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+	}
+	start := time.Now()
+	err := linker.SymlinkIfPossible(oldname, newname)
+	a.record(FileOperationLink, newname, oldname, 0, start, err)
+	return err
+}
+
+func (a *auditFs) ReadlinkIfPossible(name string) (string, error) {
+	reader, ok := a.source.(afero.LinkReader)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+	}
+	start := time.Now()
+	target, err := reader.ReadlinkIfPossible(name)
+	a.record(FileOperationReadlink, name, target, 0, start, err)
+	return target, err
+}
+
+func (a *auditFs) LinkIfPossible(oldname, newname string) error {
+	linker, ok := a.source.(ILinker)
+	if !ok {
+		return commonerrors.Newf(commonerrors.ErrNotImplemented, "cannot link `%v` to `%v`", oldname, newname)
+	}
+	start := time.Now()
+	err := linker.LinkIfPossible(oldname, newname)
+	a.record(FileOperationLink, newname, oldname, 0, start, err)
+	return err
+}
+
+func (a *auditFs) Chmod(name string, mode os.FileMode) error {
+	start := time.Now()
+	err := a.source.Chmod(name, mode)
+	a.record(FileOperationChmod, name, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) Chown(name string, uid, gid int) error {
+	start := time.Now()
+	err := a.source.Chown(name, uid, gid)
+	a.record(FileOperationChown, name, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) ChownIfPossible(name string, uid int, gid int) (err error) {
+	start := time.Now()
+	if chowner, ok := a.source.(IChowner); ok {
+		err = chowner.ChownIfPossible(name, uid, gid)
+	} else {
+		err = a.source.Chown(name, uid, gid)
+	}
+	a.record(FileOperationChown, name, "", 0, start, err)
+	return
+}
+
+func (a *auditFs) GetXattrIfPossible(name, attribute string) (value []byte, err error) {
+	xattrer, ok := a.source.(IXattrer)
+	if !ok {
+		return nil, ErrXattrNotImplemented
+	}
+	start := time.Now()
+	value, err = xattrer.GetXattrIfPossible(name, attribute)
+	a.record(FileOperationGetXattr, name, attribute, 0, start, err)
+	return
+}
+
+func (a *auditFs) SetXattrIfPossible(name, attribute string, value []byte) (err error) {
+	xattrer, ok := a.source.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	start := time.Now()
+	err = xattrer.SetXattrIfPossible(name, attribute, value)
+	a.record(FileOperationSetXattr, name, attribute, int64(len(value)), start, err)
+	return
+}
+
+func (a *auditFs) ListXattrIfPossible(name string) (attributes []string, err error) {
+	xattrer, ok := a.source.(IXattrer)
+	if !ok {
+		return nil, ErrXattrNotImplemented
+	}
+	start := time.Now()
+	attributes, err = xattrer.ListXattrIfPossible(name)
+	a.record(FileOperationGetXattr, name, "", 0, start, err)
+	return
+}
+
+func (a *auditFs) RemoveXattrIfPossible(name, attribute string) (err error) {
+	xattrer, ok := a.source.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	start := time.Now()
+	err = xattrer.RemoveXattrIfPossible(name, attribute)
+	a.record(FileOperationSetXattr, name, attribute, 0, start, err)
+	return
+}
+
+func (a *auditFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	start := time.Now()
+	err := a.source.Chtimes(name, atime, mtime)
+	a.record(FileOperationChtimes, name, "", 0, start, err)
+	return err
+}
+
+func (a *auditFs) ForceRemoveIfPossible(name string) (err error) {
+	start := time.Now()
+	if remover, ok := a.source.(IForceRemover); ok {
+		err = remover.ForceRemoveIfPossible(name)
+	} else {
+		err = a.source.RemoveAll(name)
+	}
+	a.record(FileOperationRemove, name, "", 0, start, err)
+	return
+}
+
+// auditFile is a file of an auditFs. Reads and writes are aggregated and recorded when the file is closed so that journals do not grow with every buffer transferred.
+type auditFile struct {
+	afero.File
+	fs          *auditFs
+	openedAt    time.Time
+	mu          sync.Mutex
+	read        int64
+	readTime    time.Duration
+	written     int64
+	writeTime   time.Duration
+	hasWritten  bool
+	transferErr error
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+func (f *auditFile) transfer(isWrite bool, fn func() (int, error)) (int, error) {
+	start := time.Now()
+	n, err := fn()
+	elapsed := time.Since(start)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if isWrite {
+		f.written += int64(n)
+		f.writeTime += elapsed
+		f.hasWritten = true
+	} else {
+		f.read += int64(n)
+		f.readTime += elapsed
+	}
+	if err != nil && f.transferErr == nil && (isWrite || err != io.EOF) {
+		f.transferErr = err
+	}
+	return n, err
+}
+
+func (f *auditFile) Read(p []byte) (int, error) {
+	return f.transfer(false, func() (int, error) { return f.File.Read(p) })
+}
+
+func (f *auditFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.transfer(false, func() (int, error) { return f.File.ReadAt(p, off) })
+}
+
+func (f *auditFile) Write(p []byte) (int, error) {
+	return f.transfer(true, func() (int, error) { return f.File.Write(p) })
+}
+
+func (f *auditFile) WriteAt(p []byte, off int64) (int, error) {
+	return f.transfer(true, func() (int, error) { return f.File.WriteAt(p, off) })
+}
+
+func (f *auditFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *auditFile) Readdir(count int) ([]os.FileInfo, error) {
+	start := time.Now()
+	items, err := f.File.Readdir(count)
+	f.fs.record(FileOperationList, f.Name(), "", 0, start, err)
+	return items, err
+}
+
+func (f *auditFile) Readdirnames(n int) ([]string, error) {
+	start := time.Now()
+	names, err := f.File.Readdirnames(n)
+	f.fs.record(FileOperationList, f.Name(), "", 0, start, err)
+	return names, err
+}
+
+func (f *auditFile) Truncate(size int64) error {
+	start := time.Now()
+	err := f.File.Truncate(size)
+	f.fs.record(FileOperationTruncate, f.Name(), "", size, start, err)
+	return err
+}
+
+func (f *auditFile) Close() error {
+	f.closeOnce.Do(func() {
+		f.closeErr = f.File.Close()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.hasWritten {
+			f.fs.sink.Record(f.newTransferRecord(FileOperationWrite, f.written, f.writeTime))
+		}
+		if f.fs.opts.recordReads && f.read > 0 {
+			f.fs.sink.Record(f.newTransferRecord(FileOperationRead, f.read, f.readTime))
+		}
+	})
+	return f.closeErr
+}
+
+func (f *auditFile) newTransferRecord(op FileOperation, size int64, duration time.Duration) AuditRecord {
+	record := AuditRecord{
+		Operation: op,
+		Path:      f.Name(),
+		Size:      size,
+		Time:      f.openedAt.UTC(),
+		Duration:  duration,
+		Tag:       f.fs.opts.tag,
+	}
+	if f.transferErr != nil {
+		record.Error = f.transferErr.Error()
+	}
+	return record
+}
+
+// NewAuditFileSystem returns a filesystem recording the operations performed on `fs` to `sink` (e.g. an AuditJournal or a logger sink).
+// By default, only operations modifying the filesystem are recorded. Reads and writes to a file are aggregated into a single record when the file is closed.
+func NewAuditFileSystem(fs FS, sink IAuditSink, options ...AuditOption) (FS, error) {
+	if fs == nil {
+		return nil, commonerrors.UndefinedVariable("file system")
+	}
+	if sink == nil {
+		return nil, commonerrors.UndefinedVariable("audit sink")
+	}
+	vfs, ok := fs.(*VFS)
+	if !ok {
+		return nil, commonerrors.Newf(commonerrors.ErrUnsupported, "file system of type [%T] cannot be audited", fs)
+	}
+	wrapped := &auditFs{source: vfs.vfs, sink: sink, opts: *WithAuditOptions(options...)}
+	return NewVirtualFileSystemWithPathSeparator(wrapped, vfs.fsType, vfs.pathConverter, vfs.pathSeparator), nil
+}
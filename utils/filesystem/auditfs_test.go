@@ -0,0 +1,163 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func TestAuditFileSystem(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			underlying := NewFs(fsType)
+			root, err := underlying.TempDirInTempDir("test-audit-")
+			require.NoError(t, err)
+			defer func() { _ = underlying.Rm(root) }()
+			existing := FilePathJoin(underlying, root, "existing.txt")
+			removed := FilePathJoin(underlying, root, "removed.txt")
+			renamed := FilePathJoin(underlying, root, "renamed.txt")
+			require.NoError(t, underlying.WriteFile(existing, []byte(faker.Sentence()), 0o644))
+			require.NoError(t, underlying.WriteFile(removed, []byte(faker.Sentence()), 0o644))
+			require.NoError(t, underlying.WriteFile(renamed, []byte(faker.Sentence()), 0o644))
+
+			journal := NewAuditJournal()
+			tag := faker.Word()
+			fs, err := NewAuditFileSystem(underlying, journal, WithAuditTag(tag))
+			require.NoError(t, err)
+
+			content := faker.Paragraph()
+			require.NoError(t, fs.WriteFile(existing, []byte(content), 0o644))
+			require.NoError(t, fs.MkDir(FilePathJoin(fs, root, "dir")))
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, root, "dir", "new.txt"), []byte(content), 0o644))
+			require.NoError(t, fs.Rm(removed))
+			require.NoError(t, fs.Move(renamed, FilePathJoin(fs, root, "dir", "moved.txt")))
+			temp := FilePathJoin(fs, root, "temp.txt")
+			require.NoError(t, fs.Touch(temp))
+			require.NoError(t, fs.Rm(temp))
+			_, err = fs.ReadFile(existing)
+			require.NoError(t, err)
+
+			records := journal.GetRecords()
+			require.NotEmpty(t, records)
+			for i := range records {
+				assert.Equal(t, tag, records[i].Tag)
+				assert.NotEqual(t, FileOperationRead, records[i].Operation)
+				assert.NotEqual(t, FileOperationStat, records[i].Operation)
+			}
+			found := false
+			for i := range records {
+				if records[i].Operation == FileOperationWrite && records[i].Path == existing {
+					found = true
+					assert.Equal(t, int64(len(content)), records[i].Size)
+					assert.False(t, records[i].Failed())
+				}
+			}
+			assert.True(t, found)
+
+			diff := journal.GetDiffReport()
+			assert.Equal(t, []string{FilePathJoin(fs, root, "dir"), FilePathJoin(fs, root, "dir", "moved.txt"), FilePathJoin(fs, root, "dir", "new.txt")}, diff.Added)
+			assert.Equal(t, []string{removed, renamed}, diff.Removed)
+			assert.Equal(t, []string{existing}, diff.Modified)
+
+			// The journal can be serialised.
+			data, err := json.Marshal(journal)
+			require.NoError(t, err)
+			loaded := NewAuditJournal()
+			require.NoError(t, json.Unmarshal(data, loaded))
+			assert.Equal(t, diff, loaded.GetDiffReport())
+			assert.Len(t, loaded.GetRecords(), len(records))
+			journal.Clear()
+			assert.Empty(t, journal.GetRecords())
+		})
+	}
+}
+
+func TestAuditFileSystem_Reads(t *testing.T) {
+	journal := NewAuditJournal()
+	fs, err := NewAuditFileSystem(NewInMemoryFileSystem(), journal, WithReadOperationsAudit())
+	require.NoError(t, err)
+	file := FilePathJoin(fs, t.TempDir(), "test.txt")
+	content := faker.Paragraph()
+	require.NoError(t, fs.WriteFile(file, []byte(content), 0o644))
+	journal.Clear()
+	_, err = fs.ReadFile(file)
+	require.NoError(t, err)
+	_, err = fs.ReadFile(FilePathJoin(fs, t.TempDir(), faker.Word()))
+	require.Error(t, err)
+	var operations []FileOperation
+	var failed int
+	for _, record := range journal.GetRecords() {
+		operations = append(operations, record.Operation)
+		if record.Failed() {
+			failed++
+		}
+		if record.Operation == FileOperationRead {
+			assert.Equal(t, int64(len(content)), record.Size)
+		}
+	}
+	assert.Contains(t, operations, FileOperationOpen)
+	assert.Contains(t, operations, FileOperationRead)
+	assert.NotZero(t, failed)
+	assert.False(t, journal.GetDiffReport().HasChanges())
+}
+
+type testAuditLogger struct {
+	logs   []string
+	errors []string
+}
+
+func (l *testAuditLogger) Log(output ...interface{}) {
+	l.logs = append(l.logs, fmt.Sprint(output...))
+}
+
+func (l *testAuditLogger) LogError(err ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprint(err...))
+}
+
+func TestLoggerAuditSink(t *testing.T) {
+	logger := &testAuditLogger{}
+	sink, err := NewLoggerAuditSink(logger)
+	require.NoError(t, err)
+	fs, err := NewAuditFileSystem(NewInMemoryFileSystem(), sink, WithAuditTag("plugin"))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, fs.MkDir(FilePathJoin(fs, dir, "test")))
+	require.Error(t, fs.Chmod(FilePathJoin(fs, dir, faker.Word()), 0o644))
+	require.NotEmpty(t, logger.logs)
+	assert.True(t, strings.HasPrefix(logger.logs[0], "[plugin] mkdir"))
+	require.Len(t, logger.errors, 1)
+	assert.Contains(t, logger.errors[0], "chmod")
+
+	_, err = NewLoggerAuditSink(nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewAuditFileSystem(nil, sink)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewAuditFileSystem(NewInMemoryFileSystem(), nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+}
+
+func TestReplayAuditRecords(t *testing.T) {
+	records := []AuditRecord{
+		{Operation: FileOperationMkdir, Path: "/a"},
+		{Operation: FileOperationCreate, Path: "/a/b"},
+		{Operation: FileOperationRename, Path: "/a", Target: "/c"},
+		{Operation: FileOperationRemove, Path: "/d"},
+		{Operation: FileOperationCreate, Path: "/d"},
+		{Operation: FileOperationChmod, Path: "/e"},
+		{Operation: FileOperationRemove, Path: "/f", Error: "failure"},
+		{Operation: FileOperationWrite, Path: "/g"},
+		{Operation: FileOperationRemove, Path: "/g"},
+	}
+	diff := ReplayAuditRecords(records)
+	assert.Equal(t, []string{"/c", "/c/b"}, diff.Added)
+	assert.Equal(t, []string{"/g"}, diff.Removed)
+	assert.Equal(t, []string{"/d", "/e"}, diff.Modified)
+}
@@ -0,0 +1,439 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// basePathFs is an afero.Fs confining all operations to a root directory of a source filesystem.
+// Unlike afero.BasePathFs, any path or symbolic link trying to escape the root is rejected with commonerrors.ErrForbidden rather than silently clamped.
+type basePathFs struct {
+	source afero.Fs
+	// root is the path of the root directory in the source filesystem with any symbolic link resolved.
+	root string
+}
+
+func newBasePathFs(source afero.Fs, root string) (fs *basePathFs, err error) {
+	if source == nil {
+		err = commonerrors.UndefinedVariable("source file system")
+		return
+	}
+	if root == "" {
+		err = commonerrors.UndefinedVariable("root directory")
+		return
+	}
+	unrestricted := &basePathFs{source: source, root: string(filepath.Separator)}
+	resolvedRoot, err := unrestricted.resolve(root, filepath.Clean(root), true)
+	if err != nil {
+		return
+	}
+	fi, err := source.Stat(resolvedRoot)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrNotFound, err, "could not find root directory [%v]", root)
+		return
+	}
+	if !fi.IsDir() {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "root [%v] is not a directory", root)
+		return
+	}
+	fs = &basePathFs{source: source, root: resolvedRoot}
+	return
+}
+
+func newRootEscapeError(name string) error {
+	return commonerrors.Newf(commonerrors.ErrForbidden, "path [%v] is outside the file system root", name)
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == filepath.Separator
+}
+
+func (b *basePathFs) isWithinRoot(realPath string) bool {
+	rel, err := filepath.Rel(b.root, realPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// toRealPath rebases a path onto the root. Relative paths are considered relative to the root and any traversal above the root is rejected.
+func (b *basePathFs) toRealPath(name string) (realPath string, err error) {
+	depth := 0
+	for _, element := range strings.FieldsFunc(name, isPathSeparator) {
+		switch element {
+		case ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				err = newRootEscapeError(name)
+				return
+			}
+		default:
+			depth++
+		}
+	}
+	realPath = b.convertPath(name)
+	return
+}
+
+// convertPath lexically maps a path onto the root, clamping any traversal above it.
+func (b *basePathFs) convertPath(name string) string {
+	return filepath.Join(b.root, filepath.Clean(string(filepath.Separator)+filepath.FromSlash(strings.TrimPrefix(name, filepath.VolumeName(name)))))
+}
+
+// fromRealPath converts a path of the source filesystem into a path relative to the root.
+func (b *basePathFs) fromRealPath(realPath string) string {
+	rel, err := filepath.Rel(b.root, realPath)
+	if err != nil || rel == "." {
+		return string(filepath.Separator)
+	}
+	return string(filepath.Separator) + rel
+}
+
+// resolve follows the symbolic links present in a path of the source filesystem and ensures none of them point outside the root.
+// If followLast is false, the last element of the path is not followed if it is a link.
+func (b *basePathFs) resolve(name string, realPath string, followLast bool) (resolved string, err error) {
+	lstater, isLstater := b.source.(afero.Lstater)
+	reader, isLinkReader := b.source.(afero.LinkReader)
+	if !isLstater || !isLinkReader {
+		// The source does not support links.
+		resolved = realPath
+		return
+	}
+	resolver := &symlinkResolver{
+		root:      b.root,
+		separator: filepath.Separator,
+		isAbs:     filepath.IsAbs,
+		lstat: func(path string) (os.FileInfo, error) {
+			fi, _, err := lstater.LstatIfPossible(path)
+			return fi, err
+		},
+		readlink: reader.ReadlinkIfPossible,
+	}
+	resolved, err = resolver.resolve(realPath, followLast)
+	switch {
+	case err == nil:
+	case errors.Is(err, syscall.ELOOP):
+		err = &os.PathError{Op: "stat", Path: name, Err: syscall.ELOOP}
+	case commonerrors.Any(err, commonerrors.ErrForbidden):
+		err = newRootEscapeError(name)
+	default:
+		err = b.convertError(err, name)
+	}
+	return
+}
+
+func (b *basePathFs) realPath(name string, followLast bool) (string, error) {
+	realPath, err := b.toRealPath(name)
+	if err != nil {
+		return "", err
+	}
+	return b.resolve(name, realPath, followLast)
+}
+
+// convertError ensures errors only refer to paths relative to the root.
+func (b *basePathFs) convertError(err error, name string) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.PathError{Op: e.Op, Path: name, Err: e.Err}
+	case *os.LinkError:
+		return &os.LinkError{Op: e.Op, Old: b.fromRealPath(e.Old), New: b.fromRealPath(e.New), Err: e.Err}
+	}
+	return err
+}
+
+// ensureTempDirectory creates the temporary directory within the root on demand so that temporary files and directories can be created.
+func (b *basePathFs) ensureTempDirectory(name string) {
+	if filepath.Dir(filepath.Clean(string(filepath.Separator)+name)) != filepath.Clean(os.TempDir()) {
+		return
+	}
+	tempDir, err := b.realPath(os.TempDir(), true)
+	if err == nil {
+		_ = b.source.MkdirAll(tempDir, 0o777)
+	}
+}
+
+func (b *basePathFs) Name() string {
+	return "BasePathFs"
+}
+
+func (b *basePathFs) Create(name string) (afero.File, error) {
+	return b.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func (b *basePathFs) Mkdir(name string, perm os.FileMode) error {
+	realPath, err := b.realPath(name, false)
+	if err != nil {
+		return err
+	}
+	b.ensureTempDirectory(name)
+	return b.convertError(b.source.Mkdir(realPath, perm), name)
+}
+
+func (b *basePathFs) MkdirAll(name string, perm os.FileMode) error {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	return b.convertError(b.source.MkdirAll(realPath, perm), name)
+}
+
+func (b *basePathFs) Open(name string) (afero.File, error) {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := b.source.Open(realPath)
+	if err != nil {
+		return nil, b.convertError(err, name)
+	}
+	return &basePathFile{File: f, name: name}, nil
+}
+
+func (b *basePathFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return nil, err
+	}
+	if flag&os.O_CREATE != 0 {
+		b.ensureTempDirectory(name)
+	}
+	f, err := b.source.OpenFile(realPath, flag, perm)
+	if err != nil {
+		return nil, b.convertError(err, name)
+	}
+	return &basePathFile{File: f, name: name}, nil
+}
+
+func (b *basePathFs) Remove(name string) error {
+	realPath, err := b.realPath(name, false)
+	if err != nil {
+		return err
+	}
+	if realPath == b.root {
+		return commonerrors.New(commonerrors.ErrForbidden, "the file system root cannot be removed")
+	}
+	return b.convertError(b.source.Remove(realPath), name)
+}
+
+func (b *basePathFs) RemoveAll(name string) error {
+	realPath, err := b.realPath(name, false)
+	if err != nil {
+		return err
+	}
+	if realPath == b.root {
+		return commonerrors.New(commonerrors.ErrForbidden, "the file system root cannot be removed")
+	}
+	return b.convertError(b.source.RemoveAll(realPath), name)
+}
+
+func (b *basePathFs) Rename(oldname, newname string) error {
+	oldRealPath, err := b.realPath(oldname, false)
+	if err != nil {
+		return err
+	}
+	newRealPath, err := b.realPath(newname, false)
+	if err != nil {
+		return err
+	}
+	if oldRealPath == b.root || newRealPath == b.root {
+		return commonerrors.New(commonerrors.ErrForbidden, "the file system root cannot be renamed")
+	}
+	return b.convertError(b.source.Rename(oldRealPath, newRealPath), oldname)
+}
+
+func (b *basePathFs) Stat(name string) (os.FileInfo, error) {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := b.source.Stat(realPath)
+	return fi, b.convertError(err, name)
+}
+
+func (b *basePathFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	realPath, err := b.realPath(name, false)
+	if err != nil {
+		return nil, false, err
+	}
+	if lstater, ok := b.source.(afero.Lstater); ok {
+		fi, lstatCalled, err := lstater.LstatIfPossible(realPath)
+		return fi, lstatCalled, b.convertError(err, name)
+	}
+	fi, err := b.source.Stat(realPath)
+	return fi, false, b.convertError(err, name)
+}
+
+func (b *basePathFs) SymlinkIfPossible(oldname, newname string) error {
+	linker, ok := b.source.(afero.Linker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+	}
+	newRealPath, err := b.realPath(newname, false)
+	if err != nil {
+		return err
+	}
+	target := oldname
+	if filepath.IsAbs(oldname) {
+		// Absolute targets are rebased so that the link can be followed in the source filesystem too.
+		target, err = b.toRealPath(oldname)
+		if err != nil {
+			return err
+		}
+	} else if !b.isWithinRoot(filepath.Join(filepath.Dir(newRealPath), oldname)) {
+		return newRootEscapeError(oldname)
+	}
+	return b.convertError(linker.SymlinkIfPossible(target, newRealPath), newname)
+}
+
+func (b *basePathFs) ReadlinkIfPossible(name string) (string, error) {
+	reader, ok := b.source.(afero.LinkReader)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+	}
+	realPath, err := b.realPath(name, false)
+	if err != nil {
+		return "", err
+	}
+	target, err := reader.ReadlinkIfPossible(realPath)
+	if err != nil {
+		return "", b.convertError(err, name)
+	}
+	if !filepath.IsAbs(target) {
+		return target, nil
+	}
+	if !b.isWithinRoot(target) {
+		return "", newRootEscapeError(name)
+	}
+	return b.fromRealPath(target), nil
+}
+
+func (b *basePathFs) LinkIfPossible(oldname, newname string) error {
+	linker, ok := b.source.(ILinker)
+	if !ok {
+		return commonerrors.Newf(commonerrors.ErrNotImplemented, "cannot link `%v` to `%v`", oldname, newname)
+	}
+	oldRealPath, err := b.realPath(oldname, false)
+	if err != nil {
+		return err
+	}
+	newRealPath, err := b.realPath(newname, false)
+	if err != nil {
+		return err
+	}
+	return b.convertError(linker.LinkIfPossible(oldRealPath, newRealPath), newname)
+}
+
+func (b *basePathFs) Chmod(name string, mode os.FileMode) error {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	return b.convertError(b.source.Chmod(realPath, mode), name)
+}
+
+func (b *basePathFs) Chown(name string, uid, gid int) error {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	return b.convertError(b.source.Chown(realPath, uid, gid), name)
+}
+
+func (b *basePathFs) ChownIfPossible(name string, uid int, gid int) error {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	if chowner, ok := b.source.(IChowner); ok {
+		return b.convertError(chowner.ChownIfPossible(realPath, uid, gid), name)
+	}
+	return b.convertError(b.source.Chown(realPath, uid, gid), name)
+}
+
+func (b *basePathFs) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	xattrer, ok := b.source.(IXattrer)
+	if !ok {
+		return nil, ErrXattrNotImplemented
+	}
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return nil, err
+	}
+	value, err := xattrer.GetXattrIfPossible(realPath, attribute)
+	return value, b.convertError(err, name)
+}
+
+func (b *basePathFs) SetXattrIfPossible(name, attribute string, value []byte) error {
+	xattrer, ok := b.source.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	return b.convertError(xattrer.SetXattrIfPossible(realPath, attribute, value), name)
+}
+
+func (b *basePathFs) ListXattrIfPossible(name string) ([]string, error) {
+	xattrer, ok := b.source.(IXattrer)
+	if !ok {
+		return nil, ErrXattrNotImplemented
+	}
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := xattrer.ListXattrIfPossible(realPath)
+	return attributes, b.convertError(err, name)
+}
+
+func (b *basePathFs) RemoveXattrIfPossible(name, attribute string) error {
+	xattrer, ok := b.source.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	return b.convertError(xattrer.RemoveXattrIfPossible(realPath, attribute), name)
+}
+
+func (b *basePathFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	realPath, err := b.realPath(name, true)
+	if err != nil {
+		return err
+	}
+	return b.convertError(b.source.Chtimes(realPath, atime, mtime), name)
+}
+
+func (b *basePathFs) ForceRemoveIfPossible(name string) error {
+	realPath, err := b.realPath(name, false)
+	if err != nil {
+		return err
+	}
+	if realPath == b.root {
+		return commonerrors.New(commonerrors.ErrForbidden, "the file system root cannot be removed")
+	}
+	if remover, ok := b.source.(IForceRemover); ok {
+		return b.convertError(remover.ForceRemoveIfPossible(realPath), name)
+	}
+	return b.convertError(b.source.RemoveAll(realPath), name)
+}
+
+// basePathFile is a file of a basePathFs whose name is relative to the root.
+type basePathFile struct {
+	afero.File
+	name string
+}
+
+func (f *basePathFile) Name() string {
+	return f.name
+}
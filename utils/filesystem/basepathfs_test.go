@@ -0,0 +1,212 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func newTestBasePathFileSystem(t *testing.T, fsType FilesystemType) (base FS, root string, fs FS) {
+	t.Helper()
+	base = NewFs(fsType)
+	root, err := base.TempDirInTempDir("test-chroot-")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = base.Rm(root) })
+	fs, err = NewBasePathFileSystem(base, root)
+	require.NoError(t, err)
+	return
+}
+
+func TestNewBasePathFileSystem(t *testing.T) {
+	_, err := NewBasePathFileSystem(nil, "test")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewBasePathFileSystem(NewInMemoryFileSystem(), "")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewBasePathFileSystem(NewInMemoryFileSystem(), faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	base := NewInMemoryFileSystem()
+	file := FilePathJoin(base, "/", "file.txt")
+	require.NoError(t, base.Touch(file))
+	_, err = NewBasePathFileSystem(base, file)
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			_, _, fs := newTestBasePathFileSystem(t, fsType)
+			assert.Equal(t, fsType, fs.GetType())
+		})
+	}
+}
+
+func TestBasePathFS_Rebasing(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			base, root, fs := newTestBasePathFileSystem(t, fsType)
+			content := faker.Paragraph()
+			dir := FilePathJoin(fs, "/", "dir", "subdir")
+			require.NoError(t, fs.MkDir(dir))
+			file := FilePathJoin(fs, dir, "file.txt")
+			require.NoError(t, fs.WriteFile(file, []byte(content), 0o644))
+
+			assert.True(t, base.Exists(FilePathJoin(base, root, "dir", "subdir", "file.txt")))
+			assert.True(t, fs.Exists(FilePathJoin(fs, "dir", "subdir", "file.txt")))
+			assert.Equal(t, FilePathJoin(base, root, "dir", "subdir", "file.txt"), fs.ConvertFilePath(file))
+			f, err := fs.GenericOpen(file)
+			require.NoError(t, err)
+			assert.Equal(t, file, f.Name())
+			require.NoError(t, f.Close())
+
+			names, err := fs.Ls("/")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"dir"}, names)
+			var walked []string
+			require.NoError(t, fs.Walk("/", func(path string, _ os.FileInfo, err error) error {
+				walked = append(walked, path)
+				return err
+			}))
+			assert.Contains(t, walked, file)
+
+			moved := FilePathJoin(fs, "/", "moved.txt")
+			require.NoError(t, fs.Move(file, moved))
+			assert.False(t, fs.Exists(file))
+			actual, err := fs.ReadFile(moved)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(actual))
+
+			errortest.AssertError(t, fs.Rm("/"), commonerrors.ErrForbidden)
+			assert.True(t, base.Exists(root))
+		})
+	}
+}
+
+func TestBasePathFS_TraversalEscape(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			base, root, fs := newTestBasePathFileSystem(t, fsType)
+			outside := FilePathJoin(base, FilePathDir(base, root), fmt.Sprintf("outside-%v.txt", faker.Word()))
+			require.NoError(t, base.WriteFile(outside, []byte(faker.Sentence()), 0o644))
+			defer func() { _ = base.Rm(outside) }()
+			escaping := FilePathJoin(fs, "..", FilePathBase(base, outside))
+
+			_, err := fs.ReadFile(escaping)
+			errortest.AssertError(t, err, commonerrors.ErrForbidden)
+			err = fs.WriteFile(escaping, []byte(faker.Sentence()), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrForbidden)
+			errortest.AssertError(t, fs.MkDir(FilePathJoin(fs, "dir", "..", "..", "escape")), commonerrors.ErrForbidden)
+			_, err = fs.Stat(escaping)
+			errortest.AssertError(t, err, commonerrors.ErrForbidden)
+			assert.True(t, base.Exists(outside))
+
+			// Traversals staying within the root are allowed.
+			require.NoError(t, fs.MkDir(FilePathJoin(fs, "/", "dir")))
+			require.NoError(t, fs.Touch(FilePathJoin(fs, "dir", "..", "file.txt")))
+			assert.True(t, base.Exists(FilePathJoin(base, root, "file.txt")))
+		})
+	}
+}
+
+func TestBasePathFS_SymlinkEscape(t *testing.T) {
+	base, root, fs := newTestBasePathFileSystem(t, StandardFS)
+	outsideDir := t.TempDir()
+	outside := FilePathJoin(base, outsideDir, "secret.txt")
+	secret := faker.Sentence()
+	require.NoError(t, base.WriteFile(outside, []byte(secret), 0o644))
+
+	absoluteLink := FilePathJoin(base, root, "absolute")
+	require.NoError(t, base.Symlink(outside, absoluteLink))
+	relativeLink := FilePathJoin(base, root, "relative")
+	require.NoError(t, base.Symlink(FilePathJoin(base, "..", FilePathBase(base, outsideDir)), relativeLink))
+	require.NoError(t, base.Symlink(outsideDir, FilePathJoin(base, root, "dirLink")))
+
+	for _, link := range []string{"/absolute", "/relative", FilePathJoin(fs, "/", "dirLink", "secret.txt")} {
+		t.Run(link, func(t *testing.T) {
+			_, err := fs.ReadFile(link)
+			errortest.AssertError(t, err, commonerrors.ErrForbidden)
+			_, err = fs.Stat(link)
+			errortest.AssertError(t, err, commonerrors.ErrForbidden)
+			err = fs.WriteFile(link, []byte(faker.Sentence()), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrForbidden)
+		})
+	}
+	_, err := fs.Readlink("/absolute")
+	errortest.AssertError(t, err, commonerrors.ErrForbidden)
+	_, err = fs.Lstat("/absolute")
+	require.NoError(t, err)
+	// Links themselves can still be removed.
+	require.NoError(t, fs.Rm("/absolute"))
+	actual, err := base.ReadFile(outside)
+	require.NoError(t, err)
+	assert.Equal(t, secret, string(actual))
+
+	err = fs.Symlink(FilePathJoin(fs, "..", "..", "escape"), "/escaping")
+	errortest.AssertError(t, err, commonerrors.ErrForbidden)
+
+	// Links within the root are followed.
+	target := FilePathJoin(fs, "/", "target.txt")
+	require.NoError(t, fs.WriteFile(target, []byte(secret), 0o644))
+	require.NoError(t, fs.Symlink(target, "/link"))
+	require.NoError(t, fs.Symlink("target.txt", "/relativeLink"))
+	dest, err := fs.Readlink("/link")
+	require.NoError(t, err)
+	assert.Equal(t, target, dest)
+	for _, link := range []string{"/link", "/relativeLink"} {
+		actual, err = fs.ReadFile(link)
+		require.NoError(t, err)
+		assert.Equal(t, secret, string(actual))
+	}
+	dest, err = base.Readlink(FilePathJoin(base, root, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, FilePathJoin(base, root, "target.txt"), dest)
+}
+
+func TestBasePathFS_Utilities(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			base, root, fs := newTestBasePathFileSystem(t, fsType)
+
+			tmpDir, err := fs.TempDirInTempDir("test-chroot-tmp-")
+			require.NoError(t, err)
+			assert.True(t, base.Exists(FilePathJoin(base, root, tmpDir)))
+			tmpFile, err := fs.TempFileInTempDir("test-chroot-tmp-")
+			require.NoError(t, err)
+			require.NoError(t, tmpFile.Close())
+			assert.True(t, base.Exists(FilePathJoin(base, root, tmpFile.Name())))
+
+			lock := fs.NewRemoteLockFile(faker.Word(), "/")
+			require.NoError(t, lock.Lock(context.Background()))
+			assert.True(t, base.Exists(FilePathJoin(base, root, lock.(*RemoteLockFile).lockPath())))
+			require.NoError(t, lock.Unlock(context.Background()))
+
+			srcDir := FilePathJoin(fs, "/", "src")
+			require.NoError(t, fs.MkDir(srcDir))
+			testFiles := GenerateTestFileTree(t, fs, srcDir, "", false, time.Now(), time.Now())
+
+			for _, archive := range []struct {
+				name    string
+				archive func(string, string) error
+				extract func(string, string) ([]string, error)
+			}{
+				{name: "zip", archive: fs.Zip, extract: fs.Unzip},
+				{name: "tar", archive: fs.Tar, extract: fs.Untar},
+			} {
+				archivePath := FilePathJoin(fs, "/", "archive."+archive.name)
+				require.NoError(t, archive.archive(srcDir, archivePath))
+				assert.True(t, base.Exists(FilePathJoin(base, root, "archive."+archive.name)))
+				destDir := FilePathJoin(fs, "/", "dest-"+archive.name)
+				extracted, err := archive.extract(archivePath, destDir)
+				require.NoError(t, err)
+				assert.Len(t, extracted, len(testFiles))
+				_, err = archive.extract(archivePath, FilePathJoin(fs, "..", "escape"))
+				errortest.AssertError(t, err, commonerrors.ErrForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+const (
+	directoryHashRoot      = "."
+	directoryHashFileKind  = "file"
+	directoryHashDirKind   = "dir"
+	directoryHashLinkKind  = "link"
+	directoryHashSeparator = "/"
+)
+
+// DirectoryHashOptions defines how the hash of a directory tree is calculated.
+type DirectoryHashOptions struct {
+	// includeModes states whether the permissions of items are part of the hash.
+	includeModes bool
+	// includeLinkTargets states whether symbolic links are hashed using their target path rather than the content of the file they point to.
+	includeLinkTargets bool
+	tree               []TreeOperationOption
+}
+
+// DirectoryHashOption configures DirectoryHashOptions.
+type DirectoryHashOption func(*DirectoryHashOptions) *DirectoryHashOptions
+
+// DefaultDirectoryHashOptions returns the default directory hash options i.e. only relative paths and file contents are hashed.
+// Symbolic links to files are hashed using the content of the files they point to whereas other links are ignored.
+func DefaultDirectoryHashOptions() *DirectoryHashOptions {
+	return &DirectoryHashOptions{}
+}
+
+// WithDirectoryHashOptions returns the directory hash options resulting from applying options to the defaults.
+func WithDirectoryHashOptions(options ...DirectoryHashOption) (opts *DirectoryHashOptions) {
+	opts = DefaultDirectoryHashOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithFileModes includes the permissions of files and directories in the hash.
+func WithFileModes() DirectoryHashOption {
+	return func(o *DirectoryHashOptions) *DirectoryHashOptions {
+		if o == nil {
+			o = DefaultDirectoryHashOptions()
+		}
+		o.includeModes = true
+		return o
+	}
+}
+
+// WithSymlinkTargets hashes symbolic links using their target rather than the content of the file they point to.
+func WithSymlinkTargets() DirectoryHashOption {
+	return func(o *DirectoryHashOptions) *DirectoryHashOptions {
+		if o == nil {
+			o = DefaultDirectoryHashOptions()
+		}
+		o.includeLinkTargets = true
+		return o
+	}
+}
+
+// WithTreeOptions defines how the tree is traversed e.g. exclusion patterns, limits or number of files hashed concurrently.
+func WithTreeOptions(options ...TreeOperationOption) DirectoryHashOption {
+	return func(o *DirectoryHashOptions) *DirectoryHashOptions {
+		if o == nil {
+			o = DefaultDirectoryHashOptions()
+		}
+		o.tree = append(o.tree, options...)
+		return o
+	}
+}
+
+// DirectoryHash describes the hash of a directory tree. It is computed as a Merkle tree: the hash of a directory is the hash of the path-sorted list of its children's names and hashes.
+// All paths are relative to the root of the tree and use `/` as separator.
+type DirectoryHash struct {
+	// Hash is the hash of the whole tree.
+	Hash string
+	// Subtrees lists the hash of every directory of the tree, including the root (i.e. `.`).
+	Subtrees map[string]string
+	// Files lists the hash of every file of the tree.
+	Files map[string]string
+}
+
+// GetSubtreeHash returns the hash of the subtree rooted at `path`.
+func (h *DirectoryHash) GetSubtreeHash(path string) (hash string, err error) {
+	hash, ok := h.Subtrees[path]
+	if !ok {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "could not find subtree [%v]", path)
+	}
+	return
+}
+
+func (h *fileHashing) CalculateDirectory(fs FS, root string, options ...DirectoryHashOption) (*DirectoryHash, error) {
+	return h.CalculateDirectoryWithContext(context.Background(), fs, root, options...)
+}
+
+func (h *fileHashing) CalculateDirectoryWithContext(ctx context.Context, fs FS, root string, options ...DirectoryHashOption) (dirHash *DirectoryHash, err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	opts := WithDirectoryHashOptions(options...)
+	treeOpts := WithTreeOperationOptions(opts.tree...)
+	err = treeOpts.limits.Validate()
+	if err != nil {
+		return
+	}
+	isDir, err := fs.IsDir(root)
+	if err != nil {
+		return
+	}
+	if !isDir {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "path [%v] is not a directory", root)
+		return
+	}
+	items, err := listTreeItems(ctx, fs, root, treeOpts.exclusionPatterns)
+	if err != nil {
+		return
+	}
+	leaves := determineDirectoryHashLeaves(fs, items, opts)
+	var files []*treeItem
+	for i := range leaves {
+		if !(opts.includeLinkTargets && IsSymLink(leaves[i].info)) {
+			files = append(files, leaves[i])
+		}
+	}
+	err = checkTreeLimits(fs, treeOpts.limits, files)
+	if err != nil {
+		return
+	}
+	leafHashes := make([]string, len(leaves))
+	index := make(map[*treeItem]int, len(leaves))
+	for i := range leaves {
+		index[leaves[i]] = i
+	}
+	err = processTreeItems(ctx, treeOpts.workers, leaves, func(subCtx context.Context, item *treeItem) (subErr error) {
+		leafHashes[index[item]], subErr = h.calculateLeafHash(subCtx, fs, item, opts)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	dirHash = &DirectoryHash{
+		Subtrees: map[string]string{},
+		Files:    make(map[string]string, len(leaves)),
+	}
+	children := map[string][]string{}
+	for i := range leaves {
+		dirHash.Files[leaves[i].relativePath] = leafHashes[i]
+		parent := directoryHashParent(leaves[i].relativePath)
+		children[parent] = append(children[parent], directoryHashEntry(leaves[i], leafHashes[i], opts))
+	}
+	// Directories are processed deepest first so that the hash of their children is known.
+	_, directoriesPerDepth := splitTreeItems(items)
+	for i := range directoriesPerDepth {
+		for _, dir := range directoriesPerDepth[i] {
+			entries := children[dir.relativePath]
+			sort.Strings(entries)
+			var hash string
+			hash, err = hashing.CalculateHashFromReader(ctx, h.GetType(), strings.NewReader(strings.Join(entries, "")))
+			if err != nil {
+				return
+			}
+			dirHash.Subtrees[dir.relativePath] = hash
+			if dir.relativePath != directoryHashRoot {
+				parent := directoryHashParent(dir.relativePath)
+				children[parent] = append(children[parent], directoryHashEntry(dir, hash, opts))
+			}
+		}
+	}
+	dirHash.Hash = dirHash.Subtrees[directoryHashRoot]
+	return
+}
+
+// determineDirectoryHashLeaves determines which items of the tree which are not directories should be hashed.
+func determineDirectoryHashLeaves(fs FS, items []*treeItem, opts *DirectoryHashOptions) (leaves []*treeItem) {
+	for i := range items {
+		item := items[i]
+		switch {
+		case item.info.IsDir():
+			continue
+		case IsSymLink(item.info):
+			if !opts.includeLinkTargets {
+				linked, subErr := fs.Stat(item.path)
+				if subErr != nil || !linked.Mode().IsRegular() {
+					// Dangling links and links to anything but files are ignored.
+					continue
+				}
+			}
+		case !item.info.Mode().IsRegular():
+			continue
+		}
+		leaves = append(leaves, item)
+	}
+	return
+}
+
+func (h *fileHashing) calculateLeafHash(ctx context.Context, fs FS, item *treeItem, opts *DirectoryHashOptions) (hash string, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if IsSymLink(item.info) && opts.includeLinkTargets {
+		var target string
+		target, err = fs.Readlink(item.path)
+		if err != nil {
+			return
+		}
+		hash, err = hashing.CalculateHashFromReader(ctx, h.GetType(), strings.NewReader(FilePathToSlash(fs, target)))
+		return
+	}
+	hash, err = hashTreeFile(ctx, fs, h.GetType(), item.path)
+	return
+}
+
+func directoryHashParent(relativePath string) string {
+	i := strings.LastIndex(relativePath, directoryHashSeparator)
+	if i < 0 {
+		return directoryHashRoot
+	}
+	return relativePath[:i]
+}
+
+// directoryHashEntry describes a child in the list hashed to determine the hash of its parent directory.
+func directoryHashEntry(item *treeItem, hash string, opts *DirectoryHashOptions) string {
+	kind := directoryHashFileKind
+	if item.info.IsDir() {
+		kind = directoryHashDirKind
+	} else if IsSymLink(item.info) && opts.includeLinkTargets {
+		kind = directoryHashLinkKind
+	}
+	name := strconv.Quote(item.relativePath[strings.LastIndex(item.relativePath, directoryHashSeparator)+1:])
+	if opts.includeModes {
+		return fmt.Sprintf("%v %v %v %v\n", name, kind, directoryHashMode(item.info), hash)
+	}
+	return fmt.Sprintf("%v %v %v\n", name, kind, hash)
+}
+
+func directoryHashMode(info os.FileInfo) string {
+	return strconv.FormatUint(uint64(info.Mode().Perm()), 8)
+}
@@ -0,0 +1,145 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+)
+
+func TestDirectoryHash(t *testing.T) {
+	content := faker.Paragraph()
+	var expected *DirectoryHash
+	for _, fsType := range FileSystemTypes {
+		for _, workers := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%v_%v workers", fsType, workers), func(t *testing.T) {
+				fs := NewFs(fsType)
+				hasher, err := NewFileHash(hashing.HashSha256)
+				require.NoError(t, err)
+				root := createTestFileTree(t, fs, content)
+				hash, err := hasher.CalculateDirectoryWithContext(context.Background(), fs, root, WithTreeOptions(WithWorkers(workers)))
+				require.NoError(t, err)
+				// The hash does not depend on the location of the tree, the filesystem or the number of workers.
+				if expected == nil {
+					expected = hash
+				}
+				assert.Equal(t, expected, hash)
+				assert.Len(t, hash.Files, 4)
+				assert.Len(t, hash.Subtrees, 4)
+				subtree, err := hash.GetSubtreeHash(".")
+				require.NoError(t, err)
+				assert.Equal(t, hash.Hash, subtree)
+				_, err = hash.GetSubtreeHash("dir/file.txt")
+				errortest.AssertError(t, err, commonerrors.ErrNotFound)
+				fileHash, err := hasher.CalculateFile(fs, FilePathJoin(fs, root, "dir", "subdir", "file.txt"))
+				require.NoError(t, err)
+				assert.Equal(t, fileHash, hash.Files["dir/subdir/file.txt"])
+
+				// Modifying a file only changes the hash of the subtrees it belongs to.
+				require.NoError(t, fs.WriteFile(FilePathJoin(fs, root, "dir", "subdir", "file.txt"), []byte(faker.Sentence()), 0o644))
+				modified, err := hasher.CalculateDirectory(fs, root, WithTreeOptions(WithWorkers(workers)))
+				require.NoError(t, err)
+				assert.NotEqual(t, hash.Hash, modified.Hash)
+				assert.NotEqual(t, hash.Subtrees["dir"], modified.Subtrees["dir"])
+				assert.NotEqual(t, hash.Subtrees["dir/subdir"], modified.Subtrees["dir/subdir"])
+				assert.Equal(t, hash.Subtrees["empty"], modified.Subtrees["empty"])
+
+				// Paths are part of the hash.
+				require.NoError(t, fs.Move(FilePathJoin(fs, root, "dir", "file.txt"), FilePathJoin(fs, root, "dir", "renamed.txt")))
+				renamed, err := hasher.CalculateDirectory(fs, root)
+				require.NoError(t, err)
+				assert.NotEqual(t, modified.Subtrees["dir"], renamed.Subtrees["dir"])
+				assert.Equal(t, modified.Subtrees["dir/subdir"], renamed.Subtrees["dir/subdir"])
+				assert.Equal(t, modified.Files["dir/file.txt"], renamed.Files["dir/renamed.txt"])
+
+				// Excluded items are not part of the hash.
+				excluded, err := hasher.CalculateDirectory(fs, root, WithTreeOptions(WithTreeExclusionPatterns(".*[.]tmp")))
+				require.NoError(t, err)
+				require.NoError(t, fs.WriteFile(FilePathJoin(fs, root, "dir", "test.tmp"), []byte(faker.Sentence()), 0o644))
+				excludedAgain, err := hasher.CalculateDirectory(fs, root, WithTreeOptions(WithTreeExclusionPatterns(".*[.]tmp")))
+				require.NoError(t, err)
+				assert.Equal(t, excluded, excludedAgain)
+				assert.NotContains(t, excluded.Files, "dir/test.tmp")
+			})
+		}
+	}
+}
+
+func TestDirectoryHash_Modes(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			hasher, err := NewFileHash(hashing.HashMd5)
+			require.NoError(t, err)
+			root := createTestFileTree(t, fs, faker.Paragraph())
+			withoutModes, err := hasher.CalculateDirectory(fs, root)
+			require.NoError(t, err)
+			withModes, err := hasher.CalculateDirectory(fs, root, WithFileModes())
+			require.NoError(t, err)
+			assert.NotEqual(t, withoutModes.Hash, withModes.Hash)
+
+			require.NoError(t, fs.Chmod(FilePathJoin(fs, root, "dir", "file.txt"), 0o600))
+			hash, err := hasher.CalculateDirectory(fs, root)
+			require.NoError(t, err)
+			assert.Equal(t, withoutModes.Hash, hash.Hash)
+			hash, err = hasher.CalculateDirectory(fs, root, WithFileModes())
+			require.NoError(t, err)
+			assert.NotEqual(t, withModes.Hash, hash.Hash)
+			assert.Equal(t, withModes.Subtrees["dir/subdir"], hash.Subtrees["dir/subdir"])
+			assert.NotEqual(t, withModes.Subtrees["dir"], hash.Subtrees["dir"])
+		})
+	}
+}
+
+func TestDirectoryHash_Links(t *testing.T) {
+	printWarningOnWindows(t)
+	fs := NewStandardFileSystem()
+	hasher, err := NewFileHash(hashing.HashSha1)
+	require.NoError(t, err)
+	root := createTestFileTree(t, fs, faker.Paragraph())
+	without, err := hasher.CalculateDirectory(fs, root)
+	require.NoError(t, err)
+	err = fs.Symlink(FilePathJoin(fs, root, "file.txt"), FilePathJoin(fs, root, "dir", "link.txt"))
+	skipIfLinksNotSupported(t, err)
+	require.NoError(t, fs.Symlink(FilePathJoin(fs, root, "dir"), FilePathJoin(fs, root, "dirlink")))
+
+	// By default, links to files are hashed like files whereas links to directories are ignored.
+	followed, err := hasher.CalculateDirectory(fs, root)
+	require.NoError(t, err)
+	assert.Equal(t, followed.Files["file.txt"], followed.Files["dir/link.txt"])
+	assert.NotContains(t, followed.Files, "dirlink")
+	assert.NotEqual(t, without.Hash, followed.Hash)
+
+	targets, err := hasher.CalculateDirectory(fs, root, WithSymlinkTargets())
+	require.NoError(t, err)
+	assert.Contains(t, targets.Files, "dirlink")
+	assert.NotEqual(t, targets.Files["file.txt"], targets.Files["dir/link.txt"])
+	assert.NotEqual(t, followed.Hash, targets.Hash)
+}
+
+func TestDirectoryHash_Failures(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	hasher, err := NewFileHash(hashing.HashSha256)
+	require.NoError(t, err)
+	root := createTestFileTree(t, fs, faker.Paragraph())
+
+	_, err = hasher.CalculateDirectory(nil, root)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = hasher.CalculateDirectory(fs, FilePathJoin(fs, root, "file.txt"))
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = hasher.CalculateDirectory(fs, root, WithTreeOptions(WithTreeLimits(NewLimits(1024*1024, 1024*1024, 2, 1, false))))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	_, err = hasher.CalculateDirectory(fs, root, WithTreeOptions(WithTreeLimits(NewLimits(1024*1024, 1024*1024, 2, 1, false)), WithTreeExclusionPatterns("dir")))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = hasher.CalculateDirectoryWithContext(ctx, fs, root)
+	errortest.AssertError(t, err, commonerrors.ErrCancelled)
+}
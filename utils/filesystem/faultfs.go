@@ -0,0 +1,500 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v3"
+	"github.com/spf13/afero"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// FaultRule describes when and how a fault is injected in filesystem operations.
+type FaultRule struct {
+	// PathPattern is a glob pattern (with support for `**`) which paths or base names of items must match for the fault to be injected. An empty pattern matches any item.
+	PathPattern string
+	// Operations lists the operations the fault is injected in. An empty list matches any operation.
+	Operations []FileOperation
+	// Probability is the probability (between 0 and 1) of a matching call triggering the fault. A zero value means the fault is always triggered.
+	Probability float64
+	// NthCall restricts the fault to the Nth matching call (starting from 1). A zero value means any matching call can trigger the fault.
+	NthCall int
+	// Err is the error returned by the operation when the fault is triggered (e.g. commonerrors.ErrTooLarge, os.ErrPermission, syscall.ENOSPC).
+	// If no error nor any other effect is specified, a commonerrors.ErrFailed error is returned.
+	Err error
+	// ShortWrite makes writes only write half of the data before failing with Err or io.ErrShortWrite.
+	ShortWrite bool
+	// Latency delays the operation.
+	Latency time.Duration
+	// Cancel is called when the fault is triggered, for instance for cancelling the context of the operation in progress.
+	Cancel context.CancelFunc
+}
+
+func (r *FaultRule) validate() error {
+	if r.Probability < 0 || r.Probability > 1 {
+		return commonerrors.Newf(commonerrors.ErrInvalid, "fault probability [%v] must be between 0 and 1", r.Probability)
+	}
+	if r.NthCall < 0 {
+		return commonerrors.Newf(commonerrors.ErrInvalid, "fault call number [%v] must be positive", r.NthCall)
+	}
+	if r.Latency < 0 {
+		return commonerrors.Newf(commonerrors.ErrInvalid, "fault latency [%v] must be positive", r.Latency)
+	}
+	if r.PathPattern != "" {
+		// Matching the pattern against itself forces the whole pattern to be parsed.
+		_, err := doublestar.Match(r.PathPattern, r.PathPattern)
+		if err != nil {
+			return commonerrors.WrapErrorf(commonerrors.ErrInvalid, err, "invalid fault path pattern [%v]", r.PathPattern)
+		}
+	}
+	return nil
+}
+
+func (r *FaultRule) matches(op FileOperation, name string) bool {
+	if len(r.Operations) > 0 {
+		found := false
+		for i := range r.Operations {
+			if r.Operations[i] == op || r.Operations[i] == FileOperationAny {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.PathPattern == "" {
+		return true
+	}
+	if match, _ := doublestar.PathMatch(r.PathPattern, name); match {
+		return true
+	}
+	match, _ := doublestar.Match(r.PathPattern, filepath.Base(name))
+	return match
+}
+
+func (r *FaultRule) hasEffect() bool {
+	return r.Err != nil || r.ShortWrite || r.Latency > 0 || r.Cancel != nil
+}
+
+type faultRuleState struct {
+	rule  FaultRule
+	calls int
+}
+
+// FaultInjector determines which faults are injected in operations performed on a filesystem created using NewFaultInjectionFileSystem.
+// Rules can be added or cleared at any time, e.g. once a test environment has been set up.
+type FaultInjector struct {
+	mu       sync.Mutex
+	random   *rand.Rand
+	rules    []*faultRuleState
+	injected int
+}
+
+// NewFaultInjector returns a fault injector. The seed is used for determining whether faults with a probability are triggered so that tests are reproducible.
+func NewFaultInjector(seed int64) *FaultInjector {
+	return &FaultInjector{
+		random: rand.New(rand.NewSource(seed)), //nolint:gosec // G404: a weak random number generator is sufficient, and desirable for reproducibility, when injecting faults
+	}
+}
+
+// AddRule adds a fault rule. Rules are evaluated in the order they were added and only the first rule triggered by an operation applies.
+func (i *FaultInjector) AddRule(rule FaultRule) error {
+	err := rule.validate()
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append(i.rules, &faultRuleState{rule: rule})
+	return nil
+}
+
+// ClearRules removes all the rules so that no more faults are injected.
+func (i *FaultInjector) ClearRules() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = nil
+}
+
+// GetInjectedFaultCount returns the number of faults injected so far.
+func (i *FaultInjector) GetInjectedFaultCount() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.injected
+}
+
+// evaluate returns the rule triggered by an operation, if any.
+func (i *FaultInjector) evaluate(op FileOperation, name string) *FaultRule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	var triggered *FaultRule
+	for _, state := range i.rules {
+		if !state.rule.matches(op, name) {
+			continue
+		}
+		state.calls++
+		if triggered != nil {
+			continue
+		}
+		if state.rule.NthCall > 0 && state.calls != state.rule.NthCall {
+			continue
+		}
+		if state.rule.Probability > 0 && i.random.Float64() >= state.rule.Probability {
+			continue
+		}
+		rule := state.rule
+		triggered = &rule
+	}
+	if triggered != nil {
+		i.injected++
+	}
+	return triggered
+}
+
+// faultFs is an afero.Fs injecting faults in the operations performed on a source filesystem.
+type faultFs struct {
+	source   afero.Fs
+	injector *FaultInjector
+}
+
+// inject applies the fault triggered by an operation, if any, and returns the error the operation should fail with.
+// For writes, shortWrite states whether only part of the data should be written before returning the error.
+func (f *faultFs) inject(op FileOperation, name string) (shortWrite bool, err error) {
+	rule := f.injector.evaluate(op, name)
+	if rule == nil {
+		return
+	}
+	if rule.Latency > 0 {
+		time.Sleep(rule.Latency)
+	}
+	if rule.Cancel != nil {
+		rule.Cancel()
+	}
+	if rule.ShortWrite && op == FileOperationWrite {
+		shortWrite = true
+		err = rule.Err
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+		return
+	}
+	switch {
+	case rule.Err != nil:
+		err = &os.PathError{Op: string(op), Path: name, Err: rule.Err}
+	case !rule.hasEffect():
+		err = &os.PathError{Op: string(op), Path: name, Err: commonerrors.New(commonerrors.ErrFailed, "injected fault")}
+	}
+	return
+}
+
+func (f *faultFs) Name() string {
+	return "FaultFs"
+}
+
+func (f *faultFs) Create(name string) (afero.File, error) {
+	return f.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func (f *faultFs) Mkdir(name string, perm os.FileMode) error {
+	if _, err := f.inject(FileOperationMkdir, name); err != nil {
+		return err
+	}
+	return f.source.Mkdir(name, perm)
+}
+
+func (f *faultFs) MkdirAll(path string, perm os.FileMode) error {
+	if _, err := f.inject(FileOperationMkdir, path); err != nil {
+		return err
+	}
+	return f.source.MkdirAll(path, perm)
+}
+
+func (f *faultFs) Open(name string) (afero.File, error) {
+	return f.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (f *faultFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	op := FileOperationOpen
+	if flag&os.O_CREATE != 0 {
+		op = FileOperationCreate
+	}
+	if _, err := f.inject(op, name); err != nil {
+		return nil, err
+	}
+	file, err := f.source.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f}, nil
+}
+
+func (f *faultFs) Remove(name string) error {
+	if _, err := f.inject(FileOperationRemove, name); err != nil {
+		return err
+	}
+	return f.source.Remove(name)
+}
+
+func (f *faultFs) RemoveAll(path string) error {
+	if _, err := f.inject(FileOperationRemove, path); err != nil {
+		return err
+	}
+	return f.source.RemoveAll(path)
+}
+
+func (f *faultFs) Rename(oldname, newname string) error {
+	if _, err := f.inject(FileOperationRename, oldname); err != nil {
+		return err
+	}
+	return f.source.Rename(oldname, newname)
+}
+
+func (f *faultFs) Stat(name string) (os.FileInfo, error) {
+	if _, err := f.inject(FileOperationStat, name); err != nil {
+		return nil, err
+	}
+	return f.source.Stat(name)
+}
+
+func (f *faultFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if _, err := f.inject(FileOperationStat, name); err != nil {
+		return nil, false, err
+	}
+	if lstater, ok := f.source.(afero.Lstater); ok {
+		return lstater.LstatIfPossible(name)
+	}
+	fi, err := f.source.Stat(name)
+	return fi, false, err
+}
+
+func (f *faultFs) SymlinkIfPossible(oldname, newname string) error {
+	linker, ok := f.source.(afero.Linker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+	}
+	if _, err := f.inject(FileOperationLink, newname); err != nil {
+		return err
+	}
+	return linker.SymlinkIfPossible(oldname, newname)
+}
+
+func (f *faultFs) ReadlinkIfPossible(name string) (string, error) {
+	reader, ok := f.source.(afero.LinkReader)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+	}
+	if _, err := f.inject(FileOperationReadlink, name); err != nil {
+		return "", err
+	}
+	return reader.ReadlinkIfPossible(name)
+}
+
+func (f *faultFs) LinkIfPossible(oldname, newname string) error {
+	linker, ok := f.source.(ILinker)
+	if !ok {
+		return commonerrors.Newf(commonerrors.ErrNotImplemented, "cannot link `%v` to `%v`", oldname, newname)
+	}
+	if _, err := f.inject(FileOperationLink, newname); err != nil {
+		return err
+	}
+	return linker.LinkIfPossible(oldname, newname)
+}
+
+func (f *faultFs) Chmod(name string, mode os.FileMode) error {
+	if _, err := f.inject(FileOperationChmod, name); err != nil {
+		return err
+	}
+	return f.source.Chmod(name, mode)
+}
+
+func (f *faultFs) Chown(name string, uid, gid int) error {
+	if _, err := f.inject(FileOperationChown, name); err != nil {
+		return err
+	}
+	return f.source.Chown(name, uid, gid)
+}
+
+func (f *faultFs) ChownIfPossible(name string, uid int, gid int) error {
+	if _, err := f.inject(FileOperationChown, name); err != nil {
+		return err
+	}
+	if chowner, ok := f.source.(IChowner); ok {
+		return chowner.ChownIfPossible(name, uid, gid)
+	}
+	return f.source.Chown(name, uid, gid)
+}
+
+func (f *faultFs) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	xattrer, ok := f.source.(IXattrer)
+	if !ok {
+		return nil, ErrXattrNotImplemented
+	}
+	if _, err := f.inject(FileOperationGetXattr, name); err != nil {
+		return nil, err
+	}
+	return xattrer.GetXattrIfPossible(name, attribute)
+}
+
+func (f *faultFs) SetXattrIfPossible(name, attribute string, value []byte) error {
+	xattrer, ok := f.source.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	if _, err := f.inject(FileOperationSetXattr, name); err != nil {
+		return err
+	}
+	return xattrer.SetXattrIfPossible(name, attribute, value)
+}
+
+func (f *faultFs) ListXattrIfPossible(name string) ([]string, error) {
+	xattrer, ok := f.source.(IXattrer)
+	if !ok {
+		return nil, ErrXattrNotImplemented
+	}
+	if _, err := f.inject(FileOperationGetXattr, name); err != nil {
+		return nil, err
+	}
+	return xattrer.ListXattrIfPossible(name)
+}
+
+func (f *faultFs) RemoveXattrIfPossible(name, attribute string) error {
+	xattrer, ok := f.source.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	if _, err := f.inject(FileOperationSetXattr, name); err != nil {
+		return err
+	}
+	return xattrer.RemoveXattrIfPossible(name, attribute)
+}
+
+func (f *faultFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if _, err := f.inject(FileOperationChtimes, name); err != nil {
+		return err
+	}
+	return f.source.Chtimes(name, atime, mtime)
+}
+
+func (f *faultFs) ForceRemoveIfPossible(name string) error {
+	if _, err := f.inject(FileOperationRemove, name); err != nil {
+		return err
+	}
+	if remover, ok := f.source.(IForceRemover); ok {
+		return remover.ForceRemoveIfPossible(name)
+	}
+	return f.source.RemoveAll(name)
+}
+
+// faultFile is a file of a faultFs in which faults can be injected.
+type faultFile struct {
+	afero.File
+	fs *faultFs
+}
+
+func (f *faultFile) Read(p []byte) (int, error) {
+	if _, err := f.fs.inject(FileOperationRead, f.Name()); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+func (f *faultFile) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := f.fs.inject(FileOperationRead, f.Name()); err != nil {
+		return 0, err
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *faultFile) Write(p []byte) (n int, err error) {
+	shortWrite, err := f.fs.inject(FileOperationWrite, f.Name())
+	if err == nil {
+		return f.File.Write(p)
+	}
+	if shortWrite {
+		n, _ = f.File.Write(p[:len(p)/2])
+	}
+	return
+}
+
+func (f *faultFile) WriteAt(p []byte, off int64) (n int, err error) {
+	shortWrite, err := f.fs.inject(FileOperationWrite, f.Name())
+	if err == nil {
+		return f.File.WriteAt(p, off)
+	}
+	if shortWrite {
+		n, _ = f.File.WriteAt(p[:len(p)/2], off)
+	}
+	return
+}
+
+func (f *faultFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *faultFile) Readdir(count int) ([]os.FileInfo, error) {
+	if _, err := f.fs.inject(FileOperationList, f.Name()); err != nil {
+		return nil, err
+	}
+	return f.File.Readdir(count)
+}
+
+func (f *faultFile) Readdirnames(n int) ([]string, error) {
+	if _, err := f.fs.inject(FileOperationList, f.Name()); err != nil {
+		return nil, err
+	}
+	return f.File.Readdirnames(n)
+}
+
+func (f *faultFile) Stat() (os.FileInfo, error) {
+	if _, err := f.fs.inject(FileOperationStat, f.Name()); err != nil {
+		return nil, err
+	}
+	return f.File.Stat()
+}
+
+func (f *faultFile) Sync() error {
+	if _, err := f.fs.inject(FileOperationSync, f.Name()); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+func (f *faultFile) Truncate(size int64) error {
+	if _, err := f.fs.inject(FileOperationTruncate, f.Name()); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
+
+func (f *faultFile) Close() error {
+	if _, err := f.fs.inject(FileOperationClose, f.Name()); err != nil {
+		// The underlying file is still closed so that no resources leak.
+		_ = f.File.Close()
+		return err
+	}
+	return f.File.Close()
+}
+
+// NewFaultInjectionFileSystem returns a filesystem injecting faults, as determined by `injector`, in the operations performed on `fs`.
+// It is meant for testing how code behaves in case of filesystem failures (e.g. cleanup and retries).
+func NewFaultInjectionFileSystem(fs FS, injector *FaultInjector) (FS, error) {
+	if fs == nil {
+		return nil, commonerrors.UndefinedVariable("file system")
+	}
+	if injector == nil {
+		return nil, commonerrors.UndefinedVariable("fault injector")
+	}
+	vfs, ok := fs.(*VFS)
+	if !ok {
+		return nil, commonerrors.Newf(commonerrors.ErrUnsupported, "faults cannot be injected in file system of type [%T]", fs)
+	}
+	wrapped := &faultFs{source: vfs.vfs, injector: injector}
+	return NewVirtualFileSystemWithPathSeparator(wrapped, vfs.fsType, vfs.pathConverter, vfs.pathSeparator), nil
+}
@@ -0,0 +1,129 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func newTestFaultInjectionFileSystem(t *testing.T, fsType FilesystemType) (fs FS, injector *FaultInjector, root string) {
+	t.Helper()
+	injector = NewFaultInjector(1)
+	fs, err := NewFaultInjectionFileSystem(NewFs(fsType), injector)
+	require.NoError(t, err)
+	root, err = fs.TempDirInTempDir("test-fault-")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		injector.ClearRules()
+		_ = fs.Rm(root)
+	})
+	return
+}
+
+func TestFaultInjectionFileSystem_Errors(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs, injector, root := newTestFaultInjectionFileSystem(t, fsType)
+			src := FilePathJoin(fs, root, "src.txt")
+			dest := FilePathJoin(fs, root, "dest.txt")
+			require.NoError(t, fs.WriteFile(src, []byte(faker.Paragraph()), 0o644))
+
+			require.NoError(t, injector.AddRule(FaultRule{PathPattern: "dest.txt", Operations: []FileOperation{FileOperationWrite}, Err: commonerrors.ErrTooLarge}))
+			errortest.AssertError(t, fs.Copy(src, dest), commonerrors.ErrTooLarge)
+			require.NoError(t, injector.AddRule(FaultRule{PathPattern: "**/*.lock", Operations: []FileOperation{FileOperationCreate}, Err: os.ErrPermission}))
+			errortest.AssertError(t, fs.Touch(FilePathJoin(fs, root, "test.lock")), commonerrors.ErrConflict)
+			assert.False(t, fs.Exists(FilePathJoin(fs, root, "test.lock")))
+			// Other items are not affected.
+			require.NoError(t, fs.Copy(src, FilePathJoin(fs, root, "other.txt")))
+			assert.Equal(t, 2, injector.GetInjectedFaultCount())
+
+			injector.ClearRules()
+			require.NoError(t, fs.Copy(src, dest))
+			assert.Equal(t, 2, injector.GetInjectedFaultCount())
+		})
+	}
+}
+
+func TestFaultInjectionFileSystem_NthCall(t *testing.T) {
+	fs, injector, root := newTestFaultInjectionFileSystem(t, InMemoryFS)
+	require.NoError(t, injector.AddRule(FaultRule{Operations: []FileOperation{FileOperationRemove}, NthCall: 2}))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, fs.Touch(FilePathJoin(fs, root, fmt.Sprintf("file%v", i))))
+	}
+	require.NoError(t, fs.Rm(FilePathJoin(fs, root, "file0")))
+	errortest.AssertError(t, fs.Rm(FilePathJoin(fs, root, "file1")), commonerrors.ErrFailed)
+	require.NoError(t, fs.Rm(FilePathJoin(fs, root, "file2")))
+	require.NoError(t, fs.Rm(FilePathJoin(fs, root, "file1")))
+	assert.Equal(t, 1, injector.GetInjectedFaultCount())
+}
+
+func TestFaultInjectionFileSystem_Probability(t *testing.T) {
+	fs, injector, root := newTestFaultInjectionFileSystem(t, InMemoryFS)
+	require.NoError(t, injector.AddRule(FaultRule{Operations: []FileOperation{FileOperationCreate}, Probability: 0.5}))
+	failures := 0
+	for i := 0; i < 100; i++ {
+		if fs.Touch(FilePathJoin(fs, root, fmt.Sprintf("file%v", i))) != nil {
+			failures++
+		}
+	}
+	assert.Equal(t, failures, injector.GetInjectedFaultCount())
+	assert.Greater(t, failures, 10)
+	assert.Less(t, failures, 90)
+}
+
+func TestFaultInjectionFileSystem_Effects(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs, injector, root := newTestFaultInjectionFileSystem(t, fsType)
+			file := FilePathJoin(fs, root, "file.txt")
+			content := strings.Repeat("a", 100)
+
+			// Short writes
+			require.NoError(t, injector.AddRule(FaultRule{Operations: []FileOperation{FileOperationWrite}, ShortWrite: true, NthCall: 1}))
+			f, err := fs.CreateFile(file)
+			require.NoError(t, err)
+			n, err := f.Write([]byte(content))
+			require.Error(t, err)
+			assert.Equal(t, 50, n)
+			require.NoError(t, f.Close())
+			injector.ClearRules()
+
+			// Latency
+			require.NoError(t, injector.AddRule(FaultRule{Operations: []FileOperation{FileOperationStat}, Latency: 50 * time.Millisecond, NthCall: 1}))
+			start := time.Now()
+			_, err = fs.Stat(file)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+			injector.ClearRules()
+
+			// Cancellation
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, injector.AddRule(FaultRule{Operations: []FileOperation{FileOperationCreate}, Cancel: cancel}))
+			err = fs.WriteFileWithContext(ctx, file, []byte(content), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrCancelled)
+		})
+	}
+}
+
+func TestFaultInjectionFileSystem_Failures(t *testing.T) {
+	_, err := NewFaultInjectionFileSystem(nil, NewFaultInjector(1))
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewFaultInjectionFileSystem(NewInMemoryFileSystem(), nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	injector := NewFaultInjector(1)
+	errortest.AssertError(t, injector.AddRule(FaultRule{Probability: 2}), commonerrors.ErrInvalid)
+	errortest.AssertError(t, injector.AddRule(FaultRule{NthCall: -1}), commonerrors.ErrInvalid)
+	errortest.AssertError(t, injector.AddRule(FaultRule{Latency: -time.Second}), commonerrors.ErrInvalid)
+	errortest.AssertError(t, injector.AddRule(FaultRule{PathPattern: "[a-"}), commonerrors.ErrInvalid)
+}
@@ -0,0 +1,392 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+// LockOwner describes the holder of a lock for diagnostic purposes.
+type LockOwner struct {
+	// ID is the identifier of the holder.
+	ID string `json:"id"`
+	// Host is the name of the host the holder runs on.
+	Host string `json:"host"`
+	// PID is the process ID of the holder.
+	PID int `json:"pid"`
+	// AcquiredAt is when the lock was acquired.
+	AcquiredAt time.Time `json:"acquired_at"`
+	// FencingToken is the fencing token issued when the lock was acquired.
+	FencingToken uint64 `json:"fencing_token"`
+}
+
+func newLockOwner(id string) *LockOwner {
+	host, _ := os.Hostname()
+	return &LockOwner{
+		ID:         id,
+		Host:       host,
+		PID:        os.Getpid(),
+		AcquiredAt: time.Now().UTC(),
+	}
+}
+
+func (o *LockOwner) String() string {
+	return fmt.Sprintf("%v (host: %v, pid: %v, token: %v, acquired: %v)", o.ID, o.Host, o.PID, o.FencingToken, o.AcquiredAt)
+}
+
+// lockState is the persisted state of a lock.
+type lockState struct {
+	// FencingToken is the last fencing token issued.
+	FencingToken uint64 `json:"fencing_token"`
+	// Owner is the current holder of the lock, if any.
+	Owner *LockOwner `json:"owner,omitempty"`
+}
+
+// lockStateFile stores the state of a lock in a file. It must only be modified whilst holding the lock.
+type lockStateFile struct {
+	fs   FS
+	path string
+}
+
+func (s *lockStateFile) read() (state lockState, err error) {
+	content, err := s.fs.ReadFile(s.path)
+	if commonerrors.Any(err, commonerrors.ErrNotFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(content, &state)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrMarshalling, err, "could not read lock state [%v]", s.path)
+	}
+	return
+}
+
+func (s *lockStateFile) write(ctx context.Context, state lockState) (err error) {
+	content, err := json.Marshal(state)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrMarshalling, err, "could not write lock state [%v]", s.path)
+		return
+	}
+	// Writing atomically so that the state can be read at any time.
+	err = s.fs.WriteFileAtomically(ctx, s.path, content, 0644)
+	return
+}
+
+// acquired records the acquisition of the lock by owner and issues a new fencing token.
+func (s *lockStateFile) acquired(ctx context.Context, owner *LockOwner) (token uint64, err error) {
+	state, err := s.read()
+	if err != nil {
+		return
+	}
+	token = state.FencingToken + 1
+	ownerCopy := *owner
+	ownerCopy.FencingToken = token
+	err = s.write(ctx, lockState{FencingToken: token, Owner: &ownerCopy})
+	return
+}
+
+// released records the release of the lock. The last fencing token issued is kept so that tokens keep increasing.
+func (s *lockStateFile) released(ctx context.Context) (err error) {
+	state, err := s.read()
+	if err != nil || state.Owner == nil {
+		return
+	}
+	state.Owner = nil
+	err = s.write(ctx, state)
+	return
+}
+
+func (s *lockStateFile) owner() (owner *LockOwner, err error) {
+	state, err := s.read()
+	if err != nil {
+		return
+	}
+	if state.Owner == nil {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "lock [%v] is not held", s.path)
+		return
+	}
+	owner = state.Owner
+	return
+}
+
+// LockOptions defines how a lock is acquired.
+type LockOptions struct {
+	// retryPeriod is the time between attempts to acquire a lock.
+	retryPeriod time.Duration
+	// overrideStaleLock states whether stale locks should be released so that they can be acquired.
+	overrideStaleLock bool
+}
+
+// LockOption configures LockOptions.
+type LockOption func(*LockOptions) *LockOptions
+
+// DefaultLockOptions returns the default lock options.
+func DefaultLockOptions() *LockOptions {
+	return &LockOptions{retryPeriod: 10 * time.Millisecond}
+}
+
+// WithLockOptions returns the lock options resulting from applying options to the defaults.
+func WithLockOptions(options ...LockOption) (opts *LockOptions) {
+	opts = DefaultLockOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithLockRetryPeriod sets the time to wait between attempts to acquire a lock held by someone else.
+func WithLockRetryPeriod(period time.Duration) LockOption {
+	return func(o *LockOptions) *LockOptions {
+		if o == nil {
+			o = DefaultLockOptions()
+		}
+		if period > 0 {
+			o.retryPeriod = period
+		}
+		return o
+	}
+}
+
+// WithStaleLockOverride releases stale locks (i.e. locks whose holder seems dead) so that they can be acquired.
+func WithStaleLockOverride() LockOption {
+	return func(o *LockOptions) *LockOptions {
+		if o == nil {
+			o = DefaultLockOptions()
+		}
+		o.overrideStaleLock = true
+		return o
+	}
+}
+
+// FencedLock is a lock delegating locking to a backend (see ILockBackend) and issuing fencing tokens.
+type FencedLock struct {
+	id      string
+	backend ILockBackend
+	opts    *LockOptions
+}
+
+// NewFencedLock returns a lock relying on backend.
+func NewFencedLock(lockID string, backend ILockBackend, options ...LockOption) (IFencedLock, error) {
+	if backend == nil {
+		return nil, commonerrors.UndefinedVariable("lock backend")
+	}
+	if strings.TrimSpace(lockID) == "" {
+		return nil, commonerrors.UndefinedVariable("lock ID")
+	}
+	return &FencedLock{
+		id:      lockID,
+		backend: backend,
+		opts:    WithLockOptions(options...),
+	}, nil
+}
+
+// NewFencedRemoteLockFile returns a distributed lock based on RemoteLockFile which issues fencing tokens and records its owner.
+func NewFencedRemoteLockFile(fs FS, lockID string, dirPath string, options ...LockOption) (IFencedLock, error) {
+	backend, err := NewRemoteLockBackend(fs, lockID, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewFencedLock(lockID, backend, options...)
+}
+
+// TryLockWithFencingToken attempts to lock the lock straight away.
+func (l *FencedLock) TryLockWithFencingToken(ctx context.Context) (token uint64, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	token, err = l.backend.TryAcquire(ctx, newLockOwner(l.id), l.opts.overrideStaleLock)
+	return
+}
+
+// LockWithFencingToken locks the lock. This call will block until the lock is available.
+func (l *FencedLock) LockWithFencingToken(ctx context.Context) (token uint64, err error) {
+	for {
+		token, err = l.TryLockWithFencingToken(ctx)
+		if !commonerrors.Any(err, commonerrors.ErrLocked) {
+			return
+		}
+		parallelisation.SleepWithContext(ctx, l.opts.retryPeriod)
+	}
+}
+
+// LockWithTimeoutAndFencingToken tries to lock the lock until the timeout expires.
+func (l *FencedLock) LockWithTimeoutAndFencingToken(ctx context.Context, timeout time.Duration) (token uint64, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	token, err = l.LockWithFencingToken(timeoutCtx)
+	if err != nil && parallelisation.DetermineContextError(ctx) == nil && commonerrors.Any(err, commonerrors.ErrCancelled, commonerrors.ErrTimeout) {
+		err = commonerrors.Newf(commonerrors.ErrTimeout, "could not acquire lock [%v] within %v", l.id, timeout)
+	}
+	return
+}
+
+func (l *FencedLock) Lock(ctx context.Context) (err error) {
+	_, err = l.LockWithFencingToken(ctx)
+	return
+}
+
+func (l *FencedLock) LockWithTimeout(ctx context.Context, timeout time.Duration) (err error) {
+	_, err = l.LockWithTimeoutAndFencingToken(ctx, timeout)
+	return
+}
+
+func (l *FencedLock) TryLock(ctx context.Context) (err error) {
+	_, err = l.TryLockWithFencingToken(ctx)
+	return
+}
+
+func (l *FencedLock) IsStale() bool {
+	return l.backend.IsStale()
+}
+
+func (l *FencedLock) Unlock(ctx context.Context) error {
+	return l.backend.Release(ctx)
+}
+
+// ReleaseIfStale releases the lock if its holder seems dead. As the state of a lock must only be modified by its holder, the stale lock is overridden before being released.
+func (l *FencedLock) ReleaseIfStale(ctx context.Context) (err error) {
+	if !l.IsStale() {
+		return
+	}
+	_, err = l.backend.TryAcquire(ctx, newLockOwner(l.id), true)
+	if commonerrors.Any(err, commonerrors.ErrLocked) {
+		// The lock was acquired in the meantime and is therefore no longer stale.
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = l.backend.Release(ctx)
+	return
+}
+
+func (l *FencedLock) MakeStale(ctx context.Context) error {
+	return l.backend.MakeStale(ctx)
+}
+
+func (l *FencedLock) GetOwner(ctx context.Context) (*LockOwner, error) {
+	err := parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return l.backend.GetOwner(ctx)
+}
+
+// remoteLockBackend is a lock backend based on RemoteLockFile. Its state is stored next to the lock directory.
+type remoteLockBackend struct {
+	mu    sync.Mutex
+	lock  *RemoteLockFile
+	state *lockStateFile
+	// held states whether the lock is held by this backend.
+	held bool
+	// token is the fencing token issued when the lock was acquired by this backend.
+	token uint64
+}
+
+// NewRemoteLockBackend returns a lock backend relying on directories and heart beat files of a shared file system (see RemoteLockFile).
+func NewRemoteLockBackend(fs FS, lockID string, dirPath string) (ILockBackend, error) {
+	if fs == nil {
+		return nil, commonerrors.UndefinedVariable("file system")
+	}
+	vfs, ok := fs.(*VFS)
+	if !ok {
+		return nil, commonerrors.Newf(commonerrors.ErrUnsupported, "remote locks are not supported by file system of type [%T]", fs)
+	}
+	lock := newRemoteLockFile(vfs, lockID, dirPath, false)
+	return &remoteLockBackend{
+		lock:  lock,
+		state: &lockStateFile{fs: fs, path: fmt.Sprintf("%v.state", lock.lockPath())},
+	}, nil
+}
+
+func (b *remoteLockBackend) TryAcquire(ctx context.Context, owner *LockOwner, overrideStaleLock bool) (token uint64, err error) {
+	if owner == nil {
+		err = commonerrors.UndefinedVariable("lock owner")
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.held {
+		err = commonerrors.Newf(commonerrors.ErrLocked, "lock [%v] is already held", b.lock.id)
+		return
+	}
+	// Stale locks are overridden by the lock itself so that the state is only ever written whilst holding the lock.
+	err = b.lock.tryLock(ctx, overrideStaleLock)
+	if err != nil {
+		return
+	}
+	token, err = b.state.acquired(ctx, owner)
+	if err != nil {
+		_ = b.lock.Unlock(context.Background())
+		return
+	}
+	b.held = true
+	b.token = token
+	return
+}
+
+// Release releases the lock if held by this backend. Locks held by someone else are never released: stale locks can only be overridden when acquiring the lock.
+// commonerrors.ErrConflict is returned if the lock held by this backend went stale and was overridden by someone else in the meantime.
+func (b *remoteLockBackend) Release(ctx context.Context) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.held {
+		return
+	}
+	state, err := b.state.read()
+	if err != nil {
+		return
+	}
+	if state.Owner == nil || state.Owner.FencingToken != b.token {
+		b.held = false
+		b.lock.abandon()
+		err = commonerrors.Newf(commonerrors.ErrConflict, "lock [%v] acquired with fencing token %v was overridden", b.lock.id, b.token)
+		return
+	}
+	err = b.state.released(ctx)
+	if err != nil {
+		return
+	}
+	err = b.lock.Unlock(ctx)
+	if err != nil {
+		return
+	}
+	b.held = false
+	return
+}
+
+func (b *remoteLockBackend) IsStale() bool {
+	return b.lock.IsStale()
+}
+
+func (b *remoteLockBackend) MakeStale(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.held = false
+	return b.lock.MakeStale(ctx)
+}
+
+func (b *remoteLockBackend) GetOwner(_ context.Context) (*LockOwner, error) {
+	if !b.lock.fs.Exists(b.lock.lockPath()) {
+		return nil, commonerrors.Newf(commonerrors.ErrNotFound, "lock [%v] is not held", b.lock.id)
+	}
+	return b.state.owner()
+}
@@ -0,0 +1,270 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+type fencedLockFactory struct {
+	name    string
+	newLock func(t *testing.T, lockID string, dirPath string, options ...LockOption) IFencedLock
+	fs      FS
+}
+
+func fencedLockFactories() (factories []fencedLockFactory) {
+	for _, fsType := range FileSystemTypes {
+		fs := NewFs(fsType)
+		factories = append(factories, fencedLockFactory{
+			name: fmt.Sprintf("remote lock (%v)", fsType),
+			fs:   fs,
+			newLock: func(t *testing.T, lockID string, dirPath string, options ...LockOption) IFencedLock {
+				t.Helper()
+				lock, err := NewFencedRemoteLockFile(fs, lockID, dirPath, options...)
+				require.NoError(t, err)
+				return lock
+			},
+		})
+	}
+	for _, mechanism := range []FileLockMechanism{FlockFileLock, FcntlFileLock} {
+		fs := NewStandardFileSystem()
+		factories = append(factories, fencedLockFactory{
+			name: fmt.Sprintf("%v lock", mechanism),
+			fs:   fs,
+			newLock: func(t *testing.T, lockID string, dirPath string, options ...LockOption) IFencedLock {
+				t.Helper()
+				lock, err := NewLocalLockFile(fs, lockID, dirPath, mechanism, options...)
+				if commonerrors.Any(err, commonerrors.ErrUnsupported) {
+					t.Skipf("%v locks are not supported: %v", mechanism, err)
+				}
+				require.NoError(t, err)
+				return lock
+			},
+		})
+	}
+	return
+}
+
+func TestFencedLock(t *testing.T) {
+	for _, factory := range fencedLockFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			ctx := context.Background()
+			dir, err := factory.fs.TempDirInTempDir("test-fenced-lock-")
+			require.NoError(t, err)
+			defer func() { _ = factory.fs.Rm(dir) }()
+			lockID := faker.Word()
+			lock1 := factory.newLock(t, lockID, dir)
+			lock2 := factory.newLock(t, lockID, dir)
+
+			_, err = lock1.GetOwner(ctx)
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+
+			token1, err := lock1.TryLockWithFencingToken(ctx)
+			require.NoError(t, err)
+			_, err = lock2.TryLockWithFencingToken(ctx)
+			errortest.AssertError(t, err, commonerrors.ErrLocked)
+			_, err = lock2.LockWithTimeoutAndFencingToken(ctx, 50*time.Millisecond)
+			errortest.AssertError(t, err, commonerrors.ErrTimeout)
+			assert.False(t, lock1.IsStale())
+
+			// Locks held by someone else are not released.
+			require.NoError(t, lock2.Unlock(ctx))
+			owner, err := lock2.GetOwner(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, lockID, owner.ID)
+			assert.Equal(t, os.Getpid(), owner.PID)
+			assert.Equal(t, token1, owner.FencingToken)
+			assert.WithinDuration(t, time.Now(), owner.AcquiredAt, time.Minute)
+			hostname, err := os.Hostname()
+			require.NoError(t, err)
+			assert.Equal(t, hostname, owner.Host)
+
+			require.NoError(t, lock1.Unlock(ctx))
+			_, err = lock1.GetOwner(ctx)
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+
+			token2, err := lock2.LockWithTimeoutAndFencingToken(ctx, time.Second)
+			require.NoError(t, err)
+			assert.Greater(t, token2, token1)
+			require.NoError(t, lock2.Unlock(ctx))
+
+			// Tokens keep increasing for new lock instances.
+			lock3 := factory.newLock(t, lockID, dir)
+			token3, err := lock3.LockWithFencingToken(ctx)
+			require.NoError(t, err)
+			assert.Greater(t, token3, token2)
+			require.NoError(t, lock3.Unlock(ctx))
+			require.NoError(t, lock3.Unlock(ctx))
+
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err = lock1.LockWithFencingToken(cancelledCtx)
+			errortest.AssertError(t, err, commonerrors.ErrCancelled)
+		})
+	}
+}
+
+func TestFencedLock_Concurrency(t *testing.T) {
+	for _, factory := range fencedLockFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			if factory.fs.GetType() == InMemoryFS {
+				t.Skip("the in-memory filesystem is not thread-safe enough for this test (see ILock)")
+			}
+			ctx := context.Background()
+			dir := t.TempDir()
+			lockID := faker.Word()
+			var (
+				mu     sync.Mutex
+				tokens []uint64
+				wg     sync.WaitGroup
+			)
+			holders := 0
+			for i := 0; i < 5; i++ {
+				lock := factory.newLock(t, lockID, dir, WithLockRetryPeriod(time.Millisecond))
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 5; j++ {
+						token, err := lock.LockWithTimeoutAndFencingToken(ctx, 10*time.Second)
+						if !assert.NoError(t, err) {
+							return
+						}
+						mu.Lock()
+						holders++
+						tokens = append(tokens, token)
+						mu.Unlock()
+						time.Sleep(time.Millisecond)
+						mu.Lock()
+						assert.Equal(t, 1, holders)
+						holders--
+						mu.Unlock()
+						assert.NoError(t, lock.Unlock(ctx))
+					}
+				}()
+			}
+			wg.Wait()
+			require.Len(t, tokens, 25)
+			// Tokens are issued in increasing order of acquisition.
+			assert.True(t, sort.SliceIsSorted(tokens, func(i, j int) bool { return tokens[i] < tokens[j] }))
+			for i := 1; i < len(tokens); i++ {
+				assert.Greater(t, tokens[i], tokens[i-1])
+			}
+		})
+	}
+}
+
+func TestFencedLock_Stale(t *testing.T) {
+	fs := NewStandardFileSystem()
+	ctx := context.Background()
+	dir := t.TempDir()
+	lockID := faker.Word()
+
+	t.Run("remote", func(t *testing.T) {
+		lock1, err := NewFencedRemoteLockFile(fs, lockID, dir)
+		require.NoError(t, err)
+		lock2, err := NewFencedRemoteLockFile(fs, lockID, dir)
+		require.NoError(t, err)
+		lock3, err := NewFencedRemoteLockFile(fs, lockID, dir, WithStaleLockOverride())
+		require.NoError(t, err)
+
+		token1, err := lock1.LockWithFencingToken(ctx)
+		require.NoError(t, err)
+		require.NoError(t, lock1.MakeStale(ctx))
+		assert.True(t, lock2.IsStale())
+		_, err = lock2.TryLockWithFencingToken(ctx)
+		errortest.AssertError(t, err, commonerrors.ErrStaleLock)
+
+		token3, err := lock3.TryLockWithFencingToken(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, token3, token1)
+		owner, err := lock1.GetOwner(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, token3, owner.FencingToken)
+		require.NoError(t, lock3.Unlock(ctx))
+
+		// Stale locks are overridden before being released so that fencing tokens keep increasing.
+		token1, err = lock1.LockWithFencingToken(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, token1, token3)
+		require.NoError(t, lock1.MakeStale(ctx))
+		require.NoError(t, lock2.ReleaseIfStale(ctx))
+		_, err = lock2.GetOwner(ctx)
+		errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		token2, err := lock2.TryLockWithFencingToken(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, token2, token1+1)
+		require.NoError(t, lock2.Unlock(ctx))
+	})
+	t.Run("overridden holder", func(t *testing.T) {
+		backend1, err := NewRemoteLockBackend(fs, lockID, dir)
+		require.NoError(t, err)
+		backend2, err := NewRemoteLockBackend(fs, lockID, dir)
+		require.NoError(t, err)
+
+		token1, err := backend1.TryAcquire(ctx, newLockOwner(faker.Word()), false)
+		require.NoError(t, err)
+		// The heart beat of the holder stops (e.g. the holder is paused) whilst it still considers it holds the lock.
+		lock1 := backend1.(*remoteLockBackend).lock
+		lock1.cancelStore.Cancel()
+		lock1.heartBeats.Wait()
+		require.NoError(t, newRemoteLockFile(fs, lockID, dir, false).MakeStale(ctx))
+		token2, err := backend2.TryAcquire(ctx, newLockOwner(faker.Word()), true)
+		require.NoError(t, err)
+		assert.Greater(t, token2, token1)
+
+		// The former holder cannot release the lock of the new holder.
+		errortest.AssertError(t, backend1.Release(ctx), commonerrors.ErrConflict)
+		assert.False(t, backend2.IsStale())
+		owner, err := backend2.GetOwner(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, token2, owner.FencingToken)
+		require.NoError(t, backend1.Release(ctx))
+		require.NoError(t, backend2.Release(ctx))
+		_, err = backend2.GetOwner(ctx)
+		errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	})
+	t.Run("local", func(t *testing.T) {
+		lock, err := NewLocalLockFile(fs, lockID, dir, FlockFileLock)
+		if commonerrors.Any(err, commonerrors.ErrUnsupported) {
+			t.Skipf("flock locks are not supported: %v", err)
+		}
+		require.NoError(t, err)
+		require.NoError(t, lock.Lock(ctx))
+		assert.False(t, lock.IsStale())
+		errortest.AssertError(t, lock.MakeStale(ctx), commonerrors.ErrUnsupported)
+		require.NoError(t, lock.ReleaseIfStale(ctx))
+		_, err = lock.GetOwner(ctx)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+}
+
+func TestFencedLock_Invalid(t *testing.T) {
+	_, err := NewFencedLock(faker.Word(), nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	backend, err := NewRemoteLockBackend(NewStandardFileSystem(), faker.Word(), t.TempDir())
+	require.NoError(t, err)
+	_, err = NewFencedLock(" ", backend)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewRemoteLockBackend(nil, faker.Word(), t.TempDir())
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewOSFileLockBackend(NewInMemoryFileSystem(), faker.Word(), t.TempDir(), FlockFileLock)
+	errortest.AssertError(t, err, commonerrors.ErrUnsupported)
+	_, err = NewOSFileLockBackend(NewStandardFileSystem(), faker.Word(), t.TempDir(), FileLockMechanism(10))
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = backend.TryAcquire(context.Background(), nil, false)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+}
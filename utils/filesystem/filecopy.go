@@ -0,0 +1,35 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+
+	"github.com/ARM-software/golang-utils/utils/safeio"
+)
+
+// copyFileContent copies the content of src into dest. When both files are backed by the operating system, the copy is delegated to the kernel where possible (see copyOSFileContent).
+// Otherwise, or if the kernel cannot perform the copy, data is copied through user space.
+func copyFileContent(ctx context.Context, src, dest File) (err error) {
+	srcFile, isSrcOSFile := determineOSFile(src)
+	destFile, isDestOSFile := determineOSFile(dest)
+	if isSrcOSFile && isDestOSFile {
+		copied, subErr := copyOSFileContent(ctx, srcFile, destFile)
+		if subErr != nil || copied {
+			err = ConvertFileSystemError(subErr)
+			return
+		}
+	}
+	_, err = safeio.CopyDataWithContext(ctx, src, dest)
+	return
+}
+
+// determineOSFile returns the `os` file behind f if f is a file of the standard filesystem.
+// Files of filesystem wrappers (e.g. quota or audit) are not unwrapped so that all writes go through them.
+func determineOSFile(f File) (osFile *os.File, ok bool) {
+	extended, ok := f.(*extendedFile)
+	if !ok {
+		return
+	}
+	osFile, ok = extended.File.(*os.File)
+	return
+}
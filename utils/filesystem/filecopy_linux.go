@@ -0,0 +1,117 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"context"
+	"io"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+	"github.com/ARM-software/golang-utils/utils/safeio"
+	"github.com/ARM-software/golang-utils/utils/units/size"
+)
+
+// kernelCopyChunkSize is the maximum amount of data copied by a single `copy_file_range` call so that cancellation is checked regularly.
+const kernelCopyChunkSize = int64(64 * size.MiB)
+
+// copyOSFileContent copies regular files by
+//   - cloning them (`FICLONE`) on filesystems supporting reflinks (e.g. btrfs, xfs) so that data blocks are shared until modified;
+//   - otherwise, only copying their data segments (`SEEK_DATA`/`SEEK_HOLE`) so that holes of sparse files are preserved, using `copy_file_range` where possible.
+//
+// copied is false if the files cannot be copied this way.
+func copyOSFileContent(ctx context.Context, src, dest *os.File) (copied bool, err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return
+	}
+	destInfo, err := dest.Stat()
+	if err != nil {
+		return
+	}
+	if !srcInfo.Mode().IsRegular() || !destInfo.Mode().IsRegular() || destInfo.Size() != 0 {
+		return
+	}
+	copied = true
+	if unix.IoctlFileClone(int(dest.Fd()), int(src.Fd())) == nil {
+		return
+	}
+	err = copySparseFileContent(ctx, src, dest, srcInfo.Size())
+	return
+}
+
+func copySparseFileContent(ctx context.Context, src, dest *os.File, fileSize int64) (err error) {
+	var offset int64
+	for offset < fileSize {
+		err = parallelisation.DetermineContextError(ctx)
+		if err != nil {
+			return
+		}
+		start, end := determineNextDataSegment(src, offset, fileSize)
+		if start >= fileSize {
+			break
+		}
+		err = copyFileRange(ctx, src, dest, start, end-start)
+		if err != nil {
+			return
+		}
+		offset = end
+	}
+	// Trailing holes are never written and so, the size of the destination must be set explicitly.
+	err = dest.Truncate(fileSize)
+	return
+}
+
+// determineNextDataSegment returns the boundaries of the first segment of data found from offset. If holes cannot be detected, the rest of the file is considered as data.
+func determineNextDataSegment(f *os.File, offset, fileSize int64) (start, end int64) {
+	start, err := f.Seek(offset, unix.SEEK_DATA)
+	if err != nil {
+		if commonerrors.Any(err, syscall.ENXIO) {
+			// There is no data beyond offset.
+			start = fileSize
+			end = fileSize
+			return
+		}
+		start = offset
+		end = fileSize
+		return
+	}
+	end, err = f.Seek(start, unix.SEEK_HOLE)
+	if err != nil || end > fileSize {
+		end = fileSize
+	}
+	return
+}
+
+func copyFileRange(ctx context.Context, src, dest *os.File, offset, length int64) (err error) {
+	for length > 0 {
+		err = parallelisation.DetermineContextError(ctx)
+		if err != nil {
+			return
+		}
+		srcOffset := offset
+		destOffset := offset
+		n, subErr := unix.CopyFileRange(int(src.Fd()), &srcOffset, int(dest.Fd()), &destOffset, int(min(length, kernelCopyChunkSize)), 0)
+		if subErr != nil {
+			// The kernel cannot copy these files (e.g. they are on different filesystems and the kernel is too old): falling back to copying through user space.
+			_, err = safeio.CopyDataWithContext(ctx, io.NewSectionReader(src, offset, length), io.NewOffsetWriter(dest, offset))
+			return
+		}
+		if n == 0 {
+			// The source was truncated during the copy: the remaining data cannot be copied.
+			err = commonerrors.Newf(commonerrors.ErrUnexpected, "source changed during copy: %v bytes could not be read from offset %v", length, offset)
+			return
+		}
+		offset += int64(n)
+		length -= int64(n)
+	}
+	return
+}
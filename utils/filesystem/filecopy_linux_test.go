@@ -0,0 +1,77 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func determineAllocatedSize(t *testing.T, fs FS, path string) int64 {
+	t.Helper()
+	info, err := fs.Lstat(path)
+	require.NoError(t, err)
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	require.True(t, ok)
+	return stat.Blocks * 512
+}
+
+func TestCopyToFile_SparseFiles(t *testing.T) {
+	fs := NewStandardFileSystem()
+	dir := t.TempDir()
+	src := FilePathJoin(fs, dir, "src.img")
+	dest := FilePathJoin(fs, dir, "dest.img")
+	fileSize := int64(64 * 1024 * 1024)
+	createSparseTestFile(t, fs, src, fileSize, []byte(faker.Paragraph()))
+	if determineAllocatedSize(t, fs, src) >= fileSize {
+		t.Skip("⚠️ sparse files are not supported by the filesystem")
+	}
+
+	require.NoError(t, fs.CopyToFileWithContext(context.Background(), src, dest))
+	info, err := fs.Stat(dest)
+	require.NoError(t, err)
+	assert.Equal(t, fileSize, info.Size())
+	assert.Less(t, determineAllocatedSize(t, fs, dest), fileSize/2)
+	expected, err := fs.ReadFile(src)
+	require.NoError(t, err)
+	actual, err := fs.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// Files without trailing data keep their size.
+	f, err := fs.CreateFile(src)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(fileSize))
+	require.NoError(t, f.Close())
+	require.NoError(t, fs.CopyToFileWithContext(context.Background(), src, dest))
+	info, err = fs.Stat(dest)
+	require.NoError(t, err)
+	assert.Equal(t, fileSize, info.Size())
+	assert.Less(t, determineAllocatedSize(t, fs, dest), fileSize/2)
+}
+
+func TestCopyFileRange_SourceChanged(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(faker.Paragraph())
+	src, err := os.CreateTemp(dir, "src")
+	require.NoError(t, err)
+	defer func() { _ = src.Close() }()
+	_, err = src.Write(content)
+	require.NoError(t, err)
+	dest, err := os.CreateTemp(dir, "dest")
+	require.NoError(t, err)
+	defer func() { _ = dest.Close() }()
+
+	// The source is shorter than expected, as if it had been truncated during the copy.
+	err = copyFileRange(context.Background(), src, dest, 0, int64(2*len(content)))
+	errortest.AssertError(t, err, commonerrors.ErrUnexpected)
+}
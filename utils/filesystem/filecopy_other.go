@@ -0,0 +1,13 @@
+//go:build !linux
+
+package filesystem
+
+import (
+	"context"
+	"os"
+)
+
+// copyOSFileContent is only implemented on Linux. Elsewhere, files are copied through user space.
+func copyOSFileContent(_ context.Context, _, _ *os.File) (copied bool, err error) {
+	return
+}
@@ -0,0 +1,80 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+// createSparseTestFile creates a file with data surrounded by holes (on filesystems supporting them).
+func createSparseTestFile(t *testing.T, fs FS, path string, fileSize int64, data []byte) {
+	t.Helper()
+	f, err := fs.CreateFile(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	require.NoError(t, f.Truncate(fileSize))
+	_, err = f.WriteAt(data, fileSize/3)
+	require.NoError(t, err)
+	_, err = f.WriteAt(data, 2*fileSize/3)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestCopyToFile_Content(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			dir := t.TempDir()
+			src := FilePathJoin(fs, dir, "src.img")
+			dest := FilePathJoin(fs, dir, "dest.img")
+			fileSize := int64(4 * 1024 * 1024)
+			createSparseTestFile(t, fs, src, fileSize, []byte(faker.Paragraph()))
+
+			require.NoError(t, fs.CopyToFileWithContext(context.Background(), src, dest))
+			expected, err := fs.ReadFile(src)
+			require.NoError(t, err)
+			actual, err := fs.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+
+			// Existing files are overwritten.
+			require.NoError(t, fs.WriteFile(src, []byte(faker.Sentence()), 0o644))
+			require.NoError(t, fs.CopyToFileWithContext(context.Background(), src, dest))
+			expected, err = fs.ReadFile(src)
+			require.NoError(t, err)
+			actual, err = fs.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			errortest.AssertError(t, fs.CopyToFileWithContext(ctx, src, dest), commonerrors.ErrCancelled)
+		})
+	}
+}
+
+func TestCopyToFile_Wrappers(t *testing.T) {
+	// Copies through filesystem wrappers must not bypass them.
+	underlying := NewStandardFileSystem()
+	fs, err := NewQuotaFileSystem(context.Background(), underlying, &Quota{MaxTotalSize: 1024, MaxFileCount: 10})
+	require.NoError(t, err)
+	dir := t.TempDir()
+	src := FilePathJoin(fs, dir, "src.txt")
+	require.NoError(t, underlying.WriteFile(src, []byte(faker.Paragraph()), 0o644))
+	require.NoError(t, fs.CopyToFile(src, FilePathJoin(fs, dir, "dest.txt")))
+	usage, err := GetQuotaUsage(fs)
+	require.NoError(t, err)
+	info, err := fs.Stat(src)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(info.Size()), usage.TotalSize)
+
+	createSparseTestFile(t, underlying, src, 4096, []byte(faker.Sentence()))
+	errortest.AssertError(t, fs.CopyToFile(src, FilePathJoin(fs, dir, "other.txt")), commonerrors.ErrTooLarge)
+}
@@ -0,0 +1,33 @@
+package filesystem
+
+// FileOperation describes a type of filesystem operation, e.g. for injecting faults or auditing.
+type FileOperation string
+
+const (
+	// FileOperationAny matches any operation when filtering operations.
+	FileOperationAny FileOperation = "*"
+	// FileOperationOpen corresponds to opening an existing item.
+	FileOperationOpen FileOperation = "open"
+	// FileOperationCreate corresponds to opening a file with the intention of creating it if it does not exist.
+	FileOperationCreate   FileOperation = "create"
+	FileOperationRead     FileOperation = "read"
+	FileOperationWrite    FileOperation = "write"
+	FileOperationList     FileOperation = "list"
+	FileOperationStat     FileOperation = "stat"
+	FileOperationClose    FileOperation = "close"
+	FileOperationSync     FileOperation = "sync"
+	FileOperationTruncate FileOperation = "truncate"
+	FileOperationMkdir    FileOperation = "mkdir"
+	FileOperationRemove   FileOperation = "remove"
+	FileOperationRename   FileOperation = "rename"
+	FileOperationChmod    FileOperation = "chmod"
+	FileOperationChown    FileOperation = "chown"
+	FileOperationChtimes  FileOperation = "chtimes"
+	// FileOperationLink corresponds to the creation of symbolic or hard links.
+	FileOperationLink     FileOperation = "link"
+	FileOperationReadlink FileOperation = "readlink"
+	// FileOperationGetXattr corresponds to reading or listing extended attributes.
+	FileOperationGetXattr FileOperation = "getxattr"
+	// FileOperationSetXattr corresponds to setting or removing extended attributes.
+	FileOperationSetXattr FileOperation = "setxattr"
+)
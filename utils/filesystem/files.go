@@ -36,6 +36,7 @@ import (
 var (
 	ErrLinkNotImplemented  = commonerrors.New(commonerrors.ErrNotImplemented, "link not implemented")
 	ErrChownNotImplemented = commonerrors.New(commonerrors.ErrNotImplemented, "chown not implemented")
+	ErrXattrNotImplemented = commonerrors.New(commonerrors.ErrNotImplemented, "extended attributes not implemented")
 	ErrPathNotExist        = errors.New("readdirent: no such file or directory")
 	globalFileSystem       = NewFs(StandardFS)
 )
@@ -314,6 +315,14 @@ func (fs *VFS) NewRemoteLockFile(id string, dirToLock string) ILock {
 	return NewRemoteLockFile(fs, id, dirToLock)
 }
 
+func (fs *VFS) NewRemoteRWLockFile(id string, dirToLock string) IRWLock {
+	return NewRemoteRWLockFile(fs, id, dirToLock)
+}
+
+func (fs *VFS) NewRemoteSemaphoreFile(id string, dirToLock string, permits int) ISemaphore {
+	return NewRemoteSemaphoreFile(fs, id, dirToLock, permits)
+}
+
 func ReadFile(name string) ([]byte, error) {
 	return globalFileSystem.ReadFile(name)
 }
@@ -1639,11 +1648,25 @@ func CopyBetweenFSWithExclusionRegexes(ctx context.Context, srcFs FS, src string
 	if srcFs == destFs && src == dest {
 		return
 	}
+	dst, isSrcDir, err := prepareCopyDestination(srcFs, src, destFs, dest)
+	if err != nil {
+		return
+	}
+	if isSrcDir {
+		err = copyFolderBetweenFSWithExclusionRegexes(ctx, srcFs, src, destFs, dst, exclusionSrcFsRegexes, exclusionDestFsRegexes)
+	} else {
+		err = copyFileBetweenFSWithExclusionPatternsWithExclusionRegexes(ctx, srcFs, src, destFs, dst, exclusionSrcFsRegexes, exclusionDestFsRegexes)
+	}
+	return
+}
+
+// prepareCopyDestination determines where src should be copied to, following the semantics of `cp -r`, and creates any missing parent directory.
+func prepareCopyDestination(srcFs FS, src string, destFs FS, dest string) (dst string, isSrcDir bool, err error) {
 	if !srcFs.Exists(src) {
 		err = commonerrors.Newf(commonerrors.ErrNotFound, "path [%v] does not exist", src)
 		return
 	}
-	isSrcDir, err := srcFs.IsDir(src)
+	isSrcDir, err = srcFs.IsDir(src)
 	if err != nil {
 		return
 	}
@@ -1672,17 +1695,11 @@ func CopyBetweenFSWithExclusionRegexes(ctx context.Context, srcFs FS, src string
 		}
 	}
 
-	var dst string
 	if !(isSrcDir && !destExists) && isDestDir { //nolint:staticcheck
 		dst = FilePathJoin(destFs, dest, FilePathBase(srcFs, src))
 	} else {
 		dst = dest
 	}
-	if isSrcDir {
-		err = copyFolderBetweenFSWithExclusionRegexes(ctx, srcFs, src, destFs, dst, exclusionSrcFsRegexes, exclusionDestFsRegexes)
-	} else {
-		err = copyFileBetweenFSWithExclusionPatternsWithExclusionRegexes(ctx, srcFs, src, destFs, dst, exclusionSrcFsRegexes, exclusionDestFsRegexes)
-	}
 	return
 }
 
@@ -1737,7 +1754,7 @@ func copyFileBetweenFSWithExclusionPatternsWithExclusionRegexes(ctx context.Cont
 		return
 	}
 	defer func() { _ = outputFile.Close() }()
-	_, err = safeio.CopyDataWithContext(ctx, inputFile, outputFile)
+	err = copyFileContent(ctx, inputFile, outputFile)
 	if err != nil {
 		return
 	}
@@ -2451,3 +2451,23 @@ func globToRegex(glob string) string {
 	glob = strings.ReplaceAll(glob, "*", ".*")
 	return "^" + glob + "$"
 }
+
+// createTestFileTree creates, in a temporary directory, a small file tree with known paths: `file.txt`, `dir/file.txt`, `dir/subdir/file.txt`, `dir/test.tmp` and the empty directory `empty`.
+// All files have the same content. Use GenerateTestFileTree for a random tree.
+func createTestFileTree(t *testing.T, fs FS, content string) (root string) {
+	t.Helper()
+	root, err := fs.TempDirInTempDir("test-tree-")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = fs.Rm(root) })
+	require.NoError(t, fs.MkDir(FilePathJoin(fs, root, "dir", "subdir")))
+	require.NoError(t, fs.MkDir(FilePathJoin(fs, root, "empty")))
+	for _, file := range []string{
+		FilePathJoin(fs, root, "file.txt"),
+		FilePathJoin(fs, root, "dir", "file.txt"),
+		FilePathJoin(fs, root, "dir", "subdir", "file.txt"),
+		FilePathJoin(fs, root, "dir", "test.tmp"),
+	} {
+		require.NoError(t, fs.WriteFile(file, []byte(content), 0o644))
+	}
+	return
+}
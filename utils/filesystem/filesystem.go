@@ -28,6 +28,7 @@ const (
 	Custom
 	ZipFS
 	TarFS
+	OverlayFS
 )
 
 var (
@@ -88,12 +89,69 @@ func NewTarFileSystemFromStandardFileSystem(source string, limits ILimits) (IClo
 	return NewTarFileSystem(NewStandardFileSystem(), source, limits)
 }
 
+// NewOverlayFileSystem returns a copy-on-write filesystem which layers a writable layer over a read-only base filesystem (e.g. a zip or embed filesystem).
+// Modifications only ever happen in the layer: items of the base are copied up to the layer before being modified and removals of base items are recorded as whiteouts for the lifetime of the filesystem.
+// Warning: if the base filesystem requires closing, it is the responsibility of the caller to close it once the overlay is no longer used.
+func NewOverlayFileSystem(base FS, layer afero.Fs) (FS, error) {
+	if base == nil {
+		return nil, commonerrors.UndefinedVariable("base file system")
+	}
+	if layer == nil {
+		return nil, commonerrors.UndefinedVariable("writable layer")
+	}
+	return newOverlayFileSystem(base, layer), nil
+}
+
+func newOverlayFileSystem(base FS, layer afero.Fs) FS {
+	return NewVirtualFileSystemWithPathSeparator(newOverlayFs(base, layer), OverlayFS, IdentityPathConverterFunc, base.PathSeparator())
+}
+
+// NewInMemoryOverlayFileSystem returns an overlay filesystem (see NewOverlayFileSystem) whose writable layer is held in memory.
+func NewInMemoryOverlayFileSystem(base FS) (FS, error) {
+	return NewOverlayFileSystem(base, afero.NewMemMapFs())
+}
+
+// NewOverlayFileSystemWithScratchDirectory returns an overlay filesystem (see NewOverlayFileSystem) whose writable layer is stored in a scratch directory of the standard filesystem.
+func NewOverlayFileSystemWithScratchDirectory(base FS, scratchDir string) (FS, error) {
+	if scratchDir == "" {
+		return nil, commonerrors.UndefinedVariable("scratch directory")
+	}
+	err := NewStandardFileSystem().MkDir(scratchDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewOverlayFileSystem(base, newScratchLayerFs(scratchDir))
+}
+
+// NewBasePathFileSystem returns a filesystem restricted to the `root` directory of `fs`, similarly to a chroot.
+// Every path is considered relative to `root` and any path or symbolic link escaping it is rejected with commonerrors.ErrForbidden.
+func NewBasePathFileSystem(fs FS, root string) (FS, error) {
+	if fs == nil {
+		return nil, commonerrors.UndefinedVariable("file system")
+	}
+	vfs, ok := fs.(*VFS)
+	if !ok {
+		return nil, commonerrors.Newf(commonerrors.ErrUnsupported, "file system of type [%T] cannot be restricted to a base path", fs)
+	}
+	wrapped, err := newBasePathFs(vfs.vfs, root)
+	if err != nil {
+		return nil, err
+	}
+	pathConverter := func(path string) string {
+		return vfs.pathConverter(wrapped.convertPath(path))
+	}
+	return NewVirtualFileSystemWithPathSeparator(wrapped, vfs.fsType, pathConverter, vfs.pathSeparator), nil
+}
+
 func NewFs(fsType FilesystemType) FS {
 	switch fsType {
 	case StandardFS:
 		return NewStandardFileSystem()
 	case InMemoryFS:
 		return NewInMemoryFileSystem()
+	case OverlayFS:
+		// Any modification of the standard filesystem is only performed in memory.
+		return newOverlayFileSystem(NewStandardFileSystem(), afero.NewMemMapFs())
 	default:
 		return NewStandardFileSystem()
 	}
@@ -125,7 +183,7 @@ func ConvertFileSystemError(err error) error {
 		return commonerrors.WrapError(commonerrors.ErrOutOfRange, err, "")
 	case commonerrors.Any(err, afero.ErrTooLarge):
 		return commonerrors.WrapError(commonerrors.ErrTooLarge, err, "")
-	case commonerrors.Any(err, ErrChownNotImplemented, ErrLinkNotImplemented):
+	case commonerrors.Any(err, ErrChownNotImplemented, ErrLinkNotImplemented, ErrXattrNotImplemented):
 		return commonerrors.WrapError(commonerrors.ErrNotImplemented, err, "")
 	case commonerrors.Any(err, io.ErrUnexpectedEOF):
 		// Do not add io.EOF as it is used to read files
@@ -8,11 +8,11 @@ import (
 	"strings"
 )
 
-const _FilesystemTypeName = "StandardFSInMemoryFSEmbedCustomZipFSTarFS"
+const _FilesystemTypeName = "StandardFSInMemoryFSEmbedCustomZipFSTarFSOverlayFS"
 
-var _FilesystemTypeIndex = [...]uint8{0, 10, 20, 25, 31, 36, 41}
+var _FilesystemTypeIndex = [...]uint8{0, 10, 20, 25, 31, 36, 41, 50}
 
-const _FilesystemTypeLowerName = "standardfsinmemoryfsembedcustomzipfstarfs"
+const _FilesystemTypeLowerName = "standardfsinmemoryfsembedcustomzipfstarfsoverlayfs"
 
 func (i FilesystemType) String() string {
 	if i < 0 || i >= FilesystemType(len(_FilesystemTypeIndex)-1) {
@@ -31,9 +31,10 @@ func _FilesystemTypeNoOp() {
 	_ = x[Custom-(3)]
 	_ = x[ZipFS-(4)]
 	_ = x[TarFS-(5)]
+	_ = x[OverlayFS-(6)]
 }
 
-var _FilesystemTypeValues = []FilesystemType{StandardFS, InMemoryFS, Embed, Custom, ZipFS, TarFS}
+var _FilesystemTypeValues = []FilesystemType{StandardFS, InMemoryFS, Embed, Custom, ZipFS, TarFS, OverlayFS}
 
 var _FilesystemTypeNameToValueMap = map[string]FilesystemType{
 	_FilesystemTypeName[0:10]:       StandardFS,
@@ -48,6 +49,8 @@ var _FilesystemTypeNameToValueMap = map[string]FilesystemType{
 	_FilesystemTypeLowerName[31:36]: ZipFS,
 	_FilesystemTypeName[36:41]:      TarFS,
 	_FilesystemTypeLowerName[36:41]: TarFS,
+	_FilesystemTypeName[41:50]:      OverlayFS,
+	_FilesystemTypeLowerName[41:50]: OverlayFS,
 }
 
 var _FilesystemTypeNames = []string{
@@ -57,6 +60,7 @@ var _FilesystemTypeNames = []string{
 	_FilesystemTypeName[25:31],
 	_FilesystemTypeName[31:36],
 	_FilesystemTypeName[36:41],
+	_FilesystemTypeName[41:50],
 }
 
 // FilesystemTypeString retrieves an enum value from the enum constants string name.
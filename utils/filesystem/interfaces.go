@@ -4,6 +4,7 @@ package filesystem
 import (
 	"context"
 	"io"
+	"iter"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -15,7 +16,7 @@ import (
 	"github.com/ARM-software/golang-utils/utils/config"
 )
 
-//go:generate go tool mockgen -destination=../mocks/mock_$GOPACKAGE.go -package=mocks github.com/ARM-software/golang-utils/utils/$GOPACKAGE IFileHash,IChowner,ILinker,File,DiskUsage,FileTimeInfo,ILock,ILimits,FS,ICloseableFS,IForceRemover,IStater,ILinkReader,ISymLinker
+//go:generate go tool mockgen -destination=../mocks/mock_$GOPACKAGE.go -package=mocks github.com/ARM-software/golang-utils/utils/$GOPACKAGE IFileHash,IChowner,ILinker,File,DiskUsage,FileTimeInfo,ILock,IRWLock,ISemaphore,IFencedLock,ILockBackend,ILimits,FS,ICloseableFS,IForceRemover,IStater,ILinkReader,ISymLinker,IXattrer,IAuditSink
 
 // IFileHash defines a file hash.
 // For reference.
@@ -25,6 +26,10 @@ type IFileHash interface {
 	CalculateWithContext(ctx context.Context, f File) (string, error)
 	CalculateFile(fs FS, path string) (string, error)
 	CalculateFileWithContext(ctx context.Context, fs FS, path string) (string, error)
+	// CalculateDirectory calculates a deterministic hash of the directory tree rooted at `root` (see DirectoryHash).
+	CalculateDirectory(fs FS, root string, options ...DirectoryHashOption) (*DirectoryHash, error)
+	// CalculateDirectoryWithContext calculates a deterministic hash of the directory tree rooted at `root` (see DirectoryHash).
+	CalculateDirectoryWithContext(ctx context.Context, fs FS, root string, options ...DirectoryHashOption) (*DirectoryHash, error)
 	GetType() string
 }
 
@@ -68,6 +73,19 @@ type ISymLinker interface {
 	SymlinkIfPossible(string, string) error
 }
 
+// IXattrer is an Optional interface. It is only implemented by the
+// filesystems saying so.
+type IXattrer interface {
+	// GetXattrIfPossible returns the value of an extended attribute of an item.
+	GetXattrIfPossible(name, attribute string) ([]byte, error)
+	// SetXattrIfPossible sets the value of an extended attribute of an item.
+	SetXattrIfPossible(name, attribute string, value []byte) error
+	// ListXattrIfPossible lists the extended attributes of an item.
+	ListXattrIfPossible(name string) ([]string, error)
+	// RemoveXattrIfPossible removes an extended attribute of an item.
+	RemoveXattrIfPossible(name, attribute string) error
+}
+
 type File interface {
 	afero.File
 	Fd() uintptr
@@ -94,6 +112,12 @@ type FileTimeInfo interface {
 	HasAccessTime() bool
 }
 
+// IAuditSink defines a destination for the records of operations performed on an audited filesystem (see NewAuditFileSystem).
+type IAuditSink interface {
+	// Record stores or forwards a record. It may be called concurrently.
+	Record(record AuditRecord)
+}
+
 // ILimits defines general FileSystemLimits for actions performed on the filesystem
 type ILimits interface {
 	config.IServiceConfiguration
@@ -131,6 +155,59 @@ type ILock interface {
 	MakeStale(ctx context.Context) error
 }
 
+// IRWLock defines a reader/writer lock using the file system: the lock can be held by any number of readers or by a single writer.
+// The methods inherited from ILock apply to the writer (i.e. exclusive) lock.
+type IRWLock interface {
+	ILock
+	// RLock locks the lock for reading. This call will wait (i.e. block) until no writer holds the lock.
+	RLock(ctx context.Context) error
+	// RLockWithTimeout tries to lock the lock for reading until the timeout expires. If the timeout expires, this method will return commonerror.ErrTimeout.
+	RLockWithTimeout(ctx context.Context, timeout time.Duration) error
+	// TryRLock attempts to lock the lock for reading instantly. This method will return commonerrors.ErrLocked immediately if the lock cannot be acquired straight away.
+	TryRLock(ctx context.Context) error
+	// RUnlock releases the lock held for reading.
+	RUnlock(ctx context.Context) error
+}
+
+// ISemaphore defines a counting semaphore using the file system: up to a fixed number of holders can hold it at the same time.
+// The methods inherited from ILock acquire and release a single permit.
+type ISemaphore interface {
+	ILock
+	// GetPermits returns the maximum number of concurrent holders.
+	GetPermits() int
+	// CountHolders returns the number of permits currently held.
+	CountHolders() int
+}
+
+// IFencedLock defines a lock issuing fencing tokens: every acquisition returns a token strictly greater than any token previously issued for the lock so that
+// resources protected by the lock can reject requests made with an older token i.e. by holders which lost the lock (e.g. because it was deemed stale) without noticing.
+type IFencedLock interface {
+	ILock
+	// LockWithFencingToken locks the lock similarly to Lock and returns the fencing token of the acquisition.
+	LockWithFencingToken(ctx context.Context) (uint64, error)
+	// LockWithTimeoutAndFencingToken locks the lock similarly to LockWithTimeout and returns the fencing token of the acquisition.
+	LockWithTimeoutAndFencingToken(ctx context.Context, timeout time.Duration) (uint64, error)
+	// TryLockWithFencingToken locks the lock similarly to TryLock and returns the fencing token of the acquisition.
+	TryLockWithFencingToken(ctx context.Context) (uint64, error)
+	// GetOwner returns information about the current holder of the lock. If the lock is not held, commonerrors.ErrNotFound is returned.
+	GetOwner(ctx context.Context) (*LockOwner, error)
+}
+
+// ILockBackend defines the locking primitives a lock relies on (see NewFencedLock) so that various mechanisms (e.g. lock directories on shared file systems, OS file locks) can be plugged in.
+type ILockBackend interface {
+	// TryAcquire attempts to acquire the lock straight away on behalf of owner and returns a fencing token strictly greater than any token previously issued.
+	// commonerrors.ErrLocked is returned if the lock is held by someone else and commonerrors.ErrStaleLock if its holder seems dead, unless overrideStaleLock is set in which case the stale lock is overridden.
+	TryAcquire(ctx context.Context, owner *LockOwner, overrideStaleLock bool) (uint64, error)
+	// Release releases the lock if it is held by this backend. Locks held by someone else are never released: commonerrors.ErrConflict is returned if the lock was overridden since it was acquired by this backend.
+	Release(ctx context.Context) error
+	// IsStale determines whether the lock is held by a holder which seems dead.
+	IsStale() bool
+	// MakeStale makes the lock stale. This is mostly for testing purposes.
+	MakeStale(ctx context.Context) error
+	// GetOwner returns information about the current holder of the lock. If the lock is not held, commonerrors.ErrNotFound is returned.
+	GetOwner(ctx context.Context) (*LockOwner, error)
+}
+
 // FS defines all the methods a file system should provide.
 // Note: When an API accepting exclusion patterns, it means its processing will not be applied on path matching an exclusion pattern
 // An exclusion pattern correspond to a regex string following the syntax defined in the [regexp](https://pkg.go.dev/regexp) module.
@@ -169,6 +246,8 @@ type FS interface {
 	RemoveWithContext(ctx context.Context, dir string) (err error)
 	// RemoveWithContextAndExclusionPatterns removes directory (equivalent to rm -rf) unless they match some exclusion pattern.
 	RemoveWithContextAndExclusionPatterns(ctx context.Context, dir string, exclusionPatterns ...string) (err error)
+	// RemoveWithContextAndOptions removes directory (equivalent to rm -rf) similarly to RemoveWithContextAndExclusionPatterns but items may be removed concurrently (see WithWorkers).
+	RemoveWithContextAndOptions(ctx context.Context, dir string, options ...TreeOperationOption) (err error)
 	// RemoveWithPrivileges removes a directory even if it is not owned by user (equivalent to sudo rm -rf). It expects the current user to be a superuser.
 	RemoveWithPrivileges(ctx context.Context, dir string) (err error)
 	// IsFile states whether it is a file or not
@@ -198,6 +277,10 @@ type FS interface {
 	// WalkWithContextAndExclusionPatterns walks through the file tree rooted at root, calling fn for each file or
 	// directory in the tree as long as they do not match an exclusion pattern.
 	WalkWithContextAndExclusionPatterns(ctx context.Context, root string, fn filepath.WalkFunc, exclusionPatterns ...string) error
+	// Watch watches the file tree rooted at root and returns the sequence of changes (creation, modification, deletion, renaming) happening to items not matching an exclusion pattern.
+	// Changes are notified by the kernel (e.g. inotify) for the standard filesystem whereas other filesystems are periodically scanned for changes (using StatTimes).
+	// Watching starts as soon as Watch returns. It stops, and the associated goroutines are released, only when the context is cancelled or when iteration over the sequence stops: callers not iterating over the sequence must cancel the context. The sequence can only be iterated over once.
+	Watch(ctx context.Context, root string, exclusionPatterns ...string) (iter.Seq2[WatchEvent, error], error)
 	// Ls lists all files and directory (equivalent to ls)
 	Ls(dir string) (files []string, err error)
 	// LsRecursive lists all files recursively, including subdirectories
@@ -233,6 +316,8 @@ type FS interface {
 	CopyWithContext(ctx context.Context, src string, dest string) (err error)
 	// CopyWithContextAndExclusionPatterns copies files and directory like CopyWithContext but ignores any file matching the exclusion pattern.
 	CopyWithContextAndExclusionPatterns(ctx context.Context, src string, dest string, exclusionPatterns ...string) (err error)
+	// CopyWithContextAndOptions copies files and directory like CopyWithContextAndExclusionPatterns but files may be copied concurrently (see WithWorkers) and limits are checked beforehand.
+	CopyWithContextAndOptions(ctx context.Context, src string, dest string, options ...TreeOperationOption) (err error)
 	// Move moves a file (equivalent to mv)
 	Move(src string, dest string) (err error)
 	// MoveWithContext moves a file (equivalent to mv)
@@ -275,6 +360,13 @@ type FS interface {
 	// otherwise WriteFile truncates it before writing.
 	// It returns the number of bytes written.
 	WriteToFile(ctx context.Context, filename string, reader io.Reader, perm os.FileMode) (written int64, err error)
+	// WriteFileAtomically writes data to a file named by filename similarly to WriteFileWithContext but ensures the file is either fully written or left untouched.
+	// On the standard filesystem, data is written to a temporary file in the same directory, flushed to storage and then renamed over the target whereas, on other filesystems, data is fully staged in memory before the target is modified.
+	// If the file already exists, its permissions and ownership are preserved.
+	WriteFileAtomically(ctx context.Context, filename string, data []byte, perm os.FileMode) error
+	// WriteToFileAtomically writes data from a reader to a file named by filename atomically (see WriteFileAtomically).
+	// It returns the number of bytes written.
+	WriteToFileAtomically(ctx context.Context, filename string, reader io.Reader, perm os.FileMode) (written int64, err error)
 	// GarbageCollect runs the Garbage collector on the filesystem (removes any file which has not been accessed for a certain duration)
 	GarbageCollect(root string, durationSinceLastAccess time.Duration) error
 	// GarbageCollectWithContext runs the Garbage collector on the filesystem (removes any file which has not been accessed for a certain duration)
@@ -283,6 +375,8 @@ type FS interface {
 	Chmod(name string, mode os.FileMode) error
 	// ChmodRecursively changes the mode of anything within the `path`.
 	ChmodRecursively(ctx context.Context, path string, mode os.FileMode) error
+	// ChmodRecursivelyWithOptions changes the mode of anything within the `path` similarly to ChmodRecursively but items may be processed concurrently (see WithWorkers).
+	ChmodRecursivelyWithOptions(ctx context.Context, path string, mode os.FileMode, options ...TreeOperationOption) error
 	// Chtimes changes the access and modification times of the named file
 	Chtimes(name string, atime time.Time, mtime time.Time) error
 	// Chown changes the numeric uid and gid of the named file.
@@ -303,6 +397,15 @@ type FS interface {
 	Readlink(name string) (string, error)
 	// Symlink creates newname as a symbolic link to oldname.
 	Symlink(oldname string, newname string) error
+	// GetXattr returns the value of an extended attribute (e.g. `user.comment`, `system.posix_acl_access`) of the named item. If the attribute does not exist, commonerrors.ErrNotFound is returned.
+	// If the file system does not support extended attributes, commonerrors.ErrNotImplemented is returned.
+	GetXattr(name, attribute string) ([]byte, error)
+	// SetXattr sets the value of an extended attribute of the named item.
+	SetXattr(name, attribute string, value []byte) error
+	// ListXattr lists the extended attributes of the named item.
+	ListXattr(name string) ([]string, error)
+	// RemoveXattr removes an extended attribute of the named item.
+	RemoveXattr(name, attribute string) error
 	// DiskUsage determines Disk usage
 	DiskUsage(name string) (DiskUsage, error)
 	// GetFileSize gets file size
@@ -332,6 +435,10 @@ type FS interface {
 	ConvertToAbsolutePath(rootPath string, paths ...string) ([]string, error)
 	// NewRemoteLockFile creates a lock file on a remote location (NFS)
 	NewRemoteLockFile(id string, dirToLock string) ILock
+	// NewRemoteRWLockFile creates a reader/writer lock file on a remote location (NFS)
+	NewRemoteRWLockFile(id string, dirToLock string) IRWLock
+	// NewRemoteSemaphoreFile creates a counting semaphore allowing up to `permits` concurrent holders on a remote location (NFS)
+	NewRemoteSemaphoreFile(id string, dirToLock string, permits int) ISemaphore
 	// Zip compresses a file tree (source) into a zip file (destination)
 	Zip(source string, destination string) error
 	// ZipWithContext compresses a file tree (source) into a zip file (destination)
@@ -341,6 +448,8 @@ type FS interface {
 	ZipWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) error
 	// ZipWithContextAndLimitsAndExclusionPatterns compresses a file tree (source) into a zip file (destination) but ignores any file/folder matching an exclusion pattern.
 	ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context, source string, destination string, limits ILimits, exclusionPatterns ...string) error
+	// ZipWithContextAndOptions compresses a file tree (source) into a zip file (destination) according to options (e.g. limits, exclusion patterns, preservation of extended attributes).
+	ZipWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) error
 	// Unzip decompresses a source zip archive into the destination
 	Unzip(source string, destination string) ([]string, error)
 	// UnzipWithContext decompresses a source zip archive into the destination
@@ -348,10 +457,42 @@ type FS interface {
 	// UnzipWithContextAndLimits decompresses a source zip archive into the destination. Nonetheless, if FileSystemLimits are exceeded, an error will be returned and the process will be stopped.
 	// It is however the responsibility of the caller to clean any partially unzipped archive if error occurs.
 	UnzipWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) (fileList []string, err error)
+	// UnzipWithContextAndOptions decompresses a source zip archive into the destination according to options (e.g. limits, restoration of extended attributes).
+	UnzipWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) (fileList []string, err error)
+	// UnzipFromReaderWithContextAndLimits decompresses a zip archive read from source (of the given size) into the destination without the archive having to be present on a file system.
+	// FileSystemLimits are enforced whilst extracting. It is however the responsibility of the caller to clean any partially unzipped archive if error occurs.
+	UnzipFromReaderWithContextAndLimits(ctx context.Context, source io.ReaderAt, size int64, destination string, limits ILimits) (fileList []string, err error)
+	// Tar archives a file tree (source) into a tar archive (destination). The archive is compressed according to the destination extension (i.e. `.tar.gz`/`.tgz` for gzip, `.tar.zst`/`.tzst` for zstd).
+	// File modes, symbolic links and timestamps are preserved.
+	Tar(source string, destination string) error
+	// TarWithContext archives a file tree (source) into a tar archive (destination) similarly to Tar.
+	TarWithContext(ctx context.Context, source string, destination string) error
+	// TarWithContextAndLimits archives a file tree (source) into a tar archive (destination). Nonetheless, if FileSystemLimits are exceeded, an error will be returned and the process will be stopped.
+	// It is however the responsibility of the caller to clean any partially created archive if error occurs.
+	TarWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) error
+	// TarWithContextAndLimitsAndExclusionPatterns archives a file tree (source) into a tar archive (destination) but ignores any file/folder matching an exclusion pattern.
+	TarWithContextAndLimitsAndExclusionPatterns(ctx context.Context, source string, destination string, limits ILimits, exclusionPatterns ...string) error
+	// TarWithContextAndOptions archives a file tree (source) into a tar archive (destination) according to options (e.g. limits, exclusion patterns, preservation of extended attributes).
+	TarWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) error
+	// Untar extracts a source tar archive (optionally compressed with gzip or zstd) into the destination
+	Untar(source string, destination string) ([]string, error)
+	// UntarWithContext extracts a source tar archive into the destination
+	UntarWithContext(ctx context.Context, source string, destination string) ([]string, error)
+	// UntarWithContextAndLimits extracts a source tar archive into the destination. Nonetheless, if FileSystemLimits are exceeded, an error will be returned and the process will be stopped.
+	// Any item or symbolic link pointing outside the destination is rejected with commonerrors.ErrMalicious.
+	// It is however the responsibility of the caller to clean any partially extracted archive if error occurs.
+	UntarWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) (fileList []string, err error)
+	// UntarWithContextAndOptions extracts a source tar archive into the destination according to options (e.g. limits, restoration of extended attributes).
+	UntarWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) (fileList []string, err error)
+	// UntarFromReaderWithContextAndLimits extracts a tar archive (optionally compressed with gzip or zstd) streamed from source into the destination without the archive having to be present on a file system.
+	// FileSystemLimits are enforced on the fly. It is however the responsibility of the caller to clean any partially extracted archive if error occurs.
+	UntarFromReaderWithContextAndLimits(ctx context.Context, source io.Reader, destination string, limits ILimits) (fileList []string, err error)
 	// FileHash calculates file hash
 	FileHash(hashAlgo string, path string) (string, error)
 	// FileHashWithContext calculates file hash
 	FileHashWithContext(ctx context.Context, hashAlgo string, path string) (string, error)
+	// FileHashesWithContext calculates the hash of every regular file of the tree rooted at `root`. Hashes are indexed by paths relative to root using `/` as separator and files may be hashed concurrently (see WithWorkers).
+	FileHashesWithContext(ctx context.Context, hashAlgo string, root string, options ...TreeOperationOption) (map[string]string, error)
 	// IsZip states whether a file is a zip file or not. If the file does not exist, it will state whether the filename has a zip extension or not.
 	IsZip(filepath string) bool
 	// IsZipWithContext states whether a file is a zip file or not. Since the process can take some time (i.e type detection with sniffers such as http.DetectContentType), it is controlled by a context.
@@ -10,9 +10,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"go.uber.org/atomic"
 
 	"github.com/ARM-software/golang-utils/utils/collection"
 	"github.com/ARM-software/golang-utils/utils/commonerrors"
@@ -30,14 +32,23 @@ type RemoteLockFile struct {
 	prefix               string
 	path                 string
 	timeBetweenLockTries time.Duration
-	fs                   *VFS
+	fs                   FS
 	lockHeartBeatPeriod  time.Duration
 	cancelStore          *parallelisation.CancelFunctionStore
 	overrideStaleLock    bool
+	// held states whether the lock was acquired by this instance.
+	held *atomic.Bool
+	// released states whether the lock acquired by this instance was since released, in which case it may now be held by someone else.
+	released   *atomic.Bool
+	heartBeats sync.WaitGroup
 }
 
 // NewGenericRemoteLockFile creates a new remote lock using the file system.
 func NewGenericRemoteLockFile(fs *VFS, lockID string, dirPath string, overrideStaleLock bool) ILock {
+	return newRemoteLockFile(fs, lockID, dirPath, overrideStaleLock)
+}
+
+func newRemoteLockFile(fs FS, lockID string, dirPath string, overrideStaleLock bool) *RemoteLockFile {
 	return &RemoteLockFile{
 		id:                   lockID,
 		prefix:               LockFilePrefix,
@@ -47,6 +58,8 @@ func NewGenericRemoteLockFile(fs *VFS, lockID string, dirPath string, overrideSt
 		lockHeartBeatPeriod:  50 * time.Millisecond,
 		cancelStore:          parallelisation.NewCancelFunctionsStore(),
 		overrideStaleLock:    overrideStaleLock,
+		held:                 atomic.NewBool(false),
+		released:             atomic.NewBool(false),
 	}
 }
 
@@ -95,7 +108,7 @@ func (l *RemoteLockFile) IsStale() bool {
 	return areHeartBeatFilesAllStale(l.fs, lockPath, heartBeatFiles, l.lockHeartBeatPeriod)
 }
 
-func areHeartBeatFilesAllStale(fs *VFS, lockPath string, heartBeatFiles []string, lockHeartBeatPeriod time.Duration) bool {
+func areHeartBeatFilesAllStale(fs FS, lockPath string, heartBeatFiles []string, lockHeartBeatPeriod time.Duration) bool {
 	staleFiles := []bool{}
 	for i := range heartBeatFiles {
 		heartBeat := FilePathJoin(fs, lockPath, heartBeatFiles[i]) // there should only be one file in the directory
@@ -120,25 +133,30 @@ func isStale(filetime FileTimeInfo, beatPeriod time.Duration) bool {
 
 func (l *RemoteLockFile) ReleaseIfStale(ctx context.Context) error {
 	if l.IsStale() {
-		return l.Unlock(ctx)
+		return l.forceUnlock(ctx)
 	}
 	return nil
 }
 
 // TryLock attempts to lock the lock straight away.
 func (l *RemoteLockFile) TryLock(ctx context.Context) (err error) {
+	return l.tryLock(ctx, l.overrideStaleLock)
+}
+
+// tryLock attempts to lock the lock straight away. If overrideStaleLock is set, a stale lock is released and then acquired.
+func (l *RemoteLockFile) tryLock(ctx context.Context, overrideStaleLock bool) (err error) {
 	if err := parallelisation.DetermineContextError(ctx); err != nil {
 		return err
 	}
 
 	lockPath := l.lockPath()
 	// create directory as lock
-	err = l.fs.vfs.Mkdir(lockPath, 0755)
+	err = mkdirExclusively(l.fs, lockPath)
 	if commonerrors.Any(ConvertFileSystemError(err), commonerrors.ErrExists) {
 		if l.IsStale() {
-			if l.overrideStaleLock {
+			if overrideStaleLock {
 				_ = l.ReleaseIfStale(ctx)
-				err = l.TryLock(ctx)
+				err = l.tryLock(ctx, overrideStaleLock)
 				return err
 			}
 			return commonerrors.ErrStaleLock
@@ -160,10 +178,25 @@ func (l *RemoteLockFile) TryLock(ctx context.Context) (err error) {
 	//nolint:gosec // G118: cancel is stored and invoked by cancellationStore via Stop()/Close().
 	subctx, cancelFunc := context.WithCancel(ctx)
 	l.cancelStore.RegisterCancelFunction(cancelFunc)
-	go heartBeat(subctx, l.fs, l.lockHeartBeatPeriod, heartBeatFilePath)
+	l.heartBeats.Add(1)
+	go func() {
+		defer l.heartBeats.Done()
+		heartBeat(subctx, l.fs, l.lockHeartBeatPeriod, heartBeatFilePath)
+	}()
+	l.released.Store(false)
+	l.held.Store(true)
 	return nil
 }
 
+// mkdirExclusively creates a directory atomically and fails if it already exists. Unlike FS.MkDir, it can therefore be used for locking.
+func mkdirExclusively(fs FS, path string) error {
+	vfs, ok := fs.(*VFS)
+	if !ok {
+		return commonerrors.Newf(commonerrors.ErrUnsupported, "directories cannot be created exclusively on file system of type [%T]", fs)
+	}
+	return vfs.vfs.Mkdir(path, 0755)
+}
+
 func (l *RemoteLockFile) heartBeatFile(lockPath string) string {
 	return FilePathJoin(l.fs, lockPath, fmt.Sprintf("%v.lock", l.id))
 }
@@ -196,8 +229,21 @@ func (l *RemoteLockFile) LockWithTimeout(ctx context.Context, timeout time.Durat
 	return parallelisation.RunActionWithTimeoutAndCancelStore(ctx, timeout, l.cancelStore, l.Lock)
 }
 
-// Unlock unlocks the lock
+// Unlock unlocks the lock. If the lock is not held by this instance, it is forcibly released unless this instance has already released it, in which case nothing happens.
 func (l *RemoteLockFile) Unlock(ctx context.Context) error {
+	if l.held.Swap(false) {
+		l.released.Store(true)
+		return l.release(ctx)
+	}
+	if l.released.Load() {
+		// the lock may have been acquired by someone else since.
+		return nil
+	}
+	return l.forceUnlock(ctx)
+}
+
+// forceUnlock releases the lock whoever holds it.
+func (l *RemoteLockFile) forceUnlock(ctx context.Context) error {
 	l.cancelStore.Cancel()
 	return retry.Do(
 		func() error {
@@ -217,8 +263,40 @@ func (l *RemoteLockFile) Unlock(ctx context.Context) error {
 	)
 }
 
+// release releases a lock held by this instance. Unlike forceUnlock, the lock directory is only removed once
+// so that a lock acquired by someone else straight after is not released too.
+func (l *RemoteLockFile) release(ctx context.Context) error {
+	l.cancelStore.Cancel()
+	// Waiting for heart beats to stop as some file systems (e.g. in-memory) would otherwise recreate the lock directory when writing the heart beat file.
+	l.heartBeats.Wait()
+	err := retry.Do(
+		func() error {
+			return l.fs.Rm(l.lockPath())
+		},
+		retry.MaxJitter(25*time.Millisecond),
+		retry.DelayType(retry.RandomDelay),
+		retry.Attempts(10),
+		retry.Context(ctx),
+		retry.LastErrorOnly(true),
+	)
+	if err != nil {
+		return commonerrors.Newf(err, "cannot unlock lock [%v]", l.id)
+	}
+	return nil
+}
+
+// abandon stops maintaining the lock held by this instance without releasing it, e.g. because it was overridden by someone else after going stale.
+func (l *RemoteLockFile) abandon() {
+	if l.held.Swap(false) {
+		l.released.Store(true)
+	}
+	l.cancelStore.Cancel()
+	l.heartBeats.Wait()
+}
+
 // MakeStale is mostly useful for testing purposes and tries to mock locks going stale.
 func (l *RemoteLockFile) MakeStale(ctx context.Context) error {
+	l.held.Store(false)
 	l.cancelStore.Cancel()
 	parallelisation.SleepWithContext(ctx, l.lockHeartBeatPeriod+time.Millisecond)
 	lockPath := l.lockPath()
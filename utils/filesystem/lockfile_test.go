@@ -226,6 +226,33 @@ func TestLockSimpleSequential(t *testing.T) { // Several lock/unlock sequences p
 	}
 }
 
+func TestLockUnlockAfterRelease(t *testing.T) { // Unlocking again a lock released by an instance does not release the lock acquired by someone else since
+	for j := range FileSystemTypes {
+		fsType := FileSystemTypes[j]
+		t.Run(fmt.Sprintf("%v_for_fs_%v", t.Name(), fsType), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			fs := NewFs(fsType)
+			ctx := context.Background()
+			dirToLock, err := fs.TempDirInTempDir(fmt.Sprintf("test-lock-dir-%v", faker.DomainName()))
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(dirToLock) }()
+			lockA := fs.NewRemoteLockFile("lock", dirToLock)
+			lockB := fs.NewRemoteLockFile("lock", dirToLock)
+			lockC := fs.NewRemoteLockFile("lock", dirToLock)
+
+			require.NoError(t, lockA.TryLock(ctx))
+			require.NoError(t, lockA.Unlock(ctx))
+			require.NoError(t, lockB.TryLock(ctx))
+			require.NoError(t, lockA.Unlock(ctx))
+			errortest.AssertError(t, lockC.TryLock(ctx), commonerrors.ErrLocked)
+
+			require.NoError(t, lockB.Unlock(ctx))
+			require.NoError(t, lockC.TryLock(ctx))
+			require.NoError(t, lockC.Unlock(ctx))
+		})
+	}
+}
+
 func TestLockSequential(t *testing.T) {
 	lockFuncs := []struct {
 		LockFunc      func(l ILock, ctx context.Context) error
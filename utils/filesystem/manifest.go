@@ -0,0 +1,230 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+	jsonserialization "github.com/ARM-software/golang-utils/utils/serialization/json" //nolint:misspell
+	yamlserialization "github.com/ARM-software/golang-utils/utils/serialization/yaml" //nolint:misspell
+)
+
+// ManifestEntry describes an item of a file tree.
+type ManifestEntry struct {
+	// Path is the path of the item relative to the root of the tree, using `/` as separator.
+	Path  string `json:"path"`
+	IsDir bool   `json:"isDir,omitempty"`
+	// LinkTarget is the destination of the item if it is a symbolic link.
+	LinkTarget string      `json:"linkTarget,omitempty"`
+	Size       int64       `json:"size"`
+	Mode       os.FileMode `json:"mode"`
+	ModTime    time.Time   `json:"modTime"`
+	// Hash is the hash of the content of regular files if the manifest was generated using a hashing algorithm.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Manifest describes the content of a file tree. It can be serialised using the serialization packages.
+type Manifest struct {
+	// HashAlgorithm is the algorithm used for hashing the content of files (see hashing package). It is empty if files were not hashed.
+	HashAlgorithm string          `json:"hashAlgorithm,omitempty"`
+	Entries       []ManifestEntry `json:"entries"`
+}
+
+// ManifestDiff describes the differences between two file trees. Paths are relative to the root of the trees.
+type ManifestDiff struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+// HasChanges states whether the trees differ.
+func (d *ManifestDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Modified) > 0
+}
+
+// GenerateManifest walks the file tree rooted at root and describes all the items it contains which do not match any exclusion pattern.
+// If hashAlgorithm is not empty, the content of regular files is hashed using the corresponding algorithm (see hashing package).
+func GenerateManifest(ctx context.Context, fs FS, root string, hashAlgorithm string, exclusionPatterns ...string) (manifest *Manifest, err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	var hash IFileHash
+	if hashAlgorithm != "" {
+		hash, err = NewFileHash(hashAlgorithm)
+		if err != nil {
+			return
+		}
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	isDir, err := fs.IsDir(root)
+	if err != nil {
+		return
+	}
+	if !isDir {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "path [%v] is not a directory", root)
+		return
+	}
+	m := &Manifest{HashAlgorithm: hashAlgorithm, Entries: []ManifestEntry{}}
+	cleanRoot := FilePathClean(fs, root)
+	err = fs.WalkWithContextAndExclusionPatterns(ctx, root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, subErr := FilePathRel(fs, cleanRoot, FilePathClean(fs, path))
+		if subErr != nil {
+			return subErr
+		}
+		if rel == "." {
+			return nil
+		}
+		entry := ManifestEntry{
+			Path:    FilePathToSlash(fs, rel),
+			IsDir:   info.IsDir(),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime().UTC(),
+		}
+		switch {
+		case info.IsDir():
+			entry.Size = 0
+		case IsSymLink(info):
+			entry.LinkTarget, subErr = fs.Readlink(path)
+			if subErr != nil {
+				return subErr
+			}
+		case hash != nil && info.Mode().IsRegular():
+			entry.Hash, subErr = hash.CalculateFileWithContext(ctx, fs, path)
+			if subErr != nil {
+				return subErr
+			}
+		}
+		m.Entries = append(m.Entries, entry)
+		return nil
+	}, exclusionPatterns...)
+	err = ConvertFileSystemError(err)
+	if err != nil {
+		return
+	}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	manifest = m
+	return
+}
+
+// DiffManifests determines what changed between two manifests. If both manifests contain content hashes, files are compared using their content rather than their modification time.
+func DiffManifests(before, after *Manifest) (diff *ManifestDiff, err error) {
+	if before == nil || after == nil {
+		err = commonerrors.UndefinedVariable("manifest")
+		return
+	}
+	compareHashes := before.HashAlgorithm != "" && after.HashAlgorithm != ""
+	if compareHashes && before.HashAlgorithm != after.HashAlgorithm {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "manifests were generated using different hashing algorithms (%v and %v)", before.HashAlgorithm, after.HashAlgorithm)
+		return
+	}
+	previous := make(map[string]*ManifestEntry, len(before.Entries))
+	for i := range before.Entries {
+		previous[before.Entries[i].Path] = &before.Entries[i]
+	}
+	diff = &ManifestDiff{}
+	for i := range after.Entries {
+		entry := &after.Entries[i]
+		previousEntry, found := previous[entry.Path]
+		if !found {
+			diff.Added = append(diff.Added, entry.Path)
+			continue
+		}
+		delete(previous, entry.Path)
+		if previousEntry.differsFrom(entry, compareHashes) {
+			diff.Modified = append(diff.Modified, entry.Path)
+		}
+	}
+	for path := range previous {
+		diff.Removed = append(diff.Removed, path)
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return
+}
+
+func (e *ManifestEntry) differsFrom(other *ManifestEntry, compareHashes bool) bool {
+	if e.IsDir != other.IsDir || e.Mode != other.Mode || e.LinkTarget != other.LinkTarget {
+		return true
+	}
+	if e.IsDir {
+		return false
+	}
+	if e.Size != other.Size {
+		return true
+	}
+	if compareHashes {
+		return e.Hash != other.Hash
+	}
+	return !e.ModTime.Equal(other.ModTime)
+}
+
+// DiffDirectories determines what changed between two file trees, possibly on different filesystems. See GenerateManifest and DiffManifests.
+func DiffDirectories(ctx context.Context, beforeFs FS, before string, afterFs FS, after string, hashAlgorithm string, exclusionPatterns ...string) (diff *ManifestDiff, err error) {
+	beforeManifest, err := GenerateManifest(ctx, beforeFs, before, hashAlgorithm, exclusionPatterns...)
+	if err != nil {
+		return
+	}
+	afterManifest, err := GenerateManifest(ctx, afterFs, after, hashAlgorithm, exclusionPatterns...)
+	if err != nil {
+		return
+	}
+	return DiffManifests(beforeManifest, afterManifest)
+}
+
+// SaveManifest writes a manifest to a file. The manifest is serialised as YAML if the file has a YAML extension and as JSON otherwise.
+func SaveManifest(ctx context.Context, fs FS, manifest *Manifest, path string) (err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	if manifest == nil {
+		err = commonerrors.UndefinedVariable("manifest")
+		return
+	}
+	var content []byte
+	if yamlserialization.IsYAMLFile(FilePathExt(fs, path)) {
+		content, err = yamlserialization.MarshalWithContext(ctx, manifest)
+	} else {
+		content, err = jsonserialization.MarshalWithContext(ctx, manifest)
+	}
+	if err != nil {
+		return
+	}
+	err = fs.WriteFileWithContext(ctx, path, content, 0o644)
+	return
+}
+
+// LoadManifest reads a manifest from a file written by SaveManifest.
+func LoadManifest(ctx context.Context, fs FS, path string) (manifest *Manifest, err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	content, err := fs.ReadFileWithContext(ctx, path)
+	if err != nil {
+		return
+	}
+	m := &Manifest{}
+	if yamlserialization.IsYAMLFile(FilePathExt(fs, path)) {
+		err = yamlserialization.UnmarshallWithContext(ctx, content, m)
+	} else {
+		err = jsonserialization.UnmarshallWithContext(ctx, content, m)
+	}
+	if err != nil {
+		return
+	}
+	manifest = m
+	return
+}
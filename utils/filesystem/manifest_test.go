@@ -0,0 +1,143 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+)
+
+func TestGenerateManifest(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root := createTestFileTree(t, fs, faker.Paragraph())
+			manifest, err := GenerateManifest(context.Background(), fs, root, hashing.HashMd5)
+			require.NoError(t, err)
+			assert.Equal(t, hashing.HashMd5, manifest.HashAlgorithm)
+			var paths []string
+			for i := range manifest.Entries {
+				paths = append(paths, manifest.Entries[i].Path)
+			}
+			assert.Equal(t, []string{"dir", "dir/file.txt", "dir/subdir", "dir/subdir/file.txt", "dir/test.tmp", "empty", "file.txt"}, paths)
+			expectedHash, err := fs.FileHash(hashing.HashMd5, FilePathJoin(fs, root, "file.txt"))
+			require.NoError(t, err)
+			entry := manifest.Entries[len(manifest.Entries)-1]
+			assert.Equal(t, expectedHash, entry.Hash)
+			assert.False(t, entry.IsDir)
+			assert.NotZero(t, entry.Size)
+			assert.True(t, manifest.Entries[0].IsDir)
+			assert.Empty(t, manifest.Entries[0].Hash)
+
+			manifest, err = GenerateManifest(context.Background(), fs, root, "", ".*[.]tmp")
+			require.NoError(t, err)
+			assert.Len(t, manifest.Entries, 6)
+			for i := range manifest.Entries {
+				assert.Empty(t, manifest.Entries[i].Hash)
+			}
+		})
+	}
+}
+
+func TestGenerateManifest_Failures(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	root := createTestFileTree(t, fs, faker.Paragraph())
+	_, err := GenerateManifest(context.Background(), nil, root, "")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = GenerateManifest(context.Background(), fs, FilePathJoin(fs, root, "file.txt"), "")
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = GenerateManifest(context.Background(), fs, root, faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrNotFound, commonerrors.ErrUnsupported, commonerrors.ErrInvalid)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = GenerateManifest(ctx, fs, root, "")
+	errortest.AssertError(t, err, commonerrors.ErrCancelled)
+}
+
+func TestDiffDirectories(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	content := faker.Paragraph()
+	before := createTestFileTree(t, fs, content)
+	after := createTestFileTree(t, fs, content)
+
+	diff, err := DiffDirectories(context.Background(), fs, before, fs, after, hashing.HashMd5)
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+
+	require.NoError(t, fs.WriteFile(FilePathJoin(fs, after, "dir", "file.txt"), []byte(faker.Sentence()), 0o644))
+	require.NoError(t, fs.Rm(FilePathJoin(fs, after, "dir", "subdir")))
+	require.NoError(t, fs.Touch(FilePathJoin(fs, after, "new.txt")))
+	diff, err = DiffDirectories(context.Background(), fs, before, fs, after, hashing.HashMd5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new.txt"}, diff.Added)
+	assert.Equal(t, []string{"dir/subdir", "dir/subdir/file.txt"}, diff.Removed)
+	assert.Equal(t, []string{"dir/file.txt"}, diff.Modified)
+
+	diff, err = DiffDirectories(context.Background(), fs, before, fs, after, "", ".*[.]txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dir/subdir"}, diff.Removed)
+	assert.Empty(t, diff.Added)
+}
+
+func TestDiffManifests(t *testing.T) {
+	now := time.Now().UTC()
+	before := &Manifest{Entries: []ManifestEntry{
+		{Path: "a", Size: 1, ModTime: now, Hash: "1"},
+		{Path: "b", Size: 1, ModTime: now, Hash: "2"},
+		{Path: "c", IsDir: true, ModTime: now},
+	}}
+	after := &Manifest{Entries: []ManifestEntry{
+		{Path: "a", Size: 1, ModTime: now.Add(time.Hour), Hash: "1"},
+		{Path: "b", Size: 1, ModTime: now, Hash: "3"},
+		{Path: "c", IsDir: true, ModTime: now.Add(time.Hour)},
+	}}
+	// Without hashes, modification times are compared.
+	diff, err := DiffManifests(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, diff.Modified)
+	before.HashAlgorithm = hashing.HashMd5
+	after.HashAlgorithm = hashing.HashMd5
+	diff, err = DiffManifests(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, diff.Modified)
+
+	after.HashAlgorithm = hashing.HashSha256
+	_, err = DiffManifests(before, after)
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = DiffManifests(nil, after)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+}
+
+func TestSaveLoadManifest(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	root := createTestFileTree(t, fs, faker.Paragraph())
+	manifest, err := GenerateManifest(context.Background(), fs, root, hashing.HashSha256)
+	require.NoError(t, err)
+	for _, name := range []string{"manifest.json", "manifest.yaml", "manifest.yml"} {
+		t.Run(name, func(t *testing.T) {
+			path := FilePathJoin(fs, t.TempDir(), name)
+			require.NoError(t, SaveManifest(context.Background(), fs, manifest, path))
+			loaded, err := LoadManifest(context.Background(), fs, path)
+			require.NoError(t, err)
+			assert.Equal(t, manifest.HashAlgorithm, loaded.HashAlgorithm)
+			require.Len(t, loaded.Entries, len(manifest.Entries))
+			diff, err := DiffManifests(manifest, loaded)
+			require.NoError(t, err)
+			assert.False(t, diff.HasChanges())
+			diff, err = DiffManifests(&Manifest{Entries: manifest.Entries}, &Manifest{Entries: loaded.Entries})
+			require.NoError(t, err)
+			assert.False(t, diff.HasChanges())
+		})
+	}
+	errortest.AssertError(t, SaveManifest(context.Background(), fs, nil, "test.json"), commonerrors.ErrUndefined)
+	_, err = LoadManifest(context.Background(), fs, faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+}
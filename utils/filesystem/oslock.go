@@ -0,0 +1,159 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// FileLockMechanism describes the OS primitive used for locking local files.
+type FileLockMechanism int
+
+const (
+	// FlockFileLock relies on `flock(2)` i.e. advisory locks associated with open files.
+	FlockFileLock FileLockMechanism = iota
+	// FcntlFileLock relies on `fcntl(2)` record locks. On Linux, open file description locks are used so that locks of a same process conflict.
+	// Elsewhere, traditional POSIX locks are used and hence, locks held by a same process do not exclude each other.
+	FcntlFileLock
+)
+
+func (m FileLockMechanism) String() string {
+	switch m {
+	case FlockFileLock:
+		return "flock"
+	case FcntlFileLock:
+		return "fcntl"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(m))
+	}
+}
+
+// osFileLockBackend is a lock backend relying on OS file locks. Such locks are released by the OS when their holder dies and therefore, never go stale.
+type osFileLockBackend struct {
+	mu        sync.Mutex
+	path      string
+	mechanism FileLockMechanism
+	state     *lockStateFile
+	// file is the open lock file whilst the lock is held by this backend.
+	file *os.File
+}
+
+// NewOSFileLockBackend returns a lock backend relying on OS locks (see FileLockMechanism) of a file of the standard file system.
+// OS locks are only reliable for local files: RemoteLockFile should be preferred on network file systems.
+func NewOSFileLockBackend(fs FS, lockID string, dirPath string, mechanism FileLockMechanism) (ILockBackend, error) {
+	if fs == nil {
+		return nil, commonerrors.UndefinedVariable("file system")
+	}
+	if fs.GetType() != StandardFS {
+		return nil, commonerrors.Newf(commonerrors.ErrUnsupported, "OS file locks are not supported by %v file systems", fs.GetType())
+	}
+	if mechanism != FlockFileLock && mechanism != FcntlFileLock {
+		return nil, commonerrors.Newf(commonerrors.ErrInvalid, "unknown file lock mechanism %v", mechanism)
+	}
+	lockPath := FilePathJoin(fs, dirPath, fmt.Sprintf("%v-%v", LockFilePrefix, strings.TrimSpace(lockID)))
+	return &osFileLockBackend{
+		path:      fmt.Sprintf("%v.lock", lockPath),
+		mechanism: mechanism,
+		state:     &lockStateFile{fs: fs, path: fmt.Sprintf("%v.state", lockPath)},
+	}, nil
+}
+
+// NewLocalLockFile returns a lock relying on OS file locks (see NewOSFileLockBackend) which issues fencing tokens and records its owner.
+func NewLocalLockFile(fs FS, lockID string, dirPath string, mechanism FileLockMechanism, options ...LockOption) (IFencedLock, error) {
+	backend, err := NewOSFileLockBackend(fs, lockID, dirPath, mechanism)
+	if err != nil {
+		return nil, err
+	}
+	return NewFencedLock(lockID, backend, options...)
+}
+
+// tryLockOSFile opens the lock file and tries to lock it.
+func (b *osFileLockBackend) tryLockOSFile() (f *os.File, err error) {
+	f, err = os.OpenFile(b.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		err = ConvertFileSystemError(err)
+		return
+	}
+	err = lockOSFile(f, b.mechanism)
+	if err != nil {
+		_ = f.Close()
+		f = nil
+	}
+	return
+}
+
+// TryAcquire attempts to acquire the lock straight away. OS file locks never go stale and so, there is nothing to override.
+func (b *osFileLockBackend) TryAcquire(ctx context.Context, owner *LockOwner, _ bool) (token uint64, err error) {
+	if owner == nil {
+		err = commonerrors.UndefinedVariable("lock owner")
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file != nil {
+		err = commonerrors.Newf(commonerrors.ErrLocked, "lock [%v] is already held", b.path)
+		return
+	}
+	f, err := b.tryLockOSFile()
+	if err != nil {
+		return
+	}
+	token, err = b.state.acquired(ctx, owner)
+	if err != nil {
+		_ = unlockOSFile(f, b.mechanism)
+		_ = f.Close()
+		return
+	}
+	b.file = f
+	return
+}
+
+// Release releases the lock if held by this backend. Locks held by other processes cannot be released.
+func (b *osFileLockBackend) Release(ctx context.Context) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		return
+	}
+	err = b.state.released(ctx)
+	if err != nil {
+		return
+	}
+	err = unlockOSFile(b.file, b.mechanism)
+	if err != nil {
+		return
+	}
+	err = ConvertFileSystemError(b.file.Close())
+	b.file = nil
+	return
+}
+
+func (b *osFileLockBackend) IsStale() bool {
+	return false
+}
+
+func (b *osFileLockBackend) MakeStale(_ context.Context) error {
+	return commonerrors.New(commonerrors.ErrUnsupported, "OS file locks cannot go stale as they are released when their holder dies")
+}
+
+func (b *osFileLockBackend) GetOwner(_ context.Context) (*LockOwner, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		// Probing the lock as the state of holders which died is never cleared.
+		f, err := b.tryLockOSFile()
+		if err == nil {
+			_ = unlockOSFile(f, b.mechanism)
+			_ = f.Close()
+			return nil, commonerrors.Newf(commonerrors.ErrNotFound, "lock [%v] is not held", b.path)
+		}
+		if !commonerrors.Any(err, commonerrors.ErrLocked) {
+			return nil, err
+		}
+	}
+	return b.state.owner()
+}
@@ -0,0 +1,8 @@
+//go:build darwin
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// fcntlSetLockCommand uses traditional POSIX record locks as open file description locks are not available.
+const fcntlSetLockCommand = unix.F_SETLK
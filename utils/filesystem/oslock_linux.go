@@ -0,0 +1,8 @@
+//go:build linux
+
+package filesystem
+
+import "golang.org/x/sys/unix"
+
+// fcntlSetLockCommand uses open file description locks so that locks are associated with open files rather than processes.
+const fcntlSetLockCommand = unix.F_OFD_SETLK
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+import (
+	"os"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+func lockOSFile(_ *os.File, mechanism FileLockMechanism) error {
+	return commonerrors.Newf(commonerrors.ErrUnsupported, "%v file locks are not supported on this platform", mechanism)
+}
+
+func unlockOSFile(_ *os.File, mechanism FileLockMechanism) error {
+	return commonerrors.Newf(commonerrors.ErrUnsupported, "%v file locks are not supported on this platform", mechanism)
+}
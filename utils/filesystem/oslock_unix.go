@@ -0,0 +1,34 @@
+//go:build linux || darwin
+
+package filesystem
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+func lockOSFile(f *os.File, mechanism FileLockMechanism) (err error) {
+	switch mechanism {
+	case FcntlFileLock:
+		err = unix.FcntlFlock(f.Fd(), fcntlSetLockCommand, &unix.Flock_t{Type: unix.F_WRLCK, Whence: int16(os.SEEK_SET)})
+	default:
+		err = unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	}
+	if commonerrors.Any(err, unix.EWOULDBLOCK, unix.EAGAIN, unix.EACCES) {
+		err = commonerrors.WrapErrorf(commonerrors.ErrLocked, err, "file [%v] is locked", f.Name())
+	}
+	return ConvertFileSystemError(err)
+}
+
+func unlockOSFile(f *os.File, mechanism FileLockMechanism) (err error) {
+	switch mechanism {
+	case FcntlFileLock:
+		err = unix.FcntlFlock(f.Fd(), fcntlSetLockCommand, &unix.Flock_t{Type: unix.F_UNLCK, Whence: int16(os.SEEK_SET)})
+	default:
+		err = unix.Flock(int(f.Fd()), unix.LOCK_UN)
+	}
+	return ConvertFileSystemError(err)
+}
@@ -0,0 +1,920 @@
+package filesystem
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// overlayFs is a copy-on-write afero.Fs: items are looked up in a writable layer first and then in a read-only base filesystem.
+// Any modification happens in the layer only: items of the base are copied up to the layer before being modified and removals of base items are recorded as whiteouts.
+// Whiteouts are kept in memory and so, they only last as long as the filesystem.
+type overlayFs struct {
+	base      FS
+	layer     afero.Fs
+	mu        sync.RWMutex
+	whiteouts map[string]struct{}
+	// opaques lists directories recreated in the layer after having been removed: the content of the base is hidden for them.
+	opaques map[string]struct{}
+}
+
+// newOverlayFs returns an overlay of `layer` over `base`, neither of which can be nil.
+func newOverlayFs(base FS, layer afero.Fs) afero.Fs {
+	return &overlayFs{
+		base:      base,
+		layer:     layer,
+		whiteouts: map[string]struct{}{},
+		opaques:   map[string]struct{}{},
+	}
+}
+
+func overlayKey(name string) string {
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+func overlayParent(name string) string {
+	return filepath.Dir(filepath.Clean(name))
+}
+
+func isOverlayRoot(name string) bool {
+	return overlayParent(name) == filepath.Clean(name)
+}
+
+func isOverlayNotExist(err error) bool {
+	return os.IsNotExist(err) || commonerrors.Any(err, commonerrors.ErrNotFound, afero.ErrFileNotFound)
+}
+
+func newOverlayNotExistError(op, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+func hasOverlayWriteFlags(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0
+}
+
+// isBaseHidden states whether an item of the base is hidden by a whiteout or an opaque directory. The caller must hold the lock.
+func (o *overlayFs) isBaseHidden(name string) bool {
+	key := overlayKey(name)
+	for {
+		if _, found := o.whiteouts[key]; found {
+			return true
+		}
+		if _, found := o.opaques[key]; found {
+			return true
+		}
+		parent := path.Dir(key)
+		if parent == key {
+			return false
+		}
+		key = parent
+	}
+}
+
+func (o *overlayFs) addWhiteout(name string) {
+	key := overlayKey(name)
+	prefix := strings.TrimSuffix(key, "/") + "/"
+	for k := range o.whiteouts {
+		if strings.HasPrefix(k, prefix) {
+			delete(o.whiteouts, k)
+		}
+	}
+	for k := range o.opaques {
+		if k == key || strings.HasPrefix(k, prefix) {
+			delete(o.opaques, k)
+		}
+	}
+	o.whiteouts[key] = struct{}{}
+}
+
+// clearWhiteout must be called whenever an item is created in the layer. If the item had been removed before, it becomes opaque.
+func (o *overlayFs) clearWhiteout(name string) {
+	key := overlayKey(name)
+	if _, found := o.whiteouts[key]; found {
+		delete(o.whiteouts, key)
+		o.opaques[key] = struct{}{}
+	}
+}
+
+// listHiddenBaseItems returns the names of the items of the base directly within a directory which are hidden by whiteouts or opaque items. The caller must hold the lock.
+func (o *overlayFs) listHiddenBaseItems(dir string) (names map[string]struct{}) {
+	names = map[string]struct{}{}
+	key := overlayKey(dir)
+	for _, hidden := range []map[string]struct{}{o.whiteouts, o.opaques} {
+		for k := range hidden {
+			if path.Dir(k) == key && k != key {
+				names[path.Base(k)] = struct{}{}
+			}
+		}
+	}
+	return
+}
+
+func (o *overlayFs) lstatLayer(name string) (os.FileInfo, error) {
+	if lstater, ok := o.layer.(afero.Lstater); ok {
+		fi, _, err := lstater.LstatIfPossible(name)
+		return fi, err
+	}
+	return o.layer.Stat(name)
+}
+
+func (o *overlayFs) lstatBase(name string) (fi os.FileInfo, err error) {
+	fi, err = o.base.Lstat(name)
+	if commonerrors.Any(err, commonerrors.ErrNotImplemented) {
+		fi, err = o.base.Stat(name)
+	}
+	return
+}
+
+// lookup finds an item without following symbolic links and states whether it is present in the layer. The caller must hold the lock.
+func (o *overlayFs) lookup(name string) (fi os.FileInfo, inLayer bool, err error) {
+	fi, err = o.lstatLayer(name)
+	if err == nil {
+		inLayer = true
+		return
+	}
+	if !isOverlayNotExist(err) {
+		return
+	}
+	if o.isBaseHidden(name) {
+		err = newOverlayNotExistError("lstat", name)
+		return
+	}
+	fi, err = o.lstatBase(name)
+	if isOverlayNotExist(err) {
+		err = newOverlayNotExistError("lstat", name)
+	}
+	return
+}
+
+func (o *overlayFs) existsInBase(name string) bool {
+	if o.isBaseHidden(name) {
+		return false
+	}
+	_, err := o.lstatBase(name)
+	return err == nil
+}
+
+// readlink reads the target of a symbolic link either from the layer or the base. The caller must hold the lock.
+func (o *overlayFs) readlink(name string, inLayer bool) (string, error) {
+	if !inLayer {
+		return o.base.Readlink(name)
+	}
+	if reader, ok := o.layer.(afero.LinkReader); ok {
+		return reader.ReadlinkIfPossible(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+}
+
+// resolve follows symbolic links through the overlay as their targets may be present in the layer or the base. The caller must hold the lock.
+func (o *overlayFs) resolve(name string) (resolved string, fi os.FileInfo, inLayer bool, err error) {
+	resolved = name
+	for i := 0; i < maxSymlinkResolution; i++ {
+		fi, inLayer, err = o.lookup(resolved)
+		if err != nil || !IsSymLink(fi) {
+			return
+		}
+		target, subErr := o.readlink(resolved, inLayer)
+		if subErr != nil {
+			err = subErr
+			return
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(resolved), target)
+		}
+		resolved = target
+	}
+	err = &os.PathError{Op: "stat", Path: name, Err: syscall.ELOOP}
+	return
+}
+
+// needsWhiteout states whether removing an item requires a whiteout so that any corresponding item of the base stays hidden. The caller must hold the lock.
+func (o *overlayFs) needsWhiteout(name string) bool {
+	if _, opaque := o.opaques[overlayKey(name)]; opaque {
+		return true
+	}
+	return o.existsInBase(name)
+}
+
+// copyUp copies an item of the base (and its parent directories) to the layer so that it can be modified. The caller must hold the lock.
+func (o *overlayFs) copyUp(name string) (err error) {
+	fi, inLayer, err := o.lookup(name)
+	if err != nil || inLayer {
+		return
+	}
+	err = o.copyUpParent(name)
+	if err != nil {
+		return
+	}
+	switch {
+	case fi.IsDir():
+		err = o.layer.Mkdir(name, fi.Mode().Perm())
+		if err != nil {
+			return
+		}
+		err = o.layer.Chmod(name, fi.Mode())
+	case IsSymLink(fi):
+		linker, ok := o.layer.(afero.Linker)
+		if !ok {
+			err = &os.LinkError{Op: "symlink", New: name, Err: afero.ErrNoSymlink}
+			return
+		}
+		target, subErr := o.base.Readlink(name)
+		if subErr != nil {
+			err = subErr
+			return
+		}
+		err = linker.SymlinkIfPossible(o.convertLinkTarget(target, name), name)
+		// Link timestamps cannot be changed without following the link.
+		return
+	default:
+		err = o.copyUpFile(name, fi)
+	}
+	if err != nil {
+		return
+	}
+	err = o.copyUpXattrs(name)
+	if err != nil {
+		return
+	}
+	times := newDefaultTimeInfo(fi)
+	err = o.layer.Chtimes(name, times.AccessTime(), times.ModTime())
+	return
+}
+
+// copyUpXattrs copies the extended attributes of a base item onto its copy in the layer, if the layer supports them.
+func (o *overlayFs) copyUpXattrs(name string) (err error) {
+	xattrer, ok := o.layer.(IXattrer)
+	if !ok {
+		return
+	}
+	attributes, err := GetXattrs(o.base, name)
+	if err != nil {
+		return
+	}
+	for attribute, value := range attributes {
+		err = xattrer.SetXattrIfPossible(name, attribute, value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// copyUpResolved copies up the item a path resolves to (following links) so that it can be modified. The caller must hold the lock.
+func (o *overlayFs) copyUpResolved(name string) (resolved string, err error) {
+	resolved, _, _, err = o.resolve(name)
+	if err != nil {
+		return
+	}
+	err = o.copyUp(resolved)
+	return
+}
+
+func (o *overlayFs) copyUpFile(name string, fi os.FileInfo) (err error) {
+	src, err := o.base.GenericOpen(name)
+	if err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := o.layer.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		_ = dst.Close()
+		return
+	}
+	err = dst.Close()
+	if err != nil {
+		return
+	}
+	err = o.layer.Chmod(name, fi.Mode())
+	return
+}
+
+func (o *overlayFs) copyUpParent(name string) error {
+	if isOverlayRoot(name) {
+		return nil
+	}
+	parent := overlayParent(name)
+	if isOverlayRoot(parent) {
+		// The root is expected to always be present in the layer.
+		_, err := o.lstatLayer(parent)
+		if err == nil {
+			return nil
+		}
+		return o.layer.MkdirAll(parent, 0o755)
+	}
+	fi, _, err := o.lookup(parent)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return &os.PathError{Op: "open", Path: parent, Err: syscall.ENOTDIR}
+	}
+	return o.copyUp(parent)
+}
+
+// copyUpTree copies an item of the base and, if a directory, all its content to the layer. The caller must hold the lock.
+func (o *overlayFs) copyUpTree(name string) (err error) {
+	err = o.copyUp(name)
+	if err != nil {
+		return
+	}
+	fi, _, err := o.lookup(name)
+	if err != nil || !fi.IsDir() {
+		return
+	}
+	entries, err := o.readMergedDir(name)
+	if err != nil {
+		return
+	}
+	for i := range entries {
+		err = o.copyUpTree(filepath.Join(name, entries[i].Name()))
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// readMergedDir lists the content of a directory as seen through the overlay. The caller must hold the lock.
+func (o *overlayFs) readMergedDir(name string) (entries []os.FileInfo, err error) {
+	f, err := o.openWithoutLock(name)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	entries, err = f.Readdir(-1)
+	return
+}
+
+func (o *overlayFs) Name() string {
+	return "OverlayFs"
+}
+
+func (o *overlayFs) Create(name string) (afero.File, error) {
+	return o.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func (o *overlayFs) Mkdir(name string, perm os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.mkdir(name, perm)
+}
+
+func (o *overlayFs) mkdir(name string, perm os.FileMode) (err error) {
+	_, _, err = o.lookup(name)
+	if err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if !isOverlayNotExist(err) {
+		return
+	}
+	err = o.copyUpParent(name)
+	if err != nil {
+		return
+	}
+	err = o.layer.Mkdir(name, perm)
+	if err != nil {
+		return
+	}
+	o.clearWhiteout(name)
+	return
+}
+
+func (o *overlayFs) MkdirAll(name string, perm os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.mkdirAll(name, perm)
+}
+
+func (o *overlayFs) mkdirAll(name string, perm os.FileMode) (err error) {
+	fi, err := o.stat(name)
+	if err == nil {
+		if fi.IsDir() {
+			return
+		}
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	if !isOverlayNotExist(err) {
+		return
+	}
+	if !isOverlayRoot(name) {
+		err = o.mkdirAll(overlayParent(name), perm)
+		if err != nil {
+			return
+		}
+	}
+	err = o.mkdir(name, perm)
+	if os.IsExist(err) {
+		err = nil
+	}
+	return
+}
+
+func (o *overlayFs) Open(name string) (afero.File, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.openWithoutLock(name)
+}
+
+func (o *overlayFs) openWithoutLock(name string) (f afero.File, err error) {
+	resolved, fi, inLayer, err := o.resolve(name)
+	if err != nil {
+		if isOverlayNotExist(err) {
+			err = newOverlayNotExistError("open", name)
+		}
+		return
+	}
+	if inLayer {
+		layerFile, subErr := o.layer.Open(resolved)
+		if subErr != nil || !fi.IsDir() {
+			return layerFile, subErr
+		}
+		var baseDir afero.File
+		if !o.isBaseHidden(resolved) {
+			if baseFi, subErr := o.base.Stat(resolved); subErr == nil && baseFi.IsDir() {
+				baseDir, _ = o.base.GenericOpen(resolved)
+			}
+		}
+		f = &overlayDirectory{File: layerFile, layer: layerFile, base: baseDir, hidden: o.listHiddenBaseItems(resolved)}
+		return
+	}
+	baseFile, err := o.base.GenericOpen(resolved)
+	if err != nil {
+		return
+	}
+	if fi.IsDir() {
+		f = &overlayDirectory{File: baseFile, base: baseFile, hidden: o.listHiddenBaseItems(resolved)}
+		return
+	}
+	f = baseFile
+	return
+}
+
+func (o *overlayFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if !hasOverlayWriteFlags(flag) {
+		return o.Open(name)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.openFileForWriting(name, flag, perm, 0)
+}
+
+func (o *overlayFs) openFileForWriting(name string, flag int, perm os.FileMode, resolutions int) (f afero.File, err error) {
+	fi, inLayer, err := o.lookup(name)
+	exists := err == nil
+	if err != nil && !isOverlayNotExist(err) {
+		return
+	}
+	switch {
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		err = &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		return
+	case !exists && flag&os.O_CREATE == 0:
+		err = newOverlayNotExistError("open", name)
+		return
+	case exists && IsSymLink(fi):
+		// Writing through a link modifies its target, which may only be present in the base.
+		if resolutions >= maxSymlinkResolution {
+			err = &os.PathError{Op: "open", Path: name, Err: syscall.ELOOP}
+			return
+		}
+		target, subErr := o.readlink(name, inLayer)
+		if subErr != nil {
+			err = subErr
+			return
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(name), target)
+		}
+		return o.openFileForWriting(target, flag, perm, resolutions+1)
+	case exists && !inLayer && !fi.IsDir() && flag&os.O_TRUNC != 0:
+		// No need to copy the content of the file as it is truncated.
+		err = o.copyUpParent(name)
+		flag |= os.O_CREATE
+		perm = fi.Mode().Perm()
+	case exists && !inLayer:
+		err = o.copyUp(name)
+	case !exists:
+		err = o.copyUpParent(name)
+	}
+	if err != nil {
+		return
+	}
+	f, err = o.layer.OpenFile(name, flag, perm)
+	if err == nil && !exists {
+		o.clearWhiteout(name)
+	}
+	return
+}
+
+func (o *overlayFs) Remove(name string) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fi, inLayer, err := o.lookup(name)
+	if err != nil {
+		return
+	}
+	if fi.IsDir() {
+		entries, subErr := o.readMergedDir(name)
+		if subErr != nil {
+			return subErr
+		}
+		if len(entries) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	whiteout := o.needsWhiteout(name)
+	if inLayer {
+		// The directory may still contain whiteouts in the layer and so, removing it regardless.
+		err = o.layer.RemoveAll(name)
+		if err != nil {
+			return
+		}
+	}
+	if whiteout {
+		o.addWhiteout(name)
+	}
+	return
+}
+
+func (o *overlayFs) RemoveAll(name string) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	whiteout := o.needsWhiteout(name)
+	err = o.layer.RemoveAll(name)
+	if err != nil {
+		return
+	}
+	if whiteout {
+		o.addWhiteout(name)
+	}
+	return
+}
+
+func (o *overlayFs) Rename(oldname, newname string) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, _, err = o.lookup(oldname)
+	if err != nil {
+		return
+	}
+	if overlayKey(oldname) == overlayKey(newname) {
+		return
+	}
+	// Directories may only be partly copied up (e.g. if one of their files was modified) and so, the whole tree is copied up regardless.
+	err = o.copyUpTree(oldname)
+	if err != nil {
+		return
+	}
+	err = o.copyUpParent(newname)
+	if err != nil {
+		return
+	}
+	whiteout := o.needsWhiteout(oldname)
+	err = o.layer.Rename(oldname, newname)
+	if err != nil {
+		return
+	}
+	if whiteout {
+		o.addWhiteout(oldname)
+	}
+	// The renamed item replaces anything present in the base.
+	delete(o.whiteouts, overlayKey(newname))
+	o.opaques[overlayKey(newname)] = struct{}{}
+	return
+}
+
+func (o *overlayFs) Stat(name string) (os.FileInfo, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.stat(name)
+}
+
+func (o *overlayFs) stat(name string) (fi os.FileInfo, err error) {
+	_, fi, _, err = o.resolve(name)
+	return
+}
+
+func (o *overlayFs) LstatIfPossible(name string) (fi os.FileInfo, lstatCalled bool, err error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	fi, _, err = o.lookup(name)
+	lstatCalled = true
+	return
+}
+
+func (o *overlayFs) ReadlinkIfPossible(name string) (string, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	_, inLayer, err := o.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return o.readlink(name, inLayer)
+}
+
+func (o *overlayFs) SymlinkIfPossible(oldname, newname string) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	linker, ok := o.layer.(afero.Linker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+	}
+	_, _, err = o.lookup(newname)
+	if err == nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	err = o.copyUpParent(newname)
+	if err != nil {
+		return
+	}
+	err = linker.SymlinkIfPossible(o.convertLinkTarget(oldname, newname), newname)
+	if err == nil {
+		o.clearWhiteout(newname)
+	}
+	return
+}
+
+// convertLinkTarget converts relative link targets into absolute paths as layers such as afero.BasePathFs cannot handle relative targets.
+func (o *overlayFs) convertLinkTarget(target, link string) string {
+	switch o.layer.(type) {
+	case *afero.BasePathFs, *scratchLayerFs:
+	default:
+		return target
+	}
+	if filepath.IsAbs(target) {
+		return target
+	}
+	return filepath.Join(filepath.Dir(link), target)
+}
+
+func (o *overlayFs) LinkIfPossible(oldname, newname string) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	linker, ok := o.layer.(ILinker)
+	if !ok {
+		return commonerrors.Newf(commonerrors.ErrNotImplemented, "cannot link `%v` to `%v`", oldname, newname)
+	}
+	_, _, err = o.lookup(newname)
+	if err == nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	err = o.copyUp(oldname)
+	if err != nil {
+		return
+	}
+	err = o.copyUpParent(newname)
+	if err != nil {
+		return
+	}
+	err = linker.LinkIfPossible(oldname, newname)
+	if err == nil {
+		o.clearWhiteout(newname)
+	}
+	return
+}
+
+func (o *overlayFs) Chmod(name string, mode os.FileMode) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	name, err = o.copyUpResolved(name)
+	if err != nil {
+		return
+	}
+	return o.layer.Chmod(name, mode)
+}
+
+func (o *overlayFs) Chown(name string, uid, gid int) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	name, err = o.copyUpResolved(name)
+	if err != nil {
+		return
+	}
+	return o.layer.Chown(name, uid, gid)
+}
+
+func (o *overlayFs) ChownIfPossible(name string, uid int, gid int) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	name, err = o.copyUpResolved(name)
+	if err != nil {
+		return
+	}
+	if chowner, ok := o.layer.(IChowner); ok {
+		return chowner.ChownIfPossible(name, uid, gid)
+	}
+	return o.layer.Chown(name, uid, gid)
+}
+
+func (o *overlayFs) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	resolved, _, inLayer, err := o.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if !inLayer {
+		return o.base.GetXattr(resolved, attribute)
+	}
+	if xattrer, ok := o.layer.(IXattrer); ok {
+		return xattrer.GetXattrIfPossible(resolved, attribute)
+	}
+	return nil, ErrXattrNotImplemented
+}
+
+func (o *overlayFs) ListXattrIfPossible(name string) ([]string, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	resolved, _, inLayer, err := o.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if !inLayer {
+		return o.base.ListXattr(resolved)
+	}
+	if xattrer, ok := o.layer.(IXattrer); ok {
+		return xattrer.ListXattrIfPossible(resolved)
+	}
+	return nil, ErrXattrNotImplemented
+}
+
+func (o *overlayFs) SetXattrIfPossible(name, attribute string, value []byte) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	xattrer, ok := o.layer.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	name, err = o.copyUpResolved(name)
+	if err != nil {
+		return
+	}
+	return xattrer.SetXattrIfPossible(name, attribute, value)
+}
+
+func (o *overlayFs) RemoveXattrIfPossible(name, attribute string) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	xattrer, ok := o.layer.(IXattrer)
+	if !ok {
+		return ErrXattrNotImplemented
+	}
+	name, err = o.copyUpResolved(name)
+	if err != nil {
+		return
+	}
+	return xattrer.RemoveXattrIfPossible(name, attribute)
+}
+
+func (o *overlayFs) Chtimes(name string, atime time.Time, mtime time.Time) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	name, err = o.copyUpResolved(name)
+	if err != nil {
+		return
+	}
+	return o.layer.Chtimes(name, atime, mtime)
+}
+
+// overlayDirectory is a directory whose content is the merge of the layer and base directories minus any hidden item of the base.
+type overlayDirectory struct {
+	afero.File
+	layer   afero.File
+	base    afero.File
+	hidden  map[string]struct{}
+	entries []os.FileInfo
+	listed  bool
+	offset  int
+}
+
+func (d *overlayDirectory) list() (err error) {
+	if d.listed {
+		return
+	}
+	merged := map[string]os.FileInfo{}
+	if d.layer != nil {
+		entries, subErr := d.layer.Readdir(-1)
+		if subErr != nil && !commonerrors.Any(subErr, io.EOF) {
+			return subErr
+		}
+		for i := range entries {
+			merged[entries[i].Name()] = entries[i]
+		}
+	}
+	if d.base != nil {
+		entries, subErr := d.base.Readdir(-1)
+		if subErr != nil && !commonerrors.Any(subErr, io.EOF) {
+			return subErr
+		}
+		for i := range entries {
+			name := entries[i].Name()
+			if _, hidden := d.hidden[name]; hidden {
+				continue
+			}
+			if _, found := merged[name]; !found {
+				merged[name] = entries[i]
+			}
+		}
+	}
+	for _, entry := range merged {
+		d.entries = append(d.entries, entry)
+	}
+	sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].Name() < d.entries[j].Name() })
+	d.listed = true
+	return
+}
+
+func (d *overlayDirectory) Readdir(count int) (entries []os.FileInfo, err error) {
+	err = d.list()
+	if err != nil {
+		return
+	}
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		entries = remaining
+		return
+	}
+	if len(remaining) == 0 {
+		err = io.EOF
+		return
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	entries = remaining[:count]
+	d.offset += count
+	return
+}
+
+func (d *overlayDirectory) Readdirnames(count int) (names []string, err error) {
+	entries, err := d.Readdir(count)
+	for i := range entries {
+		names = append(names, entries[i].Name())
+	}
+	return
+}
+
+func (d *overlayDirectory) Close() error {
+	var baseErr error
+	if d.base != nil && d.base != d.File {
+		baseErr = d.base.Close()
+	}
+	return commonerrors.Join(d.File.Close(), baseErr)
+}
+
+// scratchLayerFs is an overlay layer stored in a scratch directory of the standard filesystem.
+type scratchLayerFs struct {
+	*afero.BasePathFs
+}
+
+func newScratchLayerFs(scratchDir string) *scratchLayerFs {
+	return &scratchLayerFs{BasePathFs: afero.NewBasePathFs(NewExtendedOsFs(), scratchDir).(*afero.BasePathFs)}
+}
+
+func (l *scratchLayerFs) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	realPath, err := l.RealPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return getXattr(realPath, attribute)
+}
+
+func (l *scratchLayerFs) SetXattrIfPossible(name, attribute string, value []byte) error {
+	realPath, err := l.RealPath(name)
+	if err != nil {
+		return err
+	}
+	return setXattr(realPath, attribute, value)
+}
+
+func (l *scratchLayerFs) ListXattrIfPossible(name string) ([]string, error) {
+	realPath, err := l.RealPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return listXattr(realPath)
+}
+
+func (l *scratchLayerFs) RemoveXattrIfPossible(name, attribute string) error {
+	realPath, err := l.RealPath(name)
+	if err != nil {
+		return err
+	}
+	return removeXattr(realPath, attribute)
+}
@@ -0,0 +1,298 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	"github.com/ARM-software/golang-utils/utils/platform"
+)
+
+func newTestOverlayFileSystems(t *testing.T, base FS) map[string]FS {
+	t.Helper()
+	inMemory, err := NewInMemoryOverlayFileSystem(base)
+	require.NoError(t, err)
+	scratchDir := t.TempDir()
+	onDisk, err := NewOverlayFileSystemWithScratchDirectory(base, scratchDir)
+	require.NoError(t, err)
+	return map[string]FS{"in-memory layer": inMemory, "scratch directory layer": onDisk}
+}
+
+func TestNewOverlayFileSystem(t *testing.T) {
+	_, err := NewOverlayFileSystem(nil, nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewOverlayFileSystem(NewInMemoryFileSystem(), nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewOverlayFileSystemWithScratchDirectory(NewInMemoryFileSystem(), "")
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	fs, err := NewInMemoryOverlayFileSystem(NewInMemoryFileSystem())
+	require.NoError(t, err)
+	assert.Equal(t, OverlayFS, fs.GetType())
+	assert.Equal(t, OverlayFS, NewFs(OverlayFS).GetType())
+}
+
+func TestOverlayFS_CopyOnWrite(t *testing.T) {
+	base := NewInMemoryFileSystem()
+	baseDir := FilePathJoin(base, "/", "base")
+	originalContent := faker.Paragraph()
+	require.NoError(t, base.MkDir(FilePathJoin(base, baseDir, "dir", "subdir")))
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "dir", "file.txt"), []byte(originalContent), 0o644))
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "dir", "subdir", "nested.txt"), []byte(originalContent), 0o644))
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "readonly.txt"), []byte(originalContent), 0o444))
+	baseHash, err := base.FileHash(hashing.HashMd5, FilePathJoin(base, baseDir, "dir", "file.txt"))
+	require.NoError(t, err)
+
+	for name, fs := range newTestOverlayFileSystems(t, base) {
+		t.Run(name, func(t *testing.T) {
+			file := FilePathJoin(fs, baseDir, "dir", "file.txt")
+			content, err := fs.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, originalContent, string(content))
+
+			// Modifications are only made to the layer.
+			newContent := faker.Sentence()
+			require.NoError(t, fs.WriteFile(file, []byte(newContent), 0o644))
+			content, err = fs.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, newContent, string(content))
+			hash, err := base.FileHash(hashing.HashMd5, FilePathJoin(base, baseDir, "dir", "file.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, baseHash, hash)
+
+			appended := FilePathJoin(fs, baseDir, "dir", "subdir", "nested.txt")
+			f, err := fs.OpenFile(appended, os.O_WRONLY|os.O_APPEND, 0)
+			require.NoError(t, err)
+			_, err = f.Write([]byte(newContent))
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			content, err = fs.ReadFile(appended)
+			require.NoError(t, err)
+			assert.Equal(t, originalContent+newContent, string(content))
+
+			// Metadata changes are also made to the layer only.
+			readOnlyFile := FilePathJoin(fs, baseDir, "readonly.txt")
+			require.NoError(t, fs.Chmod(readOnlyFile, 0o600))
+			info, err := fs.Stat(readOnlyFile)
+			require.NoError(t, err)
+			assert.Equal(t, 0o600, int(info.Mode().Perm()))
+			info, err = base.Stat(FilePathJoin(base, baseDir, "readonly.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, 0o444, int(info.Mode().Perm()))
+			modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+			require.NoError(t, fs.Chtimes(readOnlyFile, modTime, modTime))
+			info, err = fs.Stat(readOnlyFile)
+			require.NoError(t, err)
+			assert.True(t, modTime.Equal(info.ModTime()))
+
+			// New items are merged with the base content.
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, baseDir, "dir", "new.txt"), []byte(newContent), 0o644))
+			files, err := fs.Ls(FilePathJoin(fs, baseDir, "dir"))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"file.txt", "new.txt", "subdir"}, files)
+			assert.False(t, base.Exists(FilePathJoin(base, baseDir, "dir", "new.txt")))
+			_, err = fs.OpenFile(FilePathJoin(fs, baseDir, "dir", "new.txt"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrExists)
+			_, err = fs.OpenFile(FilePathJoin(fs, baseDir, "missing", "new.txt"), os.O_WRONLY|os.O_CREATE, 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		})
+	}
+}
+
+func TestOverlayFS_Whiteouts(t *testing.T) {
+	base := NewInMemoryFileSystem()
+	baseDir := FilePathJoin(base, "/", "base")
+	require.NoError(t, base.MkDir(FilePathJoin(base, baseDir, "dir", "subdir")))
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "dir", "file1.txt"), []byte(faker.Sentence()), 0o644))
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "dir", "file2.txt"), []byte(faker.Sentence()), 0o644))
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "dir", "subdir", "nested.txt"), []byte(faker.Sentence()), 0o644))
+
+	for name, fs := range newTestOverlayFileSystems(t, base) {
+		t.Run(name, func(t *testing.T) {
+			dir := FilePathJoin(fs, baseDir, "dir")
+			require.NoError(t, fs.Rm(FilePathJoin(fs, dir, "file1.txt")))
+			assert.False(t, fs.Exists(FilePathJoin(fs, dir, "file1.txt")))
+			assert.True(t, base.Exists(FilePathJoin(base, dir, "file1.txt")))
+			files, err := fs.Ls(dir)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"file2.txt", "subdir"}, files)
+			_, err = fs.ReadFile(FilePathJoin(fs, dir, "file1.txt"))
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+
+			// An item can be recreated after being removed.
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, dir, "file1.txt"), []byte("new"), 0o644))
+			content, err := fs.ReadFile(FilePathJoin(fs, dir, "file1.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "new", string(content))
+
+			// A directory recreated after removal does not show the content of the base anymore.
+			require.NoError(t, fs.Rm(FilePathJoin(fs, dir, "subdir")))
+			assert.False(t, fs.Exists(FilePathJoin(fs, dir, "subdir", "nested.txt")))
+			require.NoError(t, fs.MkDir(FilePathJoin(fs, dir, "subdir")))
+			empty, err := fs.IsEmpty(FilePathJoin(fs, dir, "subdir"))
+			require.NoError(t, err)
+			assert.True(t, empty)
+			assert.False(t, fs.Exists(FilePathJoin(fs, dir, "subdir", "nested.txt")))
+
+			require.NoError(t, fs.CleanDir(dir))
+			empty, err = fs.IsEmpty(dir)
+			require.NoError(t, err)
+			assert.True(t, empty)
+			require.NoError(t, fs.Rm(baseDir))
+			assert.False(t, fs.Exists(baseDir))
+			files, err = base.Ls(dir)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"file1.txt", "file2.txt", "subdir"}, files)
+		})
+	}
+}
+
+func TestOverlayFS_Move(t *testing.T) {
+	base := NewInMemoryFileSystem()
+	baseDir := FilePathJoin(base, "/", "base")
+	tree := GenerateTestFileTree(t, base, FilePathJoin(base, baseDir, "src"), "", false, time.Now(), time.Now())
+	relativeTree, err := base.ConvertToRelativePath(FilePathJoin(base, baseDir, "src"), tree...)
+	require.NoError(t, err)
+	sort.Strings(relativeTree)
+
+	for name, fs := range newTestOverlayFileSystems(t, base) {
+		t.Run(name, func(t *testing.T) {
+			src := FilePathJoin(fs, baseDir, "src")
+			dest := FilePathJoin(fs, baseDir, "dest")
+			require.NoError(t, fs.Move(src, dest))
+			assert.False(t, fs.Exists(src))
+			assert.True(t, base.Exists(src))
+			var movedTree []string
+			require.NoError(t, fs.ListDirTree(dest, &movedTree))
+			movedTree, err := fs.ConvertToRelativePath(dest, movedTree...)
+			require.NoError(t, err)
+			sort.Strings(movedTree)
+			assert.Equal(t, relativeTree, movedTree)
+		})
+	}
+}
+
+func TestOverlayFS_MovePartiallyModifiedDirectory(t *testing.T) {
+	base := NewInMemoryFileSystem()
+	baseDir := FilePathJoin(base, "/", "base")
+	tree := GenerateTestFileTree(t, base, FilePathJoin(base, baseDir, "src"), "", false, time.Now(), time.Now())
+	relativeTree, err := base.ConvertToRelativePath(FilePathJoin(base, baseDir, "src"), tree...)
+	require.NoError(t, err)
+	sort.Strings(relativeTree)
+	var modified string
+	for i := range tree {
+		if isFile, _ := base.IsFile(tree[i]); isFile {
+			modified = tree[i]
+			break
+		}
+	}
+	require.NotEmpty(t, modified)
+	relativeModified, err := base.ConvertToRelativePath(FilePathJoin(base, baseDir, "src"), modified)
+	require.NoError(t, err)
+
+	for name, fs := range newTestOverlayFileSystems(t, base) {
+		t.Run(name, func(t *testing.T) {
+			newContent := faker.Sentence()
+			require.NoError(t, fs.WriteFile(modified, []byte(newContent), 0o644))
+			src := FilePathJoin(fs, baseDir, "src")
+			dest := FilePathJoin(fs, baseDir, "dest")
+			require.NoError(t, fs.Move(src, dest))
+			assert.False(t, fs.Exists(src))
+			var movedTree []string
+			require.NoError(t, fs.ListDirTree(dest, &movedTree))
+			movedTree, err := fs.ConvertToRelativePath(dest, movedTree...)
+			require.NoError(t, err)
+			sort.Strings(movedTree)
+			assert.Equal(t, relativeTree, movedTree)
+			content, err := fs.ReadFile(FilePathJoin(fs, dest, relativeModified[0]))
+			require.NoError(t, err)
+			assert.Equal(t, newContent, string(content))
+		})
+	}
+}
+
+func TestOverlayFS_Archives(t *testing.T) {
+	base, err := NewEmbedFileSystem(&testContent)
+	require.NoError(t, err)
+	fs, err := NewInMemoryOverlayFileSystem(base)
+	require.NoError(t, err)
+
+	for _, ext := range []string{zipExt, tarExt} {
+		t.Run(ext, func(t *testing.T) {
+			archive := fmt.Sprintf("testdata/embed%v", ext)
+			require.False(t, fs.Exists(archive))
+			if ext == zipExt {
+				require.NoError(t, fs.Zip("testdata/embed", archive))
+			} else {
+				require.NoError(t, fs.Tar("testdata/embed", archive))
+			}
+			assert.True(t, fs.Exists(archive))
+			assert.False(t, base.Exists(archive))
+
+			var files []string
+			if ext == zipExt {
+				files, err = fs.UnzipWithContextAndLimits(context.Background(), archive, "testdata/extracted-"+ext[1:], DefaultLimits())
+			} else {
+				files, err = fs.UntarWithContextAndLimits(context.Background(), archive, "testdata/extracted-"+ext[1:], DefaultLimits())
+			}
+			require.NoError(t, err)
+			assert.Contains(t, files, "testdata/extracted-"+ext[1:]+"/level1/test.txt")
+			content, err := fs.ReadFile("testdata/extracted-" + ext[1:] + "/test.txt")
+			require.NoError(t, err)
+			assert.Contains(t, string(content), testFile1Content)
+		})
+	}
+	var walked []string
+	require.NoError(t, fs.Walk("testdata", func(path string, _ os.FileInfo, err error) error {
+		walked = append(walked, path)
+		return err
+	}))
+	assert.Contains(t, walked, "testdata/embed.zip")
+	assert.Contains(t, walked, "testdata/extracted-tar/level1/test.txt")
+}
+
+func TestOverlayFS_Symlinks(t *testing.T) {
+	if platform.IsWindows() {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	base := NewStandardFileSystem()
+	baseDir := t.TempDir()
+	content := faker.Sentence()
+	require.NoError(t, base.WriteFile(FilePathJoin(base, baseDir, "target.txt"), []byte(content), 0o644))
+	require.NoError(t, base.Symlink("target.txt", FilePathJoin(base, baseDir, "link")))
+
+	fs, err := NewOverlayFileSystemWithScratchDirectory(base, t.TempDir())
+	require.NoError(t, err)
+	link := FilePathJoin(fs, baseDir, "link")
+	isLink, err := fs.IsLink(link)
+	require.NoError(t, err)
+	assert.True(t, isLink)
+	target, err := fs.Readlink(link)
+	require.NoError(t, err)
+	assert.Equal(t, "target.txt", target)
+
+	// Writing through a link of the base modifies its target in the layer only.
+	require.NoError(t, fs.WriteFile(link, []byte("new"), 0o644))
+	result, err := fs.ReadFile(FilePathJoin(fs, baseDir, "target.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(result))
+	result, err = base.ReadFile(FilePathJoin(base, baseDir, "target.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(result))
+
+	newLink := FilePathJoin(fs, baseDir, "new-link")
+	require.NoError(t, fs.Symlink("target.txt", newLink))
+	isLink, err = fs.IsLink(newLink)
+	require.NoError(t, err)
+	assert.True(t, isLink)
+	assert.False(t, base.Exists(newLink))
+}
@@ -0,0 +1,490 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/spf13/afero"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/config"
+)
+
+// Quota defines how much storage can be used on a filesystem. A zero value means no limit.
+type Quota struct {
+	// MaxTotalSize is the maximum number of bytes which can be stored.
+	MaxTotalSize uint64 `mapstructure:"max_total_size"`
+	// MaxFileCount is the maximum number of files (i.e. any item which is not a directory) which can be stored.
+	MaxFileCount int64 `mapstructure:"max_file_count"`
+}
+
+func (q *Quota) Validate() error {
+	validation.ErrorTag = "mapstructure"
+
+	// Validate Embedded Structs
+	err := config.ValidateEmbedded(q)
+	if err != nil {
+		return err
+	}
+
+	return validation.ValidateStruct(q,
+		validation.Field(&q.MaxFileCount, validation.Min(0)),
+	)
+}
+
+// QuotaUsage describes the storage used on a filesystem with a quota.
+type QuotaUsage struct {
+	TotalSize uint64
+	FileCount int64
+}
+
+// quotaFs is an afero.Fs enforcing a quota on all the writes performed on a source filesystem.
+// Usage is tracked for all items created, grown, truncated or removed via the filesystem; changes made directly to the source filesystem are not accounted for.
+type quotaFs struct {
+	source afero.Fs
+	quota  Quota
+	mu     sync.Mutex
+	usage  QuotaUsage
+}
+
+func newQuotaFs(source afero.Fs, quota *Quota, usage QuotaUsage) (fs *quotaFs, err error) {
+	if source == nil {
+		err = commonerrors.UndefinedVariable("source file system")
+		return
+	}
+	if quota == nil {
+		err = commonerrors.UndefinedVariable("quota")
+		return
+	}
+	err = quota.Validate()
+	if err != nil {
+		err = commonerrors.WrapError(commonerrors.ErrInvalid, err, "invalid quota")
+		return
+	}
+	fs = &quotaFs{
+		source: source,
+		quota:  *quota,
+		usage:  usage,
+	}
+	return
+}
+
+func newQuotaExceededError(name string, quota string) error {
+	return commonerrors.Newf(commonerrors.ErrTooLarge, "writing [%v] would exceed the %v quota", name, quota)
+}
+
+// reserve accounts for the creation of files and for growth in size. It fails without changing the usage if this would exceed the quota.
+func (q *quotaFs) reserve(name string, files int64, bytes int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if files > 0 && q.quota.MaxFileCount > 0 && q.usage.FileCount+files > q.quota.MaxFileCount {
+		return newQuotaExceededError(name, "file count")
+	}
+	if bytes > 0 && q.quota.MaxTotalSize > 0 && q.usage.TotalSize+uint64(bytes) > q.quota.MaxTotalSize {
+		return newQuotaExceededError(name, "size")
+	}
+	q.usage.FileCount += files
+	q.usage.TotalSize = addToUsage(q.usage.TotalSize, bytes)
+	return nil
+}
+
+// release accounts for the removal of files and for reduction in size.
+func (q *quotaFs) release(files int64, bytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.usage.FileCount -= files
+	if q.usage.FileCount < 0 {
+		q.usage.FileCount = 0
+	}
+	q.usage.TotalSize = addToUsage(q.usage.TotalSize, -bytes)
+}
+
+func addToUsage(usage uint64, delta int64) uint64 {
+	if delta >= 0 {
+		return usage + uint64(delta)
+	}
+	if uint64(-delta) > usage {
+		return 0
+	}
+	return usage - uint64(-delta)
+}
+
+func (q *quotaFs) getUsage() QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.usage
+}
+
+func (q *quotaFs) lstat(name string) (os.FileInfo, error) {
+	if lstater, ok := q.source.(afero.Lstater); ok {
+		fi, _, err := lstater.LstatIfPossible(name)
+		return fi, err
+	}
+	return q.source.Stat(name)
+}
+
+// determineItemUsage returns the number of files and bytes an existing item accounts for.
+func determineItemUsage(fi os.FileInfo) (files int64, bytes int64) {
+	if fi == nil || fi.IsDir() {
+		return
+	}
+	files = 1
+	if fi.Mode().IsRegular() {
+		bytes = fi.Size()
+	}
+	return
+}
+
+// determineTreeUsage returns the number of files and bytes the items of a file tree account for.
+func determineTreeUsage(fs afero.Fs, root string) (files int64, bytes int64) {
+	_ = afero.Walk(fs, root, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		f, b := determineItemUsage(info)
+		files += f
+		bytes += b
+		return nil
+	})
+	return
+}
+
+func (q *quotaFs) Name() string {
+	return "QuotaFs"
+}
+
+func (q *quotaFs) Create(name string) (afero.File, error) {
+	return q.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func (q *quotaFs) Mkdir(name string, perm os.FileMode) error {
+	return q.source.Mkdir(name, perm)
+}
+
+func (q *quotaFs) MkdirAll(path string, perm os.FileMode) error {
+	return q.source.MkdirAll(path, perm)
+}
+
+func (q *quotaFs) Open(name string) (afero.File, error) {
+	return q.source.Open(name)
+}
+
+func (q *quotaFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) == 0 {
+		return q.source.OpenFile(name, flag, perm)
+	}
+	existing, statErr := q.source.Stat(name)
+	// The size is determined before opening the file as some file information (e.g. in-memory filesystem) reflects the current state of the file.
+	_, existingSize := determineItemUsage(existing)
+	created := statErr != nil && flag&os.O_CREATE != 0
+	if created {
+		err := q.reserve(name, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f, err := q.source.OpenFile(name, flag, perm)
+	if err != nil {
+		if created {
+			q.release(1, 0)
+		}
+		return nil, err
+	}
+	if statErr == nil && flag&os.O_TRUNC != 0 {
+		q.release(0, existingSize)
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return f, nil
+	}
+	return &quotaFile{File: f, fs: q, appendOnly: flag&os.O_APPEND != 0}, nil
+}
+
+func (q *quotaFs) Remove(name string) error {
+	fi, statErr := q.lstat(name)
+	files, bytes := determineItemUsage(fi)
+	err := q.source.Remove(name)
+	if err == nil && statErr == nil {
+		q.release(files, bytes)
+	}
+	return err
+}
+
+func (q *quotaFs) RemoveAll(path string) error {
+	files, bytes := determineTreeUsage(q.source, path)
+	err := q.source.RemoveAll(path)
+	if err == nil {
+		q.release(files, bytes)
+	}
+	return err
+}
+
+func (q *quotaFs) Rename(oldname, newname string) error {
+	// Any file replaced by the rename no longer uses storage.
+	fi, statErr := q.lstat(newname)
+	files, bytes := determineItemUsage(fi)
+	err := q.source.Rename(oldname, newname)
+	if err == nil && statErr == nil {
+		q.release(files, bytes)
+	}
+	return err
+}
+
+func (q *quotaFs) Stat(name string) (os.FileInfo, error) {
+	return q.source.Stat(name)
+}
+
+func (q *quotaFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if lstater, ok := q.source.(afero.Lstater); ok {
+		return lstater.LstatIfPossible(name)
+	}
+	fi, err := q.source.Stat(name)
+	return fi, false, err
+}
+
+func (q *quotaFs) SymlinkIfPossible(oldname, newname string) error {
+	linker, ok := q.source.(afero.Linker)
+	if !ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: afero.ErrNoSymlink}
+	}
+	err := q.reserve(newname, 1, 0)
+	if err != nil {
+		return err
+	}
+	err = linker.SymlinkIfPossible(oldname, newname)
+	if err != nil {
+		q.release(1, 0)
+	}
+	return err
+}
+
+func (q *quotaFs) ReadlinkIfPossible(name string) (string, error) {
+	if reader, ok := q.source.(afero.LinkReader); ok {
+		return reader.ReadlinkIfPossible(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: afero.ErrNoReadlink}
+}
+
+func (q *quotaFs) LinkIfPossible(oldname, newname string) error {
+	linker, ok := q.source.(ILinker)
+	if !ok {
+		return commonerrors.Newf(commonerrors.ErrNotImplemented, "cannot link `%v` to `%v`", oldname, newname)
+	}
+	fi, err := q.lstat(oldname)
+	if err != nil {
+		return err
+	}
+	// Hard links are accounted for as distinct files, consistently with how existing usage is determined.
+	files, bytes := determineItemUsage(fi)
+	err = q.reserve(newname, files, bytes)
+	if err != nil {
+		return err
+	}
+	err = linker.LinkIfPossible(oldname, newname)
+	if err != nil {
+		q.release(files, bytes)
+	}
+	return err
+}
+
+func (q *quotaFs) Chmod(name string, mode os.FileMode) error {
+	return q.source.Chmod(name, mode)
+}
+
+func (q *quotaFs) Chown(name string, uid, gid int) error {
+	return q.source.Chown(name, uid, gid)
+}
+
+func (q *quotaFs) ChownIfPossible(name string, uid int, gid int) error {
+	if chowner, ok := q.source.(IChowner); ok {
+		return chowner.ChownIfPossible(name, uid, gid)
+	}
+	return q.source.Chown(name, uid, gid)
+}
+
+func (q *quotaFs) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	if xattrer, ok := q.source.(IXattrer); ok {
+		return xattrer.GetXattrIfPossible(name, attribute)
+	}
+	return nil, ErrXattrNotImplemented
+}
+
+// SetXattrIfPossible sets extended attributes which are not accounted for in the quota as they are stored as metadata.
+func (q *quotaFs) SetXattrIfPossible(name, attribute string, value []byte) error {
+	if xattrer, ok := q.source.(IXattrer); ok {
+		return xattrer.SetXattrIfPossible(name, attribute, value)
+	}
+	return ErrXattrNotImplemented
+}
+
+func (q *quotaFs) ListXattrIfPossible(name string) ([]string, error) {
+	if xattrer, ok := q.source.(IXattrer); ok {
+		return xattrer.ListXattrIfPossible(name)
+	}
+	return nil, ErrXattrNotImplemented
+}
+
+func (q *quotaFs) RemoveXattrIfPossible(name, attribute string) error {
+	if xattrer, ok := q.source.(IXattrer); ok {
+		return xattrer.RemoveXattrIfPossible(name, attribute)
+	}
+	return ErrXattrNotImplemented
+}
+
+func (q *quotaFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return q.source.Chtimes(name, atime, mtime)
+}
+
+func (q *quotaFs) ForceRemoveIfPossible(name string) error {
+	remover, ok := q.source.(IForceRemover)
+	if !ok {
+		return q.RemoveAll(name)
+	}
+	files, bytes := determineTreeUsage(q.source, name)
+	err := remover.ForceRemoveIfPossible(name)
+	if err == nil {
+		q.release(files, bytes)
+	}
+	return err
+}
+
+// quotaFile is a file of a quotaFs opened for writing, whose growth is checked against the quota.
+type quotaFile struct {
+	afero.File
+	fs         *quotaFs
+	appendOnly bool
+}
+
+// reserveGrowth reserves the space needed for writing `length` bytes at offset `offset` and returns the size of the file before the write.
+func (f *quotaFile) reserveGrowth(offset int64, length int) (size int64, growth int64, err error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return
+	}
+	size = fi.Size()
+	if f.appendOnly {
+		offset = size
+	}
+	growth = offset + int64(length) - size
+	if growth <= 0 {
+		growth = 0
+		return
+	}
+	err = f.fs.reserve(f.Name(), 0, growth)
+	return
+}
+
+// releaseUnusedGrowth releases the space reserved for a write which could not be fully performed.
+func (f *quotaFile) releaseUnusedGrowth(growth int64, length int, written int) {
+	unused := int64(length - written)
+	if unused > growth {
+		unused = growth
+	}
+	if unused > 0 {
+		f.fs.release(0, unused)
+	}
+}
+
+func (f *quotaFile) Write(p []byte) (n int, err error) {
+	offset, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	_, growth, err := f.reserveGrowth(offset, len(p))
+	if err != nil {
+		return
+	}
+	n, err = f.File.Write(p)
+	f.releaseUnusedGrowth(growth, len(p), n)
+	return
+}
+
+func (f *quotaFile) WriteAt(p []byte, off int64) (n int, err error) {
+	_, growth, err := f.reserveGrowth(off, len(p))
+	if err != nil {
+		return
+	}
+	n, err = f.File.WriteAt(p, off)
+	f.releaseUnusedGrowth(growth, len(p), n)
+	return
+}
+
+func (f *quotaFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *quotaFile) Truncate(size int64) (err error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return
+	}
+	delta := size - fi.Size()
+	if delta > 0 {
+		err = f.fs.reserve(f.Name(), 0, delta)
+		if err != nil {
+			return
+		}
+	}
+	err = f.File.Truncate(size)
+	switch {
+	case err != nil && delta > 0:
+		f.fs.release(0, delta)
+	case err == nil && delta < 0:
+		f.fs.release(0, -delta)
+	}
+	return
+}
+
+// NewQuotaFileSystem returns a filesystem enforcing `quota` on all writes performed on `fs` (e.g. WriteFile, CreateFile, OpenFile, Copy, Unzip).
+// Any write which would exceed the quota fails with commonerrors.ErrTooLarge. The usage of any item present in `seedRoots` is accounted for when the filesystem is created.
+func NewQuotaFileSystem(ctx context.Context, fs FS, quota *Quota, seedRoots ...string) (FS, error) {
+	if fs == nil {
+		return nil, commonerrors.UndefinedVariable("file system")
+	}
+	vfs, ok := fs.(*VFS)
+	if !ok {
+		return nil, commonerrors.Newf(commonerrors.ErrUnsupported, "file system of type [%T] cannot enforce quotas", fs)
+	}
+	usage := QuotaUsage{}
+	for i := range seedRoots {
+		err := vfs.WalkWithContext(ctx, seedRoots[i], func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			files, bytes := determineItemUsage(info)
+			usage.FileCount += files
+			usage.TotalSize = addToUsage(usage.TotalSize, bytes)
+			return nil
+		})
+		if err != nil {
+			return nil, ConvertFileSystemError(err)
+		}
+	}
+	wrapped, err := newQuotaFs(vfs.vfs, quota, usage)
+	if err != nil {
+		return nil, err
+	}
+	return NewVirtualFileSystemWithPathSeparator(wrapped, vfs.fsType, vfs.pathConverter, vfs.pathSeparator), nil
+}
+
+// GetQuotaUsage returns the storage used on a filesystem created using NewQuotaFileSystem.
+func GetQuotaUsage(fs FS) (usage QuotaUsage, err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("file system")
+		return
+	}
+	vfs, ok := fs.(*VFS)
+	if ok {
+		var q *quotaFs
+		q, ok = vfs.vfs.(*quotaFs)
+		if ok {
+			usage = q.getUsage()
+			return
+		}
+	}
+	err = commonerrors.Newf(commonerrors.ErrUnsupported, "file system of type [%T] does not enforce any quota", fs)
+	return
+}
@@ -0,0 +1,101 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func TestQuotaFileSystem(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			underlying := NewFs(fsType)
+			root, err := underlying.TempDirInTempDir("test-quota-")
+			require.NoError(t, err)
+			defer func() { _ = underlying.Rm(root) }()
+			require.NoError(t, underlying.WriteFile(FilePathJoin(underlying, root, "existing.txt"), []byte(strings.Repeat("a", 100)), 0o644))
+
+			fs, err := NewQuotaFileSystem(context.Background(), underlying, &Quota{MaxTotalSize: 1000, MaxFileCount: 4}, root)
+			require.NoError(t, err)
+			usage, err := GetQuotaUsage(fs)
+			require.NoError(t, err)
+			assert.Equal(t, QuotaUsage{TotalSize: 100, FileCount: 1}, usage)
+
+			file1 := FilePathJoin(fs, root, "file1.txt")
+			require.NoError(t, fs.WriteFile(file1, []byte(strings.Repeat("b", 500)), 0o644))
+			usage, err = GetQuotaUsage(fs)
+			require.NoError(t, err)
+			assert.Equal(t, QuotaUsage{TotalSize: 600, FileCount: 2}, usage)
+
+			// Exceeding the size quota.
+			file2 := FilePathJoin(fs, root, "file2.txt")
+			err = fs.WriteFile(file2, []byte(strings.Repeat("c", 500)), 0o644)
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			err = fs.Copy(file1, file2)
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			require.NoError(t, fs.Rm(file2))
+
+			// Overwriting a file only accounts for the difference in size.
+			require.NoError(t, fs.WriteFile(file1, []byte(strings.Repeat("b", 800)), 0o644))
+			usage, err = GetQuotaUsage(fs)
+			require.NoError(t, err)
+			assert.Equal(t, QuotaUsage{TotalSize: 900, FileCount: 2}, usage)
+			f, err := fs.OpenFile(file1, os.O_RDWR, 0o644)
+			require.NoError(t, err)
+			require.NoError(t, f.Truncate(100))
+			_, err = f.WriteAt([]byte(strings.Repeat("d", 900)), 100)
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			_, err = f.WriteAt([]byte(strings.Repeat("d", 500)), 100)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			usage, err = GetQuotaUsage(fs)
+			require.NoError(t, err)
+			assert.Equal(t, QuotaUsage{TotalSize: 700, FileCount: 2}, usage)
+
+			// Exceeding the file count quota.
+			require.NoError(t, fs.Touch(FilePathJoin(fs, root, "empty1")))
+			require.NoError(t, fs.Touch(FilePathJoin(fs, root, "empty2")))
+			_, err = fs.CreateFile(FilePathJoin(fs, root, "empty3"))
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			assert.False(t, fs.Exists(FilePathJoin(fs, root, "empty3")))
+
+			// Removing items frees up the quota.
+			require.NoError(t, fs.Rm(file1))
+			require.NoError(t, fs.MoveWithContext(context.Background(), FilePathJoin(fs, root, "empty1"), FilePathJoin(fs, root, "empty2")))
+			usage, err = GetQuotaUsage(fs)
+			require.NoError(t, err)
+			assert.Equal(t, QuotaUsage{TotalSize: 100, FileCount: 2}, usage)
+			require.NoError(t, fs.MkDir(FilePathJoin(fs, root, "dir")))
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, root, "dir", "file.txt"), []byte(faker.Sentence()), 0o644))
+			require.NoError(t, fs.Rm(FilePathJoin(fs, root, "dir")))
+			usage, err = GetQuotaUsage(fs)
+			require.NoError(t, err)
+			assert.Equal(t, QuotaUsage{TotalSize: 100, FileCount: 2}, usage)
+		})
+	}
+}
+
+func TestQuotaFileSystem_Failures(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	_, err := NewQuotaFileSystem(context.Background(), nil, &Quota{})
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewQuotaFileSystem(context.Background(), fs, nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = NewQuotaFileSystem(context.Background(), fs, &Quota{MaxFileCount: -1})
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = NewQuotaFileSystem(context.Background(), fs, &Quota{}, faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	_, err = GetQuotaUsage(fs)
+	errortest.AssertError(t, err, commonerrors.ErrUnsupported)
+	_, err = GetQuotaUsage(nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+}
@@ -0,0 +1,242 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+var readerCounter = atomic.NewUint64(0)
+
+// RemoteRWLockFile describes a distributed reader/writer lock using only the file system and relying on the same mechanisms as RemoteLockFile.
+// The writer holds a lock directory similar to RemoteLockFile's whereas every reader registers a lock directory of its own (with its own heart beat) in a `readers` directory.
+// A writer acquires the lock by creating its directory first and then waiting for all the registered readers to leave. This prevents any new reader from registering in the meantime and therefore, writers cannot be starved by readers.
+// A reader registers itself first and then checks that no writer is present. If there is, it deregisters and tries again later.
+type RemoteRWLockFile struct {
+	fs                FS
+	writer            *RemoteLockFile
+	reader            *RemoteLockFile
+	readersPath       string
+	overrideStaleLock bool
+	// Stores for cancelling pending lock attempts. They are different from the stores of the underlying locks as the latter are cancelled when readers back off.
+	writerCancelStore *parallelisation.CancelFunctionStore
+	readerCancelStore *parallelisation.CancelFunctionStore
+}
+
+// NewGenericRemoteRWLockFile creates a new remote reader/writer lock using the file system.
+func NewGenericRemoteRWLockFile(fs FS, lockID string, dirPath string, overrideStaleLock bool) IRWLock {
+	writer := newRemoteLockFile(fs, lockID, dirPath, overrideStaleLock)
+	readersPath := fmt.Sprintf("%v-readers", writer.lockPath())
+	host, _ := os.Hostname()
+	readerID := fmt.Sprintf("%v-%v-%v", strings.TrimSpace(host), os.Getpid(), readerCounter.Inc())
+	return &RemoteRWLockFile{
+		fs:                fs,
+		writer:            writer,
+		reader:            newRemoteLockFile(fs, readerID, readersPath, false),
+		readersPath:       readersPath,
+		overrideStaleLock: overrideStaleLock,
+		writerCancelStore: parallelisation.NewCancelFunctionsStore(),
+		readerCancelStore: parallelisation.NewCancelFunctionsStore(),
+	}
+}
+
+// NewRemoteRWLockFile creates a new remote reader/writer lock using the file system.
+// lockID Id for the lock.
+// dirPath path where the lock should be applied to.
+func NewRemoteRWLockFile(fs FS, lockID string, dirPath string) IRWLock {
+	return NewGenericRemoteRWLockFile(fs, lockID, dirPath, false)
+}
+
+// TryLock attempts to acquire the lock for writing straight away.
+func (l *RemoteRWLockFile) TryLock(ctx context.Context) (err error) {
+	err = l.writer.TryLock(ctx)
+	if err != nil {
+		return
+	}
+	err = l.checkNoActiveReaders(ctx)
+	if err != nil {
+		_ = l.writer.Unlock(context.Background())
+	}
+	return
+}
+
+// Lock acquires the lock for writing. This call will block until the lock is available.
+func (l *RemoteRWLockFile) Lock(ctx context.Context) (err error) {
+	err = l.writer.Lock(ctx)
+	if err != nil {
+		return
+	}
+	// New readers cannot register whilst the writer lock is held. Waiting for the current ones to leave.
+	for {
+		err = l.checkNoActiveReaders(ctx)
+		if err == nil {
+			return
+		}
+		if commonerrors.Any(err, commonerrors.ErrLocked) {
+			parallelisation.SleepWithContext(ctx, l.writer.timeBetweenLockTries)
+			err = parallelisation.DetermineContextError(ctx)
+		}
+		if err != nil {
+			_ = l.writer.Unlock(context.Background())
+			return
+		}
+	}
+}
+
+// LockWithTimeout tries to acquire the lock for writing until the timeout expires.
+func (l *RemoteRWLockFile) LockWithTimeout(ctx context.Context, timeout time.Duration) error {
+	if err := parallelisation.DetermineContextError(ctx); err != nil {
+		return err
+	}
+	return parallelisation.RunActionWithTimeoutAndCancelStore(ctx, timeout, l.writerCancelStore, l.Lock)
+}
+
+// Unlock releases the lock held for writing. If the lock is not held by this instance, it is forcibly released unless this instance has already released it.
+func (l *RemoteRWLockFile) Unlock(ctx context.Context) error {
+	l.writerCancelStore.Cancel()
+	return l.writer.Unlock(ctx)
+}
+
+// TryRLock attempts to acquire the lock for reading straight away.
+func (l *RemoteRWLockFile) TryRLock(ctx context.Context) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if l.reader.held.Load() {
+		err = commonerrors.Newf(commonerrors.ErrConflict, "lock [%v] is already held for reading", l.writer.id)
+		return
+	}
+	err = l.fs.MkDir(l.readersPath)
+	if err != nil {
+		return
+	}
+	err = l.reader.TryLock(ctx)
+	if err != nil {
+		return
+	}
+	if !l.fs.Exists(l.writer.lockPath()) {
+		return
+	}
+	_ = l.reader.Unlock(context.Background())
+	if !l.writer.IsStale() {
+		err = commonerrors.ErrLocked
+		return
+	}
+	if !l.overrideStaleLock {
+		err = commonerrors.ErrStaleLock
+		return
+	}
+	_ = l.writer.ReleaseIfStale(ctx)
+	err = l.TryRLock(ctx)
+	return
+}
+
+// RLock acquires the lock for reading. This call will block until no writer holds the lock.
+func (l *RemoteRWLockFile) RLock(ctx context.Context) error {
+	for {
+		if err := parallelisation.DetermineContextError(ctx); err != nil {
+			return err
+		}
+		err := l.TryRLock(ctx)
+		if !commonerrors.Any(err, commonerrors.ErrLocked) {
+			return err
+		}
+		parallelisation.SleepWithContext(ctx, l.writer.timeBetweenLockTries)
+	}
+}
+
+// RLockWithTimeout tries to acquire the lock for reading until the timeout expires.
+func (l *RemoteRWLockFile) RLockWithTimeout(ctx context.Context, timeout time.Duration) error {
+	if err := parallelisation.DetermineContextError(ctx); err != nil {
+		return err
+	}
+	return parallelisation.RunActionWithTimeoutAndCancelStore(ctx, timeout, l.readerCancelStore, l.RLock)
+}
+
+// RUnlock releases the lock held for reading by this instance.
+func (l *RemoteRWLockFile) RUnlock(ctx context.Context) error {
+	l.readerCancelStore.Cancel()
+	return l.reader.Unlock(ctx)
+}
+
+// IsStale checks whether the writer lock or any of the readers is stale.
+func (l *RemoteRWLockFile) IsStale() bool {
+	if l.writer.IsStale() {
+		return true
+	}
+	for _, reader := range l.listReaders() {
+		if reader.IsStale() {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseIfStale releases the writer lock and the readers which are stale.
+func (l *RemoteRWLockFile) ReleaseIfStale(ctx context.Context) error {
+	err := l.writer.ReleaseIfStale(ctx)
+	if err != nil {
+		return err
+	}
+	for _, reader := range l.listReaders() {
+		err = reader.ReleaseIfStale(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MakeStale makes the lock held by this instance (for reading or writing) stale. This is mostly useful for testing purposes.
+func (l *RemoteRWLockFile) MakeStale(ctx context.Context) error {
+	if l.reader.held.Load() {
+		return l.reader.MakeStale(ctx)
+	}
+	return l.writer.MakeStale(ctx)
+}
+
+// listReaders returns locks describing the readers currently registered.
+func (l *RemoteRWLockFile) listReaders() (readers []*RemoteLockFile) {
+	entries, err := l.fs.Ls(l.readersPath)
+	if err != nil {
+		return
+	}
+	prefix := fmt.Sprintf("%v-", LockFilePrefix)
+	for i := range entries {
+		if !strings.HasPrefix(entries[i], prefix) {
+			continue
+		}
+		readers = append(readers, newRemoteLockFile(l.fs, strings.TrimPrefix(entries[i], prefix), l.readersPath, false))
+	}
+	return
+}
+
+// checkNoActiveReaders returns commonerrors.ErrLocked if readers still hold the lock. Stale readers are released if stale locks can be overridden.
+func (l *RemoteRWLockFile) checkNoActiveReaders(ctx context.Context) error {
+	staleReaders := false
+	for _, reader := range l.listReaders() {
+		if !reader.IsStale() {
+			return commonerrors.ErrLocked
+		}
+		if !l.overrideStaleLock {
+			staleReaders = true
+			continue
+		}
+		err := reader.ReleaseIfStale(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if staleReaders {
+		return commonerrors.ErrStaleLock
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func TestRWLock(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprintf("%v_for_fs_%v", t.Name(), fsType), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			fs := NewFs(fsType)
+			ctx := context.Background()
+			dirToLock, err := fs.TempDirInTempDir("test-rw-lock-")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(dirToLock) }()
+			lockID := faker.Word()
+			reader1 := fs.NewRemoteRWLockFile(lockID, dirToLock)
+			reader2 := fs.NewRemoteRWLockFile(lockID, dirToLock)
+			writer := fs.NewRemoteRWLockFile(lockID, dirToLock)
+
+			// Readers share the lock.
+			require.NoError(t, reader1.TryRLock(ctx))
+			require.NoError(t, reader2.RLockWithTimeout(ctx, time.Second))
+			errortest.AssertError(t, reader1.TryRLock(ctx), commonerrors.ErrConflict)
+			errortest.AssertError(t, writer.TryLock(ctx), commonerrors.ErrLocked)
+			errortest.AssertError(t, writer.LockWithTimeout(ctx, 100*time.Millisecond), commonerrors.ErrTimeout)
+			time.Sleep(150 * time.Millisecond)
+			assert.False(t, writer.IsStale())
+			require.NoError(t, reader1.RUnlock(ctx))
+			errortest.AssertError(t, writer.TryLock(ctx), commonerrors.ErrLocked)
+			require.NoError(t, reader2.RUnlock(ctx))
+
+			// The writer has exclusive access.
+			require.NoError(t, writer.LockWithTimeout(ctx, time.Second))
+			errortest.AssertError(t, reader1.TryRLock(ctx), commonerrors.ErrLocked)
+			errortest.AssertError(t, reader1.RLockWithTimeout(ctx, 100*time.Millisecond), commonerrors.ErrTimeout)
+			errortest.AssertError(t, reader2.TryLock(ctx), commonerrors.ErrLocked)
+			require.NoError(t, writer.Unlock(ctx))
+			require.NoError(t, reader1.RLock(ctx))
+			require.NoError(t, reader1.RUnlock(ctx))
+
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			errortest.AssertError(t, reader1.RLock(cancelledCtx), commonerrors.ErrCancelled)
+			errortest.AssertError(t, writer.Lock(cancelledCtx), commonerrors.ErrCancelled)
+		})
+	}
+}
+
+func TestRWLock_WriterWaitsForReaders(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	fs := NewStandardFileSystem()
+	ctx := context.Background()
+	dirToLock := t.TempDir()
+	lockID := faker.Word()
+	reader := fs.NewRemoteRWLockFile(lockID, dirToLock)
+	writer := fs.NewRemoteRWLockFile(lockID, dirToLock)
+	newReader := fs.NewRemoteRWLockFile(lockID, dirToLock)
+
+	require.NoError(t, reader.RLock(ctx))
+	locked := make(chan error, 1)
+	go func() {
+		locked <- writer.Lock(ctx)
+	}()
+	// Whilst the writer is waiting, new readers are not let in.
+	require.Eventually(t, func() bool {
+		err := newReader.TryRLock(ctx)
+		if err == nil {
+			_ = newReader.RUnlock(ctx)
+		}
+		return commonerrors.Any(err, commonerrors.ErrLocked)
+	}, time.Second, 5*time.Millisecond)
+	select {
+	case <-locked:
+		t.Fatal("the writer should not have acquired the lock whilst a reader holds it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, reader.RUnlock(ctx))
+	require.NoError(t, <-locked)
+	require.NoError(t, writer.Unlock(ctx))
+}
+
+func TestRWLock_Stale(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	fs := NewStandardFileSystem().(*VFS)
+	ctx := context.Background()
+	dirToLock := t.TempDir()
+	lockID := faker.Word()
+	reader := NewRemoteRWLockFile(fs, lockID, dirToLock)
+	writer := NewRemoteRWLockFile(fs, lockID, dirToLock)
+	overridingWriter := NewGenericRemoteRWLockFile(fs, lockID, dirToLock, true)
+
+	require.NoError(t, reader.RLock(ctx))
+	require.NoError(t, reader.MakeStale(ctx))
+	assert.True(t, writer.IsStale())
+	errortest.AssertError(t, writer.TryLock(ctx), commonerrors.ErrStaleLock)
+	require.NoError(t, overridingWriter.TryLock(ctx))
+	assert.False(t, writer.IsStale())
+
+	require.NoError(t, overridingWriter.MakeStale(ctx))
+	assert.True(t, reader.IsStale())
+	errortest.AssertError(t, reader.TryRLock(ctx), commonerrors.ErrStaleLock)
+	require.NoError(t, reader.ReleaseIfStale(ctx))
+	assert.False(t, reader.IsStale())
+	require.NoError(t, reader.TryRLock(ctx))
+	require.NoError(t, reader.RUnlock(ctx))
+}
+
+func TestRWLock_Concurrency(t *testing.T) {
+	fs := NewStandardFileSystem()
+	ctx := context.Background()
+	dirToLock := t.TempDir()
+	lockID := faker.Word()
+	var (
+		mu      sync.Mutex
+		readers int
+		writers int
+		wg      sync.WaitGroup
+	)
+	check := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.LessOrEqual(t, writers, 1)
+		if writers > 0 {
+			assert.Zero(t, readers)
+		}
+	}
+	for i := 0; i < 6; i++ {
+		lock := fs.NewRemoteRWLockFile(lockID, dirToLock)
+		isWriter := i%3 == 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if isWriter {
+					if !assert.NoError(t, lock.LockWithTimeout(ctx, 10*time.Second)) {
+						return
+					}
+					mu.Lock()
+					writers++
+					mu.Unlock()
+				} else {
+					if !assert.NoError(t, lock.RLockWithTimeout(ctx, 10*time.Second)) {
+						return
+					}
+					mu.Lock()
+					readers++
+					mu.Unlock()
+				}
+				check()
+				time.Sleep(time.Millisecond)
+				check()
+				mu.Lock()
+				if isWriter {
+					writers--
+				} else {
+					readers--
+				}
+				mu.Unlock()
+				if isWriter {
+					assert.NoError(t, lock.Unlock(ctx))
+				} else {
+					assert.NoError(t, lock.RUnlock(ctx))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
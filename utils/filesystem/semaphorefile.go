@@ -0,0 +1,160 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+// RemoteSemaphoreFile describes a distributed counting semaphore using only the file system.
+// Each permit of the semaphore is a lock similar to RemoteLockFile (i.e. a directory created atomically and kept alive by a heart beat) and acquiring the semaphore consists in acquiring any of the permits.
+// An instance can hold at most one permit at a time.
+type RemoteSemaphoreFile struct {
+	mu                   sync.Mutex
+	id                   string
+	permits              []*RemoteLockFile
+	acquired             *RemoteLockFile
+	timeBetweenLockTries time.Duration
+	cancelStore          *parallelisation.CancelFunctionStore
+}
+
+// NewGenericRemoteSemaphoreFile creates a new remote semaphore allowing up to `permits` holders at the same time. If permits is less than 1, the semaphore behaves like a mutex.
+func NewGenericRemoteSemaphoreFile(fs FS, lockID string, dirPath string, permits int, overrideStaleLock bool) ISemaphore {
+	if permits < 1 {
+		permits = 1
+	}
+	semaphore := &RemoteSemaphoreFile{
+		id:                   lockID,
+		timeBetweenLockTries: 10 * time.Millisecond,
+		cancelStore:          parallelisation.NewCancelFunctionsStore(),
+	}
+	for i := 0; i < permits; i++ {
+		semaphore.permits = append(semaphore.permits, newRemoteLockFile(fs, fmt.Sprintf("%v.permit-%d", lockID, i), dirPath, overrideStaleLock))
+	}
+	return semaphore
+}
+
+// NewRemoteSemaphoreFile creates a new remote semaphore using the file system.
+// lockID Id for the semaphore.
+// dirPath path where the semaphore should be applied to.
+// permits maximum number of concurrent holders.
+func NewRemoteSemaphoreFile(fs FS, lockID string, dirPath string, permits int) ISemaphore {
+	return NewGenericRemoteSemaphoreFile(fs, lockID, dirPath, permits, false)
+}
+
+// GetPermits returns the maximum number of concurrent holders of the semaphore.
+func (s *RemoteSemaphoreFile) GetPermits() int {
+	return len(s.permits)
+}
+
+// CountHolders returns the number of permits currently acquired, including stale ones.
+func (s *RemoteSemaphoreFile) CountHolders() (count int) {
+	for i := range s.permits {
+		if s.permits[i].fs.Exists(s.permits[i].lockPath()) {
+			count++
+		}
+	}
+	return
+}
+
+// TryLock attempts to acquire a permit straight away. commonerrors.ErrLocked is returned if all permits are held and commonerrors.ErrStaleLock if some of them are stale.
+func (s *RemoteSemaphoreFile) TryLock(ctx context.Context) error {
+	if err := parallelisation.DetermineContextError(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.acquired != nil {
+		return commonerrors.Newf(commonerrors.ErrConflict, "a permit of semaphore [%v] is already held", s.id)
+	}
+	staleLocks := false
+	for i := range s.permits {
+		err := s.permits[i].TryLock(ctx)
+		switch {
+		case err == nil:
+			s.acquired = s.permits[i]
+			return nil
+		case commonerrors.Any(err, commonerrors.ErrStaleLock):
+			staleLocks = true
+		case !commonerrors.Any(err, commonerrors.ErrLocked):
+			return err
+		}
+	}
+	if staleLocks {
+		return commonerrors.ErrStaleLock
+	}
+	return commonerrors.ErrLocked
+}
+
+// Lock acquires a permit. This call will block until a permit is available.
+func (s *RemoteSemaphoreFile) Lock(ctx context.Context) error {
+	for {
+		if err := parallelisation.DetermineContextError(ctx); err != nil {
+			return err
+		}
+		err := s.TryLock(ctx)
+		if !commonerrors.Any(err, commonerrors.ErrLocked) {
+			return err
+		}
+		parallelisation.SleepWithContext(ctx, s.timeBetweenLockTries)
+	}
+}
+
+// LockWithTimeout tries to acquire a permit until the timeout expires.
+func (s *RemoteSemaphoreFile) LockWithTimeout(ctx context.Context, timeout time.Duration) error {
+	if err := parallelisation.DetermineContextError(ctx); err != nil {
+		return err
+	}
+	return parallelisation.RunActionWithTimeoutAndCancelStore(ctx, timeout, s.cancelStore, s.Lock)
+}
+
+// Unlock releases the permit held by this instance, if any. Unlike RemoteLockFile, permits held by others are not released.
+func (s *RemoteSemaphoreFile) Unlock(ctx context.Context) (err error) {
+	s.cancelStore.Cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.acquired == nil {
+		return
+	}
+	err = s.acquired.Unlock(ctx)
+	if err == nil {
+		s.acquired = nil
+	}
+	return
+}
+
+// IsStale checks whether any of the permits is stale.
+func (s *RemoteSemaphoreFile) IsStale() bool {
+	for i := range s.permits {
+		if s.permits[i].IsStale() {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseIfStale releases the permits which are stale.
+func (s *RemoteSemaphoreFile) ReleaseIfStale(ctx context.Context) error {
+	for i := range s.permits {
+		if err := s.permits[i].ReleaseIfStale(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MakeStale makes the permit held by this instance stale. This is mostly useful for testing purposes.
+func (s *RemoteSemaphoreFile) MakeStale(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.acquired == nil {
+		return commonerrors.Newf(commonerrors.ErrNotFound, "no permit of semaphore [%v] is held", s.id)
+	}
+	permit := s.acquired
+	s.acquired = nil
+	return permit.MakeStale(ctx)
+}
@@ -0,0 +1,129 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+func TestSemaphore(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprintf("%v_for_fs_%v", t.Name(), fsType), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			fs := NewFs(fsType)
+			ctx := context.Background()
+			dirToLock, err := fs.TempDirInTempDir("test-semaphore-")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(dirToLock) }()
+			lockID := faker.Word()
+			holder1 := fs.NewRemoteSemaphoreFile(lockID, dirToLock, 2)
+			holder2 := fs.NewRemoteSemaphoreFile(lockID, dirToLock, 2)
+			holder3 := fs.NewRemoteSemaphoreFile(lockID, dirToLock, 2)
+			assert.Equal(t, 2, holder1.GetPermits())
+			assert.Zero(t, holder1.CountHolders())
+
+			require.NoError(t, holder1.TryLock(ctx))
+			errortest.AssertError(t, holder1.TryLock(ctx), commonerrors.ErrConflict)
+			require.NoError(t, holder2.LockWithTimeout(ctx, time.Second))
+			assert.Equal(t, 2, holder3.CountHolders())
+			errortest.AssertError(t, holder3.TryLock(ctx), commonerrors.ErrLocked)
+			errortest.AssertError(t, holder3.LockWithTimeout(ctx, 100*time.Millisecond), commonerrors.ErrTimeout)
+			assert.False(t, holder3.IsStale())
+
+			// Releasing without holding a permit does not release the others'.
+			require.NoError(t, holder3.Unlock(ctx))
+			assert.Equal(t, 2, holder3.CountHolders())
+
+			require.NoError(t, holder1.Unlock(ctx))
+			require.NoError(t, holder3.Lock(ctx))
+			require.NoError(t, holder2.Unlock(ctx))
+			require.NoError(t, holder3.Unlock(ctx))
+			assert.Zero(t, holder3.CountHolders())
+
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			errortest.AssertError(t, holder1.Lock(cancelledCtx), commonerrors.ErrCancelled)
+		})
+	}
+}
+
+func TestSemaphore_UnsupportedFileSystem(t *testing.T) {
+	// Locking requires directories to be created atomically which only VFS file systems support.
+	fs := struct{ FS }{NewInMemoryFileSystem()}
+	dirToLock, err := fs.TempDirInTempDir("test-semaphore-")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(dirToLock) }()
+	semaphore := NewRemoteSemaphoreFile(fs, faker.Word(), dirToLock, 2)
+	errortest.AssertError(t, semaphore.TryLock(context.Background()), commonerrors.ErrUnsupported)
+}
+
+func TestSemaphore_Stale(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	fs := NewStandardFileSystem().(*VFS)
+	ctx := context.Background()
+	dirToLock := t.TempDir()
+	lockID := faker.Word()
+	holder := NewRemoteSemaphoreFile(fs, lockID, dirToLock, 0)
+	other := NewRemoteSemaphoreFile(fs, lockID, dirToLock, 1)
+	overridingHolder := NewGenericRemoteSemaphoreFile(fs, lockID, dirToLock, 1, true)
+	assert.Equal(t, 1, holder.GetPermits())
+
+	errortest.AssertError(t, holder.MakeStale(ctx), commonerrors.ErrNotFound)
+	require.NoError(t, holder.Lock(ctx))
+	require.NoError(t, holder.MakeStale(ctx))
+	assert.True(t, other.IsStale())
+	errortest.AssertError(t, other.TryLock(ctx), commonerrors.ErrStaleLock)
+	require.NoError(t, overridingHolder.TryLock(ctx))
+	assert.False(t, other.IsStale())
+	require.NoError(t, overridingHolder.MakeStale(ctx))
+	require.NoError(t, other.ReleaseIfStale(ctx))
+	assert.Zero(t, other.CountHolders())
+}
+
+func TestSemaphore_Concurrency(t *testing.T) {
+	fs := NewStandardFileSystem()
+	ctx := context.Background()
+	dirToLock := t.TempDir()
+	lockID := faker.Word()
+	permits := 3
+	var (
+		mu         sync.Mutex
+		holders    int
+		maxHolders int
+		wg         sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		semaphore := fs.NewRemoteSemaphoreFile(lockID, dirToLock, permits)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if !assert.NoError(t, semaphore.LockWithTimeout(ctx, 10*time.Second)) {
+					return
+				}
+				mu.Lock()
+				holders++
+				maxHolders = max(maxHolders, holders)
+				assert.LessOrEqual(t, holders, permits)
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				mu.Lock()
+				holders--
+				mu.Unlock()
+				assert.NoError(t, semaphore.Unlock(ctx))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Greater(t, maxHolders, 1)
+}
@@ -0,0 +1,120 @@
+package filesystem
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// maxSymlinkResolution is the maximum number of symbolic links followed when resolving a path (similar to Linux MAXSYMLINKS).
+const maxSymlinkResolution = 40
+
+// symlinkResolver resolves paths through the symbolic links present on a file system and checks that neither the resulting path nor any intermediate path leads outside a root directory.
+// Unlike filepath.EvalSymlinks, paths do not need to exist: items which do not exist are resolved lexically.
+type symlinkResolver struct {
+	root      string
+	separator rune
+	isAbs     func(path string) bool
+	lstat     func(path string) (os.FileInfo, error)
+	readlink  func(path string) (string, error)
+}
+
+func newFSSymlinkResolver(fs FS, root string) *symlinkResolver {
+	return &symlinkResolver{
+		root:      root,
+		separator: fs.PathSeparator(),
+		isAbs:     func(path string) bool { return FilePathIsAbs(fs, path) },
+		lstat:     fs.Lstat,
+		readlink:  fs.Readlink,
+	}
+}
+
+func (r *symlinkResolver) isSeparator(c rune) bool {
+	return c == '/' || c == r.separator
+}
+
+// rootPrefix returns the prefix of any path strictly within the root.
+func (r *symlinkResolver) rootPrefix() string {
+	return strings.TrimRightFunc(r.root, r.isSeparator) + string(r.separator)
+}
+
+func (r *symlinkResolver) join(parent, element string) string {
+	return strings.TrimRightFunc(parent, r.isSeparator) + string(r.separator) + element
+}
+
+func (r *symlinkResolver) parent(path string) string {
+	i := strings.LastIndexFunc(path, r.isSeparator)
+	if i < len(r.rootPrefix()) {
+		return r.root
+	}
+	return path[:i]
+}
+
+// resolve resolves `path`, which must be the root or within it. The last element of the path is only resolved if `followLast` is set.
+// Paths leading outside the root result in a commonerrors.ErrForbidden error whereas too many levels of links result in a syscall.ELOOP error.
+func (r *symlinkResolver) resolve(path string, followLast bool) (resolved string, err error) {
+	var relPath string
+	switch {
+	case path == r.root:
+	case strings.HasPrefix(path, r.rootPrefix()):
+		relPath = path[len(r.rootPrefix()):]
+	default:
+		err = commonerrors.Newf(commonerrors.ErrForbidden, "path '%s' is not within '%s'", path, r.root)
+		return
+	}
+	pending := strings.FieldsFunc(relPath, r.isSeparator)
+	resolved = r.root
+	followed := 0
+	for len(pending) > 0 {
+		element := pending[0]
+		pending = pending[1:]
+		switch element {
+		case ".":
+			continue
+		case "..":
+			if resolved == r.root {
+				err = commonerrors.Newf(commonerrors.ErrForbidden, "path '%s' leads outside '%s'", path, r.root)
+				return
+			}
+			resolved = r.parent(resolved)
+			continue
+		}
+		next := r.join(resolved, element)
+		if len(pending) == 0 && !followLast {
+			resolved = next
+			break
+		}
+		info, subErr := r.lstat(next)
+		if subErr != nil || !IsSymLink(info) {
+			// Items which do not exist yet are resolved lexically.
+			resolved = next
+			continue
+		}
+		followed++
+		if followed > maxSymlinkResolution {
+			err = &os.PathError{Op: "stat", Path: path, Err: syscall.ELOOP}
+			return
+		}
+		target, subErr := r.readlink(next)
+		if subErr != nil {
+			err = subErr
+			return
+		}
+		if r.isAbs(target) {
+			switch {
+			case target == r.root:
+				target = ""
+			case strings.HasPrefix(target, r.rootPrefix()):
+				target = target[len(r.rootPrefix()):]
+			default:
+				err = commonerrors.Newf(commonerrors.ErrForbidden, "path '%s' leads outside '%s' through link '%s'", path, r.root, next)
+				return
+			}
+			resolved = r.root
+		}
+		pending = append(strings.FieldsFunc(target, r.isSeparator), pending...)
+	}
+	return
+}
@@ -0,0 +1,383 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+// syncModTimeWindow is the tolerance used when comparing modification times of files if either side has a low time precision (e.g. zip archives only have a 2-second precision).
+const syncModTimeWindow = 2 * time.Second
+
+// SyncOptions defines how file trees are synchronised.
+type SyncOptions struct {
+	// hashAlgorithm is the algorithm used to compare file contents. If not set, files are compared using their size and modification time.
+	hashAlgorithm string
+	// deleteExtraneous states whether items of the destination which are not present in the source should be removed.
+	deleteExtraneous bool
+	// exclusionPatterns lists the patterns of items which should neither be synchronised nor removed.
+	exclusionPatterns []string
+	limits            ILimits
+}
+
+// SyncOption configures SyncOptions.
+type SyncOption func(*SyncOptions) *SyncOptions
+
+// DefaultSyncOptions returns the default synchronisation options i.e. files are compared using their size and modification time, extraneous items are kept and no limits apply.
+func DefaultSyncOptions() *SyncOptions {
+	return &SyncOptions{limits: NoLimits()}
+}
+
+// WithSyncOptions returns the synchronisation options resulting from applying options to the defaults.
+func WithSyncOptions(options ...SyncOption) (opts *SyncOptions) {
+	opts = DefaultSyncOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithContentComparison compares files using their content hash (see NewFileHash) rather than their modification time.
+func WithContentComparison(hashAlgorithm string) SyncOption {
+	return func(o *SyncOptions) *SyncOptions {
+		if o == nil {
+			o = DefaultSyncOptions()
+		}
+		o.hashAlgorithm = hashAlgorithm
+		return o
+	}
+}
+
+// WithExtraneousItemsRemoval removes items of the destination which are not present in the source (similar to rsync --delete).
+func WithExtraneousItemsRemoval() SyncOption {
+	return func(o *SyncOptions) *SyncOptions {
+		if o == nil {
+			o = DefaultSyncOptions()
+		}
+		o.deleteExtraneous = true
+		return o
+	}
+}
+
+// WithSyncExclusionPatterns ignores any item matching an exclusion pattern in both source and destination.
+func WithSyncExclusionPatterns(exclusionPatterns ...string) SyncOption {
+	return func(o *SyncOptions) *SyncOptions {
+		if o == nil {
+			o = DefaultSyncOptions()
+		}
+		o.exclusionPatterns = append(o.exclusionPatterns, exclusionPatterns...)
+		return o
+	}
+}
+
+// WithSyncLimits limits the number, size and depth of files which can be transferred during a synchronisation.
+func WithSyncLimits(limits ILimits) SyncOption {
+	return func(o *SyncOptions) *SyncOptions {
+		if o == nil {
+			o = DefaultSyncOptions()
+		}
+		if limits == nil {
+			limits = NoLimits()
+		}
+		o.limits = limits
+		return o
+	}
+}
+
+// SyncReport describes the changes made to the destination during a synchronisation. Paths are destination paths.
+type SyncReport struct {
+	Added   []string
+	Updated []string
+	Removed []string
+}
+
+// HasChanges states whether the synchronisation changed anything.
+func (r *SyncReport) HasChanges() bool {
+	return len(r.Added) > 0 || len(r.Updated) > 0 || len(r.Removed) > 0
+}
+
+// syncItem describes an item of the source to synchronise.
+type syncItem struct {
+	src   string
+	dest  string
+	isDir bool
+	info  os.FileInfo
+}
+
+// Sync synchronises the destination (dest) with the source (src) similarly to rsync: only files which have changed are transferred.
+// If src is a directory, dest is made a mirror of its content. If src is a file, it is synchronised with the file dest or with a file of the same name if dest is an existing directory.
+// By default, files are considered different if their size or modification time differ; see SyncOption for changing this behaviour.
+// Modification times are compared exactly unless either filesystem only stores them with a low precision (e.g. zip or tar archives) in which case a 2-second tolerance applies.
+// Modification times of transferred files are preserved so that subsequent synchronisations are incremental.
+// Limits are checked before any change is made to the destination.
+func Sync(ctx context.Context, srcFs FS, src string, destFs FS, dest string, options ...SyncOption) (report *SyncReport, err error) {
+	if srcFs == nil || destFs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	opts := WithSyncOptions(options...)
+	err = opts.limits.Validate()
+	if err != nil {
+		return
+	}
+	var hash IFileHash
+	if opts.hashAlgorithm != "" {
+		hash, err = NewFileHash(opts.hashAlgorithm)
+		if err != nil {
+			return
+		}
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	srcInfo, err := srcFs.Stat(src)
+	if err != nil {
+		if IsPathNotExist(err) {
+			err = commonerrors.WrapErrorf(commonerrors.ErrNotFound, err, "path [%v] does not exist", src)
+		}
+		return
+	}
+	if !srcInfo.IsDir() {
+		if isDir, _ := destFs.IsDir(dest); isDir {
+			dest = FilePathJoin(destFs, dest, FilePathBase(srcFs, src))
+		}
+	}
+	items, err := listSyncItems(ctx, srcFs, src, destFs, dest, opts.exclusionPatterns)
+	if err != nil {
+		return
+	}
+	modTimeWindow := time.Duration(0)
+	if hasLowModTimePrecision(srcFs) || hasLowModTimePrecision(destFs) {
+		modTimeWindow = syncModTimeWindow
+	}
+	report = &SyncReport{}
+	var toAdd, toUpdate []*syncItem
+	for i := range items {
+		item := items[i]
+		var added, changed bool
+		added, changed, err = syncItemStatus(ctx, srcFs, destFs, item, hash, modTimeWindow)
+		if err != nil {
+			return
+		}
+		if added {
+			toAdd = append(toAdd, item)
+		} else if changed {
+			toUpdate = append(toUpdate, item)
+		}
+	}
+	err = checkSyncLimits(srcFs, src, opts.limits, append(toAdd, toUpdate...))
+	if err != nil {
+		return
+	}
+	var toRemove []string
+	if opts.deleteExtraneous && srcInfo.IsDir() {
+		toRemove, err = listExtraneousItems(ctx, destFs, dest, items, opts.exclusionPatterns)
+		if err != nil {
+			return
+		}
+	}
+
+	for i := range toRemove {
+		err = destFs.RemoveWithContext(ctx, toRemove[i])
+		if err != nil {
+			return
+		}
+		report.Removed = append(report.Removed, toRemove[i])
+	}
+	for i := range toUpdate {
+		err = syncItemToDestination(ctx, srcFs, destFs, toUpdate[i], true)
+		if err != nil {
+			return
+		}
+		report.Updated = append(report.Updated, toUpdate[i].dest)
+	}
+	for i := range toAdd {
+		err = syncItemToDestination(ctx, srcFs, destFs, toAdd[i], false)
+		if err != nil {
+			return
+		}
+		report.Added = append(report.Added, toAdd[i].dest)
+	}
+	return
+}
+
+// listSyncItems lists the items of the source tree which are not excluded, parents first.
+func listSyncItems(ctx context.Context, srcFs FS, src string, destFs FS, dest string, exclusionPatterns []string) (items []*syncItem, err error) {
+	err = srcFs.WalkWithContextAndExclusionPatterns(ctx, src, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if IsSymLink(info) {
+			// Links are followed but linked directories are not descended into.
+			linked, subErr := srcFs.Stat(path)
+			if subErr != nil {
+				return subErr
+			}
+			info = linked
+		}
+		rel, subErr := FilePathRel(srcFs, FilePathClean(srcFs, src), FilePathClean(srcFs, path))
+		if subErr != nil {
+			return subErr
+		}
+		destPath := dest
+		if rel != "." {
+			destPath = FilePathJoin(destFs, dest, FilePathFromSlash(destFs, FilePathToSlash(srcFs, rel)))
+		}
+		items = append(items, &syncItem{src: path, dest: destPath, isDir: info.IsDir(), info: info})
+		return nil
+	}, exclusionPatterns...)
+	err = ConvertFileSystemError(err)
+	return
+}
+
+// hasLowModTimePrecision states whether modification times stored by a filesystem may have been rounded (e.g. archives).
+func hasLowModTimePrecision(fs FS) bool {
+	switch fs.GetType() {
+	case ZipFS, TarFS:
+		return true
+	default:
+		return false
+	}
+}
+
+// syncItemStatus determines whether an item is missing from the destination or differs from its destination counterpart.
+func syncItemStatus(ctx context.Context, srcFs, destFs FS, item *syncItem, hash IFileHash, modTimeWindow time.Duration) (added bool, changed bool, err error) {
+	destInfo, err := destFs.Stat(item.dest)
+	if err != nil {
+		if IsPathNotExist(err) || commonerrors.Any(err, commonerrors.ErrNotFound) {
+			err = nil
+			added = true
+		}
+		return
+	}
+	if item.isDir || destInfo.IsDir() {
+		changed = item.isDir != destInfo.IsDir()
+		return
+	}
+	if item.info.Size() != destInfo.Size() {
+		changed = true
+		return
+	}
+	if hash == nil {
+		diff := item.info.ModTime().Sub(destInfo.ModTime())
+		changed = diff > modTimeWindow || diff < -modTimeWindow
+		return
+	}
+	srcHash, err := hash.CalculateFileWithContext(ctx, srcFs, item.src)
+	if err != nil {
+		return
+	}
+	destHash, err := hash.CalculateFileWithContext(ctx, destFs, item.dest)
+	if err != nil {
+		return
+	}
+	changed = srcHash != destHash
+	return
+}
+
+func checkSyncLimits(srcFs FS, src string, limits ILimits, transfers []*syncItem) error {
+	if !limits.Apply() {
+		return nil
+	}
+	var fileCount int64
+	var totalSize uint64
+	for i := range transfers {
+		if limits.GetMaxDepth() >= 0 {
+			depth, err := FileTreeDepth(srcFs, src, transfers[i].src)
+			if err != nil {
+				return err
+			}
+			if depth > limits.GetMaxDepth() {
+				return commonerrors.Newf(commonerrors.ErrTooLarge, "depth [%v] of [%v] is beyond allowed limits (max: %v)", depth, transfers[i].src, limits.GetMaxDepth())
+			}
+		}
+		if transfers[i].isDir {
+			continue
+		}
+		size := transfers[i].info.Size()
+		if size > limits.GetMaxFileSize() {
+			return commonerrors.Newf(commonerrors.ErrTooLarge, "file [%v] is too big (%v B) and beyond limits (max: %v B)", transfers[i].src, size, limits.GetMaxFileSize())
+		}
+		fileCount++
+		if size > 0 {
+			totalSize += uint64(size)
+		}
+	}
+	if fileCount > limits.GetMaxFileCount() {
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "too many files to synchronise (%v) and beyond limits (max: %v)", fileCount, limits.GetMaxFileCount())
+	}
+	if totalSize > limits.GetMaxTotalSize() {
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "too much data to synchronise (%v B) and beyond limits (max: %v B)", totalSize, limits.GetMaxTotalSize())
+	}
+	return nil
+}
+
+// listExtraneousItems lists the items of the destination tree which are neither present in the source nor excluded, children first.
+func listExtraneousItems(ctx context.Context, destFs FS, dest string, items []*syncItem, exclusionPatterns []string) (extraneous []string, err error) {
+	if !destFs.Exists(dest) {
+		return
+	}
+	expected := make(map[string]bool, len(items))
+	for i := range items {
+		expected[FilePathClean(destFs, items[i].dest)] = true
+	}
+	err = destFs.WalkWithContextAndExclusionPatterns(ctx, dest, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if expected[FilePathClean(destFs, path)] {
+			return nil
+		}
+		extraneous = append(extraneous, path)
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}, exclusionPatterns...)
+	err = ConvertFileSystemError(err)
+	sort.Sort(sort.Reverse(sort.StringSlice(extraneous)))
+	return
+}
+
+func syncItemToDestination(ctx context.Context, srcFs, destFs FS, item *syncItem, replace bool) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if replace {
+		// The type of the item may have changed.
+		isDestDir, subErr := destFs.IsDir(item.dest)
+		if subErr != nil {
+			return subErr
+		}
+		if isDestDir != item.isDir {
+			err = destFs.RemoveWithContext(ctx, item.dest)
+			if err != nil {
+				return
+			}
+		}
+	}
+	if item.isDir {
+		err = destFs.MkDir(item.dest)
+		return
+	}
+	err = destFs.MkDir(FilePathDir(destFs, item.dest))
+	if err != nil {
+		return
+	}
+	err = copyFileBetweenFSWithExclusionPatternsWithExclusionRegexes(ctx, srcFs, item.src, destFs, item.dest, nil, nil)
+	if err != nil {
+		return
+	}
+	modTime := item.info.ModTime()
+	err = destFs.Chtimes(item.dest, modTime, modTime)
+	return
+}
@@ -0,0 +1,236 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+)
+
+func newSyncTestDestination(t *testing.T, fs FS) (dest string) {
+	t.Helper()
+	dir, err := fs.TempDirInTempDir("test-sync-dest-")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = fs.Rm(dir) })
+	dest = FilePathJoin(fs, dir, "mirror")
+	return
+}
+
+func assertSyncedTrees(t *testing.T, srcFs FS, src string, destFs FS, dest string, exclusionPatterns ...string) {
+	t.Helper()
+	srcFiles, err := srcFs.LsRecursiveWithExclusionPatterns(context.Background(), src, true, exclusionPatterns...)
+	require.NoError(t, err)
+	destFiles, err := destFs.LsRecursiveWithExclusionPatterns(context.Background(), dest, true, exclusionPatterns...)
+	require.NoError(t, err)
+	require.Len(t, destFiles, len(srcFiles))
+	for i := range srcFiles {
+		rel, err := FilePathRel(srcFs, src, srcFiles[i])
+		require.NoError(t, err)
+		destPath := FilePathJoin(destFs, dest, rel)
+		if isDir, _ := srcFs.IsDir(srcFiles[i]); isDir {
+			assert.True(t, destFs.Exists(destPath))
+			continue
+		}
+		expected, err := srcFs.ReadFile(srcFiles[i])
+		require.NoError(t, err)
+		actual, err := destFs.ReadFile(destPath)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), string(actual))
+	}
+}
+
+func TestSync(t *testing.T) {
+	for _, srcFsType := range FileSystemTypes {
+		for _, destFsType := range FileSystemTypes {
+			t.Run(fmt.Sprintf("%v to %v", srcFsType, destFsType), func(t *testing.T) {
+				srcFs := NewFs(srcFsType)
+				destFs := NewFs(destFsType)
+				src := createTestFileTree(t, srcFs, faker.Paragraph())
+				dest := newSyncTestDestination(t, destFs)
+
+				report, err := Sync(context.Background(), srcFs, src, destFs, dest)
+				require.NoError(t, err)
+				assertSyncedTrees(t, srcFs, src, destFs, dest)
+				assert.Len(t, report.Added, 8)
+				assert.Empty(t, report.Updated)
+				assert.Empty(t, report.Removed)
+
+				report, err = Sync(context.Background(), srcFs, src, destFs, dest)
+				require.NoError(t, err)
+				assert.False(t, report.HasChanges())
+
+				updated := FilePathJoin(srcFs, src, "dir", "file.txt")
+				require.NoError(t, srcFs.WriteFile(updated, []byte(faker.Sentence()), 0o644))
+				added := FilePathJoin(srcFs, src, "empty", "new.txt")
+				require.NoError(t, srcFs.WriteFile(added, []byte(faker.Sentence()), 0o644))
+				extraneous := FilePathJoin(destFs, dest, "dir", "extraneous.txt")
+				require.NoError(t, destFs.WriteFile(extraneous, []byte(faker.Sentence()), 0o644))
+				report, err = Sync(context.Background(), srcFs, src, destFs, dest)
+				require.NoError(t, err)
+				assert.Equal(t, []string{FilePathJoin(destFs, dest, "empty", "new.txt")}, report.Added)
+				assert.Equal(t, []string{FilePathJoin(destFs, dest, "dir", "file.txt")}, report.Updated)
+				assert.Empty(t, report.Removed)
+				assert.True(t, destFs.Exists(extraneous))
+
+				report, err = Sync(context.Background(), srcFs, src, destFs, dest, WithExtraneousItemsRemoval())
+				require.NoError(t, err)
+				assert.Empty(t, report.Added)
+				assert.Empty(t, report.Updated)
+				assert.Equal(t, []string{extraneous}, report.Removed)
+				assertSyncedTrees(t, srcFs, src, destFs, dest)
+			})
+		}
+	}
+}
+
+func TestSync_ContentComparison(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	dest := newSyncTestDestination(t, fs)
+	_, err := Sync(context.Background(), fs, src, fs, dest)
+	require.NoError(t, err)
+
+	// Same size and modification time but different content
+	srcFile := FilePathJoin(fs, src, "file.txt")
+	content, err := fs.ReadFile(srcFile)
+	require.NoError(t, err)
+	content[0]++
+	info, err := fs.Stat(srcFile)
+	require.NoError(t, err)
+	modTime := info.ModTime()
+	require.NoError(t, fs.WriteFile(srcFile, content, 0o644))
+	require.NoError(t, fs.Chtimes(srcFile, modTime, modTime))
+
+	report, err := Sync(context.Background(), fs, src, fs, dest)
+	require.NoError(t, err)
+	assert.False(t, report.HasChanges())
+	report, err = Sync(context.Background(), fs, src, fs, dest, WithContentComparison(hashing.HashMd5))
+	require.NoError(t, err)
+	assert.Equal(t, []string{FilePathJoin(fs, dest, "file.txt")}, report.Updated)
+	assertSyncedTrees(t, fs, src, fs, dest)
+
+	_, err = Sync(context.Background(), fs, src, fs, dest, WithContentComparison(faker.Word()))
+	errortest.AssertError(t, err, commonerrors.ErrNotFound, commonerrors.ErrUnsupported, commonerrors.ErrInvalid)
+}
+
+func TestSync_ModificationTimePrecision(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	dest := newSyncTestDestination(t, fs)
+	_, err := Sync(context.Background(), fs, src, fs, dest)
+	require.NoError(t, err)
+
+	// Same size but modified within the tolerance applied to archives
+	srcFile := FilePathJoin(fs, src, "file.txt")
+	content, err := fs.ReadFile(srcFile)
+	require.NoError(t, err)
+	content[0]++
+	info, err := fs.Stat(srcFile)
+	require.NoError(t, err)
+	modTime := info.ModTime().Add(time.Second)
+	require.NoError(t, fs.WriteFile(srcFile, content, 0o644))
+	require.NoError(t, fs.Chtimes(srcFile, modTime, modTime))
+
+	report, err := Sync(context.Background(), fs, src, fs, dest)
+	require.NoError(t, err)
+	assert.Equal(t, []string{FilePathJoin(fs, dest, "file.txt")}, report.Updated)
+	assertSyncedTrees(t, fs, src, fs, dest)
+}
+
+func TestSync_ExclusionPatterns(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	dest := newSyncTestDestination(t, fs)
+	excludedDestFile := FilePathJoin(fs, dest, "extraneous.tmp")
+	require.NoError(t, fs.WriteFile(excludedDestFile, []byte(faker.Sentence()), 0o644))
+
+	report, err := Sync(context.Background(), fs, src, fs, dest, WithSyncExclusionPatterns(".*[.]tmp"), WithExtraneousItemsRemoval())
+	require.NoError(t, err)
+	assert.Len(t, report.Added, 6)
+	assert.Empty(t, report.Removed)
+	assert.False(t, fs.Exists(FilePathJoin(fs, dest, "dir", "test.tmp")))
+	assert.True(t, fs.Exists(excludedDestFile))
+	assertSyncedTrees(t, fs, src, fs, dest, ".*[.]tmp")
+}
+
+func TestSync_Limits(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	dest := newSyncTestDestination(t, fs)
+
+	_, err := Sync(context.Background(), fs, src, fs, dest, WithSyncLimits(NewLimits(5, 1024*1024, 1000, 1, false)))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	assert.False(t, fs.Exists(dest))
+	_, err = Sync(context.Background(), fs, src, fs, dest, WithSyncLimits(NewLimits(1024*1024, 1024*1024, 2, 1, false)))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	_, err = Sync(context.Background(), fs, src, fs, dest, WithSyncLimits(NewLimits(1024*1024, 10, 1000, 1, false)))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	assert.False(t, fs.Exists(dest))
+	_, err = Sync(context.Background(), fs, src, fs, dest, WithSyncLimits(NewLimits(1024*1024, 1024*1024, 1000, 1, false)))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	assert.False(t, fs.Exists(dest))
+
+	_, err = Sync(context.Background(), fs, src, fs, dest, WithSyncLimits(DefaultLimits()))
+	require.NoError(t, err)
+	assertSyncedTrees(t, fs, src, fs, dest)
+}
+
+func TestSync_SingleFile(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	destDir := newSyncTestDestination(t, fs)
+	require.NoError(t, fs.MkDir(destDir))
+	srcFile := FilePathJoin(fs, src, "file.txt")
+
+	report, err := Sync(context.Background(), fs, srcFile, fs, destDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{FilePathJoin(fs, destDir, "file.txt")}, report.Added)
+	destFile := FilePathJoin(fs, destDir, "copy.txt")
+	report, err = Sync(context.Background(), fs, srcFile, fs, destFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{destFile}, report.Added)
+	report, err = Sync(context.Background(), fs, srcFile, fs, destFile, WithExtraneousItemsRemoval())
+	require.NoError(t, err)
+	assert.False(t, report.HasChanges())
+}
+
+func TestSync_FromArchive(t *testing.T) {
+	fs := NewStandardFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	archive := FilePathJoin(fs, t.TempDir(), "test.zip")
+	require.NoError(t, fs.Zip(src, archive))
+	zipFs, zipFile, err := NewZipFileSystemFromStandardFileSystem(archive, NoLimits())
+	require.NoError(t, err)
+	defer func() { _ = zipFile.Close() }()
+	destFs := NewInMemoryFileSystem()
+	dest := newSyncTestDestination(t, destFs)
+
+	report, err := Sync(context.Background(), zipFs, "/", destFs, dest)
+	require.NoError(t, err)
+	assert.Len(t, report.Added, 8)
+	assertSyncedTrees(t, zipFs, "/", destFs, dest)
+	time.Sleep(10 * time.Millisecond)
+	report, err = Sync(context.Background(), zipFs, "/", destFs, dest)
+	require.NoError(t, err)
+	assert.False(t, report.HasChanges())
+}
+
+func TestSync_Failures(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	_, err := Sync(context.Background(), nil, faker.Word(), fs, faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = Sync(context.Background(), fs, faker.Word(), fs, faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Sync(ctx, fs, faker.Word(), fs, faker.Word())
+	errortest.AssertError(t, err, commonerrors.ErrCancelled)
+}
@@ -2,10 +2,169 @@ package filesystem
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/atomic"
+
+	"github.com/ARM-software/golang-utils/utils/collection"
 	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+	"github.com/ARM-software/golang-utils/utils/safecast"
+	"github.com/ARM-software/golang-utils/utils/safeio"
+)
+
+const (
+	tarExt    = ".tar"
+	tarzstExt = ".tar.zst"
+	tzstExt   = ".tzst"
+)
+
+var (
+	// TarFileExtensions returns a list of extensions describing tar archive files which can be handled by Tar and Untar.
+	TarFileExtensions = []string{tarExt, targzExt, targz2Ext, tarzstExt, tzstExt}
+	gzipMagicNumber   = []byte{0x1f, 0x8b}
+	zstdMagicNumber   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type tarCompression int
+
+const (
+	noTarCompression tarCompression = iota
+	gzipTarCompression
+	zstdTarCompression
 )
 
+// determineTarCompression determines the compression to apply to a tar archive based on its filename.
+func determineTarCompression(path string) tarCompression {
+	lowerPath := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lowerPath, targzExt) || strings.HasSuffix(lowerPath, targz2Ext):
+		return gzipTarCompression
+	case strings.HasSuffix(lowerPath, tarzstExt) || strings.HasSuffix(lowerPath, tzstExt):
+		return zstdTarCompression
+	default:
+		return noTarCompression
+	}
+}
+
+// isTarFilename states whether a filename has a tar archive extension or not.
+func isTarFilename(path string) bool {
+	lowerPath := strings.ToLower(path)
+	return collection.AnyFunc(TarFileExtensions, func(ext string) bool { return strings.HasSuffix(lowerPath, ext) })
+}
+
+// tarFilenameStem returns the filename without any tar archive extension e.g. `archive` for `archive.tar.gz`.
+func tarFilenameStem(fs FS, path string) string {
+	base := FilePathBase(fs, path)
+	lowerBase := strings.ToLower(base)
+	for i := range TarFileExtensions {
+		if strings.HasSuffix(lowerBase, TarFileExtensions[i]) {
+			return base[:len(base)-len(TarFileExtensions[i])]
+		}
+	}
+	return FilePathStemOnFilesystem(fs, path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (w *nopWriteCloser) Close() error {
+	return nil
+}
+
+// zstdEncoderLevel maps a compression level (see WithCompressionLevel) onto the closest zstd encoder level.
+func zstdEncoderLevel(level int) zstd.EncoderLevel {
+	switch {
+	case level == DefaultCompressionLevel:
+		return zstd.SpeedDefault
+	case level <= 2:
+		return zstd.SpeedFastest
+	case level <= 5:
+		return zstd.SpeedDefault
+	case level <= 8:
+		return zstd.SpeedBetterCompression
+	default:
+		return zstd.SpeedBestCompression
+	}
+}
+
+func newTarCompressor(writer io.Writer, compression tarCompression, level int) (compressor io.WriteCloser, err error) {
+	switch compression {
+	case gzipTarCompression:
+		if level == DefaultCompressionLevel {
+			level = gzip.DefaultCompression
+		}
+		compressor, err = gzip.NewWriterLevel(writer, level)
+		if err != nil {
+			err = commonerrors.WrapError(commonerrors.ErrInvalid, err, "could not create a gzip compressor")
+		}
+	case zstdTarCompression:
+		compressor, err = zstd.NewWriter(writer, zstd.WithEncoderLevel(zstdEncoderLevel(level)))
+		if err != nil {
+			err = commonerrors.WrapError(commonerrors.ErrUnexpected, err, "could not create a zstd compressor")
+		}
+	default:
+		compressor = &nopWriteCloser{Writer: writer}
+	}
+	return
+}
+
+// newTarDecompressor determines whether the reader is compressed (gzip or zstd) by looking at its magic number and returns a decompressed reader accordingly.
+func newTarDecompressor(reader io.Reader) (decompressed io.Reader, closeDecompressor func() error, err error) {
+	if reader == nil {
+		err = commonerrors.UndefinedVariable("reader")
+		return
+	}
+	closeDecompressor = func() error { return nil }
+	buffered := bufio.NewReader(reader)
+	magic, _ := buffered.Peek(len(zstdMagicNumber))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagicNumber):
+		gzipReader, subErr := gzip.NewReader(buffered)
+		if subErr != nil {
+			err = commonerrors.WrapError(commonerrors.ErrInvalid, subErr, "could not read gzip compressed tar archive")
+			return
+		}
+		decompressed = gzipReader
+		closeDecompressor = gzipReader.Close
+	case bytes.HasPrefix(magic, zstdMagicNumber):
+		zstdReader, subErr := zstd.NewReader(buffered)
+		if subErr != nil {
+			err = commonerrors.WrapError(commonerrors.ErrInvalid, subErr, "could not read zstd compressed tar archive")
+			return
+		}
+		decompressed = zstdReader
+		closeDecompressor = func() error {
+			zstdReader.Close()
+			return nil
+		}
+	default:
+		decompressed = buffered
+	}
+	return
+}
+
+// decompressedTarFile is a tar archive file which may need decompressing and so, the decompressor should also be closed when the file is closed.
+type decompressedTarFile struct {
+	File
+	closeDecompressor func() error
+}
+
+func (f *decompressedTarFile) Close() error {
+	return commonerrors.Join(f.closeDecompressor(), f.File.Close())
+}
+
 func newTarReader(fs FS, source string, limits ILimits, currentDepth int64) (tarReader *tar.Reader, file File, err error) {
 	if fs == nil {
 		err = commonerrors.New(commonerrors.ErrUndefined, "missing file system")
@@ -41,7 +200,595 @@ func newTarReader(fs FS, source string, limits ILimits, currentDepth int64) (tar
 		return
 	}
 
-	tarReader = tar.NewReader(file)
+	decompressed, closeDecompressor, err := newTarDecompressor(file)
+	if err != nil {
+		return
+	}
+	file = &decompressedTarFile{File: file, closeDecompressor: closeDecompressor}
+	tarReader = tar.NewReader(decompressed)
+
+	return
+}
+
+// Tar archives a source directory into a destination tar archive. The archive is compressed depending on the destination extension (e.g. `.tar.gz` or `.tar.zst`).
+func Tar(source string, destination string) error {
+	return globalFileSystem.Tar(source, destination)
+}
+
+func (fs *VFS) Tar(source, destination string) error {
+	return fs.TarWithContext(context.Background(), source, destination)
+}
+
+func (fs *VFS) TarWithContext(ctx context.Context, source, destination string) error {
+	return fs.TarWithContextAndLimits(ctx, source, destination, NoLimits())
+}
+
+func (fs *VFS) TarWithContextAndLimits(ctx context.Context, source, destination string, limits ILimits) error {
+	return fs.TarWithContextAndLimitsAndExclusionPatterns(ctx, source, destination, limits)
+}
+
+func (fs *VFS) TarWithContextAndLimitsAndExclusionPatterns(ctx context.Context, source string, destination string, limits ILimits, exclusionPatterns ...string) (err error) {
+	return fs.TarWithContextAndOptions(ctx, source, destination, WithArchiveLimits(limits), WithArchiveExclusionPatterns(exclusionPatterns...))
+}
+
+func (fs *VFS) TarWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) (err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	opts := WithArchiveOptions(options...)
+	limits := opts.limits
+	if limits == nil {
+		err = commonerrors.New(commonerrors.ErrUndefined, "missing file system limits")
+		return
+	}
+	err = opts.checkCompressionLevel()
+	if err != nil {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if !fs.Exists(source) {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "path [%v] does not exist", source)
+		return
+	}
+	isSourceFile, err := fs.IsFile(source)
+	if err != nil {
+		return
+	}
+
+	file, err := fs.CreateFile(destination)
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	compressor, err := newTarCompressor(file, determineTarCompression(destination), opts.compressionLevel)
+	if err != nil {
+		return
+	}
+	defer func() { _ = compressor.Close() }()
+
+	// create a new tar archive
+	w := tar.NewWriter(compressor)
+	defer func() { _ = w.Close() }()
+
+	fileCounter := int64(0)
+	totalSize := uint64(0)
+	walker := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == destination {
+			// The archive should not contain itself.
+			return nil
+		}
+		if info.IsDir() && path == source {
+			return nil
+		}
+		if IsSpecialFile(info) && info.Mode()&os.ModeSocket != 0 {
+			// Sockets cannot be archived.
+			return nil
+		}
+		if limits.Apply() && info.Size() > limits.GetMaxFileSize() {
+			return commonerrors.Newf(commonerrors.ErrTooLarge, "file [%v] is too big (%v B) and beyond limits (max: %v B)", path, info.Size(), limits.GetMaxFileSize())
+		}
+		fileCounter++
+		if limits.Apply() && fileCounter > limits.GetMaxFileCount() {
+			return commonerrors.Newf(commonerrors.ErrTooLarge, "more than %v files were archived into %v", limits.GetMaxFileCount(), destination)
+		}
+
+		// Get the relative path
+		var relPath string
+		if isSourceFile {
+			relPath = FilePathBase(fs, path)
+		} else {
+			relPath, err = FilePathRel(fs, source, path)
+			if err != nil {
+				return err
+			}
+		}
+
+		link := ""
+		if IsSymLink(info) {
+			link, err = fs.Readlink(path)
+			if err != nil {
+				return err
+			}
+			link = FilePathToSlash(fs, link)
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return commonerrors.WrapErrorf(commonerrors.ErrUnsupported, err, "could not archive [%v]", path)
+		}
+		header.Name = FilePathToSlash(fs, relPath)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if times, subErr := fs.StatTimes(path); subErr == nil && times.HasAccessTime() {
+			header.AccessTime = times.AccessTime()
+		}
+		if opts.preserveXattrs && !IsSymLink(info) {
+			attributes, subErr := GetXattrs(fs, path)
+			if subErr != nil {
+				return subErr
+			}
+			addXattrsToTarHeader(header, attributes)
+		}
+		err = w.WriteHeader(header)
+		if err != nil {
+			return commonerrors.WrapErrorf(commonerrors.ErrUnexpected, err, "could not add [%v] to archive", path)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		totalSize += safecast.ToUint64(info.Size())
+		if limits.Apply() && totalSize > limits.GetMaxTotalSize() {
+			return commonerrors.Newf(commonerrors.ErrTooLarge, "more than %v B of data were archived into %v", limits.GetMaxTotalSize(), destination)
+		}
+
+		src, err := fs.GenericOpen(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = src.Close() }()
+		n, err := safeio.CopyNWithContext(ctx, src, w, info.Size())
+		if err != nil {
+			return err
+		}
+		if info.Size() != n {
+			return commonerrors.Newf(commonerrors.ErrUnexpected, "could not write the full file [%v] content (wrote %v/%v bytes)", relPath, n, info.Size())
+		}
+		return src.Close()
+	}
+	err = fs.WalkWithContextAndExclusionPatterns(ctx, source, walker, opts.exclusionPatterns...)
+	if err != nil {
+		return
+	}
+	err = w.Close()
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrUnexpected, err, "could not finalise archive [%v]", destination)
+		return
+	}
+	err = compressor.Close()
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrUnexpected, err, "could not finalise archive compression [%v]", destination)
+		return
+	}
+
+	if limits.Apply() {
+		stat, subErr := file.Stat()
+		if subErr != nil {
+			return subErr
+		}
+		if stat.Size() > limits.GetMaxFileSize() {
+			return commonerrors.Newf(commonerrors.ErrTooLarge, "file [%v] is too big (%v B) and beyond limits (max: %v B)", destination, stat.Size(), limits.GetMaxFileSize())
+		}
+	}
+	err = file.Close()
+	return
+}
+
+// Untar extracts a source tar archive (which can be compressed using gzip or zstd) into destination.
+func Untar(source, destination string) ([]string, error) {
+	return globalFileSystem.Untar(source, destination)
+}
+
+func (fs *VFS) Untar(source, destination string) ([]string, error) {
+	return fs.UntarWithContext(context.Background(), source, destination)
+}
+
+func (fs *VFS) UntarWithContext(ctx context.Context, source string, destination string) (fileList []string, err error) {
+	fileList, _, _, err = fs.untar(ctx, source, destination, NoLimits(), 0, false)
+	return
+}
+
+// UntarWithContextAndLimits extracts a source tar archive into destination.
+func UntarWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) ([]string, error) {
+	return globalFileSystem.UntarWithContextAndLimits(ctx, source, destination, limits)
+}
+
+func (fs *VFS) UntarWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) (fileList []string, err error) {
+	fileList, _, _, err = fs.untar(ctx, source, destination, limits, 0, false)
+	return
+}
+
+func (fs *VFS) UntarWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) (fileList []string, err error) {
+	opts := WithArchiveOptions(options...)
+	fileList, _, _, err = fs.untar(ctx, source, destination, opts.limits, 0, opts.preserveXattrs)
+	return
+}
+
+// UntarFromReaderWithContextAndLimits extracts a tar archive (optionally gzip or zstd compressed) streamed from source into destination without the archive having to be stored on a file system first.
+func UntarFromReaderWithContextAndLimits(ctx context.Context, source io.Reader, destination string, limits ILimits) ([]string, error) {
+	return globalFileSystem.UntarFromReaderWithContextAndLimits(ctx, source, destination, limits)
+}
+
+func (fs *VFS) UntarFromReaderWithContextAndLimits(ctx context.Context, source io.Reader, destination string, limits ILimits) (fileList []string, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if source == nil {
+		err = commonerrors.UndefinedVariable("tar reader")
+		return
+	}
+	if limits == nil {
+		err = commonerrors.UndefinedVariable("file system limits")
+		return
+	}
+	archiveName := "tar stream"
+	limitedSource := newLimitedArchiveReader(safeio.NewContextualReader(ctx, source), archiveName, limits)
+	decompressed, closeDecompressor, err := newTarDecompressor(limitedSource)
+	if err != nil {
+		err = commonerrors.Join(limitedSource.checkLimits(), err)
+		return
+	}
+	defer func() { _ = closeDecompressor() }()
+	fileList, _, _, err = fs.extractTar(ctx, tar.NewReader(decompressed), archiveName, destination, limits, 0, false)
+	if err == nil {
+		// Readers may stop consuming the stream without noticing the limits were exceeded whilst reading the very end of the archive.
+		err = limitedSource.checkLimits()
+	}
+	return
+}
+
+// limitedArchiveReader is a reader of an archive which fails as soon as more data than allowed by the limits is read.
+type limitedArchiveReader struct {
+	reader      io.Reader
+	archiveName string
+	limits      ILimits
+	read        int64
+}
 
+func newLimitedArchiveReader(reader io.Reader, archiveName string, limits ILimits) *limitedArchiveReader {
+	return &limitedArchiveReader{
+		reader:      reader,
+		archiveName: archiveName,
+		limits:      limits,
+	}
+}
+
+func (r *limitedArchiveReader) checkLimits() error {
+	if r.limits.Apply() && r.read > r.limits.GetMaxFileSize() {
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "archive [%v] is too big and beyond limits (max: %v B)", r.archiveName, r.limits.GetMaxFileSize())
+	}
+	return nil
+}
+
+func (r *limitedArchiveReader) Read(p []byte) (n int, err error) {
+	if !r.limits.Apply() {
+		return r.reader.Read(p)
+	}
+	err = r.checkLimits()
+	if err != nil {
+		return
+	}
+	// Reading at most one byte more than allowed so that an archive of exactly the maximum size is not rejected.
+	if remaining := r.limits.GetMaxFileSize() - r.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err = r.reader.Read(p)
+	r.read += int64(n)
+	if err == nil {
+		err = r.checkLimits()
+	}
 	return
 }
+
+func (fs *VFS) untar(ctx context.Context, source string, destination string, limits ILimits, currentDepth int64, preserveXattrs bool) (fileList []string, fileOnDiskCount uint64, sizeOnDisk uint64, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+
+	tarReader, f, err := newTarReader(fs, source, limits, currentDepth)
+	defer func() {
+		if f != nil {
+			_ = f.Close()
+		}
+	}()
+	if err != nil {
+		return
+	}
+	return fs.extractTar(ctx, tarReader, FilePathBase(fs, source), destination, limits, currentDepth, preserveXattrs)
+}
+
+// extractTar extracts all the items of a tar archive into destination whilst enforcing limits. If preserveXattrs is set, extended attributes recorded in the archive are restored.
+func (fs *VFS) extractTar(ctx context.Context, tarReader *tar.Reader, archiveName string, destination string, limits ILimits, currentDepth int64, preserveXattrs bool) (fileList []string, fileOnDiskCount uint64, sizeOnDisk uint64, err error) {
+	if tarReader == nil {
+		err = commonerrors.UndefinedVariable("tar reader")
+		return
+	}
+	if limits == nil {
+		err = commonerrors.UndefinedVariable("file system limits")
+		return
+	}
+	fileCounter := atomic.NewUint64(0)
+	totalSizeOnDisk := atomic.NewUint64(0)
+
+	// Clean the destination to find shortest dirPath
+	destination = FilePathClean(fs, destination)
+	err = fs.MkDir(destination)
+	if err != nil {
+		return
+	}
+	directoryInfo := map[string]os.FileInfo{}
+
+	for {
+		subErr := parallelisation.DetermineContextError(ctx)
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+		}
+		header, subErr := tarReader.Next()
+		if commonerrors.Any(subErr, io.EOF) {
+			break
+		}
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.WrapIfNotCommonErrorf(commonerrors.ErrInvalid, subErr, "could not read tar archive [%v]", archiveName)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink, tar.TypeLink:
+		default:
+			// Special files (e.g. devices or pipes) and extended headers are not extracted.
+			continue
+		}
+
+		// Detection of Zip slip https://cwe.mitre.org/data/definitions/22.html (CodeQL)
+		filePath, subErr := sanitiseZipExtractPath(fs, FilePathFromSlash(fs, header.Name), destination)
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+		}
+		if filePath == destination {
+			continue
+		}
+		// Entries must not be extracted through symbolic links created by previous entries if they lead outside the destination.
+		parentPath, subErr := resolvePathWithinDestination(fs, FilePathDir(fs, filePath), destination)
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.WrapErrorf(commonerrors.ErrMalicious, subErr, "entry [%v] would be extracted outside the destination directory '%s'", header.Name, destination)
+		}
+		filePath = FilePathJoin(fs, parentPath, FilePathBase(fs, filePath))
+
+		var fileDepth int64
+		if limits.Apply() && limits.GetMaxDepth() >= 0 {
+			depth, subErr := FileTreeDepth(fs, destination, filePath)
+			if subErr != nil {
+				return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+			}
+			fileDepth = depth + currentDepth
+			if fileDepth > limits.GetMaxDepth() {
+				subErr = commonerrors.Newf(commonerrors.ErrTooLarge, "depth [%v] of file [%v] within tar [%v] is beyond allowed limits (max: %v)", fileDepth, FilePathBase(fs, filePath), archiveName, limits.GetMaxDepth())
+				return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+			}
+		}
+
+		isNestedArchive := limits.ApplyRecursively() && header.Typeflag == tar.TypeReg && isTarFilename(header.Name)
+		// record extracted files (except tar files if they get extracted later)
+		if !isNestedArchive {
+			fileCounter.Inc()
+			fileList = append(fileList, filePath)
+		}
+
+		info := header.FileInfo()
+		if header.Typeflag == tar.TypeDir {
+			subErr = fs.MkDir(filePath)
+			if subErr != nil {
+				return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.Newf(subErr, "unable to create directory [%s]", filePath)
+			}
+			if preserveXattrs {
+				subErr = SetXattrs(fs, filePath, determineTarHeaderXattrs(header))
+				if subErr != nil {
+					return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+				}
+			}
+			// recording directory info to preserve mode and timestamps
+			directoryInfo[filePath] = info
+			continue
+		}
+
+		directoryPath := FilePathDir(fs, filePath)
+		subErr = fs.MkDir(directoryPath)
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.Newf(subErr, "unable to create directory '%s'", directoryPath)
+		}
+		// Similarly to tar, an existing symbolic link is replaced rather than followed.
+		if existing, lstatErr := fs.Lstat(filePath); lstatErr == nil && IsSymLink(existing) {
+			subErr = fs.Rm(filePath)
+			if subErr != nil {
+				return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+			}
+		}
+
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			subErr = fs.extractTarSymlink(header, filePath, destination)
+		case tar.TypeLink:
+			subErr = fs.extractTarHardLink(ctx, header, filePath, destination)
+		default:
+			var xattrs map[string][]byte
+			if preserveXattrs && !isNestedArchive {
+				xattrs = determineTarHeaderXattrs(header)
+			}
+			var fileSizeOnDisk int64
+			fileSizeOnDisk, subErr = fs.extractTarFile(ctx, tarReader, header, filePath, limits, xattrs)
+			if subErr == nil && isNestedArchive {
+				nestedFiles, nestedCount, nestedSize, nestedErr := fs.untarNestedTarFiles(ctx, filePath, limits, fileDepth, preserveXattrs)
+				if nestedErr != nil {
+					return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), nestedErr
+				}
+				totalSizeOnDisk.Add(nestedSize)
+				fileCounter.Add(nestedCount)
+				fileList = append(fileList, nestedFiles...)
+			} else {
+				totalSizeOnDisk.Add(safecast.ToUint64(fileSizeOnDisk))
+			}
+		}
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+		}
+
+		if limits.Apply() && totalSizeOnDisk.Load() > limits.GetMaxTotalSize() {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.Newf(commonerrors.ErrTooLarge, "more than %v B of disk space was used while extracting %v (%v B used already)", limits.GetMaxTotalSize(), archiveName, totalSizeOnDisk.Load())
+		}
+		if filecount := fileCounter.Load(); limits.Apply() && filecount <= math.MaxInt64 && safecast.ToInt64(filecount) > limits.GetMaxFileCount() {
+			return fileList, filecount, totalSizeOnDisk.Load(), commonerrors.Newf(commonerrors.ErrTooLarge, "more than %v files were created while extracting %v (%v files created already)", limits.GetMaxFileCount(), archiveName, filecount)
+		}
+	}
+
+	// Ensuring directory modes and timestamps are preserved (this needs to be done after all the files have been created).
+	for dirPath, dirInfo := range directoryInfo {
+		subErr := fs.Chmod(dirPath, dirInfo.Mode().Perm())
+		if subErr != nil {
+			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.Newf(subErr, "unable to set directory mode [%s]", dirPath)
+		}
+	}
+	err = preserveDirectoriesTimestamps(ctx, fs, directoryInfo)
+	if err != nil {
+		return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), err
+	}
+
+	return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), nil
+}
+
+// extractTarFile extracts a regular file from a tar archive and preserves its mode and timestamps.
+func (fs *VFS) extractTarFile(ctx context.Context, tarReader *tar.Reader, header *tar.Header, filePath string, limits ILimits, xattrs map[string][]byte) (fileSizeOnDisk int64, err error) {
+	fileSizeOnDisk = header.Size
+	if limits.Apply() && fileSizeOnDisk > limits.GetMaxFileSize() {
+		err = commonerrors.Newf(commonerrors.ErrTooLarge, "archived file [%v] is too big (%v B) and above max size (%v B)", header.Name, fileSizeOnDisk, limits.GetMaxFileSize())
+		return
+	}
+
+	destinationPath, err := determineUnzippedFilepath(filePath)
+	if err != nil {
+		return
+	}
+	info := header.FileInfo()
+	// The file is kept writable by its owner until its extended attributes are restored.
+	destinationFile, err := fs.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm()|0o200)
+	if err != nil {
+		err = commonerrors.WrapIfNotCommonErrorf(commonerrors.ErrUnexpected, err, "unable to open file '%s'", destinationPath)
+		return
+	}
+	defer func() { _ = destinationFile.Close() }()
+
+	_, err = safeio.CopyNWithContext(ctx, tarReader, destinationFile, fileSizeOnDisk)
+	if err != nil {
+		err = commonerrors.Newf(err, "copy of archived file to '%s' failed", destinationPath)
+		return
+	}
+	err = destinationFile.Close()
+	if err != nil {
+		return
+	}
+	err = SetXattrs(fs, destinationPath, xattrs)
+	if err != nil {
+		return
+	}
+	// Ensuring the mode is preserved regardless of the process umask.
+	err = fs.Chmod(destinationPath, info.Mode())
+	if err != nil {
+		return
+	}
+	// Ensuring the timestamp is preserved.
+	err = fs.Chtimes(destinationPath, determineTarHeaderAccessTime(header), header.ModTime)
+	return
+}
+
+// extractTarSymlink creates a symbolic link described in a tar archive. Links pointing outside the destination, once resolved through the links already extracted, are rejected.
+// Note: the link timestamps are not preserved as it is not possible to change them without following the link.
+func (fs *VFS) extractTarSymlink(header *tar.Header, filePath string, destination string) (err error) {
+	linkTarget := FilePathFromSlash(fs, header.Linkname)
+	err = checkSymlinkWithinDestination(fs, filePath, linkTarget, destination)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrMalicious, err, "symbolic link [%v] points outside the destination directory '%s'", header.Name, destination)
+		return
+	}
+	err = fs.Symlink(linkTarget, filePath)
+	return
+}
+
+// checkSymlinkWithinDestination checks that a symbolic link located at `linkPath` and pointing to `linkTarget` does not lead outside the destination directory once resolved through the links present on disk.
+func checkSymlinkWithinDestination(fs FS, linkPath string, linkTarget string, destination string) (err error) {
+	resolvedTarget := linkTarget
+	if !FilePathIsAbs(fs, linkTarget) {
+		// The target is not cleaned so that it is resolved the same way as the operating system would.
+		resolvedTarget = fmt.Sprintf("%v%v%v", FilePathDir(fs, linkPath), string(fs.PathSeparator()), linkTarget)
+	}
+	_, err = resolvePathWithinDestination(fs, resolvedTarget, destination)
+	return
+}
+
+// extractTarHardLink creates a hard link described in a tar archive or copies the linked file if links are not supported by the filesystem.
+func (fs *VFS) extractTarHardLink(ctx context.Context, header *tar.Header, filePath string, destination string) (err error) {
+	_, err = sanitiseZipExtractPath(fs, FilePathFromSlash(fs, header.Linkname), destination)
+	if err != nil {
+		return
+	}
+	linkTarget, err := resolvePathWithinDestination(fs, fmt.Sprintf("%v%v%v", destination, string(fs.PathSeparator()), FilePathFromSlash(fs, header.Linkname)), destination)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrMalicious, err, "hard link [%v] points outside the destination directory '%s'", header.Name, destination)
+		return
+	}
+	err = fs.Link(linkTarget, filePath)
+	if commonerrors.Any(err, commonerrors.ErrNotImplemented) {
+		err = fs.CopyToFileWithContext(ctx, linkTarget, filePath)
+	}
+	return
+}
+
+func (fs *VFS) untarNestedTarFiles(ctx context.Context, nestedTarFile string, limits ILimits, currentDepth int64, preserveXattrs bool) (nestedFiles []string, fileOnDiskCount uint64, filesSizeOnDisk uint64, err error) {
+	destination := FilePathJoin(fs, FilePathDir(fs, nestedTarFile), tarFilenameStem(fs, nestedTarFile))
+	nestedFiles, fileOnDiskCount, filesSizeOnDisk, subErr := fs.untar(ctx, nestedTarFile, destination, limits, currentDepth+1, preserveXattrs)
+	if subErr != nil {
+		err = commonerrors.Newf(subErr, "unable to extract nested tar [%s] present at depth (%d) to [%s]", FilePathBase(fs, nestedTarFile), currentDepth, destination)
+		return
+	}
+	subErr = fs.Rm(nestedTarFile)
+	if subErr != nil {
+		err = commonerrors.Newf(subErr, "unable to remove nested tar [%s] ", nestedTarFile)
+	}
+	return
+}
+
+func determineTarHeaderAccessTime(header *tar.Header) time.Time {
+	if header.AccessTime.IsZero() {
+		return header.ModTime
+	}
+	return header.AccessTime
+}
+
+// resolvePathWithinDestination resolves `path`, which must be within the destination directory, through the symbolic links present on disk
+// and checks that neither the resulting path nor any intermediate path escapes the destination directory.
+func resolvePathWithinDestination(fs FS, path string, destination string) (string, error) {
+	return newFSSymlinkResolver(fs, destination).resolve(path, true)
+}
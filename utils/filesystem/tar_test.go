@@ -0,0 +1,425 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+	"github.com/ARM-software/golang-utils/utils/platform"
+)
+
+type testTarEntry struct {
+	header  tar.Header
+	content string
+}
+
+func createTestTar(t *testing.T, fs FS, path string, entries ...testTarEntry) {
+	t.Helper()
+	f, err := fs.CreateFile(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	w := tar.NewWriter(f)
+	for i := range entries {
+		header := entries[i].header
+		header.Size = int64(len(entries[i].content))
+		if header.Mode == 0 {
+			header.Mode = 0o644
+		}
+		require.NoError(t, w.WriteHeader(&header))
+		if entries[i].content != "" {
+			_, err = w.Write([]byte(entries[i].content))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func TestTar(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		for _, ext := range []string{tarExt, targzExt, tarzstExt} {
+			t.Run(fmt.Sprintf("%v_for_fs_%v_and_extension_%v", t.Name(), fsType, ext), func(t *testing.T) {
+				fs := NewFs(fsType)
+				tmpDir, err := fs.TempDirInTempDir("temp")
+				require.NoError(t, err)
+				defer func() { _ = fs.Rm(tmpDir) }()
+
+				testDir := FilePathJoin(fs, tmpDir, "test")
+				archive := FilePathJoin(fs, tmpDir, "test"+ext)
+				outDir := FilePathJoin(fs, tmpDir, "output")
+				tree := GenerateTestFileTree(t, fs, testDir, "", false, time.Now().Add(-3*time.Second), time.Now())
+				require.NoError(t, fs.Chmod(tree[len(tree)-1], 0o600))
+
+				require.NoError(t, fs.Tar(testDir, archive))
+				tree2, err := fs.Untar(archive, outDir)
+				require.NoError(t, err)
+
+				relativeSrcTree, err := fs.ConvertToRelativePath(testDir, tree...)
+				require.NoError(t, err)
+				relativeResultTree, err := fs.ConvertToRelativePath(outDir, tree2...)
+				require.NoError(t, err)
+				sort.Strings(relativeSrcTree)
+				sort.Strings(relativeResultTree)
+				require.Equal(t, relativeSrcTree, relativeResultTree)
+
+				hasher, err := NewFileHash(hashing.HashXXHash)
+				require.NoError(t, err)
+				for _, path := range relativeSrcTree {
+					srcFilePath := FilePathJoin(fs, testDir, path)
+					fileinfoSrc, err := fs.Lstat(srcFilePath)
+					require.NoError(t, err)
+					resultFilePath := FilePathJoin(fs, outDir, path)
+					fileinfoResult, err := fs.Lstat(resultFilePath)
+					require.NoError(t, err)
+					assert.Equal(t, fileinfoSrc.Mode(), fileinfoResult.Mode())
+					if fs.GetType() != InMemoryFS {
+						// Unlike zip, tar timestamps have a one-second resolution at worst.
+						assert.True(t, math.Abs(fileinfoSrc.ModTime().Sub(fileinfoResult.ModTime()).Seconds()) <= 1)
+					}
+					if IsRegularFile(fileinfoSrc) {
+						assert.Equal(t, fileinfoSrc.Size(), fileinfoResult.Size())
+						hashSrc, err := hasher.CalculateFile(fs, srcFilePath)
+						require.NoError(t, err)
+						hashResult, err := hasher.CalculateFile(fs, resultFilePath)
+						require.NoError(t, err)
+						assert.Equal(t, hashSrc, hashResult)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTar_SingleFile(t *testing.T) {
+	fs := NewFs(InMemoryFS)
+	tmpDir, err := fs.TempDirInTempDir("temp")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(tmpDir) }()
+	content := faker.Paragraph()
+	src := FilePathJoin(fs, tmpDir, "test.txt")
+	require.NoError(t, fs.WriteFile(src, []byte(content), 0o644))
+	archive := FilePathJoin(fs, tmpDir, "test.tgz")
+	require.NoError(t, fs.Tar(src, archive))
+	outDir := FilePathJoin(fs, tmpDir, "output")
+	files, err := fs.Untar(archive, outDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, FilePathJoin(fs, outDir, "test.txt"), files[0])
+	result, err := fs.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, content, string(result))
+}
+
+func TestArchive_CompressionLevel(t *testing.T) {
+	fs := NewFs(InMemoryFS)
+	tmpDir, err := fs.TempDirInTempDir("temp")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(tmpDir) }()
+	content := faker.Paragraph()
+	testDir := FilePathJoin(fs, tmpDir, "test")
+	require.NoError(t, fs.MkDir(testDir))
+	require.NoError(t, fs.WriteFile(FilePathJoin(fs, testDir, "test.txt"), []byte(content), 0o644))
+
+	for _, ext := range []string{zipExt, targzExt, tarzstExt} {
+		for _, level := range []int{DefaultCompressionLevel, BestSpeedCompressionLevel, 5, BestCompressionLevel} {
+			t.Run(fmt.Sprintf("level %v for extension %v", level, ext), func(t *testing.T) {
+				archive := FilePathJoin(fs, tmpDir, fmt.Sprintf("test-%v%v", level, ext))
+				outDir := FilePathJoin(fs, tmpDir, fmt.Sprintf("output-%v-%v", level, ext))
+				var files []string
+				if ext == zipExt {
+					require.NoError(t, fs.ZipWithContextAndOptions(context.Background(), testDir, archive, WithCompressionLevel(level)))
+					files, err = fs.Unzip(archive, outDir)
+				} else {
+					require.NoError(t, fs.TarWithContextAndOptions(context.Background(), testDir, archive, WithCompressionLevel(level)))
+					files, err = fs.Untar(archive, outDir)
+				}
+				require.NoError(t, err)
+				require.Len(t, files, 1)
+				result, err := fs.ReadFile(files[0])
+				require.NoError(t, err)
+				assert.Equal(t, content, string(result))
+			})
+		}
+		t.Run(fmt.Sprintf("invalid level for extension %v", ext), func(t *testing.T) {
+			archive := FilePathJoin(fs, tmpDir, "invalid"+ext)
+			errortest.AssertError(t, fs.TarWithContextAndOptions(context.Background(), testDir, archive, WithCompressionLevel(BestCompressionLevel+1)), commonerrors.ErrInvalid)
+			errortest.AssertError(t, fs.ZipWithContextAndOptions(context.Background(), testDir, archive, WithCompressionLevel(-1)), commonerrors.ErrInvalid)
+		})
+	}
+}
+
+func TestTar_Symlinks(t *testing.T) {
+	if platform.IsWindows() {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	fs := NewFs(StandardFS)
+	tmpDir, err := fs.TempDirInTempDir("test-tar-links-")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(tmpDir) }()
+	testDir := FilePathJoin(fs, tmpDir, "test")
+	require.NoError(t, fs.MkDir(FilePathJoin(fs, testDir, "sub")))
+	require.NoError(t, fs.WriteFile(FilePathJoin(fs, testDir, "target.txt"), []byte(faker.Sentence()), 0o755))
+	require.NoError(t, fs.Symlink(FilePathJoin(fs, "..", "target.txt"), FilePathJoin(fs, testDir, "sub", "link")))
+	archive := FilePathJoin(fs, tmpDir, "test.tar")
+	require.NoError(t, fs.Tar(testDir, archive))
+
+	outDir := FilePathJoin(fs, tmpDir, "output")
+	_, err = fs.Untar(archive, outDir)
+	require.NoError(t, err)
+	isLink, err := fs.IsLink(FilePathJoin(fs, outDir, "sub", "link"))
+	require.NoError(t, err)
+	assert.True(t, isLink)
+	target, err := fs.Readlink(FilePathJoin(fs, outDir, "sub", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, FilePathJoin(fs, "..", "target.txt"), target)
+	info, err := fs.Stat(FilePathJoin(fs, outDir, "target.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, 0o755, int(info.Mode().Perm()))
+}
+
+func TestTarWithExclusion(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprintf("%v_for_fs_%v", t.Name(), fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			tmpDir, err := fs.TempDirInTempDir("temp")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(tmpDir) }()
+			testDir := FilePathJoin(fs, tmpDir, "test")
+			require.NoError(t, fs.MkDir(FilePathJoin(fs, testDir, "excluded")))
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, testDir, "excluded", "file.txt"), []byte(faker.Sentence()), 0o644))
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, testDir, "file.txt"), []byte(faker.Sentence()), 0o644))
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, testDir, "file.log"), []byte(faker.Sentence()), 0o644))
+			archive := FilePathJoin(fs, tmpDir, "test.tar.gz")
+			require.NoError(t, fs.TarWithContextAndLimitsAndExclusionPatterns(context.Background(), testDir, archive, NoLimits(), ".*excluded.*", ".*[.]log"))
+			outDir := FilePathJoin(fs, tmpDir, "output")
+			files, err := fs.Untar(archive, outDir)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{FilePathJoin(fs, outDir, "file.txt")}, files)
+		})
+	}
+}
+
+func TestTar_Limits(t *testing.T) {
+	fs := NewFs(InMemoryFS)
+	tmpDir, err := fs.TempDirInTempDir("temp")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(tmpDir) }()
+	testDir := FilePathJoin(fs, tmpDir, "test")
+	_ = GenerateTestFileTree(t, fs, testDir, "", false, time.Now(), time.Now())
+	archive := FilePathJoin(fs, tmpDir, "test.tar")
+
+	err = fs.TarWithContextAndLimits(context.Background(), testDir, archive, nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	err = fs.TarWithContextAndLimits(context.Background(), testDir, archive, NewLimits(1, 1e10, 1e6, -1, false))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	err = fs.TarWithContextAndLimits(context.Background(), testDir, archive, NewLimits(1e10, 1e10, 1, -1, false))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	require.NoError(t, fs.TarWithContextAndLimits(context.Background(), testDir, archive, DefaultLimits()))
+
+	outDir := FilePathJoin(fs, tmpDir, "output")
+	_, err = fs.UntarWithContextAndLimits(context.Background(), archive, outDir, NewLimits(1e10, 1e10, 1, -1, false))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	_, err = fs.UntarWithContextAndLimits(context.Background(), archive, outDir, NewLimits(1e10, 1, 1e6, -1, false))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	_, err = fs.UntarWithContextAndLimits(context.Background(), archive, outDir, NewLimits(1e10, 1e10, 1e6, 0, false))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	_, err = fs.UntarWithContextAndLimits(context.Background(), archive, outDir, DefaultLimits())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fs.UntarWithContextAndLimits(ctx, archive, outDir, DefaultLimits())
+	errortest.AssertError(t, err, commonerrors.ErrCancelled)
+}
+
+func TestUntar_Malicious(t *testing.T) {
+	fs := NewFs(StandardFS)
+	tmpDir, err := fs.TempDirInTempDir("test-untar-malicious-")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(tmpDir) }()
+
+	tests := []struct {
+		name    string
+		entries []testTarEntry
+	}{
+		{
+			name:    "path traversal",
+			entries: []testTarEntry{{header: tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg}, content: faker.Sentence()}},
+		},
+		{
+			name:    "symlink with relative target outside destination",
+			entries: []testTarEntry{{header: tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}}},
+		},
+		{
+			name:    "symlink with absolute target outside destination",
+			entries: []testTarEntry{{header: tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}}},
+		},
+		{
+			name:    "hard link outside destination",
+			entries: []testTarEntry{{header: tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"}}},
+		},
+		{
+			name: "symlink chain leading outside destination",
+			entries: []testTarEntry{
+				{header: tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."}},
+				{header: tar.Header{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/.."}},
+				{header: tar.Header{Name: "b/escaped.txt", Typeflag: tar.TypeReg}, content: faker.Sentence()},
+			},
+		},
+		{
+			name: "symlink through a symlink to a parent directory",
+			entries: []testTarEntry{
+				{header: tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}},
+				{header: tar.Header{Name: "dir/up", Typeflag: tar.TypeSymlink, Linkname: ".."}},
+				{header: tar.Header{Name: "outside", Typeflag: tar.TypeSymlink, Linkname: "dir/up/.."}},
+				{header: tar.Header{Name: "outside/escaped.txt", Typeflag: tar.TypeReg}, content: faker.Sentence()},
+			},
+		},
+		{
+			name: "hard link through a symlink to a parent directory",
+			entries: []testTarEntry{
+				{header: tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."}},
+				{header: tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "a/../escaped.txt"}},
+			},
+		},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			archive := FilePathJoin(fs, tmpDir, fmt.Sprintf("malicious%v.tar", i))
+			createTestTar(t, fs, archive, test.entries...)
+			_, err := fs.Untar(archive, FilePathJoin(fs, tmpDir, fmt.Sprintf("output%v", i)))
+			errortest.AssertError(t, err, commonerrors.ErrMalicious)
+			assert.False(t, fs.Exists(FilePathJoin(fs, tmpDir, "escaped.txt")))
+		})
+	}
+}
+
+func TestUntar_ThroughSymlinks(t *testing.T) {
+	if platform.IsWindows() {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	fs := NewFs(StandardFS)
+	// the archive contains a symbolic link loop which fs.Rm would follow.
+	tmpDir := t.TempDir()
+	archive := FilePathJoin(fs, tmpDir, "links.tar")
+	content := faker.Sentence()
+	createTestTar(t, fs, archive,
+		testTarEntry{header: tar.Header{Name: "real/", Typeflag: tar.TypeDir, Mode: 0o755}},
+		testTarEntry{header: tar.Header{Name: "real/up", Typeflag: tar.TypeSymlink, Linkname: ".."}},
+		testTarEntry{header: tar.Header{Name: "alias", Typeflag: tar.TypeSymlink, Linkname: "real/up/real"}},
+		testTarEntry{header: tar.Header{Name: "alias/file.txt", Typeflag: tar.TypeReg}, content: content},
+	)
+	outDir := FilePathJoin(fs, tmpDir, "output")
+	_, err := fs.Untar(archive, outDir)
+	require.NoError(t, err)
+	result, err := fs.ReadFile(FilePathJoin(fs, outDir, "real", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(result))
+}
+
+func TestUntar_Nested(t *testing.T) {
+	fs := NewFs(InMemoryFS)
+	tmpDir, err := fs.TempDirInTempDir("temp")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(tmpDir) }()
+
+	nestedDir := FilePathJoin(fs, tmpDir, "nested")
+	require.NoError(t, fs.MkDir(nestedDir))
+	require.NoError(t, fs.WriteFile(FilePathJoin(fs, nestedDir, "nested.txt"), []byte(faker.Sentence()), 0o644))
+	parentDir := FilePathJoin(fs, tmpDir, "parent")
+	require.NoError(t, fs.MkDir(parentDir))
+	require.NoError(t, fs.WriteFile(FilePathJoin(fs, parentDir, "parent.txt"), []byte(faker.Sentence()), 0o644))
+	require.NoError(t, fs.Tar(nestedDir, FilePathJoin(fs, parentDir, "child.tar.gz")))
+	archive := FilePathJoin(fs, tmpDir, "parent.tar")
+	require.NoError(t, fs.Tar(parentDir, archive))
+
+	outDir := FilePathJoin(fs, tmpDir, "output")
+	files, err := fs.UntarWithContextAndLimits(context.Background(), archive, outDir, NewLimits(1e10, 1e10, 1e6, 10, true))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{FilePathJoin(fs, outDir, "parent.txt"), FilePathJoin(fs, outDir, "child", "nested.txt")}, files)
+	assert.False(t, fs.Exists(FilePathJoin(fs, outDir, "child.tar.gz")))
+
+	outDir2 := FilePathJoin(fs, tmpDir, "output2")
+	files, err = fs.UntarWithContextAndLimits(context.Background(), archive, outDir2, NewLimits(1e10, 1e10, 1e6, 10, false))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{FilePathJoin(fs, outDir2, "parent.txt"), FilePathJoin(fs, outDir2, "child.tar.gz")}, files)
+}
+
+func TestUntarFromReader(t *testing.T) {
+	for _, ext := range []string{tarExt, targzExt, tarzstExt} {
+		t.Run(fmt.Sprintf("%v_for_extension_%v", t.Name(), ext), func(t *testing.T) {
+			fs := NewFs(InMemoryFS)
+			tmpDir, err := fs.TempDirInTempDir("temp")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(tmpDir) }()
+			testDir := FilePathJoin(fs, tmpDir, "test")
+			tree := GenerateTestFileTree(t, fs, testDir, "", false, time.Now(), time.Now())
+			archive := FilePathJoin(fs, tmpDir, "test"+ext)
+			require.NoError(t, fs.Tar(testDir, archive))
+			content, err := fs.ReadFile(archive)
+			require.NoError(t, err)
+
+			_, err = fs.UntarFromReaderWithContextAndLimits(context.Background(), nil, tmpDir, NoLimits())
+			errortest.AssertError(t, err, commonerrors.ErrUndefined)
+			_, err = fs.UntarFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), tmpDir, nil)
+			errortest.AssertError(t, err, commonerrors.ErrUndefined)
+			_, err = fs.UntarFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), FilePathJoin(fs, tmpDir, "too-large"), NewLimits(int64(len(content)-1), 1e10, 1e6, -1, false))
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			_, err = fs.UntarFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), FilePathJoin(fs, tmpDir, "too-many"), NewLimits(1e10, 1e10, 1, -1, false))
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = fs.UntarFromReaderWithContextAndLimits(ctx, bytes.NewReader(content), FilePathJoin(fs, tmpDir, "cancelled"), DefaultLimits())
+			errortest.AssertError(t, err, commonerrors.ErrCancelled)
+
+			outDir := FilePathJoin(fs, tmpDir, "output")
+			fileList, err := fs.UntarFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), outDir, NewLimits(int64(len(content)), 1e10, 1e6, -1, false))
+			require.NoError(t, err)
+			relativeSrcTree, err := fs.ConvertToRelativePath(testDir, tree...)
+			require.NoError(t, err)
+			relativeResultTree, err := fs.ConvertToRelativePath(outDir, fileList...)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, relativeSrcTree, relativeResultTree)
+		})
+	}
+}
+
+func TestUntarFromReader_Malicious(t *testing.T) {
+	if platform.IsWindows() {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	fs := NewFs(StandardFS)
+	tmpDir := t.TempDir()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	entries := []testTarEntry{
+		{header: tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."}},
+		{header: tar.Header{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/.."}},
+		{header: tar.Header{Name: "b/escaped.txt", Typeflag: tar.TypeReg}, content: faker.Sentence()},
+	}
+	for i := range entries {
+		header := entries[i].header
+		header.Size = int64(len(entries[i].content))
+		require.NoError(t, w.WriteHeader(&header))
+		_, err := w.Write([]byte(entries[i].content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	_, err := fs.UntarFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(buf.Bytes()), FilePathJoin(fs, tmpDir, "output"), NoLimits())
+	errortest.AssertError(t, err, commonerrors.ErrMalicious)
+	assert.False(t, fs.Exists(FilePathJoin(fs, tmpDir, "escaped.txt")))
+}
@@ -0,0 +1,602 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+// TreeOperationOptions defines how operations on whole file trees (copy, removal, permission changes, hashing) are performed.
+type TreeOperationOptions struct {
+	// workers is the maximum number of items processed concurrently.
+	workers int
+	// exclusionPatterns lists the patterns of items which should be ignored.
+	exclusionPatterns []string
+	limits            ILimits
+	// preserveXattrs states whether extended attributes of copied items should be copied too.
+	preserveXattrs bool
+	// preserveSymlinks states whether symbolic links should be copied as links rather than followed.
+	preserveSymlinks bool
+	// preserveModes states whether modes and modification times of copied items should be preserved.
+	preserveModes bool
+	// contentOnly states whether the content of a source directory should be copied rather than the directory itself.
+	contentOnly bool
+}
+
+// TreeOperationOption configures TreeOperationOptions.
+type TreeOperationOption func(*TreeOperationOptions) *TreeOperationOptions
+
+// DefaultTreeOperationOptions returns the default tree operation options i.e. items are processed sequentially, nothing is excluded and no limits apply.
+func DefaultTreeOperationOptions() *TreeOperationOptions {
+	return &TreeOperationOptions{workers: 1, limits: NoLimits()}
+}
+
+// WithTreeOperationOptions returns the tree operation options resulting from applying options to the defaults.
+func WithTreeOperationOptions(options ...TreeOperationOption) (opts *TreeOperationOptions) {
+	opts = DefaultTreeOperationOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithWorkers processes up to `workers` items of the tree concurrently. If workers is not strictly positive, the number of CPUs is used.
+func WithWorkers(workers int) TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		o.workers = workers
+		return o
+	}
+}
+
+// WithTreeExclusionPatterns ignores any item matching an exclusion pattern.
+func WithTreeExclusionPatterns(exclusionPatterns ...string) TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		o.exclusionPatterns = append(o.exclusionPatterns, exclusionPatterns...)
+		return o
+	}
+}
+
+// WithXattrPreservation copies the extended attributes (and hence, ACLs) of items along with their content. Sources which do not support extended attributes are considered as not having any.
+func WithXattrPreservation() TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		o.preserveXattrs = true
+		return o
+	}
+}
+
+// WithSymlinkPreservation copies symbolic links as links rather than following them. Similarly to archive extraction, links with an absolute target or leading outside the tree copied are rejected with commonerrors.ErrMalicious before anything is copied.
+func WithSymlinkPreservation() TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		o.preserveSymlinks = true
+		return o
+	}
+}
+
+// WithModePreservation preserves the modes of copied files and directories as well as the modification times of copied files. Directory modes are applied once their content has been copied so that read-only directories can be copied.
+func WithModePreservation() TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		o.preserveModes = true
+		return o
+	}
+}
+
+// WithTreeContentCopy copies the content of a source directory directly into the destination (similarly to `cp -r src/. dest`) rather than the directory itself. The destination directory is created if it does not exist but its mode is never changed.
+func WithTreeContentCopy() TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		o.contentOnly = true
+		return o
+	}
+}
+
+// WithTreeLimits limits the number and size of files an operation can process. Limits are checked before any change is made.
+func WithTreeLimits(limits ILimits) TreeOperationOption {
+	return func(o *TreeOperationOptions) *TreeOperationOptions {
+		if o == nil {
+			o = DefaultTreeOperationOptions()
+		}
+		if limits == nil {
+			limits = NoLimits()
+		}
+		o.limits = limits
+		return o
+	}
+}
+
+// treeItem describes an item of a file tree.
+type treeItem struct {
+	path string
+	// relativePath is the path of the item relative to the root of the tree, using `/` as separator.
+	relativePath string
+	// target is the path the item should be copied to, if any.
+	target string
+	info   os.FileInfo
+}
+
+func (i *treeItem) depth() int {
+	if i.relativePath == "." {
+		return 0
+	}
+	return strings.Count(i.relativePath, "/") + 1
+}
+
+// listTreeItems lists the items of a tree which are not excluded, parents first. Symbolic links are not followed.
+func listTreeItems(ctx context.Context, fs FS, root string, exclusionPatterns []string) (items []*treeItem, err error) {
+	cleanRoot := FilePathClean(fs, root)
+	err = fs.WalkWithContextAndExclusionPatterns(ctx, root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, subErr := FilePathRel(fs, cleanRoot, FilePathClean(fs, path))
+		if subErr != nil {
+			return subErr
+		}
+		items = append(items, &treeItem{path: path, relativePath: FilePathToSlash(fs, rel), info: info})
+		return nil
+	}, exclusionPatterns...)
+	err = ConvertFileSystemError(err)
+	return
+}
+
+// checkTreeLimits checks that the files of a tree are within limits.
+func checkTreeLimits(fs FS, limits ILimits, items []*treeItem) error {
+	if !limits.Apply() {
+		return nil
+	}
+	var fileCount int64
+	var totalSize uint64
+	for i := range items {
+		info := items[i].info
+		if IsSymLink(info) {
+			linked, err := fs.Stat(items[i].path)
+			if err != nil {
+				return ConvertFileSystemError(err)
+			}
+			info = linked
+		}
+		if info.IsDir() {
+			continue
+		}
+		size := info.Size()
+		if size > limits.GetMaxFileSize() {
+			return commonerrors.Newf(commonerrors.ErrTooLarge, "file [%v] is too big (%v B) and beyond limits (max: %v B)", items[i].path, size, limits.GetMaxFileSize())
+		}
+		fileCount++
+		if size > 0 {
+			totalSize += uint64(size)
+		}
+	}
+	if fileCount > limits.GetMaxFileCount() {
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "too many files to process (%v) and beyond limits (max: %v)", fileCount, limits.GetMaxFileCount())
+	}
+	if totalSize > limits.GetMaxTotalSize() {
+		return commonerrors.Newf(commonerrors.ErrTooLarge, "too much data to process (%v B) and beyond limits (max: %v B)", totalSize, limits.GetMaxTotalSize())
+	}
+	return nil
+}
+
+// hashTreeFile calculates the hash of a file of a tree. Hashes are stateful and so, a new one is created for every file as files may be hashed concurrently.
+func hashTreeFile(ctx context.Context, fs FS, hashAlgo string, path string) (hash string, err error) {
+	hasher, err := NewFileHash(hashAlgo)
+	if err != nil {
+		return
+	}
+	hash, err = hasher.CalculateFileWithContext(ctx, fs, path)
+	return
+}
+
+// processTreeItems applies fn to every item using up to `workers` workers. All items are processed even if some fail and errors are collated in the order of the items so that the result does not depend on scheduling.
+func processTreeItems(ctx context.Context, workers int, items []*treeItem, fn func(ctx context.Context, item *treeItem) error) (err error) {
+	if len(items) == 0 {
+		return
+	}
+	errs := make([]error, len(items))
+	options := []parallelisation.StoreOption{parallelisation.ExecuteAll}
+	if workers > 1 {
+		options = append(options, parallelisation.Workers(workers))
+	} else {
+		options = append(options, parallelisation.Sequential)
+	}
+	group := parallelisation.NewOrderedExecutionGroup[*treeItem](func(ctx context.Context, index int, item *treeItem) error {
+		errs[index] = fn(ctx, item)
+		return errs[index]
+	}, options...)
+	group.RegisterFunction(items...)
+	_ = group.Execute(ctx)
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	var failures []error
+	for i := range errs {
+		if errs[i] != nil {
+			failures = append(failures, errs[i])
+		}
+	}
+	err = commonerrors.Join(failures...)
+	return
+}
+
+// splitTreeItems separates directories from other items. Directories are grouped by depth, deepest first.
+func splitTreeItems(items []*treeItem) (files []*treeItem, directoriesPerDepth [][]*treeItem) {
+	perDepth := map[int][]*treeItem{}
+	for i := range items {
+		if items[i].info.IsDir() {
+			depth := items[i].depth()
+			perDepth[depth] = append(perDepth[depth], items[i])
+		} else {
+			files = append(files, items[i])
+		}
+	}
+	depths := make([]int, 0, len(perDepth))
+	for depth := range perDepth {
+		depths = append(depths, depth)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+	for i := range depths {
+		directoriesPerDepth = append(directoriesPerDepth, perDepth[depths[i]])
+	}
+	return
+}
+
+func (fs *VFS) CopyWithContextAndOptions(ctx context.Context, src string, dest string, options ...TreeOperationOption) error {
+	return CopyBetweenFSWithOptions(ctx, fs, src, fs, dest, options...)
+}
+
+// CopyBetweenFSWithOptions copies files and directories similarly to CopyBetweenFSWithExclusionPatterns but files may be copied concurrently (see WithWorkers) and limits are checked before anything is copied.
+// Directories are created beforehand, parents first.
+func CopyBetweenFSWithOptions(ctx context.Context, srcFs FS, src string, destFs FS, dest string, options ...TreeOperationOption) (err error) {
+	if srcFs == nil || destFs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	opts := WithTreeOperationOptions(options...)
+	err = opts.limits.Validate()
+	if err != nil {
+		return
+	}
+	exclusionSrcFsRegexes, err := NewExclusionRegexList(srcFs.PathSeparator(), opts.exclusionPatterns...)
+	if err != nil {
+		return
+	}
+	exclusionDestFsRegexes, err := NewExclusionRegexList(destFs.PathSeparator(), opts.exclusionPatterns...)
+	if err != nil {
+		return
+	}
+	if IsPathExcluded(src, exclusionSrcFsRegexes...) || IsPathExcluded(dest, exclusionDestFsRegexes...) {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if srcFs == destFs && src == dest {
+		return
+	}
+	var items []*treeItem
+	if isDir, _ := srcFs.IsDir(src); isDir {
+		items, err = listTreeItems(ctx, srcFs, src, opts.exclusionPatterns)
+		if err != nil {
+			return
+		}
+	} else if srcInfo, subErr := srcFs.Lstat(src); subErr == nil {
+		items = []*treeItem{{path: src, relativePath: ".", info: srcInfo}}
+	}
+	err = checkTreeLimits(srcFs, opts.limits, items)
+	if err != nil {
+		return
+	}
+	if opts.preserveSymlinks {
+		err = checkTreeSymlinks(srcFs, src, items)
+		if err != nil {
+			return
+		}
+	}
+	dst, isSrcDir, err := prepareCopyDestination(srcFs, src, destFs, dest)
+	if err != nil {
+		return
+	}
+	if !isSrcDir {
+		err = copyFileBetweenFSWithExclusionPatternsWithExclusionRegexes(ctx, srcFs, src, destFs, dst, exclusionSrcFsRegexes, exclusionDestFsRegexes)
+		if err == nil && len(items) == 1 && !IsSymLink(items[0].info) {
+			err = copyTreeItemMetadata(srcFs, items[0].path, items[0].info, destFs, dst, opts)
+		}
+		return
+	}
+	if opts.contentOnly {
+		dst = dest
+	}
+	var files []*treeItem
+	var directories []*treeItem
+	for i := range items {
+		item := items[i]
+		destPath := dst
+		if item.relativePath != "." {
+			destPath = FilePathJoin(destFs, dst, FilePathFromSlash(destFs, item.relativePath))
+		}
+		if IsPathExcluded(destPath, exclusionDestFsRegexes...) {
+			continue
+		}
+		if item.info.IsDir() {
+			// Directories are listed parents first and so, can be created in order.
+			err = destFs.MkDir(destPath)
+			if err != nil {
+				return
+			}
+			if opts.contentOnly && item.relativePath == "." {
+				continue
+			}
+			if opts.preserveXattrs {
+				err = CopyXattrs(srcFs, item.path, destFs, destPath)
+				if err != nil {
+					return
+				}
+			}
+			item.target = destPath
+			directories = append(directories, item)
+			continue
+		}
+		item.target = destPath
+		files = append(files, item)
+	}
+	err = processTreeItems(ctx, opts.workers, files, func(subCtx context.Context, item *treeItem) error {
+		if IsSymLink(item.info) {
+			if opts.preserveSymlinks {
+				return copySymlinkBetweenFS(srcFs, item.path, destFs, item.target)
+			}
+			// Links are followed as done by CopyBetweenFSWithExclusionPatterns.
+			return CopyBetweenFSWithExclusionRegexes(subCtx, srcFs, item.path, destFs, item.target, exclusionSrcFsRegexes, exclusionDestFsRegexes)
+		}
+		subErr := copyFileBetweenFSWithExclusionPatternsWithExclusionRegexes(subCtx, srcFs, item.path, destFs, item.target, exclusionSrcFsRegexes, exclusionDestFsRegexes)
+		if subErr != nil {
+			return subErr
+		}
+		return copyTreeItemMetadata(srcFs, item.path, item.info, destFs, item.target, opts)
+	})
+	if err != nil || !opts.preserveModes {
+		return
+	}
+	// Directory modes are applied children first, once their content has been copied, in case they are read-only.
+	for i := len(directories) - 1; i >= 0; i-- {
+		err = destFs.Chmod(directories[i].target, directories[i].info.Mode().Perm())
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// copyTreeItemMetadata copies the metadata of a file according to the options i.e. its extended attributes, mode and modification time.
+func copyTreeItemMetadata(srcFs FS, src string, srcInfo os.FileInfo, destFs FS, dest string, opts *TreeOperationOptions) (err error) {
+	if opts.preserveXattrs {
+		// Extended attributes are copied before the mode is changed in case the file is read-only.
+		err = CopyXattrs(srcFs, src, destFs, dest)
+		if err != nil {
+			return
+		}
+	}
+	if !opts.preserveModes {
+		return
+	}
+	err = destFs.Chmod(dest, srcInfo.Mode().Perm())
+	if err != nil {
+		return
+	}
+	err = destFs.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime())
+	return
+}
+
+// copySymlinkBetweenFS creates a symbolic link at `dest` with the same target as the link at `src`.
+func copySymlinkBetweenFS(srcFs FS, src string, destFs FS, dest string) (err error) {
+	linkTarget, err := srcFs.Readlink(src)
+	if err != nil {
+		return
+	}
+	err = destFs.Symlink(FilePathFromSlash(destFs, FilePathToSlash(srcFs, linkTarget)), dest)
+	return
+}
+
+// checkTreeSymlinks checks that none of the symbolic links of a tree has an absolute target or leads outside the tree once resolved.
+func checkTreeSymlinks(fs FS, root string, items []*treeItem) (err error) {
+	cleanRoot := FilePathClean(fs, root)
+	for i := range items {
+		item := items[i]
+		if item.relativePath == "." || !IsSymLink(item.info) {
+			continue
+		}
+		linkTarget, subErr := fs.Readlink(item.path)
+		if subErr != nil {
+			err = subErr
+			return
+		}
+		if FilePathIsAbs(fs, linkTarget) {
+			err = commonerrors.Newf(commonerrors.ErrMalicious, "symbolic link [%v] has an absolute target '%s'", item.relativePath, linkTarget)
+			return
+		}
+		subErr = checkSymlinkWithinDestination(fs, FilePathJoin(fs, cleanRoot, FilePathFromSlash(fs, item.relativePath)), linkTarget, cleanRoot)
+		if subErr != nil {
+			err = commonerrors.WrapErrorf(commonerrors.ErrMalicious, subErr, "symbolic link [%v] points outside the tree '%s'", item.relativePath, root)
+			return
+		}
+	}
+	return
+}
+
+func (fs *VFS) RemoveWithContextAndOptions(ctx context.Context, dir string, options ...TreeOperationOption) (err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	opts := WithTreeOperationOptions(options...)
+	if dir == "" || !fs.Exists(dir) {
+		return
+	}
+	isDir, err := fs.IsDir(dir)
+	if err != nil || !isDir {
+		if err == nil && !IsPathExcludedFromPatterns(dir, fs.PathSeparator(), opts.exclusionPatterns...) {
+			err = fs.removeItem(dir)
+		}
+		return
+	}
+	items, err := listTreeItems(ctx, fs, dir, opts.exclusionPatterns)
+	if err != nil {
+		return
+	}
+	files, directoriesPerDepth := splitTreeItems(items)
+	err = processTreeItems(ctx, opts.workers, files, func(_ context.Context, item *treeItem) error {
+		return fs.removeItem(item.path)
+	})
+	if err != nil {
+		return
+	}
+	// Directories are removed deepest first and only if empty as some of their items may have been excluded.
+	for i := range directoriesPerDepth {
+		err = processTreeItems(ctx, opts.workers, directoriesPerDepth[i], func(_ context.Context, item *treeItem) error {
+			if IsPathExcludedFromPatterns(item.path, fs.PathSeparator(), opts.exclusionPatterns...) {
+				return nil
+			}
+			empty, subErr := fs.IsEmpty(item.path)
+			if subErr != nil || !empty {
+				return subErr
+			}
+			return fs.removeItem(item.path)
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (fs *VFS) removeItem(path string) (err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	err = ConvertFileSystemError(fs.vfs.Remove(path))
+	if commonerrors.Any(err, commonerrors.ErrNotFound) {
+		err = nil
+	}
+	return
+}
+
+func (fs *VFS) ChmodRecursivelyWithOptions(ctx context.Context, path string, mode os.FileMode, options ...TreeOperationOption) (err error) {
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	opts := WithTreeOperationOptions(options...)
+	if isFile, _ := fs.IsFile(path); isFile {
+		if !IsPathExcludedFromPatterns(path, fs.PathSeparator(), opts.exclusionPatterns...) {
+			err = fs.Chmod(path, mode)
+		}
+		return
+	}
+	items, err := listTreeItems(ctx, fs, path, opts.exclusionPatterns)
+	if err != nil {
+		return
+	}
+	// Files are processed before directories, deepest directories first, so that the tree can still be traversed if the new mode prevents it.
+	files, directoriesPerDepth := splitTreeItems(items)
+	chmod := func(_ context.Context, item *treeItem) error {
+		return fs.Chmod(item.path, mode)
+	}
+	err = processTreeItems(ctx, opts.workers, files, chmod)
+	if err != nil {
+		return
+	}
+	for i := range directoriesPerDepth {
+		err = processTreeItems(ctx, opts.workers, directoriesPerDepth[i], chmod)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (fs *VFS) FileHashesWithContext(ctx context.Context, hashAlgo string, root string, options ...TreeOperationOption) (hashes map[string]string, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	opts := WithTreeOperationOptions(options...)
+	err = opts.limits.Validate()
+	if err != nil {
+		return
+	}
+	// Checking the algorithm is supported before going through the tree.
+	_, err = NewFileHash(hashAlgo)
+	if err != nil {
+		return
+	}
+	isDir, err := fs.IsDir(root)
+	if err != nil {
+		return
+	}
+	if !isDir {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "path [%v] is not a directory", root)
+		return
+	}
+	items, err := listTreeItems(ctx, fs, root, opts.exclusionPatterns)
+	if err != nil {
+		return
+	}
+	var files []*treeItem
+	for i := range items {
+		if items[i].info.Mode().IsRegular() {
+			files = append(files, items[i])
+		}
+	}
+	err = checkTreeLimits(fs, opts.limits, files)
+	if err != nil {
+		return
+	}
+	fileHashes := make([]string, len(files))
+	index := make(map[*treeItem]int, len(files))
+	for i := range files {
+		index[files[i]] = i
+	}
+	err = processTreeItems(ctx, opts.workers, files, func(subCtx context.Context, item *treeItem) error {
+		hash, subErr := hashTreeFile(subCtx, fs, hashAlgo, item.path)
+		if subErr != nil {
+			return subErr
+		}
+		fileHashes[index[item]] = hash
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	hashes = make(map[string]string, len(files))
+	for i := range files {
+		hashes[files[i].relativePath] = fileHashes[i]
+	}
+	return
+}
@@ -0,0 +1,222 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+	"github.com/ARM-software/golang-utils/utils/hashing"
+)
+
+func createTreeOperationTestTree(t *testing.T, fs FS) (root string) {
+	t.Helper()
+	root = createTestFileTree(t, fs, faker.Paragraph())
+	for i := 0; i < 20; i++ {
+		dir := FilePathJoin(fs, root, fmt.Sprintf("dir%v", i%4), fmt.Sprintf("subdir%v", i%3))
+		require.NoError(t, fs.MkDir(dir))
+		require.NoError(t, fs.WriteFile(FilePathJoin(fs, dir, fmt.Sprintf("file%v.txt", i)), []byte(faker.Paragraph()), 0o644))
+	}
+	return
+}
+
+func TestCopyWithContextAndOptions(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		for _, workers := range []int{1, 4, 0} {
+			t.Run(fmt.Sprintf("%v_%v workers", fsType, workers), func(t *testing.T) {
+				fs := NewFs(fsType)
+				src := createTreeOperationTestTree(t, fs)
+				dest := newSyncTestDestination(t, fs)
+				require.NoError(t, fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(workers)))
+				assertSyncedTrees(t, fs, src, fs, dest)
+
+				// Same behaviour as a sequential copy.
+				expected := newSyncTestDestination(t, fs)
+				require.NoError(t, fs.CopyWithContextAndExclusionPatterns(context.Background(), src, expected, ".*[.]tmp"))
+				require.NoError(t, fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(workers), WithTreeExclusionPatterns(".*[.]tmp")))
+				assertSyncedTrees(t, fs, expected, fs, FilePathJoin(fs, dest, FilePathBase(fs, src)))
+
+				file := FilePathJoin(fs, src, "file.txt")
+				require.NoError(t, fs.CopyWithContextAndOptions(context.Background(), file, dest, WithWorkers(workers)))
+				assertSyncedTrees(t, fs, file, fs, FilePathJoin(fs, dest, "file.txt"))
+			})
+		}
+	}
+}
+
+func TestCopyWithContextAndOptions_Limits(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	src := createTreeOperationTestTree(t, fs)
+	dest := newSyncTestDestination(t, fs)
+
+	err := fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(4), WithTreeLimits(NewLimits(5, 1024*1024, 1000, 1, false)))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	assert.False(t, fs.Exists(dest))
+	err = fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(4), WithTreeLimits(NewLimits(1024*1024, 1024*1024, 2, 1, false)))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	assert.False(t, fs.Exists(dest))
+	err = fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(4), WithTreeLimits(NewLimits(1024*1024, 1024*1024, 2, 1, false)), WithTreeExclusionPatterns(".*[.]txt"))
+	require.NoError(t, err)
+	assertSyncedTrees(t, fs, src, fs, dest, ".*[.]txt")
+}
+
+func TestCopyWithContextAndOptions_Preservation(t *testing.T) {
+	printWarningOnWindows(t)
+	fs := NewStandardFileSystem()
+	src := createTestFileTree(t, fs, faker.Paragraph())
+	file := FilePathJoin(fs, src, "dir", "file.txt")
+	require.NoError(t, fs.WriteFile(file, []byte(faker.Paragraph()), 0o640))
+	require.NoError(t, fs.Chmod(file, 0o640))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, fs.Chtimes(file, modTime, modTime))
+	readOnlyDir := FilePathJoin(fs, src, "readonly")
+	require.NoError(t, fs.MkDir(readOnlyDir))
+	require.NoError(t, fs.WriteFile(FilePathJoin(fs, readOnlyDir, "file.txt"), []byte(faker.Paragraph()), 0o644))
+	require.NoError(t, fs.Chmod(readOnlyDir, 0o555))
+	t.Cleanup(func() { _ = fs.Chmod(readOnlyDir, 0o755) })
+	err := fs.Symlink(FilePathJoin(fs, "..", "file.txt"), FilePathJoin(fs, src, "dir", "link.txt"))
+	skipIfLinksNotSupported(t, err)
+
+	dest := newSyncTestDestination(t, fs)
+	require.NoError(t, fs.MkDir(dest))
+	require.NoError(t, fs.Chmod(dest, 0o700))
+	t.Cleanup(func() { _ = fs.ChmodRecursively(context.Background(), dest, 0o755) })
+	require.NoError(t, fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(4), WithTreeContentCopy(), WithSymlinkPreservation(), WithModePreservation()))
+
+	info, err := fs.Lstat(dest)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	info, err = fs.Lstat(FilePathJoin(fs, dest, "dir", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	assert.True(t, modTime.Equal(info.ModTime()))
+	info, err = fs.Lstat(FilePathJoin(fs, dest, "readonly"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o555), info.Mode().Perm())
+	assert.True(t, fs.Exists(FilePathJoin(fs, dest, "readonly", "file.txt")))
+	link, err := fs.Readlink(FilePathJoin(fs, dest, "dir", "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, FilePathJoin(fs, "..", "file.txt"), link)
+
+	t.Run("links leading outside the tree", func(t *testing.T) {
+		for i, target := range []string{FilePathJoin(fs, "..", "..", "outside.txt"), FilePathJoin(fs, src, "file.txt")} {
+			escapingSrc := createTestFileTree(t, fs, faker.Paragraph())
+			require.NoError(t, fs.Symlink(target, FilePathJoin(fs, escapingSrc, "dir", fmt.Sprintf("escaping%v.txt", i))))
+			escapingDest := newSyncTestDestination(t, fs)
+			err := fs.CopyWithContextAndOptions(context.Background(), escapingSrc, escapingDest, WithSymlinkPreservation())
+			errortest.AssertError(t, err, commonerrors.ErrMalicious)
+			assert.False(t, fs.Exists(escapingDest))
+		}
+	})
+}
+
+func TestTreeOperations_DeterministicErrors(t *testing.T) {
+	fs, injector, root := newTestFaultInjectionFileSystem(t, InMemoryFS)
+	src := FilePathJoin(fs, root, "src")
+	require.NoError(t, fs.MkDir(src))
+	for i := 0; i < 30; i++ {
+		require.NoError(t, fs.WriteFile(FilePathJoin(fs, src, fmt.Sprintf("file%02d.txt", i)), []byte(faker.Sentence()), 0o644))
+	}
+	require.NoError(t, injector.AddRule(FaultRule{PathPattern: "file*[05].txt", Operations: []FileOperation{FileOperationOpen}, Err: commonerrors.ErrFailed}))
+
+	var expected string
+	for i := 0; i < 5; i++ {
+		dest := FilePathJoin(fs, root, fmt.Sprintf("dest%v", i))
+		err := fs.CopyWithContextAndOptions(context.Background(), src, dest, WithWorkers(8))
+		errortest.AssertError(t, err, commonerrors.ErrFailed)
+		if expected == "" {
+			expected = err.Error()
+		}
+		assert.Equal(t, expected, err.Error())
+		// Other files are still copied.
+		assert.True(t, fs.Exists(FilePathJoin(fs, dest, "file01.txt")))
+
+		_, err = fs.FileHashesWithContext(context.Background(), hashing.HashMd5, src, WithWorkers(8))
+		errortest.AssertError(t, err, commonerrors.ErrFailed)
+	}
+}
+
+func TestRemoveWithContextAndOptions(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root := createTreeOperationTestTree(t, fs)
+			require.NoError(t, fs.RemoveWithContextAndOptions(context.Background(), root, WithWorkers(4), WithTreeExclusionPatterns(".*[.]tmp")))
+			remaining, err := fs.LsRecursive(context.Background(), root, true)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{root, FilePathJoin(fs, root, "dir"), FilePathJoin(fs, root, "dir", "test.tmp")}, remaining)
+
+			require.NoError(t, fs.RemoveWithContextAndOptions(context.Background(), root, WithWorkers(4)))
+			assert.False(t, fs.Exists(root))
+			require.NoError(t, fs.RemoveWithContextAndOptions(context.Background(), root, WithWorkers(4)))
+		})
+	}
+}
+
+func TestChmodRecursivelyWithOptions(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root := createTreeOperationTestTree(t, fs)
+			require.NoError(t, fs.ChmodRecursivelyWithOptions(context.Background(), root, 0o700, WithWorkers(4)))
+			items, err := fs.LsRecursive(context.Background(), root, true)
+			require.NoError(t, err)
+			require.NotEmpty(t, items)
+			for _, item := range items {
+				info, err := fs.Stat(item)
+				require.NoError(t, err)
+				assert.Equal(t, 0o700, int(info.Mode().Perm()))
+			}
+		})
+	}
+}
+
+func TestFileHashesWithContext(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root := createTreeOperationTestTree(t, fs)
+			sequential, err := fs.FileHashesWithContext(context.Background(), hashing.HashSha256, root)
+			require.NoError(t, err)
+			parallel, err := fs.FileHashesWithContext(context.Background(), hashing.HashSha256, root, WithWorkers(0))
+			require.NoError(t, err)
+			assert.Equal(t, sequential, parallel)
+			assert.Len(t, sequential, 24)
+			expected, err := fs.FileHash(hashing.HashSha256, FilePathJoin(fs, root, "dir", "subdir", "file.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, expected, sequential["dir/subdir/file.txt"])
+
+			filtered, err := fs.FileHashesWithContext(context.Background(), hashing.HashSha256, root, WithWorkers(4), WithTreeExclusionPatterns(".*[.]tmp"))
+			require.NoError(t, err)
+			assert.Len(t, filtered, 23)
+			assert.NotContains(t, filtered, "dir/test.tmp")
+
+			_, err = fs.FileHashesWithContext(context.Background(), hashing.HashSha256, root, WithTreeLimits(NewLimits(1024*1024, 1024*1024, 2, 1, false)))
+			errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+			_, err = fs.FileHashesWithContext(context.Background(), hashing.HashSha256, FilePathJoin(fs, root, "file.txt"))
+			errortest.AssertError(t, err, commonerrors.ErrInvalid)
+			_, err = fs.FileHashesWithContext(context.Background(), "unknown", root)
+			errortest.AssertError(t, err, commonerrors.ErrNotFound, commonerrors.ErrUnsupported, commonerrors.ErrInvalid)
+		})
+	}
+}
+
+func TestTreeOperations_Cancellation(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	root := createTreeOperationTestTree(t, fs)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errortest.AssertError(t, fs.CopyWithContextAndOptions(ctx, root, newSyncTestDestination(t, fs), WithWorkers(4)), commonerrors.ErrCancelled)
+	errortest.AssertError(t, fs.RemoveWithContextAndOptions(ctx, root, WithWorkers(4)), commonerrors.ErrCancelled)
+	errortest.AssertError(t, fs.ChmodRecursivelyWithOptions(ctx, root, 0o700, WithWorkers(4)), commonerrors.ErrCancelled)
+	_, err := fs.FileHashesWithContext(ctx, hashing.HashMd5, root, WithWorkers(4))
+	errortest.AssertError(t, err, commonerrors.ErrCancelled)
+	assert.True(t, fs.Exists(FilePathJoin(fs, root, "file.txt")))
+}
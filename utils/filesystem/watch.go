@@ -0,0 +1,321 @@
+package filesystem
+
+import (
+	"context"
+	"iter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/parallelisation"
+)
+
+// watchPollingPeriod is the period at which file trees are scanned for changes when the filesystem does not support change notifications.
+const watchPollingPeriod = 200 * time.Millisecond
+
+// WatchOperation describes the kind of change reported by a filesystem watcher.
+type WatchOperation string
+
+const (
+	WatchCreate WatchOperation = "create"
+	WatchModify WatchOperation = "modify"
+	WatchDelete WatchOperation = "delete"
+	WatchRename WatchOperation = "rename"
+)
+
+// WatchEvent describes a change which happened in a watched file tree.
+type WatchEvent struct {
+	// Operation is the kind of change.
+	Operation WatchOperation
+	// Path is the path of the item which changed. For a rename, it corresponds to the former path of the item and a WatchCreate event is also emitted for its new path.
+	Path string
+}
+
+type watchResult struct {
+	event WatchEvent
+	err   error
+}
+
+// Watch watches the file tree rooted at root on the global filesystem and returns the sequence of changes happening to items not matching an exclusion pattern.
+// Watching starts as soon as this function returns: the goroutines and watches it relies on are only released when ctx is cancelled or when iteration over the sequence stops. Callers which do not iterate over the sequence must therefore cancel ctx.
+func Watch(ctx context.Context, root string, exclusionPatterns ...string) (iter.Seq2[WatchEvent, error], error) {
+	return globalFileSystem.Watch(ctx, root, exclusionPatterns...)
+}
+
+// Watch watches the file tree rooted at root and returns the sequence of changes happening to items not matching an exclusion pattern. See FS.Watch.
+func (fs *VFS) Watch(ctx context.Context, root string, exclusionPatterns ...string) (events iter.Seq2[WatchEvent, error], err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if !fs.Exists(root) {
+		err = commonerrors.Newf(commonerrors.ErrNotFound, "could not find path [%v] to watch", root)
+		return
+	}
+	exclusionRegex, err := NewExclusionRegexList(fs.PathSeparator(), exclusionPatterns...)
+	if err != nil {
+		return
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	results := make(chan watchResult)
+	if fs.supportsChangeNotifications() {
+		err = fs.watchWithNotifications(watchCtx, root, exclusionRegex, results)
+	} else {
+		err = fs.watchWithPolling(watchCtx, root, exclusionRegex, results)
+	}
+	if err != nil {
+		cancel()
+		return
+	}
+	events = func(yield func(WatchEvent, error) bool) {
+		defer cancel()
+		for result := range results {
+			if !yield(result.event, result.err) {
+				return
+			}
+		}
+	}
+	return
+}
+
+// supportsChangeNotifications states whether the underlying filesystem is the OS filesystem and so, whether changes can be notified by the kernel (e.g. inotify).
+func (fs *VFS) supportsChangeNotifications() bool {
+	switch fs.vfs.(type) {
+	case *ExtendedOsFs, *afero.OsFs:
+		return true
+	default:
+		return false
+	}
+}
+
+func sendWatchResult(ctx context.Context, results chan<- watchResult, result watchResult) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case results <- result:
+		return true
+	}
+}
+
+func (fs *VFS) watchWithNotifications(ctx context.Context, root string, exclusions []*regexp.Regexp, results chan<- watchResult) (err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		err = commonerrors.WrapError(commonerrors.ErrUnexpected, err, "could not create a filesystem watcher")
+		return
+	}
+	_, err = fs.addToWatcher(ctx, watcher, root, exclusions)
+	if err != nil {
+		_ = watcher.Close()
+		return
+	}
+	go func() {
+		defer close(results)
+		defer func() { _ = watcher.Close() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case watcherErr, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				if !sendWatchResult(ctx, results, watchResult{err: commonerrors.WrapError(commonerrors.ErrUnexpected, watcherErr, "filesystem watcher failure")}) {
+					return
+				}
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if IsPathExcluded(event.Name, exclusions...) {
+					continue
+				}
+				for _, result := range fs.convertNotification(ctx, watcher, event, exclusions) {
+					if !sendWatchResult(ctx, results, result) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return
+}
+
+func (fs *VFS) walkWatchedTree(ctx context.Context, root string, exclusions []*regexp.Regexp, fn filepath.WalkFunc) error {
+	info, err := fs.Lstat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	return fs.walk(ctx, root, info, exclusions, fn)
+}
+
+// addToWatcher adds a directory and all its subdirectories to the watcher and returns the items it contains.
+func (fs *VFS) addToWatcher(ctx context.Context, watcher *fsnotify.Watcher, dir string, exclusions []*regexp.Regexp) (items []string, err error) {
+	err = fs.walkWatchedTree(ctx, dir, exclusions, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			// Items may disappear in the meantime.
+			return commonerrors.Ignore(ConvertFileSystemError(walkErr), commonerrors.ErrNotFound)
+		}
+		if path != dir {
+			items = append(items, path)
+		}
+		if !info.IsDir() && path != dir {
+			return nil
+		}
+		return ConvertFileSystemError(watcher.Add(path))
+	})
+	return
+}
+
+func (fs *VFS) convertNotification(ctx context.Context, watcher *fsnotify.Watcher, event fsnotify.Event, exclusions []*regexp.Regexp) (results []watchResult) {
+	switch {
+	case event.Has(fsnotify.Create):
+		results = append(results, watchResult{event: WatchEvent{Operation: WatchCreate, Path: event.Name}})
+		if isDir, _ := fs.IsDir(event.Name); isDir {
+			// Items may have been created in the new directory before it could be watched.
+			items, err := fs.addToWatcher(ctx, watcher, event.Name, exclusions)
+			for i := range items {
+				results = append(results, watchResult{event: WatchEvent{Operation: WatchCreate, Path: items[i]}})
+			}
+			if err != nil && !commonerrors.Any(err, commonerrors.ErrNotFound, commonerrors.ErrCancelled, commonerrors.ErrTimeout) {
+				results = append(results, watchResult{err: err})
+			}
+		}
+	case event.Has(fsnotify.Remove):
+		results = append(results, watchResult{event: WatchEvent{Operation: WatchDelete, Path: event.Name}})
+	case event.Has(fsnotify.Rename):
+		results = append(results, watchResult{event: WatchEvent{Operation: WatchRename, Path: event.Name}})
+	case event.Has(fsnotify.Write), event.Has(fsnotify.Chmod):
+		results = append(results, watchResult{event: WatchEvent{Operation: WatchModify, Path: event.Name}})
+	}
+	return
+}
+
+// watchedItem records the state of an item of a file tree scanned for changes.
+type watchedItem struct {
+	isDir      bool
+	size       int64
+	mode       os.FileMode
+	modTime    time.Time
+	changeTime time.Time
+}
+
+func (i *watchedItem) hasChanged(other *watchedItem) bool {
+	return i.isDir != other.isDir || i.mode != other.mode || (!i.isDir && (i.size != other.size || !i.modTime.Equal(other.modTime) || !i.changeTime.Equal(other.changeTime)))
+}
+
+func (i *watchedItem) mayBeRenamedTo(other *watchedItem) bool {
+	return i.isDir == other.isDir && i.size == other.size && i.mode == other.mode && i.modTime.Equal(other.modTime)
+}
+
+func (fs *VFS) scanWatchedTree(ctx context.Context, root string, exclusions []*regexp.Regexp) (items map[string]*watchedItem, err error) {
+	items = map[string]*watchedItem{}
+	err = fs.walkWatchedTree(ctx, root, exclusions, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			// Items may disappear in the meantime.
+			return commonerrors.Ignore(ConvertFileSystemError(walkErr), commonerrors.ErrNotFound)
+		}
+		item := &watchedItem{
+			isDir:   info.IsDir(),
+			size:    info.Size(),
+			mode:    info.Mode(),
+			modTime: info.ModTime(),
+		}
+		if times, subErr := fs.StatTimes(path); subErr == nil && times != nil {
+			item.modTime = times.ModTime()
+			if times.HasChangeTime() {
+				item.changeTime = times.ChangeTime()
+			}
+		}
+		items[path] = item
+		return nil
+	})
+	return
+}
+
+func (fs *VFS) watchWithPolling(ctx context.Context, root string, exclusions []*regexp.Regexp, results chan<- watchResult) (err error) {
+	previous, err := fs.scanWatchedTree(ctx, root, exclusions)
+	if err != nil {
+		return
+	}
+	go func() {
+		defer close(results)
+		ticker := time.NewTicker(watchPollingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, scanErr := fs.scanWatchedTree(ctx, root, exclusions)
+			if scanErr != nil {
+				if commonerrors.Any(scanErr, commonerrors.ErrCancelled, commonerrors.ErrTimeout) {
+					return
+				}
+				if !sendWatchResult(ctx, results, watchResult{err: scanErr}) {
+					return
+				}
+				continue
+			}
+			for _, event := range diffWatchedTrees(previous, current) {
+				if !sendWatchResult(ctx, results, watchResult{event: event}) {
+					return
+				}
+			}
+			previous = current
+		}
+	}()
+	return
+}
+
+// diffWatchedTrees determines the changes between two scans of a file tree.
+// As renames cannot be detected with certainty when polling, an item is considered renamed if it disappeared while a similar one (same type, size, mode and modification time) appeared.
+func diffWatchedTrees(previous, current map[string]*watchedItem) (events []WatchEvent) {
+	var removed, created, modified []string
+	for path, item := range previous {
+		currentItem, found := current[path]
+		switch {
+		case !found:
+			removed = append(removed, path)
+		case item.hasChanged(currentItem):
+			modified = append(modified, path)
+		}
+	}
+	for path := range current {
+		if _, found := previous[path]; !found {
+			created = append(created, path)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(created)
+	sort.Strings(modified)
+	renamedTo := map[string]bool{}
+	for _, path := range removed {
+		operation := WatchDelete
+		for _, newPath := range created {
+			if !renamedTo[newPath] && previous[path].mayBeRenamedTo(current[newPath]) {
+				renamedTo[newPath] = true
+				operation = WatchRename
+				break
+			}
+		}
+		events = append(events, WatchEvent{Operation: operation, Path: path})
+	}
+	for _, path := range modified {
+		events = append(events, WatchEvent{Operation: WatchModify, Path: path})
+	}
+	for _, path := range created {
+		events = append(events, WatchEvent{Operation: WatchCreate, Path: path})
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+const watchTestTimeout = 10 * time.Second
+
+func startWatching(t *testing.T, ctx context.Context, fs FS, root string, exclusionPatterns ...string) <-chan WatchEvent {
+	t.Helper()
+	events, err := fs.Watch(ctx, root, exclusionPatterns...)
+	require.NoError(t, err)
+	received := make(chan WatchEvent, 100)
+	go func() {
+		defer close(received)
+		for event, err := range events {
+			if err == nil {
+				received <- event
+			}
+		}
+	}()
+	return received
+}
+
+func waitForWatchEvent(t *testing.T, events <-chan WatchEvent, operation WatchOperation, path string) (skipped []WatchEvent) {
+	t.Helper()
+	timeout := time.After(watchTestTimeout)
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "watching stopped before receiving %v event for %v", operation, path)
+			if event.Operation == operation && event.Path == path {
+				return
+			}
+			skipped = append(skipped, event)
+		case <-timeout:
+			require.FailNow(t, fmt.Sprintf("no %v event received for %v", operation, path))
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root, err := fs.TempDirInTempDir("test-watch-")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(root) }()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := startWatching(t, ctx, fs, root)
+
+			file := FilePathJoin(fs, root, "file.txt")
+			require.NoError(t, fs.WriteFile(file, []byte(faker.Sentence()), 0o644))
+			waitForWatchEvent(t, events, WatchCreate, file)
+			time.Sleep(10 * time.Millisecond)
+			require.NoError(t, fs.WriteFile(file, []byte(faker.Paragraph()), 0o644))
+			waitForWatchEvent(t, events, WatchModify, file)
+
+			dir := FilePathJoin(fs, root, "dir", "subdir")
+			require.NoError(t, fs.MkDir(dir))
+			waitForWatchEvent(t, events, WatchCreate, dir)
+			nestedFile := FilePathJoin(fs, dir, "nested.txt")
+			require.NoError(t, fs.WriteFile(nestedFile, []byte(faker.Sentence()), 0o644))
+			waitForWatchEvent(t, events, WatchCreate, nestedFile)
+
+			renamed := FilePathJoin(fs, root, "renamed.txt")
+			require.NoError(t, fs.Move(file, renamed))
+			waitForWatchEvent(t, events, WatchRename, file)
+			waitForWatchEvent(t, events, WatchCreate, renamed)
+
+			require.NoError(t, fs.Rm(renamed))
+			waitForWatchEvent(t, events, WatchDelete, renamed)
+
+			cancel()
+			select {
+			case _, ok := <-events:
+				for ok {
+					_, ok = <-events
+				}
+			case <-time.After(watchTestTimeout):
+				assert.FailNow(t, "watching did not stop on cancellation")
+			}
+		})
+	}
+}
+
+func TestWatch_ExclusionPatterns(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root, err := fs.TempDirInTempDir("test-watch-")
+			require.NoError(t, err)
+			defer func() { _ = fs.Rm(root) }()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := startWatching(t, ctx, fs, root, ".*[.]ignore")
+
+			excluded := FilePathJoin(fs, root, "test.ignore")
+			require.NoError(t, fs.Touch(excluded))
+			file := FilePathJoin(fs, root, "file.txt")
+			require.NoError(t, fs.Touch(file))
+			skipped := waitForWatchEvent(t, events, WatchCreate, file)
+			for i := range skipped {
+				assert.NotEqual(t, excluded, skipped[i].Path)
+			}
+		})
+	}
+}
+
+func TestWatch_Failures(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			_, err := fs.Watch(context.Background(), FilePathJoin(fs, fs.TempDirectory(), faker.UUIDHyphenated()))
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = fs.Watch(ctx, fs.TempDirectory())
+			errortest.AssertError(t, err, commonerrors.ErrCancelled)
+		})
+	}
+}
+
+func TestDiffWatchedTrees(t *testing.T) {
+	now := time.Now()
+	previous := map[string]*watchedItem{
+		"/a":       {size: 1, modTime: now},
+		"/b":       {size: 2, modTime: now},
+		"/c":       {size: 3, modTime: now},
+		"/dir":     {isDir: true, modTime: now},
+		"/removed": {size: 4, modTime: now},
+	}
+	current := map[string]*watchedItem{
+		"/a":   {size: 1, modTime: now},
+		"/b":   {size: 5, modTime: now.Add(time.Second)},
+		"/d":   {size: 3, modTime: now},
+		"/dir": {isDir: true, modTime: now.Add(time.Second)},
+		"/new": {size: 6, modTime: now},
+	}
+	assert.Equal(t, []WatchEvent{
+		{Operation: WatchRename, Path: "/c"},
+		{Operation: WatchDelete, Path: "/removed"},
+		{Operation: WatchModify, Path: "/b"},
+		{Operation: WatchCreate, Path: "/d"},
+		{Operation: WatchCreate, Path: "/new"},
+	}, diffWatchedTrees(previous, current))
+}
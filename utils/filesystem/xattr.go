@@ -0,0 +1,253 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+const (
+	// tarXattrPrefix is the prefix of PAX records describing extended attributes (as used by GNU tar and bsdtar).
+	tarXattrPrefix = "SCHILY.xattr."
+	// zipXattrExtraID is the identifier of the zip extra field used to store extended attributes ("xa" in little endian).
+	zipXattrExtraID = 0x6178
+)
+
+func (c *ExtendedOsFs) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	return getXattr(name, attribute)
+}
+
+func (c *ExtendedOsFs) SetXattrIfPossible(name, attribute string, value []byte) error {
+	return setXattr(name, attribute, value)
+}
+
+func (c *ExtendedOsFs) ListXattrIfPossible(name string) ([]string, error) {
+	return listXattr(name)
+}
+
+func (c *ExtendedOsFs) RemoveXattrIfPossible(name, attribute string) error {
+	return removeXattr(name, attribute)
+}
+
+func (fs *VFS) GetXattr(name, attribute string) (value []byte, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	if correctobj, ok := fs.vfs.(IXattrer); ok {
+		value, err = correctobj.GetXattrIfPossible(name, attribute)
+		err = ConvertFileSystemError(err)
+		return
+	}
+	err = ErrXattrNotImplemented
+	return
+}
+
+func (fs *VFS) SetXattr(name, attribute string, value []byte) (err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	if correctobj, ok := fs.vfs.(IXattrer); ok {
+		err = ConvertFileSystemError(correctobj.SetXattrIfPossible(name, attribute, value))
+		return
+	}
+	err = ErrXattrNotImplemented
+	return
+}
+
+func (fs *VFS) ListXattr(name string) (attributes []string, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	if correctobj, ok := fs.vfs.(IXattrer); ok {
+		attributes, err = correctobj.ListXattrIfPossible(name)
+		err = ConvertFileSystemError(err)
+		sort.Strings(attributes)
+		return
+	}
+	err = ErrXattrNotImplemented
+	return
+}
+
+func (fs *VFS) RemoveXattr(name, attribute string) (err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	if correctobj, ok := fs.vfs.(IXattrer); ok {
+		err = ConvertFileSystemError(correctobj.RemoveXattrIfPossible(name, attribute))
+		return
+	}
+	err = ErrXattrNotImplemented
+	return
+}
+
+// GetXattrs returns all the extended attributes of an item. Filesystems which do not support extended attributes are considered as not having any.
+func GetXattrs(fs FS, name string) (attributes map[string][]byte, err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	names, err := fs.ListXattr(name)
+	if err != nil {
+		if commonerrors.Any(err, commonerrors.ErrNotImplemented, commonerrors.ErrUnsupported) {
+			err = nil
+		}
+		return
+	}
+	attributes = make(map[string][]byte, len(names))
+	for i := range names {
+		var value []byte
+		value, err = fs.GetXattr(name, names[i])
+		if err != nil {
+			if commonerrors.Any(err, commonerrors.ErrNotFound) {
+				// The attribute was removed in the meantime.
+				err = nil
+				continue
+			}
+			return
+		}
+		attributes[names[i]] = value
+	}
+	return
+}
+
+// SetXattrs sets extended attributes of an item.
+func SetXattrs(fs FS, name string, attributes map[string][]byte) (err error) {
+	if fs == nil {
+		err = commonerrors.UndefinedVariable("filesystem")
+		return
+	}
+	names := make([]string, 0, len(attributes))
+	for attribute := range attributes {
+		names = append(names, attribute)
+	}
+	sort.Strings(names)
+	for i := range names {
+		err = fs.SetXattr(name, names[i], attributes[names[i]])
+		if err != nil {
+			err = commonerrors.WrapErrorf(err, err, "could not set extended attribute [%v] of [%v]", names[i], name)
+			return
+		}
+	}
+	return
+}
+
+// CopyXattrs copies all the extended attributes of src onto dest.
+func CopyXattrs(srcFs FS, src string, destFs FS, dest string) (err error) {
+	attributes, err := GetXattrs(srcFs, src)
+	if err != nil {
+		return
+	}
+	err = SetXattrs(destFs, dest, attributes)
+	return
+}
+
+// addXattrsToTarHeader records extended attributes in a tar header using PAX records.
+func addXattrsToTarHeader(header *tar.Header, attributes map[string][]byte) {
+	if len(attributes) == 0 {
+		return
+	}
+	if header.PAXRecords == nil {
+		header.PAXRecords = make(map[string]string, len(attributes))
+	}
+	for attribute, value := range attributes {
+		header.PAXRecords[tarXattrPrefix+attribute] = string(value)
+	}
+	header.Format = tar.FormatPAX
+}
+
+// determineTarHeaderXattrs returns the extended attributes recorded in a tar header.
+func determineTarHeaderXattrs(header *tar.Header) (attributes map[string][]byte) {
+	attributes = map[string][]byte{}
+	for key, value := range header.PAXRecords {
+		if attribute, found := strings.CutPrefix(key, tarXattrPrefix); found && attribute != "" {
+			attributes[attribute] = []byte(value)
+		}
+	}
+	return
+}
+
+// encodeZipXattrExtraField encodes extended attributes as a zip extra field i.e. a header (identifier and size) followed by, for each attribute, the length of its name, its name, the length of its value and its value.
+func encodeZipXattrExtraField(attributes map[string][]byte) (field []byte, err error) {
+	if len(attributes) == 0 {
+		return
+	}
+	names := make([]string, 0, len(attributes))
+	for attribute := range attributes {
+		names = append(names, attribute)
+	}
+	sort.Strings(names)
+	var data []byte
+	for i := range names {
+		value := attributes[names[i]]
+		if len(names[i]) > math.MaxUint16 || len(value) > math.MaxUint16 {
+			err = commonerrors.Newf(commonerrors.ErrTooLarge, "extended attribute [%v] is too big to be archived", names[i])
+			return
+		}
+		data = binary.LittleEndian.AppendUint16(data, uint16(len(names[i])))
+		data = append(data, names[i]...)
+		data = binary.LittleEndian.AppendUint16(data, uint16(len(value)))
+		data = append(data, value...)
+	}
+	if len(data) > math.MaxUint16 {
+		err = commonerrors.New(commonerrors.ErrTooLarge, "extended attributes are too big to be archived")
+		return
+	}
+	field = binary.LittleEndian.AppendUint16(field, zipXattrExtraID)
+	field = binary.LittleEndian.AppendUint16(field, uint16(len(data)))
+	field = append(field, data...)
+	return
+}
+
+// decodeZipXattrExtraField returns the extended attributes stored in the extra fields of a zipped file.
+func decodeZipXattrExtraField(extra []byte) (attributes map[string][]byte, err error) {
+	attributes = map[string][]byte{}
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			err = commonerrors.New(commonerrors.ErrInvalid, "invalid zip extra field")
+			return
+		}
+		data := extra[4 : 4+size]
+		extra = extra[4+size:]
+		if id != zipXattrExtraID {
+			continue
+		}
+		for len(data) > 0 {
+			var name, value []byte
+			name, data, err = readZipXattrExtraFieldElement(data)
+			if err != nil {
+				return
+			}
+			value, data, err = readZipXattrExtraFieldElement(data)
+			if err != nil {
+				return
+			}
+			attributes[string(name)] = value
+		}
+	}
+	return
+}
+
+func readZipXattrExtraFieldElement(data []byte) (element []byte, remaining []byte, err error) {
+	if len(data) < 2 {
+		err = commonerrors.New(commonerrors.ErrInvalid, "invalid extended attribute zip extra field")
+		return
+	}
+	size := int(binary.LittleEndian.Uint16(data[0:2]))
+	if len(data) < 2+size {
+		err = commonerrors.New(commonerrors.ErrInvalid, "invalid extended attribute zip extra field")
+		return
+	}
+	element = data[2 : 2+size]
+	remaining = data[2+size:]
+	return
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+func getXattr(_, _ string) ([]byte, error) {
+	return nil, ErrXattrNotImplemented
+}
+
+func setXattr(_, _ string, _ []byte) error {
+	return ErrXattrNotImplemented
+}
+
+func listXattr(_ string) ([]string, error) {
+	return nil, ErrXattrNotImplemented
+}
+
+func removeXattr(_, _ string) error {
+	return ErrXattrNotImplemented
+}
@@ -0,0 +1,85 @@
+//go:build linux || darwin
+
+package filesystem
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+)
+
+// xattrMaxAttempts is the number of attempts made to read attributes which keep changing size.
+const xattrMaxAttempts = 5
+
+func convertXattrError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if commonerrors.Any(err, unix.ENODATA) || commonerrors.CorrespondTo(err, "attribute not found") {
+		return commonerrors.WrapErrorf(commonerrors.ErrNotFound, err, "%v failed for [%v]", op, path)
+	}
+	return ConvertFileSystemError(&os.PathError{Op: op, Path: path, Err: err})
+}
+
+func getXattr(path, attribute string) (value []byte, err error) {
+	for range xattrMaxAttempts {
+		var size int
+		size, err = unix.Getxattr(path, attribute, nil)
+		if err != nil {
+			break
+		}
+		value = make([]byte, size)
+		size, err = unix.Getxattr(path, attribute, value)
+		if commonerrors.Any(err, unix.ERANGE) {
+			// The attribute grew in the meantime.
+			continue
+		}
+		if err == nil {
+			value = value[:size]
+		}
+		break
+	}
+	err = convertXattrError("getxattr", path, err)
+	return
+}
+
+func setXattr(path, attribute string, value []byte) error {
+	return convertXattrError("setxattr", path, unix.Setxattr(path, attribute, value, 0))
+}
+
+func listXattr(path string) (attributes []string, err error) {
+	var list []byte
+	for range xattrMaxAttempts {
+		var size int
+		size, err = unix.Listxattr(path, nil)
+		if err != nil || size == 0 {
+			break
+		}
+		list = make([]byte, size)
+		size, err = unix.Listxattr(path, list)
+		if commonerrors.Any(err, unix.ERANGE) {
+			continue
+		}
+		if err == nil {
+			list = list[:size]
+		}
+		break
+	}
+	err = convertXattrError("listxattr", path, err)
+	if err != nil {
+		return
+	}
+	for _, attribute := range strings.Split(string(list), "\x00") {
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	return
+}
+
+func removeXattr(path, attribute string) error {
+	return convertXattrError("removexattr", path, unix.Removexattr(path, attribute))
+}
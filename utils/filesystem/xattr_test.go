@@ -0,0 +1,369 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
+)
+
+const testXattr = "user.golang-utils.test"
+
+// skipIfXattrsNotSupported skips tests if extended attributes cannot be set on items of the filesystem.
+func skipIfXattrsNotSupported(t *testing.T, fs FS, path string) {
+	t.Helper()
+	err := fs.SetXattr(path, testXattr, []byte("probe"))
+	if commonerrors.Any(err, commonerrors.ErrNotImplemented, commonerrors.ErrUnsupported, commonerrors.ErrForbidden) {
+		t.Skipf("extended attributes are not supported: %v", err)
+	}
+	require.NoError(t, err)
+	require.NoError(t, fs.RemoveXattr(path, testXattr))
+}
+
+func createXattrTestTree(t *testing.T, fs FS, root string) (attributes map[string]map[string][]byte) {
+	t.Helper()
+	require.NoError(t, fs.MkDir(FilePathJoin(fs, root, "dir", "subdir")))
+	attributes = map[string]map[string][]byte{
+		"dir":                    {testXattr: []byte(faker.Word())},
+		"dir/file1.txt":          {testXattr: []byte(faker.Sentence()), testXattr + ".other": []byte{0, 1, 2}},
+		"dir/subdir/file2.txt":   {testXattr: []byte(faker.Paragraph())},
+		"dir/subdir/noattrs.txt": {},
+	}
+	for _, name := range []string{"dir/file1.txt", "dir/subdir/file2.txt", "dir/subdir/noattrs.txt"} {
+		require.NoError(t, fs.WriteFile(FilePathJoin(fs, root, FilePathFromSlash(fs, name)), []byte(faker.Paragraph()), 0o644))
+	}
+	for name, attrs := range attributes {
+		require.NoError(t, SetXattrs(fs, FilePathJoin(fs, root, FilePathFromSlash(fs, name)), attrs))
+	}
+	return
+}
+
+func assertXattrTestTree(t *testing.T, fs FS, root string, expected map[string]map[string][]byte) {
+	t.Helper()
+	for name, attrs := range expected {
+		actual, err := GetXattrs(fs, FilePathJoin(fs, root, FilePathFromSlash(fs, name)))
+		require.NoError(t, err)
+		assert.Equal(t, attrs, actual, name)
+	}
+}
+
+func TestXattr(t *testing.T) {
+	fs := NewStandardFileSystem()
+	path := FilePathJoin(fs, t.TempDir(), "test.txt")
+	require.NoError(t, fs.WriteFile(path, []byte(faker.Sentence()), 0o644))
+	skipIfXattrsNotSupported(t, fs, path)
+
+	_, err := fs.GetXattr(path, testXattr)
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	value := []byte(faker.Paragraph())
+	require.NoError(t, fs.SetXattr(path, testXattr, value))
+	require.NoError(t, fs.SetXattr(path, testXattr+".empty", nil))
+	actual, err := fs.GetXattr(path, testXattr)
+	require.NoError(t, err)
+	assert.Equal(t, value, actual)
+	actual, err = fs.GetXattr(path, testXattr+".empty")
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+	attributes, err := fs.ListXattr(path)
+	require.NoError(t, err)
+	assert.Subset(t, attributes, []string{testXattr, testXattr + ".empty"})
+
+	require.NoError(t, fs.RemoveXattr(path, testXattr))
+	_, err = fs.GetXattr(path, testXattr)
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	errortest.AssertError(t, fs.RemoveXattr(path, testXattr), commonerrors.ErrNotFound)
+	attributes, err = fs.ListXattr(path)
+	require.NoError(t, err)
+	assert.NotContains(t, attributes, testXattr)
+
+	missing := FilePathJoin(fs, t.TempDir(), "missing.txt")
+	_, err = fs.ListXattr(missing)
+	errortest.AssertError(t, err, commonerrors.ErrNotFound)
+	errortest.AssertError(t, fs.SetXattr(missing, testXattr, value), commonerrors.ErrNotFound)
+}
+
+func TestXattr_NotImplemented(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	path := FilePathJoin(fs, t.TempDir(), "test.txt")
+	require.NoError(t, fs.WriteFile(path, []byte(faker.Sentence()), 0o644))
+
+	_, err := fs.GetXattr(path, testXattr)
+	errortest.AssertError(t, err, commonerrors.ErrNotImplemented)
+	_, err = fs.ListXattr(path)
+	errortest.AssertError(t, err, commonerrors.ErrNotImplemented)
+	errortest.AssertError(t, fs.SetXattr(path, testXattr, nil), commonerrors.ErrNotImplemented)
+	errortest.AssertError(t, fs.RemoveXattr(path, testXattr), commonerrors.ErrNotImplemented)
+
+	// Filesystems without extended attributes are considered as not having any.
+	attributes, err := GetXattrs(fs, path)
+	require.NoError(t, err)
+	assert.Empty(t, attributes)
+	require.NoError(t, CopyXattrs(fs, path, fs, path))
+}
+
+func TestXattr_Wrappers(t *testing.T) {
+	fs := NewStandardFileSystem()
+	root := t.TempDir()
+	path := FilePathJoin(fs, root, "test.txt")
+	require.NoError(t, fs.WriteFile(path, []byte(faker.Sentence()), 0o644))
+	skipIfXattrsNotSupported(t, fs, path)
+	value := []byte(faker.Sentence())
+	require.NoError(t, fs.SetXattr(path, testXattr, value))
+
+	basePathFs, err := NewBasePathFileSystem(fs, root)
+	require.NoError(t, err)
+	quotaFs, err := NewQuotaFileSystem(context.Background(), fs, &Quota{MaxTotalSize: 1000000, MaxFileCount: 10}, root)
+	require.NoError(t, err)
+	journal := NewAuditJournal()
+	auditFs, err := NewAuditFileSystem(fs, journal)
+	require.NoError(t, err)
+	injector := NewFaultInjector(1)
+	faultFs, err := NewFaultInjectionFileSystem(fs, injector)
+	require.NoError(t, err)
+	overlayFs, err := NewOverlayFileSystemWithScratchDirectory(fs, t.TempDir())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		fs   FS
+		path string
+	}{
+		{name: "base path", fs: basePathFs, path: "/test.txt"},
+		{name: "quota", fs: quotaFs, path: path},
+		{name: "audit", fs: auditFs, path: path},
+		{name: "fault injection", fs: faultFs, path: path},
+		{name: "overlay", fs: overlayFs, path: path},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := test.fs.GetXattr(test.path, testXattr)
+			require.NoError(t, err)
+			assert.Equal(t, value, actual)
+			attributes, err := test.fs.ListXattr(test.path)
+			require.NoError(t, err)
+			assert.Contains(t, attributes, testXattr)
+			other := testXattr + "." + test.name[:1]
+			require.NoError(t, test.fs.SetXattr(test.path, other, value))
+			actual, err = test.fs.GetXattr(test.path, other)
+			require.NoError(t, err)
+			assert.Equal(t, value, actual)
+			require.NoError(t, test.fs.RemoveXattr(test.path, other))
+			_, err = test.fs.GetXattr(test.path, other)
+			errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		})
+	}
+
+	t.Run("overlay copy-up", func(t *testing.T) {
+		// Changes happen in the layer only and attributes of the base are carried over.
+		actual, err := fs.GetXattr(path, testXattr+".o")
+		errortest.AssertError(t, err, commonerrors.ErrNotFound)
+		assert.Empty(t, actual)
+		actual, err = overlayFs.GetXattr(path, testXattr)
+		require.NoError(t, err)
+		assert.Equal(t, value, actual)
+	})
+	t.Run("audit records", func(t *testing.T) {
+		diff := journal.GetDiffReport()
+		assert.Equal(t, []string{path}, diff.Modified)
+	})
+	t.Run("fault injection", func(t *testing.T) {
+		require.NoError(t, injector.AddRule(FaultRule{Operations: []FileOperation{FileOperationSetXattr}, Err: commonerrors.ErrForbidden}))
+		errortest.AssertError(t, faultFs.SetXattr(path, testXattr, value), commonerrors.ErrForbidden)
+		_, err := faultFs.GetXattr(path, testXattr)
+		require.NoError(t, err)
+	})
+	t.Run("no support", func(t *testing.T) {
+		overlayFs, err := NewOverlayFileSystem(fs, afero.NewMemMapFs())
+		require.NoError(t, err)
+		actual, err := overlayFs.GetXattr(path, testXattr)
+		require.NoError(t, err)
+		assert.Equal(t, value, actual)
+		errortest.AssertError(t, overlayFs.SetXattr(path, testXattr, value), commonerrors.ErrNotImplemented)
+	})
+}
+
+func TestCopyBetweenFSWithOptions_Xattrs(t *testing.T) {
+	fs := NewStandardFileSystem()
+	root := t.TempDir()
+	skipIfXattrsNotSupported(t, fs, root)
+	expected := createXattrTestTree(t, fs, root)
+
+	dest := t.TempDir()
+	require.NoError(t, CopyBetweenFSWithOptions(context.Background(), fs, FilePathJoin(fs, root, "dir"), fs, FilePathJoin(fs, dest, "dir"), WithXattrPreservation(), WithWorkers(3)))
+	assertXattrTestTree(t, fs, dest, expected)
+
+	// Attributes are only copied if required.
+	dest = t.TempDir()
+	require.NoError(t, CopyBetweenFSWithOptions(context.Background(), fs, FilePathJoin(fs, root, "dir"), fs, FilePathJoin(fs, dest, "dir")))
+	attributes, err := GetXattrs(fs, FilePathJoin(fs, dest, "dir", "file1.txt"))
+	require.NoError(t, err)
+	assert.NotContains(t, attributes, testXattr)
+
+	// Single files
+	dest = t.TempDir()
+	require.NoError(t, CopyBetweenFSWithOptions(context.Background(), fs, FilePathJoin(fs, root, "dir", "file1.txt"), fs, FilePathJoin(fs, dest, "file1.txt"), WithXattrPreservation()))
+	assertXattrTestTree(t, fs, dest, map[string]map[string][]byte{"file1.txt": expected["dir/file1.txt"]})
+
+	// Destinations without support for extended attributes cannot preserve them.
+	memFs := NewInMemoryFileSystem()
+	errortest.AssertError(t, CopyBetweenFSWithOptions(context.Background(), fs, FilePathJoin(fs, root, "dir"), memFs, FilePathJoin(memFs, t.TempDir(), "dir"), WithXattrPreservation()), commonerrors.ErrNotImplemented)
+}
+
+func TestArchives_Xattrs(t *testing.T) {
+	fs := NewStandardFileSystem()
+	root := t.TempDir()
+	skipIfXattrsNotSupported(t, fs, root)
+	expected := createXattrTestTree(t, fs, root)
+
+	tests := []struct {
+		archive string
+		create  func(ctx context.Context, source string, destination string, options ...ArchiveOption) error
+		extract func(ctx context.Context, source string, destination string, options ...ArchiveOption) ([]string, error)
+	}{
+		{archive: "test.tar.gz", create: fs.TarWithContextAndOptions, extract: fs.UntarWithContextAndOptions},
+		{archive: "test.zip", create: fs.ZipWithContextAndOptions, extract: fs.UnzipWithContextAndOptions},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.archive, func(t *testing.T) {
+			archive := FilePathJoin(fs, t.TempDir(), test.archive)
+			require.NoError(t, test.create(context.Background(), root, archive, WithXattrs(), WithArchiveLimits(DefaultLimits())))
+
+			dest := t.TempDir()
+			files, err := test.extract(context.Background(), archive, dest, WithXattrs())
+			require.NoError(t, err)
+			assert.NotEmpty(t, files)
+			assertXattrTestTree(t, fs, dest, expected)
+
+			// Attributes are only restored if required.
+			dest = t.TempDir()
+			_, err = test.extract(context.Background(), archive, dest)
+			require.NoError(t, err)
+			attributes, err := GetXattrs(fs, FilePathJoin(fs, dest, "dir", "file1.txt"))
+			require.NoError(t, err)
+			assert.NotContains(t, attributes, testXattr)
+
+			// Archives created without attributes can be extracted with attribute restoration.
+			archive = FilePathJoin(fs, t.TempDir(), test.archive)
+			require.NoError(t, test.create(context.Background(), root, archive, WithArchiveExclusionPatterns(".*subdir.*")))
+			dest = t.TempDir()
+			_, err = test.extract(context.Background(), archive, dest, WithXattrs())
+			require.NoError(t, err)
+			assert.False(t, fs.Exists(FilePathJoin(fs, dest, "dir", "subdir")))
+			attributes, err = GetXattrs(fs, FilePathJoin(fs, dest, "dir", "file1.txt"))
+			require.NoError(t, err)
+			assert.NotContains(t, attributes, testXattr)
+		})
+	}
+}
+
+func TestArchives_XattrsOfReadOnlyFiles(t *testing.T) {
+	fs := NewStandardFileSystem()
+	root := t.TempDir()
+	skipIfXattrsNotSupported(t, fs, root)
+	file := FilePathJoin(fs, root, "readonly.txt")
+	require.NoError(t, fs.WriteFile(file, []byte(faker.Paragraph()), 0o644))
+	value := []byte(faker.Sentence())
+	require.NoError(t, fs.SetXattr(file, testXattr, value))
+	require.NoError(t, fs.Chmod(file, 0o444))
+
+	tests := []struct {
+		archive string
+		create  func(ctx context.Context, source string, destination string, options ...ArchiveOption) error
+		extract func(ctx context.Context, source string, destination string, options ...ArchiveOption) ([]string, error)
+	}{
+		{archive: "test.tar", create: fs.TarWithContextAndOptions, extract: fs.UntarWithContextAndOptions},
+		{archive: "test.zip", create: func(_ context.Context, _ string, destination string, _ ...ArchiveOption) error {
+			// File modes are not recorded when creating zip archives and so, the archive is created manually.
+			extra, err := encodeZipXattrExtraField(map[string][]byte{testXattr: value})
+			if err != nil {
+				return err
+			}
+			header := &zip.FileHeader{Name: "readonly.txt", Method: zip.Deflate, Extra: extra}
+			header.SetMode(0o444)
+			var buf bytes.Buffer
+			w := zip.NewWriter(&buf)
+			f, err := w.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = f.Write([]byte(faker.Paragraph()))
+			if err != nil {
+				return err
+			}
+			err = w.Close()
+			if err != nil {
+				return err
+			}
+			return fs.WriteFile(destination, buf.Bytes(), 0o644)
+		}, extract: fs.UnzipWithContextAndOptions},
+	}
+	for i := range tests {
+		test := tests[i]
+		t.Run(test.archive, func(t *testing.T) {
+			archive := FilePathJoin(fs, t.TempDir(), test.archive)
+			require.NoError(t, test.create(context.Background(), root, archive, WithXattrs()))
+			dest := t.TempDir()
+			_, err := test.extract(context.Background(), archive, dest, WithXattrs())
+			require.NoError(t, err)
+			extracted := FilePathJoin(fs, dest, "readonly.txt")
+			actual, err := fs.GetXattr(extracted, testXattr)
+			require.NoError(t, err)
+			assert.Equal(t, value, actual)
+			info, err := fs.Stat(extracted)
+			require.NoError(t, err)
+			assert.Zero(t, info.Mode().Perm()&0o222)
+		})
+	}
+}
+
+func TestZipXattrExtraField(t *testing.T) {
+	attributes := map[string][]byte{
+		"user.a":              []byte(faker.Sentence()),
+		"user.b":              {},
+		"system.posix_acl_ac": {2, 0, 0, 0, 1, 0, 6, 0},
+	}
+	field, err := encodeZipXattrExtraField(attributes)
+	require.NoError(t, err)
+	// Unknown extra fields are ignored.
+	decoded, err := decodeZipXattrExtraField(append([]byte{0x55, 0x54, 1, 0, 0}, field...))
+	require.NoError(t, err)
+	assert.Equal(t, attributes, decoded)
+
+	field, err = encodeZipXattrExtraField(nil)
+	require.NoError(t, err)
+	assert.Empty(t, field)
+
+	_, err = encodeZipXattrExtraField(map[string][]byte{"user.a": make([]byte, 70000)})
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+	_, err = decodeZipXattrExtraField([]byte{0x78, 0x61, 10, 0, 1})
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+	_, err = decodeZipXattrExtraField([]byte{0x78, 0x61, 3, 0, 5, 0, 1})
+	errortest.AssertError(t, err, commonerrors.ErrInvalid)
+}
+
+func TestTarHeaderXattrs(t *testing.T) {
+	for _, fsType := range FileSystemTypes {
+		t.Run(fmt.Sprint(fsType), func(t *testing.T) {
+			fs := NewFs(fsType)
+			root := t.TempDir()
+			require.NoError(t, fs.WriteFile(FilePathJoin(fs, root, "test.txt"), []byte(faker.Sentence()), 0o644))
+			archive := FilePathJoin(fs, t.TempDir(), "test.tar")
+			// Filesystems without extended attributes can still create and extract archives preserving them.
+			require.NoError(t, fs.TarWithContextAndOptions(context.Background(), root, archive, WithXattrs()))
+			files, err := fs.UntarWithContextAndOptions(context.Background(), archive, t.TempDir(), WithXattrs())
+			require.NoError(t, err)
+			assert.Len(t, files, 1)
+		})
+	}
+}
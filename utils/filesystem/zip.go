@@ -2,8 +2,10 @@ package filesystem
 
 import (
 	"archive/zip"
+	"compress/flate"
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"os"
@@ -69,10 +71,20 @@ func (fs *VFS) ZipWithContextAndLimits(ctx context.Context, source, destination
 }
 
 func (fs *VFS) ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context, source string, destination string, limits ILimits, exclusionPatterns ...string) (err error) {
+	return fs.ZipWithContextAndOptions(ctx, source, destination, WithArchiveLimits(limits), WithArchiveExclusionPatterns(exclusionPatterns...))
+}
+
+func (fs *VFS) ZipWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) (err error) {
+	opts := WithArchiveOptions(options...)
+	limits := opts.limits
 	if limits == nil {
 		err = commonerrors.New(commonerrors.ErrUndefined, "missing file system limits")
 		return
 	}
+	err = opts.checkCompressionLevel()
+	if err != nil {
+		return
+	}
 
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
@@ -88,6 +100,11 @@ func (fs *VFS) ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context,
 	// create a new zip archive
 	w := zip.NewWriter(file)
 	defer func() { _ = w.Close() }()
+	if opts.compressionLevel != DefaultCompressionLevel {
+		w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, opts.compressionLevel)
+		})
+	}
 
 	walker := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -114,6 +131,10 @@ func (fs *VFS) ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context,
 				Method:   zip.Deflate,
 				Modified: info.ModTime(),
 			}
+			err = fs.addXattrsToZipHeader(header, path, opts.preserveXattrs)
+			if err != nil {
+				return err
+			}
 			_, err = w.CreateHeader(header)
 			return err
 		}
@@ -135,6 +156,10 @@ func (fs *VFS) ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context,
 			Method:   zip.Deflate,
 			Modified: info.ModTime(),
 		}
+		err = fs.addXattrsToZipHeader(header, path, opts.preserveXattrs)
+		if err != nil {
+			return err
+		}
 		dest, err := w.CreateHeader(header)
 		if err != nil {
 			return err
@@ -149,7 +174,7 @@ func (fs *VFS) ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context,
 		}
 		return nil
 	}
-	err = fs.WalkWithContextAndExclusionPatterns(ctx, source, walker, exclusionPatterns...)
+	err = fs.WalkWithContextAndExclusionPatterns(ctx, source, walker, opts.exclusionPatterns...)
 
 	if limits.Apply() {
 		stat, subErr := file.Stat()
@@ -164,6 +189,34 @@ func (fs *VFS) ZipWithContextAndLimitsAndExclusionPatterns(ctx context.Context,
 	return
 }
 
+// addXattrsToZipHeader records the extended attributes of an item in the extra field of its zip header if required.
+func (fs *VFS) addXattrsToZipHeader(header *zip.FileHeader, path string, preserveXattrs bool) (err error) {
+	if !preserveXattrs {
+		return
+	}
+	attributes, err := GetXattrs(fs, path)
+	if err != nil {
+		return
+	}
+	extra, err := encodeZipXattrExtraField(attributes)
+	if err != nil {
+		return
+	}
+	header.Extra = append(header.Extra, extra...)
+	return
+}
+
+// setZippedFileXattrs restores the extended attributes recorded in the extra field of a zipped file.
+func (fs *VFS) setZippedFileXattrs(path string, zippedFile *zip.File) (err error) {
+	attributes, err := decodeZipXattrExtraField(zippedFile.Extra)
+	if err != nil {
+		err = commonerrors.WrapErrorf(commonerrors.ErrInvalid, err, "could not read extended attributes of zipped file [%v]", zippedFile.Name)
+		return
+	}
+	err = SetXattrs(fs, path, attributes)
+	return
+}
+
 // Prevents any ZipSlip ([CWE-22](https://cwe.mitre.org/data/definitions/22.html)) (files outside extraction dirPath) https://snyk.io/research/zip-slip-vulnerability#go
 func sanitiseZipExtractPath(fs FS, filePath string, destination string) (destPath string, err error) {
 	destPath = FilePathJoin(fs, destination, filePath) // join cleans the destpath so we can check for ZipSlip
@@ -193,7 +246,7 @@ func (fs *VFS) Unzip(source, destination string) ([]string, error) {
 }
 
 func (fs *VFS) UnzipWithContext(ctx context.Context, source string, destination string) (fileList []string, err error) {
-	fileList, _, _, err = fs.unzip(ctx, source, destination, NoLimits(), 0)
+	fileList, _, _, err = fs.unzip(ctx, source, destination, NoLimits(), 0, false)
 	return
 }
 
@@ -203,7 +256,13 @@ func UnzipWithContextAndLimits(ctx context.Context, source string, destination s
 }
 
 func (fs *VFS) UnzipWithContextAndLimits(ctx context.Context, source string, destination string, limits ILimits) (fileList []string, err error) {
-	fileList, _, _, err = fs.unzip(ctx, source, destination, limits, 0)
+	fileList, _, _, err = fs.unzip(ctx, source, destination, limits, 0, false)
+	return
+}
+
+func (fs *VFS) UnzipWithContextAndOptions(ctx context.Context, source string, destination string, options ...ArchiveOption) (fileList []string, err error) {
+	opts := WithArchiveOptions(options...)
+	fileList, _, _, err = fs.unzip(ctx, source, destination, opts.limits, 0, opts.preserveXattrs)
 	return
 }
 
@@ -248,18 +307,13 @@ func newZipReader(fs FS, source string, limits ILimits, currentDepth int64) (zip
 	return
 }
 
-func (fs *VFS) unzip(ctx context.Context, source string, destination string, limits ILimits, currentDepth int64) (fileList []string, fileOnDiskCount uint64, sizeOnDisk uint64, err error) {
+func (fs *VFS) unzip(ctx context.Context, source string, destination string, limits ILimits, currentDepth int64, preserveXattrs bool) (fileList []string, fileOnDiskCount uint64, sizeOnDisk uint64, err error) {
 
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
 		return
 	}
 
-	fileCounter := atomic.NewUint64(0)
-
-	// List of file paths to return
-	totalSizeOnDisk := atomic.NewUint64(0)
-
 	zipReader, f, err := newZipReader(fs, source, limits, currentDepth)
 	defer func() {
 		if f != nil {
@@ -269,6 +323,54 @@ func (fs *VFS) unzip(ctx context.Context, source string, destination string, lim
 	if err != nil {
 		return
 	}
+	return fs.extractZip(ctx, zipReader, source, destination, limits, currentDepth, preserveXattrs)
+}
+
+// UnzipFromReaderWithContextAndLimits unzips a zip archive read from source (of size `size`) into destination without the archive having to be stored on a file system first.
+func UnzipFromReaderWithContextAndLimits(ctx context.Context, source io.ReaderAt, size int64, destination string, limits ILimits) ([]string, error) {
+	return globalFileSystem.UnzipFromReaderWithContextAndLimits(ctx, source, size, destination, limits)
+}
+
+func (fs *VFS) UnzipFromReaderWithContextAndLimits(ctx context.Context, source io.ReaderAt, size int64, destination string, limits ILimits) (fileList []string, err error) {
+	err = fs.checkWhetherUnderlyingResourceIsClosed()
+	if err != nil {
+		return
+	}
+	err = parallelisation.DetermineContextError(ctx)
+	if err != nil {
+		return
+	}
+	if source == nil {
+		err = commonerrors.UndefinedVariable("zip reader")
+		return
+	}
+	if limits == nil {
+		err = commonerrors.UndefinedVariable("file system limits")
+		return
+	}
+	if size < 0 {
+		err = commonerrors.Newf(commonerrors.ErrInvalid, "invalid zip archive size (%v B)", size)
+		return
+	}
+	if limits.Apply() && size > limits.GetMaxFileSize() {
+		err = commonerrors.Newf(commonerrors.ErrTooLarge, "zip archive is too big (%v B) and beyond limits (max: %v B)", size, limits.GetMaxFileSize())
+		return
+	}
+	zipReader, err := zip.NewReader(source, size)
+	err = convertZipError(err)
+	if err != nil {
+		return
+	}
+	fileList, _, _, err = fs.extractZip(ctx, zipReader, "zip stream", destination, limits, 0, false)
+	return
+}
+
+// extractZip extracts all the items of a zip archive into destination whilst enforcing limits. If preserveXattrs is set, extended attributes recorded in the archive are restored.
+func (fs *VFS) extractZip(ctx context.Context, zipReader *zip.Reader, source string, destination string, limits ILimits, currentDepth int64, preserveXattrs bool) (fileList []string, fileOnDiskCount uint64, sizeOnDisk uint64, err error) {
+	fileCounter := atomic.NewUint64(0)
+
+	// List of file paths to return
+	totalSizeOnDisk := atomic.NewUint64(0)
 
 	// Clean the destination to find shortest dirPath
 	destination = FilePathClean(fs, destination)
@@ -323,6 +425,12 @@ func (fs *VFS) unzip(ctx context.Context, source string, destination string, lim
 			if subErr != nil {
 				return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.Newf(subErr, "unable to create directory [%s]", filePath)
 			}
+			if preserveXattrs {
+				subErr = fs.setZippedFileXattrs(filePath, zippedFile)
+				if subErr != nil {
+					return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
+				}
+			}
 			// recording directory dirInfo to preserve timestamps
 			directoryInfo[filePath] = zippedFile.FileInfo()
 			// Nothing more to do for a directory, move to next zip file
@@ -336,7 +444,7 @@ func (fs *VFS) unzip(ctx context.Context, source string, destination string, lim
 			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), commonerrors.Newf(subErr, "unable to create directory '%s'", directoryPath)
 		}
 
-		fileSizeOnDisk, subErr := fs.unzipZippedFile(ctx, filePath, zippedFile, limits, fileDepth)
+		fileSizeOnDisk, subErr := fs.unzipZippedFile(ctx, filePath, zippedFile, limits, fileDepth, preserveXattrs)
 		if subErr != nil {
 			return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
 		}
@@ -344,7 +452,7 @@ func (fs *VFS) unzip(ctx context.Context, source string, destination string, lim
 		// If the copied file is a zip, unzip that zip if the action is marked as recursive
 		if limits.ApplyRecursively() {
 			if fs.isZipWithContext(ctx, filePath) {
-				nestedUnzippedFiles, filesOnDiskCount, filesSizeOnDisk, subErr := fs.unzipNestedZipFiles(ctx, filePath, limits, fileDepth)
+				nestedUnzippedFiles, filesOnDiskCount, filesSizeOnDisk, subErr := fs.unzipNestedZipFiles(ctx, filePath, limits, fileDepth, preserveXattrs)
 				if subErr != nil {
 					return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), subErr
 				}
@@ -379,9 +487,9 @@ func (fs *VFS) unzip(ctx context.Context, source string, destination string, lim
 	return fileList, fileCounter.Load(), totalSizeOnDisk.Load(), nil
 }
 
-func (fs *VFS) unzipNestedZipFiles(ctx context.Context, nestedZipFile string, limits ILimits, currentDepth int64) (nestedUnzippedFiles []string, fileOnDiskCount uint64, filesSizeOnDisk uint64, err error) {
+func (fs *VFS) unzipNestedZipFiles(ctx context.Context, nestedZipFile string, limits ILimits, currentDepth int64, preserveXattrs bool) (nestedUnzippedFiles []string, fileOnDiskCount uint64, filesSizeOnDisk uint64, err error) {
 	destination := FilePathJoin(fs, FilePathDir(fs, nestedZipFile), FilepathStem(nestedZipFile))
-	nestedUnzippedFiles, fileOnDiskCount, filesSizeOnDisk, subErr := fs.unzip(ctx, nestedZipFile, destination, limits, currentDepth+1)
+	nestedUnzippedFiles, fileOnDiskCount, filesSizeOnDisk, subErr := fs.unzip(ctx, nestedZipFile, destination, limits, currentDepth+1, preserveXattrs)
 	if subErr != nil {
 		err = commonerrors.Newf(subErr, "unable to unzip nested zip [%s] present at depth (%d) to [%s]", FilePathBase(fs, nestedZipFile), currentDepth, destination)
 		return
@@ -409,7 +517,7 @@ func preserveDirectoriesTimestamps(ctx context.Context, fs FS, directoryInfo map
 }
 
 // unzipZippedFile unzips file to destination directory
-func (fs *VFS) unzipZippedFile(ctx context.Context, dest string, zippedFile *zip.File, limits ILimits, currentDepth int64) (fileSizeOnDisk int64, err error) {
+func (fs *VFS) unzipZippedFile(ctx context.Context, dest string, zippedFile *zip.File, limits ILimits, currentDepth int64, preserveXattrs bool) (fileSizeOnDisk int64, err error) {
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
 		return
@@ -425,7 +533,12 @@ func (fs *VFS) unzipZippedFile(ctx context.Context, dest string, zippedFile *zip
 		return
 	}
 
-	destinationFile, err := fs.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, zippedFile.Mode())
+	mode := zippedFile.Mode()
+	if preserveXattrs {
+		// The file is kept writable by its owner until its extended attributes are restored.
+		mode |= 0o200
+	}
+	destinationFile, err := fs.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	err = convertZipError(err)
 	if err != nil {
 		err = commonerrors.WrapIfNotCommonErrorf(commonerrors.ErrUnexpected, err, "unable to open file '%s'", destinationPath)
@@ -459,12 +572,33 @@ func (fs *VFS) unzipZippedFile(ctx context.Context, dest string, zippedFile *zip
 	if err != nil {
 		return
 	}
+	if preserveXattrs {
+		err = fs.setZippedFileXattrs(destinationPath, zippedFile)
+		if err != nil {
+			return
+		}
+		if mode != zippedFile.Mode() {
+			err = fs.restoreOwnerReadOnlyMode(destinationPath)
+			if err != nil {
+				return
+			}
+		}
+	}
 	// Ensuring the timestamp is preserved.
 	times := newDefaultTimeInfo(info)
 	err = fs.Chtimes(destinationPath, times.AccessTime(), times.ModTime())
 	return
 }
 
+// restoreOwnerReadOnlyMode removes the write permission of the owner of a file, whilst leaving the rest of its mode (e.g. as set according to the umask) unchanged.
+func (fs *VFS) restoreOwnerReadOnlyMode(path string) error {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return err
+	}
+	return fs.Chmod(path, info.Mode()&^0o200)
+}
+
 func determineUnzippedFilepath(destinationPath string) (string, error) {
 
 	// See https://go-review.googlesource.com/c/go/+/75592/
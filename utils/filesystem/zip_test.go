@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math"
@@ -872,3 +873,57 @@ func TestZipBetweenFilesystemWithDifferentFilePathSeparators(t *testing.T) {
 	}
 
 }
+
+func TestUnzipFromReader(t *testing.T) {
+	fs := NewFs(StandardFS)
+
+	testInDir := "testdata"
+	testFile := "testunzip"
+	srcPath := FilePathJoin(fs, testInDir, testFile+".zip")
+	destPath, err := fs.TempDirInTempDir("unzip-stream")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(destPath) }()
+	expectedfileList, err := fs.UnzipWithContextAndLimits(context.Background(), srcPath, FilePathJoin(fs, destPath, "expected"), DefaultLimits())
+	require.NoError(t, err)
+	expectedfileList, err = fs.ConvertToRelativePath(FilePathJoin(fs, destPath, "expected"), expectedfileList...)
+	require.NoError(t, err)
+	sort.Strings(expectedfileList)
+
+	content, err := fs.ReadFile(srcPath)
+	require.NoError(t, err)
+
+	_, err = fs.UnzipFromReaderWithContextAndLimits(context.Background(), nil, int64(len(content)), destPath, NoLimits())
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = fs.UnzipFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), int64(len(content)), destPath, nil)
+	errortest.AssertError(t, err, commonerrors.ErrUndefined)
+	_, err = fs.UnzipFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), int64(len(content)), destPath, NewLimits(int64(len(content)-1), uint64(size.GiB), multiplication.Mega, -1, false))
+	errortest.AssertError(t, err, commonerrors.ErrTooLarge)
+
+	outPath := FilePathJoin(fs, destPath, "output")
+	fileList, err := fs.UnzipFromReaderWithContextAndLimits(context.Background(), bytes.NewReader(content), int64(len(content)), outPath, DefaultLimits())
+	require.NoError(t, err)
+	fileList, err = fs.ConvertToRelativePath(outPath, fileList...)
+	require.NoError(t, err)
+	sort.Strings(fileList)
+	assert.Equal(t, expectedfileList, fileList)
+}
+
+func TestUnzipFromReader_ZipBomb(t *testing.T) {
+	fs := NewFs(StandardFS)
+	destPath, err := fs.TempDirInTempDir("unzip-stream-limits-")
+	require.NoError(t, err)
+	defer func() { _ = fs.Rm(destPath) }()
+	limits := NewLimits(int64(size.GiB), uint64(size.MiB), multiplication.Mega, 3, true)
+
+	for _, testFile := range []string{"42", "zbsm", "zip-bomb-nested-large", "zip-bomb-nested-small", "zip-bomb"} {
+		t.Run(testFile, func(t *testing.T) {
+			f, err := fs.GenericOpen(FilePathJoin(fs, "testdata", testFile+".zip"))
+			require.NoError(t, err)
+			defer func() { _ = f.Close() }()
+			info, err := f.Stat()
+			require.NoError(t, err)
+			_, err = fs.UnzipFromReaderWithContextAndLimits(context.Background(), f, info.Size(), destPath, limits)
+			errortest.AssertError(t, err, commonerrors.ErrUnsupported, commonerrors.ErrTooLarge)
+		})
+	}
+}
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/ARM-software/golang-utils/utils/filesystem (interfaces: IFileHash,IChowner,ILinker,File,DiskUsage,FileTimeInfo,ILock,ILimits,FS,ICloseableFS,IForceRemover,IStater,ILinkReader,ISymLinker)
+// Source: github.com/ARM-software/golang-utils/utils/filesystem (interfaces: IFileHash,IChowner,ILinker,File,DiskUsage,FileTimeInfo,ILock,IRWLock,ISemaphore,IFencedLock,ILockBackend,ILimits,FS,ICloseableFS,IForceRemover,IStater,ILinkReader,ISymLinker,IXattrer,IAuditSink)
 //
 // Generated by this command:
 //
-//	mockgen -destination=../mocks/mock_filesystem.go -package=mocks github.com/ARM-software/golang-utils/utils/filesystem IFileHash,IChowner,ILinker,File,DiskUsage,FileTimeInfo,ILock,ILimits,FS,ICloseableFS,IForceRemover,IStater,ILinkReader,ISymLinker
+//	mockgen -destination=../mocks/mock_filesystem.go -package=mocks github.com/ARM-software/golang-utils/utils/filesystem IFileHash,IChowner,ILinker,File,DiskUsage,FileTimeInfo,ILock,IFencedLock,ILockBackend,ILimits,FS,ICloseableFS,IForceRemover,IStater,ILinkReader,ISymLinker,IXattrer,IAuditSink
 //
 
 // Package mocks is a generated GoMock package.
@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	io "io"
+	iter "iter"
 	os "os"
 	user "os/user"
 	filepath "path/filepath"
@@ -62,6 +63,46 @@ func (mr *MockIFileHashMockRecorder) Calculate(f any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Calculate", reflect.TypeOf((*MockIFileHash)(nil).Calculate), f)
 }
 
+// CalculateDirectory mocks base method.
+func (m *MockIFileHash) CalculateDirectory(fs filesystem.FS, root string, options ...filesystem.DirectoryHashOption) (*filesystem.DirectoryHash, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{fs, root}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CalculateDirectory", varargs...)
+	ret0, _ := ret[0].(*filesystem.DirectoryHash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CalculateDirectory indicates an expected call of CalculateDirectory.
+func (mr *MockIFileHashMockRecorder) CalculateDirectory(fs, root any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{fs, root}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CalculateDirectory", reflect.TypeOf((*MockIFileHash)(nil).CalculateDirectory), varargs...)
+}
+
+// CalculateDirectoryWithContext mocks base method.
+func (m *MockIFileHash) CalculateDirectoryWithContext(ctx context.Context, fs filesystem.FS, root string, options ...filesystem.DirectoryHashOption) (*filesystem.DirectoryHash, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, fs, root}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CalculateDirectoryWithContext", varargs...)
+	ret0, _ := ret[0].(*filesystem.DirectoryHash)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CalculateDirectoryWithContext indicates an expected call of CalculateDirectoryWithContext.
+func (mr *MockIFileHashMockRecorder) CalculateDirectoryWithContext(ctx, fs, root any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, fs, root}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CalculateDirectoryWithContext", reflect.TypeOf((*MockIFileHash)(nil).CalculateDirectoryWithContext), varargs...)
+}
+
 // CalculateFile mocks base method.
 func (m *MockIFileHash) CalculateFile(fs filesystem.FS, path string) (string, error) {
 	m.ctrl.T.Helper()
@@ -806,326 +847,951 @@ func (mr *MockILockMockRecorder) Unlock(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockILock)(nil).Unlock), ctx)
 }
 
-// MockILimits is a mock of ILimits interface.
-type MockILimits struct {
+// MockIRWLock is a mock of IRWLock interface.
+type MockIRWLock struct {
 	ctrl     *gomock.Controller
-	recorder *MockILimitsMockRecorder
+	recorder *MockIRWLockMockRecorder
 	isgomock struct{}
 }
 
-// MockILimitsMockRecorder is the mock recorder for MockILimits.
-type MockILimitsMockRecorder struct {
-	mock *MockILimits
+// MockIRWLockMockRecorder is the mock recorder for MockIRWLock.
+type MockIRWLockMockRecorder struct {
+	mock *MockIRWLock
 }
 
-// NewMockILimits creates a new mock instance.
-func NewMockILimits(ctrl *gomock.Controller) *MockILimits {
-	mock := &MockILimits{ctrl: ctrl}
-	mock.recorder = &MockILimitsMockRecorder{mock}
+// NewMockIRWLock creates a new mock instance.
+func NewMockIRWLock(ctrl *gomock.Controller) *MockIRWLock {
+	mock := &MockIRWLock{ctrl: ctrl}
+	mock.recorder = &MockIRWLockMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockILimits) EXPECT() *MockILimitsMockRecorder {
+func (m *MockIRWLock) EXPECT() *MockIRWLockMockRecorder {
 	return m.recorder
 }
 
-// Apply mocks base method.
-func (m *MockILimits) Apply() bool {
+// IsStale mocks base method.
+func (m *MockIRWLock) IsStale() bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Apply")
+	ret := m.ctrl.Call(m, "IsStale")
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
-// Apply indicates an expected call of Apply.
-func (mr *MockILimitsMockRecorder) Apply() *gomock.Call {
+// IsStale indicates an expected call of IsStale.
+func (mr *MockIRWLockMockRecorder) IsStale() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Apply", reflect.TypeOf((*MockILimits)(nil).Apply))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStale", reflect.TypeOf((*MockIRWLock)(nil).IsStale))
 }
 
-// ApplyRecursively mocks base method.
-func (m *MockILimits) ApplyRecursively() bool {
+// Lock mocks base method.
+func (m *MockIRWLock) Lock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ApplyRecursively")
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "Lock", ctx)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ApplyRecursively indicates an expected call of ApplyRecursively.
-func (mr *MockILimitsMockRecorder) ApplyRecursively() *gomock.Call {
+// Lock indicates an expected call of Lock.
+func (mr *MockIRWLockMockRecorder) Lock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyRecursively", reflect.TypeOf((*MockILimits)(nil).ApplyRecursively))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockIRWLock)(nil).Lock), ctx)
 }
 
-// GetMaxDepth mocks base method.
-func (m *MockILimits) GetMaxDepth() int64 {
+// LockWithTimeout mocks base method.
+func (m *MockIRWLock) LockWithTimeout(ctx context.Context, timeout time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMaxDepth")
-	ret0, _ := ret[0].(int64)
+	ret := m.ctrl.Call(m, "LockWithTimeout", ctx, timeout)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetMaxDepth indicates an expected call of GetMaxDepth.
-func (mr *MockILimitsMockRecorder) GetMaxDepth() *gomock.Call {
+// LockWithTimeout indicates an expected call of LockWithTimeout.
+func (mr *MockIRWLockMockRecorder) LockWithTimeout(ctx, timeout any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxDepth", reflect.TypeOf((*MockILimits)(nil).GetMaxDepth))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWithTimeout", reflect.TypeOf((*MockIRWLock)(nil).LockWithTimeout), ctx, timeout)
 }
 
-// GetMaxFileCount mocks base method.
-func (m *MockILimits) GetMaxFileCount() int64 {
+// MakeStale mocks base method.
+func (m *MockIRWLock) MakeStale(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMaxFileCount")
-	ret0, _ := ret[0].(int64)
+	ret := m.ctrl.Call(m, "MakeStale", ctx)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetMaxFileCount indicates an expected call of GetMaxFileCount.
-func (mr *MockILimitsMockRecorder) GetMaxFileCount() *gomock.Call {
+// MakeStale indicates an expected call of MakeStale.
+func (mr *MockIRWLockMockRecorder) MakeStale(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxFileCount", reflect.TypeOf((*MockILimits)(nil).GetMaxFileCount))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeStale", reflect.TypeOf((*MockIRWLock)(nil).MakeStale), ctx)
 }
 
-// GetMaxFileSize mocks base method.
-func (m *MockILimits) GetMaxFileSize() int64 {
+// RLock mocks base method.
+func (m *MockIRWLock) RLock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMaxFileSize")
-	ret0, _ := ret[0].(int64)
+	ret := m.ctrl.Call(m, "RLock", ctx)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetMaxFileSize indicates an expected call of GetMaxFileSize.
-func (mr *MockILimitsMockRecorder) GetMaxFileSize() *gomock.Call {
+// RLock indicates an expected call of RLock.
+func (mr *MockIRWLockMockRecorder) RLock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxFileSize", reflect.TypeOf((*MockILimits)(nil).GetMaxFileSize))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RLock", reflect.TypeOf((*MockIRWLock)(nil).RLock), ctx)
 }
 
-// GetMaxTotalSize mocks base method.
-func (m *MockILimits) GetMaxTotalSize() uint64 {
+// RLockWithTimeout mocks base method.
+func (m *MockIRWLock) RLockWithTimeout(ctx context.Context, timeout time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMaxTotalSize")
-	ret0, _ := ret[0].(uint64)
+	ret := m.ctrl.Call(m, "RLockWithTimeout", ctx, timeout)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetMaxTotalSize indicates an expected call of GetMaxTotalSize.
-func (mr *MockILimitsMockRecorder) GetMaxTotalSize() *gomock.Call {
+// RLockWithTimeout indicates an expected call of RLockWithTimeout.
+func (mr *MockIRWLockMockRecorder) RLockWithTimeout(ctx, timeout any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxTotalSize", reflect.TypeOf((*MockILimits)(nil).GetMaxTotalSize))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RLockWithTimeout", reflect.TypeOf((*MockIRWLock)(nil).RLockWithTimeout), ctx, timeout)
 }
 
-// Validate mocks base method.
-func (m *MockILimits) Validate() error {
+// RUnlock mocks base method.
+func (m *MockIRWLock) RUnlock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Validate")
+	ret := m.ctrl.Call(m, "RUnlock", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Validate indicates an expected call of Validate.
-func (mr *MockILimitsMockRecorder) Validate() *gomock.Call {
+// RUnlock indicates an expected call of RUnlock.
+func (mr *MockIRWLockMockRecorder) RUnlock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockILimits)(nil).Validate))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RUnlock", reflect.TypeOf((*MockIRWLock)(nil).RUnlock), ctx)
 }
 
-// MockFS is a mock of FS interface.
-type MockFS struct {
-	ctrl     *gomock.Controller
-	recorder *MockFSMockRecorder
-	isgomock struct{}
+// ReleaseIfStale mocks base method.
+func (m *MockIRWLock) ReleaseIfStale(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIfStale", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// MockFSMockRecorder is the mock recorder for MockFS.
-type MockFSMockRecorder struct {
-	mock *MockFS
+// ReleaseIfStale indicates an expected call of ReleaseIfStale.
+func (mr *MockIRWLockMockRecorder) ReleaseIfStale(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIfStale", reflect.TypeOf((*MockIRWLock)(nil).ReleaseIfStale), ctx)
 }
 
-// NewMockFS creates a new mock instance.
-func NewMockFS(ctrl *gomock.Controller) *MockFS {
-	mock := &MockFS{ctrl: ctrl}
-	mock.recorder = &MockFSMockRecorder{mock}
-	return mock
+// TryLock mocks base method.
+func (m *MockIRWLock) TryLock(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryLock", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockFS) EXPECT() *MockFSMockRecorder {
-	return m.recorder
+// TryLock indicates an expected call of TryLock.
+func (mr *MockIRWLockMockRecorder) TryLock(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryLock", reflect.TypeOf((*MockIRWLock)(nil).TryLock), ctx)
 }
 
-// ChangeOwnership mocks base method.
-func (m *MockFS) ChangeOwnership(name string, owner *user.User) error {
+// TryRLock mocks base method.
+func (m *MockIRWLock) TryRLock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChangeOwnership", name, owner)
+	ret := m.ctrl.Call(m, "TryRLock", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ChangeOwnership indicates an expected call of ChangeOwnership.
-func (mr *MockFSMockRecorder) ChangeOwnership(name, owner any) *gomock.Call {
+// TryRLock indicates an expected call of TryRLock.
+func (mr *MockIRWLockMockRecorder) TryRLock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOwnership", reflect.TypeOf((*MockFS)(nil).ChangeOwnership), name, owner)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRLock", reflect.TypeOf((*MockIRWLock)(nil).TryRLock), ctx)
 }
 
-// ChangeOwnershipRecursively mocks base method.
-func (m *MockFS) ChangeOwnershipRecursively(ctx context.Context, path string, owner *user.User) error {
+// Unlock mocks base method.
+func (m *MockIRWLock) Unlock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChangeOwnershipRecursively", ctx, path, owner)
+	ret := m.ctrl.Call(m, "Unlock", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ChangeOwnershipRecursively indicates an expected call of ChangeOwnershipRecursively.
-func (mr *MockFSMockRecorder) ChangeOwnershipRecursively(ctx, path, owner any) *gomock.Call {
+// Unlock indicates an expected call of Unlock.
+func (mr *MockIRWLockMockRecorder) Unlock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOwnershipRecursively", reflect.TypeOf((*MockFS)(nil).ChangeOwnershipRecursively), ctx, path, owner)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockIRWLock)(nil).Unlock), ctx)
 }
 
-// Chmod mocks base method.
-func (m *MockFS) Chmod(name string, mode os.FileMode) error {
+// MockISemaphore is a mock of ISemaphore interface.
+type MockISemaphore struct {
+	ctrl     *gomock.Controller
+	recorder *MockISemaphoreMockRecorder
+	isgomock struct{}
+}
+
+// MockISemaphoreMockRecorder is the mock recorder for MockISemaphore.
+type MockISemaphoreMockRecorder struct {
+	mock *MockISemaphore
+}
+
+// NewMockISemaphore creates a new mock instance.
+func NewMockISemaphore(ctrl *gomock.Controller) *MockISemaphore {
+	mock := &MockISemaphore{ctrl: ctrl}
+	mock.recorder = &MockISemaphoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockISemaphore) EXPECT() *MockISemaphoreMockRecorder {
+	return m.recorder
+}
+
+// CountHolders mocks base method.
+func (m *MockISemaphore) CountHolders() int {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Chmod", name, mode)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "CountHolders")
+	ret0, _ := ret[0].(int)
 	return ret0
 }
 
-// Chmod indicates an expected call of Chmod.
-func (mr *MockFSMockRecorder) Chmod(name, mode any) *gomock.Call {
+// CountHolders indicates an expected call of CountHolders.
+func (mr *MockISemaphoreMockRecorder) CountHolders() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chmod", reflect.TypeOf((*MockFS)(nil).Chmod), name, mode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountHolders", reflect.TypeOf((*MockISemaphore)(nil).CountHolders))
 }
 
-// ChmodRecursively mocks base method.
-func (m *MockFS) ChmodRecursively(ctx context.Context, path string, mode os.FileMode) error {
+// GetPermits mocks base method.
+func (m *MockISemaphore) GetPermits() int {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChmodRecursively", ctx, path, mode)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "GetPermits")
+	ret0, _ := ret[0].(int)
 	return ret0
 }
 
-// ChmodRecursively indicates an expected call of ChmodRecursively.
-func (mr *MockFSMockRecorder) ChmodRecursively(ctx, path, mode any) *gomock.Call {
+// GetPermits indicates an expected call of GetPermits.
+func (mr *MockISemaphoreMockRecorder) GetPermits() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChmodRecursively", reflect.TypeOf((*MockFS)(nil).ChmodRecursively), ctx, path, mode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermits", reflect.TypeOf((*MockISemaphore)(nil).GetPermits))
 }
 
-// Chown mocks base method.
-func (m *MockFS) Chown(name string, uid, gid int) error {
+// IsStale mocks base method.
+func (m *MockISemaphore) IsStale() bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Chown", name, uid, gid)
-	ret0, _ := ret[0].(error)
+	ret := m.ctrl.Call(m, "IsStale")
+	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
-// Chown indicates an expected call of Chown.
-func (mr *MockFSMockRecorder) Chown(name, uid, gid any) *gomock.Call {
+// IsStale indicates an expected call of IsStale.
+func (mr *MockISemaphoreMockRecorder) IsStale() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chown", reflect.TypeOf((*MockFS)(nil).Chown), name, uid, gid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStale", reflect.TypeOf((*MockISemaphore)(nil).IsStale))
 }
 
-// ChownRecursively mocks base method.
-func (m *MockFS) ChownRecursively(ctx context.Context, path string, uid, gid int) error {
+// Lock mocks base method.
+func (m *MockISemaphore) Lock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChownRecursively", ctx, path, uid, gid)
+	ret := m.ctrl.Call(m, "Lock", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ChownRecursively indicates an expected call of ChownRecursively.
-func (mr *MockFSMockRecorder) ChownRecursively(ctx, path, uid, gid any) *gomock.Call {
+// Lock indicates an expected call of Lock.
+func (mr *MockISemaphoreMockRecorder) Lock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChownRecursively", reflect.TypeOf((*MockFS)(nil).ChownRecursively), ctx, path, uid, gid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockISemaphore)(nil).Lock), ctx)
 }
 
-// Chtimes mocks base method.
-func (m *MockFS) Chtimes(name string, atime, mtime time.Time) error {
+// LockWithTimeout mocks base method.
+func (m *MockISemaphore) LockWithTimeout(ctx context.Context, timeout time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Chtimes", name, atime, mtime)
+	ret := m.ctrl.Call(m, "LockWithTimeout", ctx, timeout)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Chtimes indicates an expected call of Chtimes.
-func (mr *MockFSMockRecorder) Chtimes(name, atime, mtime any) *gomock.Call {
+// LockWithTimeout indicates an expected call of LockWithTimeout.
+func (mr *MockISemaphoreMockRecorder) LockWithTimeout(ctx, timeout any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chtimes", reflect.TypeOf((*MockFS)(nil).Chtimes), name, atime, mtime)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWithTimeout", reflect.TypeOf((*MockISemaphore)(nil).LockWithTimeout), ctx, timeout)
 }
 
-// CleanDir mocks base method.
-func (m *MockFS) CleanDir(dir string) error {
+// MakeStale mocks base method.
+func (m *MockISemaphore) MakeStale(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CleanDir", dir)
+	ret := m.ctrl.Call(m, "MakeStale", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// CleanDir indicates an expected call of CleanDir.
-func (mr *MockFSMockRecorder) CleanDir(dir any) *gomock.Call {
+// MakeStale indicates an expected call of MakeStale.
+func (mr *MockISemaphoreMockRecorder) MakeStale(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanDir", reflect.TypeOf((*MockFS)(nil).CleanDir), dir)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeStale", reflect.TypeOf((*MockISemaphore)(nil).MakeStale), ctx)
 }
 
-// CleanDirWithContext mocks base method.
-func (m *MockFS) CleanDirWithContext(ctx context.Context, dir string) error {
+// ReleaseIfStale mocks base method.
+func (m *MockISemaphore) ReleaseIfStale(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CleanDirWithContext", ctx, dir)
+	ret := m.ctrl.Call(m, "ReleaseIfStale", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// CleanDirWithContext indicates an expected call of CleanDirWithContext.
-func (mr *MockFSMockRecorder) CleanDirWithContext(ctx, dir any) *gomock.Call {
+// ReleaseIfStale indicates an expected call of ReleaseIfStale.
+func (mr *MockISemaphoreMockRecorder) ReleaseIfStale(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanDirWithContext", reflect.TypeOf((*MockFS)(nil).CleanDirWithContext), ctx, dir)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIfStale", reflect.TypeOf((*MockISemaphore)(nil).ReleaseIfStale), ctx)
 }
 
-// CleanDirWithContextAndExclusionPatterns mocks base method.
-func (m *MockFS) CleanDirWithContextAndExclusionPatterns(ctx context.Context, dir string, exclusionPatterns ...string) error {
+// TryLock mocks base method.
+func (m *MockISemaphore) TryLock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	varargs := []any{ctx, dir}
-	for _, a := range exclusionPatterns {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "CleanDirWithContextAndExclusionPatterns", varargs...)
+	ret := m.ctrl.Call(m, "TryLock", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// CleanDirWithContextAndExclusionPatterns indicates an expected call of CleanDirWithContextAndExclusionPatterns.
-func (mr *MockFSMockRecorder) CleanDirWithContextAndExclusionPatterns(ctx, dir any, exclusionPatterns ...any) *gomock.Call {
+// TryLock indicates an expected call of TryLock.
+func (mr *MockISemaphoreMockRecorder) TryLock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{ctx, dir}, exclusionPatterns...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanDirWithContextAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).CleanDirWithContextAndExclusionPatterns), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryLock", reflect.TypeOf((*MockISemaphore)(nil).TryLock), ctx)
 }
 
-// ConvertFilePath mocks base method.
-func (m *MockFS) ConvertFilePath(name string) string {
+// Unlock mocks base method.
+func (m *MockISemaphore) Unlock(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ConvertFilePath", name)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "Unlock", ctx)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ConvertFilePath indicates an expected call of ConvertFilePath.
-func (mr *MockFSMockRecorder) ConvertFilePath(name any) *gomock.Call {
+// Unlock indicates an expected call of Unlock.
+func (mr *MockISemaphoreMockRecorder) Unlock(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertFilePath", reflect.TypeOf((*MockFS)(nil).ConvertFilePath), name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockISemaphore)(nil).Unlock), ctx)
 }
 
-// ConvertToAbsolutePath mocks base method.
-func (m *MockFS) ConvertToAbsolutePath(rootPath string, paths ...string) ([]string, error) {
-	m.ctrl.T.Helper()
-	varargs := []any{rootPath}
-	for _, a := range paths {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "ConvertToAbsolutePath", varargs...)
-	ret0, _ := ret[0].([]string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// MockIFencedLock is a mock of IFencedLock interface.
+type MockIFencedLock struct {
+	ctrl     *gomock.Controller
+	recorder *MockIFencedLockMockRecorder
+	isgomock struct{}
 }
 
-// ConvertToAbsolutePath indicates an expected call of ConvertToAbsolutePath.
-func (mr *MockFSMockRecorder) ConvertToAbsolutePath(rootPath any, paths ...any) *gomock.Call {
+// MockIFencedLockMockRecorder is the mock recorder for MockIFencedLock.
+type MockIFencedLockMockRecorder struct {
+	mock *MockIFencedLock
+}
+
+// NewMockIFencedLock creates a new mock instance.
+func NewMockIFencedLock(ctrl *gomock.Controller) *MockIFencedLock {
+	mock := &MockIFencedLock{ctrl: ctrl}
+	mock.recorder = &MockIFencedLockMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIFencedLock) EXPECT() *MockIFencedLockMockRecorder {
+	return m.recorder
+}
+
+// GetOwner mocks base method.
+func (m *MockIFencedLock) GetOwner(ctx context.Context) (*filesystem.LockOwner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwner", ctx)
+	ret0, _ := ret[0].(*filesystem.LockOwner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwner indicates an expected call of GetOwner.
+func (mr *MockIFencedLockMockRecorder) GetOwner(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwner", reflect.TypeOf((*MockIFencedLock)(nil).GetOwner), ctx)
+}
+
+// IsStale mocks base method.
+func (m *MockIFencedLock) IsStale() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsStale")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsStale indicates an expected call of IsStale.
+func (mr *MockIFencedLockMockRecorder) IsStale() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStale", reflect.TypeOf((*MockIFencedLock)(nil).IsStale))
+}
+
+// Lock mocks base method.
+func (m *MockIFencedLock) Lock(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockIFencedLockMockRecorder) Lock(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockIFencedLock)(nil).Lock), ctx)
+}
+
+// LockWithFencingToken mocks base method.
+func (m *MockIFencedLock) LockWithFencingToken(ctx context.Context) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockWithFencingToken", ctx)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockWithFencingToken indicates an expected call of LockWithFencingToken.
+func (mr *MockIFencedLockMockRecorder) LockWithFencingToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWithFencingToken", reflect.TypeOf((*MockIFencedLock)(nil).LockWithFencingToken), ctx)
+}
+
+// LockWithTimeout mocks base method.
+func (m *MockIFencedLock) LockWithTimeout(ctx context.Context, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockWithTimeout", ctx, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockWithTimeout indicates an expected call of LockWithTimeout.
+func (mr *MockIFencedLockMockRecorder) LockWithTimeout(ctx, timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWithTimeout", reflect.TypeOf((*MockIFencedLock)(nil).LockWithTimeout), ctx, timeout)
+}
+
+// LockWithTimeoutAndFencingToken mocks base method.
+func (m *MockIFencedLock) LockWithTimeoutAndFencingToken(ctx context.Context, timeout time.Duration) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockWithTimeoutAndFencingToken", ctx, timeout)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockWithTimeoutAndFencingToken indicates an expected call of LockWithTimeoutAndFencingToken.
+func (mr *MockIFencedLockMockRecorder) LockWithTimeoutAndFencingToken(ctx, timeout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWithTimeoutAndFencingToken", reflect.TypeOf((*MockIFencedLock)(nil).LockWithTimeoutAndFencingToken), ctx, timeout)
+}
+
+// MakeStale mocks base method.
+func (m *MockIFencedLock) MakeStale(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MakeStale", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MakeStale indicates an expected call of MakeStale.
+func (mr *MockIFencedLockMockRecorder) MakeStale(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeStale", reflect.TypeOf((*MockIFencedLock)(nil).MakeStale), ctx)
+}
+
+// ReleaseIfStale mocks base method.
+func (m *MockIFencedLock) ReleaseIfStale(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIfStale", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIfStale indicates an expected call of ReleaseIfStale.
+func (mr *MockIFencedLockMockRecorder) ReleaseIfStale(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIfStale", reflect.TypeOf((*MockIFencedLock)(nil).ReleaseIfStale), ctx)
+}
+
+// TryLock mocks base method.
+func (m *MockIFencedLock) TryLock(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryLock", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TryLock indicates an expected call of TryLock.
+func (mr *MockIFencedLockMockRecorder) TryLock(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryLock", reflect.TypeOf((*MockIFencedLock)(nil).TryLock), ctx)
+}
+
+// TryLockWithFencingToken mocks base method.
+func (m *MockIFencedLock) TryLockWithFencingToken(ctx context.Context) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryLockWithFencingToken", ctx)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryLockWithFencingToken indicates an expected call of TryLockWithFencingToken.
+func (mr *MockIFencedLockMockRecorder) TryLockWithFencingToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryLockWithFencingToken", reflect.TypeOf((*MockIFencedLock)(nil).TryLockWithFencingToken), ctx)
+}
+
+// Unlock mocks base method.
+func (m *MockIFencedLock) Unlock(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockIFencedLockMockRecorder) Unlock(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockIFencedLock)(nil).Unlock), ctx)
+}
+
+// MockILockBackend is a mock of ILockBackend interface.
+type MockILockBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockILockBackendMockRecorder
+	isgomock struct{}
+}
+
+// MockILockBackendMockRecorder is the mock recorder for MockILockBackend.
+type MockILockBackendMockRecorder struct {
+	mock *MockILockBackend
+}
+
+// NewMockILockBackend creates a new mock instance.
+func NewMockILockBackend(ctrl *gomock.Controller) *MockILockBackend {
+	mock := &MockILockBackend{ctrl: ctrl}
+	mock.recorder = &MockILockBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockILockBackend) EXPECT() *MockILockBackendMockRecorder {
+	return m.recorder
+}
+
+// GetOwner mocks base method.
+func (m *MockILockBackend) GetOwner(ctx context.Context) (*filesystem.LockOwner, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwner", ctx)
+	ret0, _ := ret[0].(*filesystem.LockOwner)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwner indicates an expected call of GetOwner.
+func (mr *MockILockBackendMockRecorder) GetOwner(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwner", reflect.TypeOf((*MockILockBackend)(nil).GetOwner), ctx)
+}
+
+// IsStale mocks base method.
+func (m *MockILockBackend) IsStale() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsStale")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsStale indicates an expected call of IsStale.
+func (mr *MockILockBackendMockRecorder) IsStale() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStale", reflect.TypeOf((*MockILockBackend)(nil).IsStale))
+}
+
+// MakeStale mocks base method.
+func (m *MockILockBackend) MakeStale(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MakeStale", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MakeStale indicates an expected call of MakeStale.
+func (mr *MockILockBackendMockRecorder) MakeStale(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeStale", reflect.TypeOf((*MockILockBackend)(nil).MakeStale), ctx)
+}
+
+// Release mocks base method.
+func (m *MockILockBackend) Release(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockILockBackendMockRecorder) Release(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockILockBackend)(nil).Release), ctx)
+}
+
+// TryAcquire mocks base method.
+func (m *MockILockBackend) TryAcquire(ctx context.Context, owner *filesystem.LockOwner, overrideStaleLock bool) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryAcquire", ctx, owner, overrideStaleLock)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryAcquire indicates an expected call of TryAcquire.
+func (mr *MockILockBackendMockRecorder) TryAcquire(ctx, owner, overrideStaleLock any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryAcquire", reflect.TypeOf((*MockILockBackend)(nil).TryAcquire), ctx, owner, overrideStaleLock)
+}
+
+// MockILimits is a mock of ILimits interface.
+type MockILimits struct {
+	ctrl     *gomock.Controller
+	recorder *MockILimitsMockRecorder
+	isgomock struct{}
+}
+
+// MockILimitsMockRecorder is the mock recorder for MockILimits.
+type MockILimitsMockRecorder struct {
+	mock *MockILimits
+}
+
+// NewMockILimits creates a new mock instance.
+func NewMockILimits(ctrl *gomock.Controller) *MockILimits {
+	mock := &MockILimits{ctrl: ctrl}
+	mock.recorder = &MockILimitsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockILimits) EXPECT() *MockILimitsMockRecorder {
+	return m.recorder
+}
+
+// Apply mocks base method.
+func (m *MockILimits) Apply() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Apply")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Apply indicates an expected call of Apply.
+func (mr *MockILimitsMockRecorder) Apply() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Apply", reflect.TypeOf((*MockILimits)(nil).Apply))
+}
+
+// ApplyRecursively mocks base method.
+func (m *MockILimits) ApplyRecursively() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyRecursively")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ApplyRecursively indicates an expected call of ApplyRecursively.
+func (mr *MockILimitsMockRecorder) ApplyRecursively() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyRecursively", reflect.TypeOf((*MockILimits)(nil).ApplyRecursively))
+}
+
+// GetMaxDepth mocks base method.
+func (m *MockILimits) GetMaxDepth() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMaxDepth")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetMaxDepth indicates an expected call of GetMaxDepth.
+func (mr *MockILimitsMockRecorder) GetMaxDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxDepth", reflect.TypeOf((*MockILimits)(nil).GetMaxDepth))
+}
+
+// GetMaxFileCount mocks base method.
+func (m *MockILimits) GetMaxFileCount() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMaxFileCount")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetMaxFileCount indicates an expected call of GetMaxFileCount.
+func (mr *MockILimitsMockRecorder) GetMaxFileCount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxFileCount", reflect.TypeOf((*MockILimits)(nil).GetMaxFileCount))
+}
+
+// GetMaxFileSize mocks base method.
+func (m *MockILimits) GetMaxFileSize() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMaxFileSize")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetMaxFileSize indicates an expected call of GetMaxFileSize.
+func (mr *MockILimitsMockRecorder) GetMaxFileSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxFileSize", reflect.TypeOf((*MockILimits)(nil).GetMaxFileSize))
+}
+
+// GetMaxTotalSize mocks base method.
+func (m *MockILimits) GetMaxTotalSize() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMaxTotalSize")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// GetMaxTotalSize indicates an expected call of GetMaxTotalSize.
+func (mr *MockILimitsMockRecorder) GetMaxTotalSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMaxTotalSize", reflect.TypeOf((*MockILimits)(nil).GetMaxTotalSize))
+}
+
+// Validate mocks base method.
+func (m *MockILimits) Validate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockILimitsMockRecorder) Validate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockILimits)(nil).Validate))
+}
+
+// MockFS is a mock of FS interface.
+type MockFS struct {
+	ctrl     *gomock.Controller
+	recorder *MockFSMockRecorder
+	isgomock struct{}
+}
+
+// MockFSMockRecorder is the mock recorder for MockFS.
+type MockFSMockRecorder struct {
+	mock *MockFS
+}
+
+// NewMockFS creates a new mock instance.
+func NewMockFS(ctrl *gomock.Controller) *MockFS {
+	mock := &MockFS{ctrl: ctrl}
+	mock.recorder = &MockFSMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFS) EXPECT() *MockFSMockRecorder {
+	return m.recorder
+}
+
+// ChangeOwnership mocks base method.
+func (m *MockFS) ChangeOwnership(name string, owner *user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeOwnership", name, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeOwnership indicates an expected call of ChangeOwnership.
+func (mr *MockFSMockRecorder) ChangeOwnership(name, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOwnership", reflect.TypeOf((*MockFS)(nil).ChangeOwnership), name, owner)
+}
+
+// ChangeOwnershipRecursively mocks base method.
+func (m *MockFS) ChangeOwnershipRecursively(ctx context.Context, path string, owner *user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeOwnershipRecursively", ctx, path, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeOwnershipRecursively indicates an expected call of ChangeOwnershipRecursively.
+func (mr *MockFSMockRecorder) ChangeOwnershipRecursively(ctx, path, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOwnershipRecursively", reflect.TypeOf((*MockFS)(nil).ChangeOwnershipRecursively), ctx, path, owner)
+}
+
+// Chmod mocks base method.
+func (m *MockFS) Chmod(name string, mode os.FileMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Chmod", name, mode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Chmod indicates an expected call of Chmod.
+func (mr *MockFSMockRecorder) Chmod(name, mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chmod", reflect.TypeOf((*MockFS)(nil).Chmod), name, mode)
+}
+
+// ChmodRecursively mocks base method.
+func (m *MockFS) ChmodRecursively(ctx context.Context, path string, mode os.FileMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChmodRecursively", ctx, path, mode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChmodRecursively indicates an expected call of ChmodRecursively.
+func (mr *MockFSMockRecorder) ChmodRecursively(ctx, path, mode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChmodRecursively", reflect.TypeOf((*MockFS)(nil).ChmodRecursively), ctx, path, mode)
+}
+
+// ChmodRecursivelyWithOptions mocks base method.
+func (m *MockFS) ChmodRecursivelyWithOptions(ctx context.Context, path string, mode os.FileMode, options ...filesystem.TreeOperationOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, path, mode}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ChmodRecursivelyWithOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChmodRecursivelyWithOptions indicates an expected call of ChmodRecursivelyWithOptions.
+func (mr *MockFSMockRecorder) ChmodRecursivelyWithOptions(ctx, path, mode any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, path, mode}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChmodRecursivelyWithOptions", reflect.TypeOf((*MockFS)(nil).ChmodRecursivelyWithOptions), varargs...)
+}
+
+// Chown mocks base method.
+func (m *MockFS) Chown(name string, uid, gid int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Chown", name, uid, gid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Chown indicates an expected call of Chown.
+func (mr *MockFSMockRecorder) Chown(name, uid, gid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chown", reflect.TypeOf((*MockFS)(nil).Chown), name, uid, gid)
+}
+
+// ChownRecursively mocks base method.
+func (m *MockFS) ChownRecursively(ctx context.Context, path string, uid, gid int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChownRecursively", ctx, path, uid, gid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChownRecursively indicates an expected call of ChownRecursively.
+func (mr *MockFSMockRecorder) ChownRecursively(ctx, path, uid, gid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChownRecursively", reflect.TypeOf((*MockFS)(nil).ChownRecursively), ctx, path, uid, gid)
+}
+
+// Chtimes mocks base method.
+func (m *MockFS) Chtimes(name string, atime, mtime time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Chtimes", name, atime, mtime)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Chtimes indicates an expected call of Chtimes.
+func (mr *MockFSMockRecorder) Chtimes(name, atime, mtime any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chtimes", reflect.TypeOf((*MockFS)(nil).Chtimes), name, atime, mtime)
+}
+
+// CleanDir mocks base method.
+func (m *MockFS) CleanDir(dir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanDir", dir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CleanDir indicates an expected call of CleanDir.
+func (mr *MockFSMockRecorder) CleanDir(dir any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanDir", reflect.TypeOf((*MockFS)(nil).CleanDir), dir)
+}
+
+// CleanDirWithContext mocks base method.
+func (m *MockFS) CleanDirWithContext(ctx context.Context, dir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanDirWithContext", ctx, dir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CleanDirWithContext indicates an expected call of CleanDirWithContext.
+func (mr *MockFSMockRecorder) CleanDirWithContext(ctx, dir any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanDirWithContext", reflect.TypeOf((*MockFS)(nil).CleanDirWithContext), ctx, dir)
+}
+
+// CleanDirWithContextAndExclusionPatterns mocks base method.
+func (m *MockFS) CleanDirWithContextAndExclusionPatterns(ctx context.Context, dir string, exclusionPatterns ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, dir}
+	for _, a := range exclusionPatterns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CleanDirWithContextAndExclusionPatterns", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CleanDirWithContextAndExclusionPatterns indicates an expected call of CleanDirWithContextAndExclusionPatterns.
+func (mr *MockFSMockRecorder) CleanDirWithContextAndExclusionPatterns(ctx, dir any, exclusionPatterns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, dir}, exclusionPatterns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanDirWithContextAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).CleanDirWithContextAndExclusionPatterns), varargs...)
+}
+
+// ConvertFilePath mocks base method.
+func (m *MockFS) ConvertFilePath(name string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConvertFilePath", name)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ConvertFilePath indicates an expected call of ConvertFilePath.
+func (mr *MockFSMockRecorder) ConvertFilePath(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertFilePath", reflect.TypeOf((*MockFS)(nil).ConvertFilePath), name)
+}
+
+// ConvertToAbsolutePath mocks base method.
+func (m *MockFS) ConvertToAbsolutePath(rootPath string, paths ...string) ([]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{rootPath}
+	for _, a := range paths {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ConvertToAbsolutePath", varargs...)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConvertToAbsolutePath indicates an expected call of ConvertToAbsolutePath.
+func (mr *MockFSMockRecorder) ConvertToAbsolutePath(rootPath any, paths ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]any{rootPath}, paths...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertToAbsolutePath", reflect.TypeOf((*MockFS)(nil).ConvertToAbsolutePath), varargs...)
@@ -1254,6 +1920,25 @@ func (mr *MockFSMockRecorder) CopyWithContextAndExclusionPatterns(ctx, src, dest
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyWithContextAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).CopyWithContextAndExclusionPatterns), varargs...)
 }
 
+// CopyWithContextAndOptions mocks base method.
+func (m *MockFS) CopyWithContextAndOptions(ctx context.Context, src, dest string, options ...filesystem.TreeOperationOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, src, dest}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CopyWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CopyWithContextAndOptions indicates an expected call of CopyWithContextAndOptions.
+func (mr *MockFSMockRecorder) CopyWithContextAndOptions(ctx, src, dest any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, src, dest}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyWithContextAndOptions", reflect.TypeOf((*MockFS)(nil).CopyWithContextAndOptions), varargs...)
+}
+
 // CreateFile mocks base method.
 func (m *MockFS) CreateFile(name string) (filesystem.File, error) {
 	m.ctrl.T.Helper()
@@ -1394,6 +2079,26 @@ func (mr *MockFSMockRecorder) FileHashWithContext(ctx, hashAlgo, path any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileHashWithContext", reflect.TypeOf((*MockFS)(nil).FileHashWithContext), ctx, hashAlgo, path)
 }
 
+// FileHashesWithContext mocks base method.
+func (m *MockFS) FileHashesWithContext(ctx context.Context, hashAlgo, root string, options ...filesystem.TreeOperationOption) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, hashAlgo, root}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "FileHashesWithContext", varargs...)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FileHashesWithContext indicates an expected call of FileHashesWithContext.
+func (mr *MockFSMockRecorder) FileHashesWithContext(ctx, hashAlgo, root any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, hashAlgo, root}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileHashesWithContext", reflect.TypeOf((*MockFS)(nil).FileHashesWithContext), varargs...)
+}
+
 // FindAll mocks base method.
 func (m *MockFS) FindAll(dir string, extensions ...string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -1486,6 +2191,21 @@ func (mr *MockFSMockRecorder) GetType() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetType", reflect.TypeOf((*MockFS)(nil).GetType))
 }
 
+// GetXattr mocks base method.
+func (m *MockFS) GetXattr(name, attribute string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetXattr", name, attribute)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetXattr indicates an expected call of GetXattr.
+func (mr *MockFSMockRecorder) GetXattr(name, attribute any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetXattr", reflect.TypeOf((*MockFS)(nil).GetXattr), name, attribute)
+}
+
 // Glob mocks base method.
 func (m *MockFS) Glob(pattern string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -1651,6 +2371,21 @@ func (mr *MockFSMockRecorder) ListDirTreeWithContextAndExclusionPatterns(ctx, di
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDirTreeWithContextAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).ListDirTreeWithContextAndExclusionPatterns), varargs...)
 }
 
+// ListXattr mocks base method.
+func (m *MockFS) ListXattr(name string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListXattr", name)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListXattr indicates an expected call of ListXattr.
+func (mr *MockFSMockRecorder) ListXattr(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListXattr", reflect.TypeOf((*MockFS)(nil).ListXattr), name)
+}
+
 // Lls mocks base method.
 func (m *MockFS) Lls(dir string) ([]os.FileInfo, error) {
 	m.ctrl.T.Helper()
@@ -1875,15 +2610,43 @@ func (mr *MockFSMockRecorder) MoveWithContext(ctx, src, dest any) *gomock.Call {
 // NewRemoteLockFile mocks base method.
 func (m *MockFS) NewRemoteLockFile(id, dirToLock string) filesystem.ILock {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewRemoteLockFile", id, dirToLock)
-	ret0, _ := ret[0].(filesystem.ILock)
+	ret := m.ctrl.Call(m, "NewRemoteLockFile", id, dirToLock)
+	ret0, _ := ret[0].(filesystem.ILock)
+	return ret0
+}
+
+// NewRemoteLockFile indicates an expected call of NewRemoteLockFile.
+func (mr *MockFSMockRecorder) NewRemoteLockFile(id, dirToLock any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteLockFile", reflect.TypeOf((*MockFS)(nil).NewRemoteLockFile), id, dirToLock)
+}
+
+// NewRemoteRWLockFile mocks base method.
+func (m *MockFS) NewRemoteRWLockFile(id, dirToLock string) filesystem.IRWLock {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRemoteRWLockFile", id, dirToLock)
+	ret0, _ := ret[0].(filesystem.IRWLock)
+	return ret0
+}
+
+// NewRemoteRWLockFile indicates an expected call of NewRemoteRWLockFile.
+func (mr *MockFSMockRecorder) NewRemoteRWLockFile(id, dirToLock any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteRWLockFile", reflect.TypeOf((*MockFS)(nil).NewRemoteRWLockFile), id, dirToLock)
+}
+
+// NewRemoteSemaphoreFile mocks base method.
+func (m *MockFS) NewRemoteSemaphoreFile(id, dirToLock string, permits int) filesystem.ISemaphore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRemoteSemaphoreFile", id, dirToLock, permits)
+	ret0, _ := ret[0].(filesystem.ISemaphore)
 	return ret0
 }
 
-// NewRemoteLockFile indicates an expected call of NewRemoteLockFile.
-func (mr *MockFSMockRecorder) NewRemoteLockFile(id, dirToLock any) *gomock.Call {
+// NewRemoteSemaphoreFile indicates an expected call of NewRemoteSemaphoreFile.
+func (mr *MockFSMockRecorder) NewRemoteSemaphoreFile(id, dirToLock, permits any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteLockFile", reflect.TypeOf((*MockFS)(nil).NewRemoteLockFile), id, dirToLock)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteSemaphoreFile", reflect.TypeOf((*MockFS)(nil).NewRemoteSemaphoreFile), id, dirToLock, permits)
 }
 
 // Open mocks base method.
@@ -2053,6 +2816,25 @@ func (mr *MockFSMockRecorder) RemoveWithContextAndExclusionPatterns(ctx, dir any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWithContextAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).RemoveWithContextAndExclusionPatterns), varargs...)
 }
 
+// RemoveWithContextAndOptions mocks base method.
+func (m *MockFS) RemoveWithContextAndOptions(ctx context.Context, dir string, options ...filesystem.TreeOperationOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, dir}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RemoveWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveWithContextAndOptions indicates an expected call of RemoveWithContextAndOptions.
+func (mr *MockFSMockRecorder) RemoveWithContextAndOptions(ctx, dir any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, dir}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWithContextAndOptions", reflect.TypeOf((*MockFS)(nil).RemoveWithContextAndOptions), varargs...)
+}
+
 // RemoveWithPrivileges mocks base method.
 func (m *MockFS) RemoveWithPrivileges(ctx context.Context, dir string) error {
 	m.ctrl.T.Helper()
@@ -2067,6 +2849,20 @@ func (mr *MockFSMockRecorder) RemoveWithPrivileges(ctx, dir any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWithPrivileges", reflect.TypeOf((*MockFS)(nil).RemoveWithPrivileges), ctx, dir)
 }
 
+// RemoveXattr mocks base method.
+func (m *MockFS) RemoveXattr(name, attribute string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveXattr", name, attribute)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveXattr indicates an expected call of RemoveXattr.
+func (mr *MockFSMockRecorder) RemoveXattr(name, attribute any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveXattr", reflect.TypeOf((*MockFS)(nil).RemoveXattr), name, attribute)
+}
+
 // Rm mocks base method.
 func (m *MockFS) Rm(dir string) error {
 	m.ctrl.T.Helper()
@@ -2081,6 +2877,20 @@ func (mr *MockFSMockRecorder) Rm(dir any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rm", reflect.TypeOf((*MockFS)(nil).Rm), dir)
 }
 
+// SetXattr mocks base method.
+func (m *MockFS) SetXattr(name, attribute string, value []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetXattr", name, attribute, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetXattr indicates an expected call of SetXattr.
+func (mr *MockFSMockRecorder) SetXattr(name, attribute, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetXattr", reflect.TypeOf((*MockFS)(nil).SetXattr), name, attribute, value)
+}
+
 // Stat mocks base method.
 func (m *MockFS) Stat(name string) (os.FileInfo, error) {
 	m.ctrl.T.Helper()
@@ -2175,6 +2985,86 @@ func (mr *MockFSMockRecorder) Symlink(oldname, newname any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Symlink", reflect.TypeOf((*MockFS)(nil).Symlink), oldname, newname)
 }
 
+// Tar mocks base method.
+func (m *MockFS) Tar(source, destination string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tar", source, destination)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Tar indicates an expected call of Tar.
+func (mr *MockFSMockRecorder) Tar(source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tar", reflect.TypeOf((*MockFS)(nil).Tar), source, destination)
+}
+
+// TarWithContext mocks base method.
+func (m *MockFS) TarWithContext(ctx context.Context, source, destination string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TarWithContext", ctx, source, destination)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContext indicates an expected call of TarWithContext.
+func (mr *MockFSMockRecorder) TarWithContext(ctx, source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContext", reflect.TypeOf((*MockFS)(nil).TarWithContext), ctx, source, destination)
+}
+
+// TarWithContextAndLimits mocks base method.
+func (m *MockFS) TarWithContextAndLimits(ctx context.Context, source, destination string, limits filesystem.ILimits) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TarWithContextAndLimits", ctx, source, destination, limits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContextAndLimits indicates an expected call of TarWithContextAndLimits.
+func (mr *MockFSMockRecorder) TarWithContextAndLimits(ctx, source, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContextAndLimits", reflect.TypeOf((*MockFS)(nil).TarWithContextAndLimits), ctx, source, destination, limits)
+}
+
+// TarWithContextAndLimitsAndExclusionPatterns mocks base method.
+func (m *MockFS) TarWithContextAndLimitsAndExclusionPatterns(ctx context.Context, source, destination string, limits filesystem.ILimits, exclusionPatterns ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination, limits}
+	for _, a := range exclusionPatterns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TarWithContextAndLimitsAndExclusionPatterns", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContextAndLimitsAndExclusionPatterns indicates an expected call of TarWithContextAndLimitsAndExclusionPatterns.
+func (mr *MockFSMockRecorder) TarWithContextAndLimitsAndExclusionPatterns(ctx, source, destination, limits any, exclusionPatterns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination, limits}, exclusionPatterns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContextAndLimitsAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).TarWithContextAndLimitsAndExclusionPatterns), varargs...)
+}
+
+// TarWithContextAndOptions mocks base method.
+func (m *MockFS) TarWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TarWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContextAndOptions indicates an expected call of TarWithContextAndOptions.
+func (mr *MockFSMockRecorder) TarWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContextAndOptions", reflect.TypeOf((*MockFS)(nil).TarWithContextAndOptions), varargs...)
+}
+
 // TempDir mocks base method.
 func (m *MockFS) TempDir(dir, prefix string) (string, error) {
 	m.ctrl.T.Helper()
@@ -2293,6 +3183,86 @@ func (mr *MockFSMockRecorder) TouchTempFileInTempDir(pattern any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchTempFileInTempDir", reflect.TypeOf((*MockFS)(nil).TouchTempFileInTempDir), pattern)
 }
 
+// Untar mocks base method.
+func (m *MockFS) Untar(source, destination string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Untar", source, destination)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Untar indicates an expected call of Untar.
+func (mr *MockFSMockRecorder) Untar(source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Untar", reflect.TypeOf((*MockFS)(nil).Untar), source, destination)
+}
+
+// UntarFromReaderWithContextAndLimits mocks base method.
+func (m *MockFS) UntarFromReaderWithContextAndLimits(ctx context.Context, source io.Reader, destination string, limits filesystem.ILimits) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntarFromReaderWithContextAndLimits", ctx, source, destination, limits)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarFromReaderWithContextAndLimits indicates an expected call of UntarFromReaderWithContextAndLimits.
+func (mr *MockFSMockRecorder) UntarFromReaderWithContextAndLimits(ctx, source, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarFromReaderWithContextAndLimits", reflect.TypeOf((*MockFS)(nil).UntarFromReaderWithContextAndLimits), ctx, source, destination, limits)
+}
+
+// UntarWithContext mocks base method.
+func (m *MockFS) UntarWithContext(ctx context.Context, source, destination string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntarWithContext", ctx, source, destination)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarWithContext indicates an expected call of UntarWithContext.
+func (mr *MockFSMockRecorder) UntarWithContext(ctx, source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarWithContext", reflect.TypeOf((*MockFS)(nil).UntarWithContext), ctx, source, destination)
+}
+
+// UntarWithContextAndLimits mocks base method.
+func (m *MockFS) UntarWithContextAndLimits(ctx context.Context, source, destination string, limits filesystem.ILimits) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntarWithContextAndLimits", ctx, source, destination, limits)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarWithContextAndLimits indicates an expected call of UntarWithContextAndLimits.
+func (mr *MockFSMockRecorder) UntarWithContextAndLimits(ctx, source, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarWithContextAndLimits", reflect.TypeOf((*MockFS)(nil).UntarWithContextAndLimits), ctx, source, destination, limits)
+}
+
+// UntarWithContextAndOptions mocks base method.
+func (m *MockFS) UntarWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) ([]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UntarWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarWithContextAndOptions indicates an expected call of UntarWithContextAndOptions.
+func (mr *MockFSMockRecorder) UntarWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarWithContextAndOptions", reflect.TypeOf((*MockFS)(nil).UntarWithContextAndOptions), varargs...)
+}
+
 // Unzip mocks base method.
 func (m *MockFS) Unzip(source, destination string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -2308,6 +3278,21 @@ func (mr *MockFSMockRecorder) Unzip(source, destination any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unzip", reflect.TypeOf((*MockFS)(nil).Unzip), source, destination)
 }
 
+// UnzipFromReaderWithContextAndLimits mocks base method.
+func (m *MockFS) UnzipFromReaderWithContextAndLimits(ctx context.Context, source io.ReaderAt, size int64, destination string, limits filesystem.ILimits) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnzipFromReaderWithContextAndLimits", ctx, source, size, destination, limits)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnzipFromReaderWithContextAndLimits indicates an expected call of UnzipFromReaderWithContextAndLimits.
+func (mr *MockFSMockRecorder) UnzipFromReaderWithContextAndLimits(ctx, source, size, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnzipFromReaderWithContextAndLimits", reflect.TypeOf((*MockFS)(nil).UnzipFromReaderWithContextAndLimits), ctx, source, size, destination, limits)
+}
+
 // UnzipWithContext mocks base method.
 func (m *MockFS) UnzipWithContext(ctx context.Context, source, destination string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -2338,6 +3323,26 @@ func (mr *MockFSMockRecorder) UnzipWithContextAndLimits(ctx, source, destination
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnzipWithContextAndLimits", reflect.TypeOf((*MockFS)(nil).UnzipWithContextAndLimits), ctx, source, destination, limits)
 }
 
+// UnzipWithContextAndOptions mocks base method.
+func (m *MockFS) UnzipWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) ([]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UnzipWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnzipWithContextAndOptions indicates an expected call of UnzipWithContextAndOptions.
+func (mr *MockFSMockRecorder) UnzipWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnzipWithContextAndOptions", reflect.TypeOf((*MockFS)(nil).UnzipWithContextAndOptions), varargs...)
+}
+
 // Walk mocks base method.
 func (m *MockFS) Walk(root string, fn filepath.WalkFunc) error {
 	m.ctrl.T.Helper()
@@ -2385,6 +3390,26 @@ func (mr *MockFSMockRecorder) WalkWithContextAndExclusionPatterns(ctx, root, fn
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WalkWithContextAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).WalkWithContextAndExclusionPatterns), varargs...)
 }
 
+// Watch mocks base method.
+func (m *MockFS) Watch(ctx context.Context, root string, exclusionPatterns ...string) (iter.Seq2[filesystem.WatchEvent, error], error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, root}
+	for _, a := range exclusionPatterns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Watch", varargs...)
+	ret0, _ := ret[0].(iter.Seq2[filesystem.WatchEvent, error])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockFSMockRecorder) Watch(ctx, root any, exclusionPatterns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, root}, exclusionPatterns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockFS)(nil).Watch), varargs...)
+}
+
 // WriteFile mocks base method.
 func (m *MockFS) WriteFile(filename string, data []byte, perm os.FileMode) error {
 	m.ctrl.T.Helper()
@@ -2399,6 +3424,20 @@ func (mr *MockFSMockRecorder) WriteFile(filename, data, perm any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteFile", reflect.TypeOf((*MockFS)(nil).WriteFile), filename, data, perm)
 }
 
+// WriteFileAtomically mocks base method.
+func (m *MockFS) WriteFileAtomically(ctx context.Context, filename string, data []byte, perm os.FileMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteFileAtomically", ctx, filename, data, perm)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteFileAtomically indicates an expected call of WriteFileAtomically.
+func (mr *MockFSMockRecorder) WriteFileAtomically(ctx, filename, data, perm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteFileAtomically", reflect.TypeOf((*MockFS)(nil).WriteFileAtomically), ctx, filename, data, perm)
+}
+
 // WriteFileWithContext mocks base method.
 func (m *MockFS) WriteFileWithContext(ctx context.Context, filename string, data []byte, perm os.FileMode) error {
 	m.ctrl.T.Helper()
@@ -2428,6 +3467,21 @@ func (mr *MockFSMockRecorder) WriteToFile(ctx, filename, reader, perm any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteToFile", reflect.TypeOf((*MockFS)(nil).WriteToFile), ctx, filename, reader, perm)
 }
 
+// WriteToFileAtomically mocks base method.
+func (m *MockFS) WriteToFileAtomically(ctx context.Context, filename string, reader io.Reader, perm os.FileMode) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteToFileAtomically", ctx, filename, reader, perm)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteToFileAtomically indicates an expected call of WriteToFileAtomically.
+func (mr *MockFSMockRecorder) WriteToFileAtomically(ctx, filename, reader, perm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteToFileAtomically", reflect.TypeOf((*MockFS)(nil).WriteToFileAtomically), ctx, filename, reader, perm)
+}
+
 // Zip mocks base method.
 func (m *MockFS) Zip(source, destination string) error {
 	m.ctrl.T.Helper()
@@ -2489,6 +3543,25 @@ func (mr *MockFSMockRecorder) ZipWithContextAndLimitsAndExclusionPatterns(ctx, s
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZipWithContextAndLimitsAndExclusionPatterns", reflect.TypeOf((*MockFS)(nil).ZipWithContextAndLimitsAndExclusionPatterns), varargs...)
 }
 
+// ZipWithContextAndOptions mocks base method.
+func (m *MockFS) ZipWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ZipWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ZipWithContextAndOptions indicates an expected call of ZipWithContextAndOptions.
+func (mr *MockFSMockRecorder) ZipWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZipWithContextAndOptions", reflect.TypeOf((*MockFS)(nil).ZipWithContextAndOptions), varargs...)
+}
+
 // MockICloseableFS is a mock of ICloseableFS interface.
 type MockICloseableFS struct {
 	ctrl     *gomock.Controller
@@ -2569,6 +3642,25 @@ func (mr *MockICloseableFSMockRecorder) ChmodRecursively(ctx, path, mode any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChmodRecursively", reflect.TypeOf((*MockICloseableFS)(nil).ChmodRecursively), ctx, path, mode)
 }
 
+// ChmodRecursivelyWithOptions mocks base method.
+func (m *MockICloseableFS) ChmodRecursivelyWithOptions(ctx context.Context, path string, mode os.FileMode, options ...filesystem.TreeOperationOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, path, mode}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ChmodRecursivelyWithOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChmodRecursivelyWithOptions indicates an expected call of ChmodRecursivelyWithOptions.
+func (mr *MockICloseableFSMockRecorder) ChmodRecursivelyWithOptions(ctx, path, mode any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, path, mode}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChmodRecursivelyWithOptions", reflect.TypeOf((*MockICloseableFS)(nil).ChmodRecursivelyWithOptions), varargs...)
+}
+
 // Chown mocks base method.
 func (m *MockICloseableFS) Chown(name string, uid, gid int) error {
 	m.ctrl.T.Helper()
@@ -2829,6 +3921,25 @@ func (mr *MockICloseableFSMockRecorder) CopyWithContextAndExclusionPatterns(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyWithContextAndExclusionPatterns", reflect.TypeOf((*MockICloseableFS)(nil).CopyWithContextAndExclusionPatterns), varargs...)
 }
 
+// CopyWithContextAndOptions mocks base method.
+func (m *MockICloseableFS) CopyWithContextAndOptions(ctx context.Context, src, dest string, options ...filesystem.TreeOperationOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, src, dest}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CopyWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CopyWithContextAndOptions indicates an expected call of CopyWithContextAndOptions.
+func (mr *MockICloseableFSMockRecorder) CopyWithContextAndOptions(ctx, src, dest any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, src, dest}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyWithContextAndOptions", reflect.TypeOf((*MockICloseableFS)(nil).CopyWithContextAndOptions), varargs...)
+}
+
 // CreateFile mocks base method.
 func (m *MockICloseableFS) CreateFile(name string) (filesystem.File, error) {
 	m.ctrl.T.Helper()
@@ -2969,6 +4080,26 @@ func (mr *MockICloseableFSMockRecorder) FileHashWithContext(ctx, hashAlgo, path
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileHashWithContext", reflect.TypeOf((*MockICloseableFS)(nil).FileHashWithContext), ctx, hashAlgo, path)
 }
 
+// FileHashesWithContext mocks base method.
+func (m *MockICloseableFS) FileHashesWithContext(ctx context.Context, hashAlgo, root string, options ...filesystem.TreeOperationOption) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, hashAlgo, root}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "FileHashesWithContext", varargs...)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FileHashesWithContext indicates an expected call of FileHashesWithContext.
+func (mr *MockICloseableFSMockRecorder) FileHashesWithContext(ctx, hashAlgo, root any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, hashAlgo, root}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileHashesWithContext", reflect.TypeOf((*MockICloseableFS)(nil).FileHashesWithContext), varargs...)
+}
+
 // FindAll mocks base method.
 func (m *MockICloseableFS) FindAll(dir string, extensions ...string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -3061,6 +4192,21 @@ func (mr *MockICloseableFSMockRecorder) GetType() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetType", reflect.TypeOf((*MockICloseableFS)(nil).GetType))
 }
 
+// GetXattr mocks base method.
+func (m *MockICloseableFS) GetXattr(name, attribute string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetXattr", name, attribute)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetXattr indicates an expected call of GetXattr.
+func (mr *MockICloseableFSMockRecorder) GetXattr(name, attribute any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetXattr", reflect.TypeOf((*MockICloseableFS)(nil).GetXattr), name, attribute)
+}
+
 // Glob mocks base method.
 func (m *MockICloseableFS) Glob(pattern string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -3226,6 +4372,21 @@ func (mr *MockICloseableFSMockRecorder) ListDirTreeWithContextAndExclusionPatter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDirTreeWithContextAndExclusionPatterns", reflect.TypeOf((*MockICloseableFS)(nil).ListDirTreeWithContextAndExclusionPatterns), varargs...)
 }
 
+// ListXattr mocks base method.
+func (m *MockICloseableFS) ListXattr(name string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListXattr", name)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListXattr indicates an expected call of ListXattr.
+func (mr *MockICloseableFSMockRecorder) ListXattr(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListXattr", reflect.TypeOf((*MockICloseableFS)(nil).ListXattr), name)
+}
+
 // Lls mocks base method.
 func (m *MockICloseableFS) Lls(dir string) ([]os.FileInfo, error) {
 	m.ctrl.T.Helper()
@@ -3441,24 +4602,52 @@ func (m *MockICloseableFS) MoveWithContext(ctx context.Context, src, dest string
 	return ret0
 }
 
-// MoveWithContext indicates an expected call of MoveWithContext.
-func (mr *MockICloseableFSMockRecorder) MoveWithContext(ctx, src, dest any) *gomock.Call {
+// MoveWithContext indicates an expected call of MoveWithContext.
+func (mr *MockICloseableFSMockRecorder) MoveWithContext(ctx, src, dest any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveWithContext", reflect.TypeOf((*MockICloseableFS)(nil).MoveWithContext), ctx, src, dest)
+}
+
+// NewRemoteLockFile mocks base method.
+func (m *MockICloseableFS) NewRemoteLockFile(id, dirToLock string) filesystem.ILock {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRemoteLockFile", id, dirToLock)
+	ret0, _ := ret[0].(filesystem.ILock)
+	return ret0
+}
+
+// NewRemoteLockFile indicates an expected call of NewRemoteLockFile.
+func (mr *MockICloseableFSMockRecorder) NewRemoteLockFile(id, dirToLock any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteLockFile", reflect.TypeOf((*MockICloseableFS)(nil).NewRemoteLockFile), id, dirToLock)
+}
+
+// NewRemoteRWLockFile mocks base method.
+func (m *MockICloseableFS) NewRemoteRWLockFile(id, dirToLock string) filesystem.IRWLock {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRemoteRWLockFile", id, dirToLock)
+	ret0, _ := ret[0].(filesystem.IRWLock)
+	return ret0
+}
+
+// NewRemoteRWLockFile indicates an expected call of NewRemoteRWLockFile.
+func (mr *MockICloseableFSMockRecorder) NewRemoteRWLockFile(id, dirToLock any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveWithContext", reflect.TypeOf((*MockICloseableFS)(nil).MoveWithContext), ctx, src, dest)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteRWLockFile", reflect.TypeOf((*MockICloseableFS)(nil).NewRemoteRWLockFile), id, dirToLock)
 }
 
-// NewRemoteLockFile mocks base method.
-func (m *MockICloseableFS) NewRemoteLockFile(id, dirToLock string) filesystem.ILock {
+// NewRemoteSemaphoreFile mocks base method.
+func (m *MockICloseableFS) NewRemoteSemaphoreFile(id, dirToLock string, permits int) filesystem.ISemaphore {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewRemoteLockFile", id, dirToLock)
-	ret0, _ := ret[0].(filesystem.ILock)
+	ret := m.ctrl.Call(m, "NewRemoteSemaphoreFile", id, dirToLock, permits)
+	ret0, _ := ret[0].(filesystem.ISemaphore)
 	return ret0
 }
 
-// NewRemoteLockFile indicates an expected call of NewRemoteLockFile.
-func (mr *MockICloseableFSMockRecorder) NewRemoteLockFile(id, dirToLock any) *gomock.Call {
+// NewRemoteSemaphoreFile indicates an expected call of NewRemoteSemaphoreFile.
+func (mr *MockICloseableFSMockRecorder) NewRemoteSemaphoreFile(id, dirToLock, permits any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteLockFile", reflect.TypeOf((*MockICloseableFS)(nil).NewRemoteLockFile), id, dirToLock)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRemoteSemaphoreFile", reflect.TypeOf((*MockICloseableFS)(nil).NewRemoteSemaphoreFile), id, dirToLock, permits)
 }
 
 // Open mocks base method.
@@ -3628,6 +4817,25 @@ func (mr *MockICloseableFSMockRecorder) RemoveWithContextAndExclusionPatterns(ct
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWithContextAndExclusionPatterns", reflect.TypeOf((*MockICloseableFS)(nil).RemoveWithContextAndExclusionPatterns), varargs...)
 }
 
+// RemoveWithContextAndOptions mocks base method.
+func (m *MockICloseableFS) RemoveWithContextAndOptions(ctx context.Context, dir string, options ...filesystem.TreeOperationOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, dir}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RemoveWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveWithContextAndOptions indicates an expected call of RemoveWithContextAndOptions.
+func (mr *MockICloseableFSMockRecorder) RemoveWithContextAndOptions(ctx, dir any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, dir}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWithContextAndOptions", reflect.TypeOf((*MockICloseableFS)(nil).RemoveWithContextAndOptions), varargs...)
+}
+
 // RemoveWithPrivileges mocks base method.
 func (m *MockICloseableFS) RemoveWithPrivileges(ctx context.Context, dir string) error {
 	m.ctrl.T.Helper()
@@ -3642,6 +4850,20 @@ func (mr *MockICloseableFSMockRecorder) RemoveWithPrivileges(ctx, dir any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWithPrivileges", reflect.TypeOf((*MockICloseableFS)(nil).RemoveWithPrivileges), ctx, dir)
 }
 
+// RemoveXattr mocks base method.
+func (m *MockICloseableFS) RemoveXattr(name, attribute string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveXattr", name, attribute)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveXattr indicates an expected call of RemoveXattr.
+func (mr *MockICloseableFSMockRecorder) RemoveXattr(name, attribute any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveXattr", reflect.TypeOf((*MockICloseableFS)(nil).RemoveXattr), name, attribute)
+}
+
 // Rm mocks base method.
 func (m *MockICloseableFS) Rm(dir string) error {
 	m.ctrl.T.Helper()
@@ -3656,6 +4878,20 @@ func (mr *MockICloseableFSMockRecorder) Rm(dir any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rm", reflect.TypeOf((*MockICloseableFS)(nil).Rm), dir)
 }
 
+// SetXattr mocks base method.
+func (m *MockICloseableFS) SetXattr(name, attribute string, value []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetXattr", name, attribute, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetXattr indicates an expected call of SetXattr.
+func (mr *MockICloseableFSMockRecorder) SetXattr(name, attribute, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetXattr", reflect.TypeOf((*MockICloseableFS)(nil).SetXattr), name, attribute, value)
+}
+
 // Stat mocks base method.
 func (m *MockICloseableFS) Stat(name string) (os.FileInfo, error) {
 	m.ctrl.T.Helper()
@@ -3750,6 +4986,86 @@ func (mr *MockICloseableFSMockRecorder) Symlink(oldname, newname any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Symlink", reflect.TypeOf((*MockICloseableFS)(nil).Symlink), oldname, newname)
 }
 
+// Tar mocks base method.
+func (m *MockICloseableFS) Tar(source, destination string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tar", source, destination)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Tar indicates an expected call of Tar.
+func (mr *MockICloseableFSMockRecorder) Tar(source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tar", reflect.TypeOf((*MockICloseableFS)(nil).Tar), source, destination)
+}
+
+// TarWithContext mocks base method.
+func (m *MockICloseableFS) TarWithContext(ctx context.Context, source, destination string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TarWithContext", ctx, source, destination)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContext indicates an expected call of TarWithContext.
+func (mr *MockICloseableFSMockRecorder) TarWithContext(ctx, source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContext", reflect.TypeOf((*MockICloseableFS)(nil).TarWithContext), ctx, source, destination)
+}
+
+// TarWithContextAndLimits mocks base method.
+func (m *MockICloseableFS) TarWithContextAndLimits(ctx context.Context, source, destination string, limits filesystem.ILimits) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TarWithContextAndLimits", ctx, source, destination, limits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContextAndLimits indicates an expected call of TarWithContextAndLimits.
+func (mr *MockICloseableFSMockRecorder) TarWithContextAndLimits(ctx, source, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContextAndLimits", reflect.TypeOf((*MockICloseableFS)(nil).TarWithContextAndLimits), ctx, source, destination, limits)
+}
+
+// TarWithContextAndLimitsAndExclusionPatterns mocks base method.
+func (m *MockICloseableFS) TarWithContextAndLimitsAndExclusionPatterns(ctx context.Context, source, destination string, limits filesystem.ILimits, exclusionPatterns ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination, limits}
+	for _, a := range exclusionPatterns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TarWithContextAndLimitsAndExclusionPatterns", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContextAndLimitsAndExclusionPatterns indicates an expected call of TarWithContextAndLimitsAndExclusionPatterns.
+func (mr *MockICloseableFSMockRecorder) TarWithContextAndLimitsAndExclusionPatterns(ctx, source, destination, limits any, exclusionPatterns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination, limits}, exclusionPatterns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContextAndLimitsAndExclusionPatterns", reflect.TypeOf((*MockICloseableFS)(nil).TarWithContextAndLimitsAndExclusionPatterns), varargs...)
+}
+
+// TarWithContextAndOptions mocks base method.
+func (m *MockICloseableFS) TarWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TarWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TarWithContextAndOptions indicates an expected call of TarWithContextAndOptions.
+func (mr *MockICloseableFSMockRecorder) TarWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TarWithContextAndOptions", reflect.TypeOf((*MockICloseableFS)(nil).TarWithContextAndOptions), varargs...)
+}
+
 // TempDir mocks base method.
 func (m *MockICloseableFS) TempDir(dir, prefix string) (string, error) {
 	m.ctrl.T.Helper()
@@ -3868,6 +5184,86 @@ func (mr *MockICloseableFSMockRecorder) TouchTempFileInTempDir(pattern any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchTempFileInTempDir", reflect.TypeOf((*MockICloseableFS)(nil).TouchTempFileInTempDir), pattern)
 }
 
+// Untar mocks base method.
+func (m *MockICloseableFS) Untar(source, destination string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Untar", source, destination)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Untar indicates an expected call of Untar.
+func (mr *MockICloseableFSMockRecorder) Untar(source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Untar", reflect.TypeOf((*MockICloseableFS)(nil).Untar), source, destination)
+}
+
+// UntarFromReaderWithContextAndLimits mocks base method.
+func (m *MockICloseableFS) UntarFromReaderWithContextAndLimits(ctx context.Context, source io.Reader, destination string, limits filesystem.ILimits) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntarFromReaderWithContextAndLimits", ctx, source, destination, limits)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarFromReaderWithContextAndLimits indicates an expected call of UntarFromReaderWithContextAndLimits.
+func (mr *MockICloseableFSMockRecorder) UntarFromReaderWithContextAndLimits(ctx, source, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarFromReaderWithContextAndLimits", reflect.TypeOf((*MockICloseableFS)(nil).UntarFromReaderWithContextAndLimits), ctx, source, destination, limits)
+}
+
+// UntarWithContext mocks base method.
+func (m *MockICloseableFS) UntarWithContext(ctx context.Context, source, destination string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntarWithContext", ctx, source, destination)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarWithContext indicates an expected call of UntarWithContext.
+func (mr *MockICloseableFSMockRecorder) UntarWithContext(ctx, source, destination any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarWithContext", reflect.TypeOf((*MockICloseableFS)(nil).UntarWithContext), ctx, source, destination)
+}
+
+// UntarWithContextAndLimits mocks base method.
+func (m *MockICloseableFS) UntarWithContextAndLimits(ctx context.Context, source, destination string, limits filesystem.ILimits) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntarWithContextAndLimits", ctx, source, destination, limits)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarWithContextAndLimits indicates an expected call of UntarWithContextAndLimits.
+func (mr *MockICloseableFSMockRecorder) UntarWithContextAndLimits(ctx, source, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarWithContextAndLimits", reflect.TypeOf((*MockICloseableFS)(nil).UntarWithContextAndLimits), ctx, source, destination, limits)
+}
+
+// UntarWithContextAndOptions mocks base method.
+func (m *MockICloseableFS) UntarWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) ([]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UntarWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntarWithContextAndOptions indicates an expected call of UntarWithContextAndOptions.
+func (mr *MockICloseableFSMockRecorder) UntarWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntarWithContextAndOptions", reflect.TypeOf((*MockICloseableFS)(nil).UntarWithContextAndOptions), varargs...)
+}
+
 // Unzip mocks base method.
 func (m *MockICloseableFS) Unzip(source, destination string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -3883,6 +5279,21 @@ func (mr *MockICloseableFSMockRecorder) Unzip(source, destination any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unzip", reflect.TypeOf((*MockICloseableFS)(nil).Unzip), source, destination)
 }
 
+// UnzipFromReaderWithContextAndLimits mocks base method.
+func (m *MockICloseableFS) UnzipFromReaderWithContextAndLimits(ctx context.Context, source io.ReaderAt, size int64, destination string, limits filesystem.ILimits) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnzipFromReaderWithContextAndLimits", ctx, source, size, destination, limits)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnzipFromReaderWithContextAndLimits indicates an expected call of UnzipFromReaderWithContextAndLimits.
+func (mr *MockICloseableFSMockRecorder) UnzipFromReaderWithContextAndLimits(ctx, source, size, destination, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnzipFromReaderWithContextAndLimits", reflect.TypeOf((*MockICloseableFS)(nil).UnzipFromReaderWithContextAndLimits), ctx, source, size, destination, limits)
+}
+
 // UnzipWithContext mocks base method.
 func (m *MockICloseableFS) UnzipWithContext(ctx context.Context, source, destination string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -3913,6 +5324,26 @@ func (mr *MockICloseableFSMockRecorder) UnzipWithContextAndLimits(ctx, source, d
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnzipWithContextAndLimits", reflect.TypeOf((*MockICloseableFS)(nil).UnzipWithContextAndLimits), ctx, source, destination, limits)
 }
 
+// UnzipWithContextAndOptions mocks base method.
+func (m *MockICloseableFS) UnzipWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) ([]string, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UnzipWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnzipWithContextAndOptions indicates an expected call of UnzipWithContextAndOptions.
+func (mr *MockICloseableFSMockRecorder) UnzipWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnzipWithContextAndOptions", reflect.TypeOf((*MockICloseableFS)(nil).UnzipWithContextAndOptions), varargs...)
+}
+
 // Walk mocks base method.
 func (m *MockICloseableFS) Walk(root string, fn filepath.WalkFunc) error {
 	m.ctrl.T.Helper()
@@ -3960,6 +5391,26 @@ func (mr *MockICloseableFSMockRecorder) WalkWithContextAndExclusionPatterns(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WalkWithContextAndExclusionPatterns", reflect.TypeOf((*MockICloseableFS)(nil).WalkWithContextAndExclusionPatterns), varargs...)
 }
 
+// Watch mocks base method.
+func (m *MockICloseableFS) Watch(ctx context.Context, root string, exclusionPatterns ...string) (iter.Seq2[filesystem.WatchEvent, error], error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, root}
+	for _, a := range exclusionPatterns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Watch", varargs...)
+	ret0, _ := ret[0].(iter.Seq2[filesystem.WatchEvent, error])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch.
+func (mr *MockICloseableFSMockRecorder) Watch(ctx, root any, exclusionPatterns ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, root}, exclusionPatterns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockICloseableFS)(nil).Watch), varargs...)
+}
+
 // WriteFile mocks base method.
 func (m *MockICloseableFS) WriteFile(filename string, data []byte, perm os.FileMode) error {
 	m.ctrl.T.Helper()
@@ -3974,6 +5425,20 @@ func (mr *MockICloseableFSMockRecorder) WriteFile(filename, data, perm any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteFile", reflect.TypeOf((*MockICloseableFS)(nil).WriteFile), filename, data, perm)
 }
 
+// WriteFileAtomically mocks base method.
+func (m *MockICloseableFS) WriteFileAtomically(ctx context.Context, filename string, data []byte, perm os.FileMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteFileAtomically", ctx, filename, data, perm)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteFileAtomically indicates an expected call of WriteFileAtomically.
+func (mr *MockICloseableFSMockRecorder) WriteFileAtomically(ctx, filename, data, perm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteFileAtomically", reflect.TypeOf((*MockICloseableFS)(nil).WriteFileAtomically), ctx, filename, data, perm)
+}
+
 // WriteFileWithContext mocks base method.
 func (m *MockICloseableFS) WriteFileWithContext(ctx context.Context, filename string, data []byte, perm os.FileMode) error {
 	m.ctrl.T.Helper()
@@ -4003,6 +5468,21 @@ func (mr *MockICloseableFSMockRecorder) WriteToFile(ctx, filename, reader, perm
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteToFile", reflect.TypeOf((*MockICloseableFS)(nil).WriteToFile), ctx, filename, reader, perm)
 }
 
+// WriteToFileAtomically mocks base method.
+func (m *MockICloseableFS) WriteToFileAtomically(ctx context.Context, filename string, reader io.Reader, perm os.FileMode) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteToFileAtomically", ctx, filename, reader, perm)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteToFileAtomically indicates an expected call of WriteToFileAtomically.
+func (mr *MockICloseableFSMockRecorder) WriteToFileAtomically(ctx, filename, reader, perm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteToFileAtomically", reflect.TypeOf((*MockICloseableFS)(nil).WriteToFileAtomically), ctx, filename, reader, perm)
+}
+
 // Zip mocks base method.
 func (m *MockICloseableFS) Zip(source, destination string) error {
 	m.ctrl.T.Helper()
@@ -4064,6 +5544,25 @@ func (mr *MockICloseableFSMockRecorder) ZipWithContextAndLimitsAndExclusionPatte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZipWithContextAndLimitsAndExclusionPatterns", reflect.TypeOf((*MockICloseableFS)(nil).ZipWithContextAndLimitsAndExclusionPatterns), varargs...)
 }
 
+// ZipWithContextAndOptions mocks base method.
+func (m *MockICloseableFS) ZipWithContextAndOptions(ctx context.Context, source, destination string, options ...filesystem.ArchiveOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, source, destination}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ZipWithContextAndOptions", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ZipWithContextAndOptions indicates an expected call of ZipWithContextAndOptions.
+func (mr *MockICloseableFSMockRecorder) ZipWithContextAndOptions(ctx, source, destination any, options ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, source, destination}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZipWithContextAndOptions", reflect.TypeOf((*MockICloseableFS)(nil).ZipWithContextAndOptions), varargs...)
+}
+
 // MockIForceRemover is a mock of IForceRemover interface.
 type MockIForceRemover struct {
 	ctrl     *gomock.Controller
@@ -4218,3 +5717,121 @@ func (mr *MockISymLinkerMockRecorder) SymlinkIfPossible(arg0, arg1 any) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SymlinkIfPossible", reflect.TypeOf((*MockISymLinker)(nil).SymlinkIfPossible), arg0, arg1)
 }
+
+// MockIXattrer is a mock of IXattrer interface.
+type MockIXattrer struct {
+	ctrl     *gomock.Controller
+	recorder *MockIXattrerMockRecorder
+	isgomock struct{}
+}
+
+// MockIXattrerMockRecorder is the mock recorder for MockIXattrer.
+type MockIXattrerMockRecorder struct {
+	mock *MockIXattrer
+}
+
+// NewMockIXattrer creates a new mock instance.
+func NewMockIXattrer(ctrl *gomock.Controller) *MockIXattrer {
+	mock := &MockIXattrer{ctrl: ctrl}
+	mock.recorder = &MockIXattrerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIXattrer) EXPECT() *MockIXattrerMockRecorder {
+	return m.recorder
+}
+
+// GetXattrIfPossible mocks base method.
+func (m *MockIXattrer) GetXattrIfPossible(name, attribute string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetXattrIfPossible", name, attribute)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetXattrIfPossible indicates an expected call of GetXattrIfPossible.
+func (mr *MockIXattrerMockRecorder) GetXattrIfPossible(name, attribute any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetXattrIfPossible", reflect.TypeOf((*MockIXattrer)(nil).GetXattrIfPossible), name, attribute)
+}
+
+// ListXattrIfPossible mocks base method.
+func (m *MockIXattrer) ListXattrIfPossible(name string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListXattrIfPossible", name)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListXattrIfPossible indicates an expected call of ListXattrIfPossible.
+func (mr *MockIXattrerMockRecorder) ListXattrIfPossible(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListXattrIfPossible", reflect.TypeOf((*MockIXattrer)(nil).ListXattrIfPossible), name)
+}
+
+// RemoveXattrIfPossible mocks base method.
+func (m *MockIXattrer) RemoveXattrIfPossible(name, attribute string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveXattrIfPossible", name, attribute)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveXattrIfPossible indicates an expected call of RemoveXattrIfPossible.
+func (mr *MockIXattrerMockRecorder) RemoveXattrIfPossible(name, attribute any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveXattrIfPossible", reflect.TypeOf((*MockIXattrer)(nil).RemoveXattrIfPossible), name, attribute)
+}
+
+// SetXattrIfPossible mocks base method.
+func (m *MockIXattrer) SetXattrIfPossible(name, attribute string, value []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetXattrIfPossible", name, attribute, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetXattrIfPossible indicates an expected call of SetXattrIfPossible.
+func (mr *MockIXattrerMockRecorder) SetXattrIfPossible(name, attribute, value any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetXattrIfPossible", reflect.TypeOf((*MockIXattrer)(nil).SetXattrIfPossible), name, attribute, value)
+}
+
+// MockIAuditSink is a mock of IAuditSink interface.
+type MockIAuditSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAuditSinkMockRecorder
+	isgomock struct{}
+}
+
+// MockIAuditSinkMockRecorder is the mock recorder for MockIAuditSink.
+type MockIAuditSinkMockRecorder struct {
+	mock *MockIAuditSink
+}
+
+// NewMockIAuditSink creates a new mock instance.
+func NewMockIAuditSink(ctrl *gomock.Controller) *MockIAuditSink {
+	mock := &MockIAuditSink{ctrl: ctrl}
+	mock.recorder = &MockIAuditSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAuditSink) EXPECT() *MockIAuditSinkMockRecorder {
+	return m.recorder
+}
+
+// Record mocks base method.
+func (m *MockIAuditSink) Record(record filesystem.AuditRecord) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", record)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockIAuditSinkMockRecorder) Record(record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockIAuditSink)(nil).Record), record)
+}
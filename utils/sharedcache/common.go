@@ -3,10 +3,12 @@ package sharedcache
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
 	"github.com/ARM-software/golang-utils/utils/collection"
 	"github.com/ARM-software/golang-utils/utils/commonerrors"
 	"github.com/ARM-software/golang-utils/utils/filesystem"
@@ -15,8 +17,9 @@ import (
 	"github.com/ARM-software/golang-utils/utils/reflection"
 )
 
-const defaultCachedPackage = "cache.zip"
+const cachedPackageStem = "cache"
 const hashFileDescriptor = ".hash"
+const zipFileExtension = ".zip"
 
 // GenerateKey generates a key based on a list of key elements `elems`.
 func GenerateKey(elems ...string) string {
@@ -127,10 +130,48 @@ func TransferFiles(ctx context.Context, fs filesystem.FS, dst, src string) (dest
 	return
 }
 
+// SharedCacheOptions defines the settings of a shared cache which cannot be set via configuration.
+type SharedCacheOptions struct {
+	observer metrics.ICacheObserver
+}
+
+// SharedCacheOption configures SharedCacheOptions.
+type SharedCacheOption func(*SharedCacheOptions) *SharedCacheOptions
+
+// DefaultSharedCacheOptions returns the default options i.e. the cache is not observed.
+func DefaultSharedCacheOptions() *SharedCacheOptions {
+	return &SharedCacheOptions{
+		observer: metrics.NewNoOpObserver(),
+	}
+}
+
+// WithSharedCacheOptions returns the options resulting from applying options to the defaults.
+func WithSharedCacheOptions(options ...SharedCacheOption) (opts *SharedCacheOptions) {
+	opts = DefaultSharedCacheOptions()
+	for i := range options {
+		if options[i] != nil {
+			opts = options[i](opts)
+		}
+	}
+	return
+}
+
+// WithObserver sets the observer notified of the cache hits, misses, stores, evictions and fetches e.g. metrics.NewInMemoryObserver().
+func WithObserver(observer metrics.ICacheObserver) SharedCacheOption {
+	return func(o *SharedCacheOptions) *SharedCacheOptions {
+		if o == nil {
+			o = DefaultSharedCacheOptions()
+		}
+		o.observer = metrics.ObserverOrNoOp(observer)
+		return o
+	}
+}
+
 // AbstractSharedCacheRepository defines an abstract cache repository.
 type AbstractSharedCacheRepository struct {
-	cfg *Configuration
-	fs  *filesystem.VFS
+	cfg      *Configuration
+	fs       *filesystem.VFS
+	observer metrics.ICacheObserver
 }
 
 func (c *AbstractSharedCacheRepository) getCacheEntryPath(key string) string {
@@ -157,7 +198,56 @@ func (c *AbstractSharedCacheRepository) RemoveEntry(ctx context.Context, key str
 	if err != nil {
 		return err
 	}
-	return c.fs.Rm(c.getCacheEntryPath(key))
+	entryPath := c.getCacheEntryPath(key)
+	if !c.fs.Exists(entryPath) {
+		return nil
+	}
+	size := c.determineEntrySize(ctx, entryPath)
+	err = c.fs.Rm(entryPath)
+	if err != nil {
+		return err
+	}
+	c.observer.OnEviction(key, size)
+	return nil
+}
+
+// determineEntrySize returns the total size of the files of an entry or -1 if it cannot be determined.
+func (c *AbstractSharedCacheRepository) determineEntrySize(ctx context.Context, entryPath string) (size int64) {
+	err := c.fs.WalkWithContext(ctx, entryPath, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		size = -1
+	}
+	return
+}
+
+// observeStore notifies the observer that the package `cachedPackage` was stored for `key`.
+func (c *AbstractSharedCacheRepository) observeStore(ctx context.Context, key, cachedPackage string) {
+	var size int64
+	if isDir, err := c.fs.IsDir(cachedPackage); err == nil && isDir {
+		size = c.determineEntrySize(ctx, cachedPackage)
+	} else if size, err = c.fs.GetFileSize(cachedPackage); err != nil {
+		size = -1
+	}
+	c.observer.OnStore(key, size)
+}
+
+// observeFetch notifies the observer of the outcome of a request for `key` started at `start`.
+func (c *AbstractSharedCacheRepository) observeFetch(key string, start time.Time, err error) {
+	switch {
+	case err == nil:
+		c.observer.OnHit(key)
+	case commonerrors.Any(err, commonerrors.ErrNotFound, commonerrors.ErrEmpty):
+		c.observer.OnMiss(key)
+	}
+	c.observer.OnFetch(key, time.Since(start), err)
 }
 
 func (c *AbstractSharedCacheRepository) GetEntries(ctx context.Context) (entries []string, err error) {
@@ -203,29 +293,100 @@ func (c *AbstractSharedCacheRepository) setUpLocalDestination(ctx context.Contex
 	return
 }
 
+// packageFormat returns the format in which entries are packaged.
+func (c *AbstractSharedCacheRepository) packageFormat() PackageFormat {
+	if reflection.IsEmpty(c.cfg.PackageFormat) {
+		return ZipPackageFormat
+	}
+	return c.cfg.PackageFormat
+}
+
+// cachedPackageName returns the name of packages in the configured format e.g. `cache.tar.zst`.
+func (c *AbstractSharedCacheRepository) cachedPackageName() string {
+	return getPackageName(c.packageFormat())
+}
+
+// getPackageName returns the name of packages in `format`.
+func getPackageName(format PackageFormat) string {
+	if format == DirectoryPackageFormat {
+		return cachedPackageStem
+	}
+	return fmt.Sprintf("%v.%v", cachedPackageStem, format)
+}
+
+// packEntry packages the content of `src` into `cachedPackage` according to the configured package format.
+func (c *AbstractSharedCacheRepository) packEntry(ctx context.Context, src, cachedPackage string) error {
+	switch c.packageFormat() {
+	case DirectoryPackageFormat:
+		return copyDirectory(ctx, c.fs, src, cachedPackage)
+	case ZipPackageFormat:
+		return c.fs.ZipWithContextAndOptions(ctx, src, cachedPackage, filesystem.WithCompressionLevel(c.cfg.CompressionLevel))
+	default:
+		// the tar compression is determined by the package extension.
+		return c.fs.TarWithContextAndOptions(ctx, src, cachedPackage, filesystem.WithCompressionLevel(c.cfg.CompressionLevel))
+	}
+}
+
+// transferPackage transfers a package to the `dst` directory and returns the path of the package transferred.
+func (c *AbstractSharedCacheRepository) transferPackage(ctx context.Context, dst, cachedPackage string) (destPackage string, err error) {
+	isDir, err := c.fs.IsDir(cachedPackage)
+	if err != nil {
+		return
+	}
+	if !isDir {
+		destPackage, err = TransferFiles(ctx, c.fs, dst, cachedPackage)
+		return
+	}
+	destPackage = filepath.Join(dst, filepath.Base(cachedPackage))
+	err = c.fs.Rm(destPackage)
+	if err != nil {
+		return
+	}
+	err = copyDirectory(ctx, c.fs, cachedPackage, destPackage)
+	return
+}
+
 func (c *AbstractSharedCacheRepository) unpackPackageToLocalDestination(ctx context.Context, cachedPackagePath, dest string) (err error) {
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
 		return
 	}
-	// create temp location for cached package to be unzipped
+	isDir, err := c.fs.IsDir(cachedPackagePath)
+	if err != nil {
+		return
+	}
+	if isDir {
+		// packages stored as directories do not need unpacking.
+		err = copyDirectory(ctx, c.fs, cachedPackagePath, dest)
+		return
+	}
+	// create temp location for cached package to be unpacked
 	tempDir, err := c.fs.TempDirInTempDir(tempDirPrefix)
 	if err != nil {
 		return
 	}
 	defer func() { _ = c.fs.Rm(tempDir) }()
 	// do the transfer to a temporary folder.
-	destZip, err := TransferFiles(ctx, c.fs, tempDir, cachedPackagePath)
-	defer func() { _ = c.fs.Rm(destZip) }()
+	destPackage, err := TransferFiles(ctx, c.fs, tempDir, cachedPackagePath)
+	defer func() { _ = c.fs.Rm(destPackage) }()
 	if err != nil {
 		return
 	}
 
-	// unpack package into destination
-	_, err = c.fs.UnzipWithContext(ctx, destZip, dest)
+	// unpack package into destination. The format is determined from the package itself so that entries stored using a different format can still be retrieved.
+	if strings.EqualFold(filepath.Ext(destPackage), zipFileExtension) {
+		_, err = c.fs.UnzipWithContext(ctx, destPackage, dest)
+	} else {
+		_, err = c.fs.UntarWithContext(ctx, destPackage, dest)
+	}
 	return
 }
 
+// copyDirectory copies the content of the `src` directory into `dest`. Unlike default filesystem copies, file modes, modification times and symbolic links are preserved. Similarly to tar extraction, links leading outside `src` are rejected. The mode of `dest` itself is left unchanged.
+func copyDirectory(ctx context.Context, fs filesystem.FS, src, dest string) error {
+	return fs.CopyWithContextAndOptions(ctx, src, dest, filesystem.WithTreeContentCopy(), filesystem.WithSymlinkPreservation(), filesystem.WithModePreservation())
+}
+
 func (c *AbstractSharedCacheRepository) getEntryAge(ctx context.Context, key string, getCachedPackageFromEntryPath func(ctx context.Context, key, entryDir string) (string, error)) (age time.Duration, err error) {
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
@@ -289,7 +450,7 @@ func (c *AbstractSharedCacheRepository) setEntryAge(ctx context.Context, key str
 	return
 }
 
-func NewAbstractSharedCacheRepository(cfg *Configuration, fs filesystem.FS) (cache *AbstractSharedCacheRepository, err error) {
+func NewAbstractSharedCacheRepository(cfg *Configuration, fs filesystem.FS, options ...SharedCacheOption) (cache *AbstractSharedCacheRepository, err error) {
 	if cfg == nil {
 		err = fmt.Errorf("%w: missing configuration", commonerrors.ErrUndefined)
 		return
@@ -305,8 +466,9 @@ func NewAbstractSharedCacheRepository(cfg *Configuration, fs filesystem.FS) (cac
 		return
 	}
 	cache = &AbstractSharedCacheRepository{
-		cfg: cfg,
-		fs:  rawFs,
+		cfg:      cfg,
+		fs:       rawFs,
+		observer: WithSharedCacheOptions(options...).observer,
 	}
 	return
 }
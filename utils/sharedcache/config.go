@@ -6,13 +6,37 @@ import (
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 
 	configUtils "github.com/ARM-software/golang-utils/utils/config"
+	"github.com/ARM-software/golang-utils/utils/filesystem"
 	validationRules "github.com/ARM-software/golang-utils/utils/validation"
 )
 
+// PackageFormat defines how cache entries are packaged in the remote storage.
+type PackageFormat string
+
+const (
+	// ZipPackageFormat packages entries as zip archives. File modes and symbolic links are not preserved.
+	ZipPackageFormat PackageFormat = "zip"
+	// TarPackageFormat packages entries as uncompressed tar archives.
+	TarPackageFormat PackageFormat = "tar"
+	// TarGzPackageFormat packages entries as gzip compressed tar archives.
+	TarGzPackageFormat PackageFormat = "tar.gz"
+	// TarZstPackageFormat packages entries as zstd compressed tar archives.
+	TarZstPackageFormat PackageFormat = "tar.zst"
+	// DirectoryPackageFormat stores entries as plain directories, avoiding any archiving or compression overhead.
+	DirectoryPackageFormat PackageFormat = "dir"
+)
+
+var (
+	// PackageFormats lists all the supported package formats. Apart from ZipPackageFormat, they all preserve file modes and symbolic links.
+	PackageFormats = []PackageFormat{ZipPackageFormat, TarPackageFormat, TarGzPackageFormat, TarZstPackageFormat, DirectoryPackageFormat}
+)
+
 type Configuration struct {
 	RemoteStoragePath       string        `mapstructure:"remote_storage_path"` // Path where the cache will be stored.
 	Timeout                 time.Duration `mapstructure:"timeout"`             // Cache timeout if need be
 	FilesystemItemsToIgnore string        `mapstructure:"ignore_fs_items"`     // List of files/folders to ignore (pattern list separated by commas)
+	PackageFormat           PackageFormat `mapstructure:"package_format"`      // Format of the packages stored in the cache. ZipPackageFormat is used if not set.
+	CompressionLevel        int           `mapstructure:"compression_level"`   // Compression level of packages from 1 (fastest) to 9 (smallest). 0 means the default level of the format's compression algorithm is used.
 }
 
 func (cfg *Configuration) Validate() error {
@@ -25,9 +49,14 @@ func (cfg *Configuration) Validate() error {
 
 	return validation.ValidateStruct(cfg,
 		validation.Field(&cfg.RemoteStoragePath, validationRules.Required),
+		validation.Field(&cfg.PackageFormat, validation.In(ZipPackageFormat, TarPackageFormat, TarGzPackageFormat, TarZstPackageFormat, DirectoryPackageFormat)),
+		validation.Field(&cfg.CompressionLevel, validation.Min(filesystem.DefaultCompressionLevel), validation.Max(filesystem.BestCompressionLevel)),
 	)
 }
 
 func DefaultSharedCacheConfiguration() *Configuration {
-	return &Configuration{}
+	return &Configuration{
+		PackageFormat:    ZipPackageFormat,
+		CompressionLevel: filesystem.DefaultCompressionLevel,
+	}
 }
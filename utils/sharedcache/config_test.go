@@ -4,7 +4,10 @@ import (
 	"testing"
 
 	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ARM-software/golang-utils/utils/filesystem"
 )
 
 func TestDefaultSharedCacheConfiguration(t *testing.T) {
@@ -13,3 +16,23 @@ func TestDefaultSharedCacheConfiguration(t *testing.T) {
 	cfg.RemoteStoragePath = faker.URL()
 	require.NoError(t, cfg.Validate())
 }
+
+func TestConfiguration_PackageFormat(t *testing.T) {
+	cfg := DefaultSharedCacheConfiguration()
+	cfg.RemoteStoragePath = faker.URL()
+	assert.Equal(t, ZipPackageFormat, cfg.PackageFormat)
+	for i := range PackageFormats {
+		cfg.PackageFormat = PackageFormats[i]
+		require.NoError(t, cfg.Validate())
+	}
+	cfg.PackageFormat = PackageFormat(faker.Word())
+	require.Error(t, cfg.Validate())
+	cfg.PackageFormat = ""
+	require.NoError(t, cfg.Validate())
+	cfg.CompressionLevel = filesystem.BestCompressionLevel
+	require.NoError(t, cfg.Validate())
+	cfg.CompressionLevel = filesystem.BestCompressionLevel + 1
+	require.Error(t, cfg.Validate())
+	cfg.CompressionLevel = -1
+	require.Error(t, cfg.Validate())
+}
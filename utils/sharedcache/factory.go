@@ -19,12 +19,12 @@ var (
 	CacheTypes = []CacheType{CacheMutable, CacheImmutable}
 )
 
-func NewCache(cacheType CacheType, fs filesystem.FS, cfg *Configuration) (ISharedCacheRepository, error) {
+func NewCache(cacheType CacheType, fs filesystem.FS, cfg *Configuration, options ...SharedCacheOption) (ISharedCacheRepository, error) {
 	switch cacheType {
 	case CacheMutable:
-		return NewSharedMutableCacheRepository(cfg, fs)
+		return NewSharedMutableCacheRepository(cfg, fs, options...)
 	case CacheImmutable:
-		return NewSharedImmutableCacheRepository(cfg, fs)
+		return NewSharedImmutableCacheRepository(cfg, fs, options...)
 	}
 	return nil, fmt.Errorf("%w: unknown cache type [%v]", commonerrors.ErrNotFound, cacheType)
 }
@@ -28,8 +28,8 @@ type FileWithModTime struct {
 	modTime  time.Time
 }
 
-func NewSharedImmutableCacheRepository(cfg *Configuration, fs filesystem.FS) (repository *SharedImmutableCacheRepository, err error) {
-	abstractCache, err := NewAbstractSharedCacheRepository(cfg, fs)
+func NewSharedImmutableCacheRepository(cfg *Configuration, fs filesystem.FS, options ...SharedCacheOption) (repository *SharedImmutableCacheRepository, err error) {
+	abstractCache, err := NewAbstractSharedCacheRepository(cfg, fs, options...)
 	if err != nil {
 		return
 	}
@@ -93,6 +93,13 @@ func listCompleteFilesByModTime(ctx context.Context, fs filesystem.FS, entryDir
 }
 
 func (s *SharedImmutableCacheRepository) Fetch(ctx context.Context, key, dest string) (err error) {
+	start := time.Now()
+	err = s.fetch(ctx, key, dest)
+	s.observeFetch(key, start, err)
+	return
+}
+
+func (s *SharedImmutableCacheRepository) fetch(ctx context.Context, key, dest string) (err error) {
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
 		return
@@ -132,39 +139,47 @@ func (s *SharedImmutableCacheRepository) Store(ctx context.Context, key, src str
 		return
 	}
 
-	// create temp location for files so we don't include zip inside itself
+	// create temp location for files so we don't include the package inside itself
 	tempDir, err := s.fs.TempDirInTempDir(tempDirPrefix)
 	if err != nil {
 		return
 	}
 	defer func() { _ = s.fs.Rm(tempDir) }()
 
-	// zip the local cache
-	// generate zip
-	zipped := filepath.Join(tempDir, s.generateCachedPackageName())
-	err = s.fs.ZipWithContext(ctx, src, zipped)
+	// package the local cache
+	// the package is only marked as partial once created since the tar compression is determined by its extension.
+	partPackage := filepath.Join(tempDir, s.generateCachedPackageName())
+	packaged := strings.TrimSuffix(partPackage, partFileDescriptor)
+	err = s.packEntry(ctx, src, packaged)
+	if err != nil {
+		return
+	}
+	err = s.fs.Move(packaged, partPackage)
 	if err != nil {
 		return
 	}
 
 	// do the transfer
-	destZip, err := TransferFiles(ctx, s.fs, remoteDir, zipped)
+	destPackage, err := s.transferPackage(ctx, remoteDir, partPackage)
 	if err != nil {
-		_ = s.fs.Rm(destZip)
+		_ = s.fs.Rm(destPackage)
 		return
 	}
 
 	// remove .part from uploaded cache file
-	if strings.EqualFold(filepath.Ext(destZip), partFileDescriptor) {
-		finalZip := strings.ReplaceAll(destZip, partFileDescriptor, "")
-		err = s.fs.Move(destZip, finalZip)
+	if strings.EqualFold(filepath.Ext(destPackage), partFileDescriptor) {
+		finalPackage := strings.ReplaceAll(destPackage, partFileDescriptor, "")
+		err = s.fs.Move(destPackage, finalPackage)
 		// Don't forget the hash file
-		hashFile := filepath.Join(filepath.Dir(destZip), generateHashFileName(destZip))
+		hashFile := filepath.Join(filepath.Dir(destPackage), generateHashFileName(destPackage))
 		if s.fs.Exists(hashFile) {
 			finalHash := strings.ReplaceAll(hashFile, partFileDescriptor, "")
 			_ = s.fs.Move(hashFile, finalHash)
 		}
 	}
+	if err == nil {
+		s.observeStore(ctx, key, partPackage)
+	}
 	return
 }
 
@@ -173,7 +188,7 @@ func (s *SharedImmutableCacheRepository) generateCachedPackageName() string {
 	if err != nil {
 		cacheUUID = defaultCachedPackageID
 	}
-	return fmt.Sprintf("%v-%v%v", cacheUUID, defaultCachedPackage, partFileDescriptor)
+	return fmt.Sprintf("%v-%v%v", cacheUUID, s.cachedPackageName(), partFileDescriptor)
 }
 
 func (s *SharedImmutableCacheRepository) CleanEntry(ctx context.Context, key string) (err error) {
@@ -23,8 +23,8 @@ type SharedMutableCacheRepository struct {
 	lockTimeout time.Duration
 }
 
-func NewSharedMutableCacheRepository(cfg *Configuration, fs filesystem.FS) (repository ISharedCacheRepository, err error) {
-	abstractCache, err := NewAbstractSharedCacheRepository(cfg, fs)
+func NewSharedMutableCacheRepository(cfg *Configuration, fs filesystem.FS, options ...SharedCacheOption) (repository ISharedCacheRepository, err error) {
+	abstractCache, err := NewAbstractSharedCacheRepository(cfg, fs, options...)
 	if err != nil {
 		return
 	}
@@ -39,6 +39,13 @@ func NewSharedMutableCacheRepository(cfg *Configuration, fs filesystem.FS) (repo
 }
 
 func (s *SharedMutableCacheRepository) Fetch(ctx context.Context, key, dest string) (err error) {
+	start := time.Now()
+	err = s.fetch(ctx, key, dest)
+	s.observeFetch(key, start, err)
+	return
+}
+
+func (s *SharedMutableCacheRepository) fetch(ctx context.Context, key, dest string) (err error) {
 	err = parallelisation.DetermineContextError(ctx)
 	if err != nil {
 		return
@@ -90,16 +97,16 @@ func (s *SharedMutableCacheRepository) Store(ctx context.Context, key, src strin
 		return
 	}
 
-	// create temp location for files so we don't include zip inside itself
+	// create temp location for files so we don't include the package inside itself
 	tempDir, err := s.fs.TempDirInTempDir(tempDirPrefix)
 	if err != nil {
 		return
 	}
 	defer func() { _ = s.fs.Rm(tempDir) }()
 
-	// zip the local cache
-	zipped := filepath.Join(tempDir, defaultCachedPackage)
-	err = s.fs.ZipWithContext(ctx, src, zipped)
+	// package the local cache
+	packaged := filepath.Join(tempDir, s.cachedPackageName())
+	err = s.packEntry(ctx, src, packaged)
 	if err != nil {
 		return
 	}
@@ -112,12 +119,19 @@ func (s *SharedMutableCacheRepository) Store(ctx context.Context, key, src strin
 	if err != nil {
 		return
 	}
-	destZip, err := TransferFiles(ctx, s.fs, remoteDir, zipped)
+	destPackage, err := s.transferPackage(ctx, remoteDir, packaged)
+	if err != nil {
+		_ = s.fs.Rm(destPackage)
+		return
+	}
+	err = s.removeStalePackages(remoteDir)
 	if err != nil {
-		_ = s.fs.Rm(destZip)
 		return
 	}
 	err = remoteLock.Unlock(ctx)
+	if err == nil {
+		s.observeStore(ctx, key, packaged)
+	}
 	return
 }
 
@@ -136,8 +150,8 @@ func (s *SharedMutableCacheRepository) findCachedPackageFromEntryDir(ctx context
 	if err != nil {
 		return
 	}
-	cachedPackage = getCachedPackagePath(entryDir)
-	if !s.fs.Exists(cachedPackage) {
+	cachedPackage = s.getCachedPackagePath(entryDir)
+	if cachedPackage == "" {
 		err = fmt.Errorf("no entry for key [%v] in cache: %w", key, commonerrors.ErrEmpty)
 	}
 	return
@@ -151,6 +165,37 @@ func (s *SharedMutableCacheRepository) SetEntryAge(ctx context.Context, key stri
 	return s.setEntryAge(ctx, key, age, s.findCachedPackageFromEntryDir)
 }
 
-func getCachedPackagePath(remoteDir string) string {
-	return filepath.Join(remoteDir, defaultCachedPackage)
+// getCachedPackagePath returns the path of the package stored in `remoteDir` or an empty string if there is none.
+// Packages in the configured format are looked for first but packages in any other format are accepted so that entries stored before a format change can still be retrieved.
+func (s *SharedMutableCacheRepository) getCachedPackagePath(remoteDir string) string {
+	cachedPackage := filepath.Join(remoteDir, s.cachedPackageName())
+	if s.fs.Exists(cachedPackage) {
+		return cachedPackage
+	}
+	for i := range PackageFormats {
+		cachedPackage = filepath.Join(remoteDir, getPackageName(PackageFormats[i]))
+		if s.fs.Exists(cachedPackage) {
+			return cachedPackage
+		}
+	}
+	return ""
+}
+
+// removeStalePackages removes the packages stored in `remoteDir` in a format other than the configured one, e.g. before a format change.
+func (s *SharedMutableCacheRepository) removeStalePackages(remoteDir string) error {
+	current := s.cachedPackageName()
+	for i := range PackageFormats {
+		name := getPackageName(PackageFormats[i])
+		if name == current {
+			continue
+		}
+		stalePackage := filepath.Join(remoteDir, name)
+		if err := s.fs.Rm(stalePackage); err != nil {
+			return err
+		}
+		if err := s.fs.Rm(filepath.Join(remoteDir, generateHashFileName(stalePackage))); err != nil {
+			return err
+		}
+	}
+	return nil
 }
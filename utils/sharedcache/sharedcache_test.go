@@ -11,9 +11,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ARM-software/golang-utils/utils/cache/metrics"
 	"github.com/ARM-software/golang-utils/utils/commonerrors"
+	"github.com/ARM-software/golang-utils/utils/commonerrors/errortest"
 	"github.com/ARM-software/golang-utils/utils/filesystem"
 	"github.com/ARM-software/golang-utils/utils/filesystem/filesystemtest"
+	"github.com/ARM-software/golang-utils/utils/platform"
 )
 
 func TestNothingInCacheWorkflow(t *testing.T) { // Single fetch with no file previously cached
@@ -495,3 +498,215 @@ func TestEntryAge(t *testing.T) { // A store followed by a remove entry followed
 		}
 	}
 }
+
+func TestCacheMetrics(t *testing.T) { // A fetch followed by a store, a fetch and a remove entry
+	for c := range CacheTypes {
+		cacheType := CacheTypes[c]
+		testName := fmt.Sprintf("%v_for_cache_%v", t.Name(), cacheType)
+		t.Run(testName, func(t *testing.T) {
+			ctx := context.Background()
+			fs := filesystem.NewStandardFileSystem()
+			tmpRemoteDir := t.TempDir()
+			tmpSrcDir := t.TempDir()
+			tmpDestDir := t.TempDir()
+			_ = filesystemtest.CreateTestFileTree(t, fs, tmpSrcDir, time.Now(), time.Now())
+
+			observer := metrics.NewInMemoryObserver()
+			remoteCache, err := NewCache(cacheType, fs, &Configuration{
+				RemoteStoragePath: tmpRemoteDir,
+				Timeout:           time.Second,
+			}, WithObserver(observer))
+			require.NoError(t, err)
+			key := remoteCache.GenerateKey("test", "metrics", fmt.Sprintf("%v", cacheType))
+
+			require.Error(t, remoteCache.Fetch(ctx, key, tmpDestDir))
+			require.NoError(t, remoteCache.Store(ctx, key, tmpSrcDir))
+			require.NoError(t, remoteCache.Fetch(ctx, key, tmpDestDir))
+			require.NoError(t, remoteCache.RemoveEntry(ctx, key))
+			require.NoError(t, remoteCache.RemoveEntry(ctx, key))
+
+			snapshot := observer.Snapshot()
+			assert.Equal(t, uint64(1), snapshot.Hits)
+			assert.Equal(t, uint64(1), snapshot.Misses)
+			assert.Equal(t, uint64(1), snapshot.Stores)
+			assert.Positive(t, snapshot.BytesStored)
+			assert.Equal(t, uint64(1), snapshot.Evictions)
+			assert.GreaterOrEqual(t, snapshot.BytesEvicted, snapshot.BytesStored)
+			assert.Equal(t, uint64(2), snapshot.Fetches)
+			assert.Equal(t, uint64(1), snapshot.FailedFetches)
+		})
+	}
+}
+
+func TestPackageFormats(t *testing.T) { // Store followed by fetch for each package format, checking file modes and symbolic links are preserved
+	if platform.IsWindows() {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	for c := range CacheTypes {
+		cacheType := CacheTypes[c]
+		for i := range PackageFormats {
+			format := PackageFormats[i]
+			testName := fmt.Sprintf("%v_for_format_%v_and_cache_%v", t.Name(), format, cacheType)
+			t.Run(testName, func(t *testing.T) {
+				t.Parallel()
+				ctx := context.Background()
+				fs := filesystem.NewStandardFileSystem()
+				tmpRemoteDir := t.TempDir()
+				tmpSrcDir := t.TempDir()
+				tmpDestDir := t.TempDir()
+
+				tree := filesystemtest.CreateTestFileTree(t, fs, tmpSrcDir, time.Now(), time.Now())
+				executable := filesystem.FilePathJoin(fs, tmpSrcDir, "tool.sh")
+				require.NoError(t, fs.WriteFile(executable, []byte("#!/bin/sh"), 0o755))
+				require.NoError(t, fs.Chmod(executable, 0o755))
+				require.NoError(t, fs.Symlink("tool.sh", filesystem.FilePathJoin(fs, tmpSrcDir, "tool")))
+				expectedTree, err := fs.ConvertToRelativePath(tmpSrcDir, tree...)
+				require.NoError(t, err)
+				expectedTree = append(expectedTree, "tool.sh", "tool")
+
+				cfg := DefaultSharedCacheConfiguration()
+				cfg.RemoteStoragePath = tmpRemoteDir
+				cfg.Timeout = time.Second
+				cfg.PackageFormat = format
+				cfg.CompressionLevel = filesystem.BestSpeedCompressionLevel
+				remoteCache, err := NewCache(cacheType, fs, cfg)
+				require.NoError(t, err)
+
+				key := remoteCache.GenerateKey("test", "cache", fmt.Sprintf("%v", cacheType))
+				require.NoError(t, remoteCache.Store(ctx, key, tmpSrcDir))
+				// storing again replaces the package
+				require.NoError(t, remoteCache.Store(ctx, key, tmpSrcDir))
+				age, err := remoteCache.GetEntryAge(ctx, key)
+				require.NoError(t, err)
+				assert.Less(t, age, time.Minute)
+
+				destInfo, err := fs.Stat(tmpDestDir)
+				require.NoError(t, err)
+				destMode := destInfo.Mode().Perm()
+				require.NoError(t, fs.Chmod(tmpSrcDir, 0o750))
+				require.NoError(t, remoteCache.Store(ctx, key, tmpSrcDir))
+
+				require.NoError(t, remoteCache.Fetch(ctx, key, tmpDestDir))
+				// the destination mode is not altered.
+				destInfo, err = fs.Stat(tmpDestDir)
+				require.NoError(t, err)
+				assert.Equal(t, destMode, destInfo.Mode().Perm())
+				var content []string
+				require.NoError(t, fs.ListDirTree(tmpDestDir, &content))
+				actualTree, err := fs.ConvertToRelativePath(tmpDestDir, content...)
+				require.NoError(t, err)
+				assert.ElementsMatch(t, expectedTree, actualTree)
+
+				if format == ZipPackageFormat {
+					// zip archives do not preserve file modes and symbolic links.
+					return
+				}
+				info, err := fs.Stat(filesystem.FilePathJoin(fs, tmpDestDir, "tool.sh"))
+				require.NoError(t, err)
+				assert.Equal(t, 0o755, int(info.Mode().Perm()))
+				isLink, err := fs.IsLink(filesystem.FilePathJoin(fs, tmpDestDir, "tool"))
+				require.NoError(t, err)
+				assert.True(t, isLink)
+				target, err := fs.Readlink(filesystem.FilePathJoin(fs, tmpDestDir, "tool"))
+				require.NoError(t, err)
+				assert.Equal(t, "tool.sh", target)
+			})
+		}
+	}
+}
+
+func TestPackageFormats_MaliciousLinks(t *testing.T) { // Packages stored as directories reject symbolic links leading outside the entry, as tar extraction does
+	if platform.IsWindows() {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	for c := range CacheTypes {
+		cacheType := CacheTypes[c]
+		t.Run(fmt.Sprintf("%v_for_cache_%v", t.Name(), cacheType), func(t *testing.T) {
+			ctx := context.Background()
+			fs := filesystem.NewStandardFileSystem()
+			tmpRemoteDir := t.TempDir()
+			tmpSrcDir := t.TempDir()
+			_ = filesystemtest.CreateTestFileTree(t, fs, tmpSrcDir, time.Now(), time.Now())
+			require.NoError(t, fs.WriteFile(filesystem.FilePathJoin(fs, tmpSrcDir, "tool.sh"), []byte("#!/bin/sh"), 0o755))
+			link := filesystem.FilePathJoin(fs, tmpSrcDir, "tool")
+			require.NoError(t, fs.Symlink("tool.sh", link))
+
+			remoteCache, err := NewCache(cacheType, fs, &Configuration{RemoteStoragePath: tmpRemoteDir, Timeout: time.Second, PackageFormat: DirectoryPackageFormat})
+			require.NoError(t, err)
+			key := remoteCache.GenerateKey("test", "cache")
+			require.NoError(t, remoteCache.Store(ctx, key, tmpSrcDir))
+
+			// links leading outside the entry cannot be stored.
+			require.NoError(t, fs.Rm(link))
+			require.NoError(t, fs.Symlink(filesystem.FilePathJoin(fs, "..", "..", "outside"), link))
+			errortest.AssertError(t, remoteCache.Store(ctx, key, tmpSrcDir), commonerrors.ErrMalicious)
+
+			// nor fetched if the shared store was tampered with.
+			var content []string
+			require.NoError(t, fs.ListDirTree(tmpRemoteDir, &content))
+			tampered := 0
+			for i := range content {
+				if filesystem.FilePathBase(fs, content[i]) == "tool" {
+					require.NoError(t, fs.Rm(content[i]))
+					require.NoError(t, fs.Symlink("/etc", content[i]))
+					tampered++
+				}
+			}
+			require.NotZero(t, tampered)
+			tmpDestDir := t.TempDir()
+			errortest.AssertError(t, remoteCache.Fetch(ctx, key, tmpDestDir), commonerrors.ErrMalicious)
+			isLink, err := fs.IsLink(filesystem.FilePathJoin(fs, tmpDestDir, "tool"))
+			assert.False(t, err == nil && isLink)
+		})
+	}
+}
+
+func TestPackageFormats_Change(t *testing.T) { // Entries stored using a package format can still be fetched once the format changes
+	for c := range CacheTypes {
+		cacheType := CacheTypes[c]
+		t.Run(fmt.Sprintf("%v_for_cache_%v", t.Name(), cacheType), func(t *testing.T) {
+			ctx := context.Background()
+			fs := filesystem.NewStandardFileSystem()
+			tmpRemoteDir := t.TempDir()
+			tmpSrcDir := t.TempDir()
+			tree := filesystemtest.CreateTestFileTree(t, fs, tmpSrcDir, time.Now(), time.Now())
+			expectedTree, err := fs.ConvertToRelativePath(tmpSrcDir, tree...)
+			require.NoError(t, err)
+
+			remoteCache, err := NewCache(cacheType, fs, &Configuration{RemoteStoragePath: tmpRemoteDir, Timeout: time.Second})
+			require.NoError(t, err)
+			key := remoteCache.GenerateKey("test", "cache")
+			require.NoError(t, remoteCache.Store(ctx, key, tmpSrcDir))
+
+			for i := range PackageFormats {
+				format := PackageFormats[i]
+				t.Run(fmt.Sprintf("fetch with format %v", format), func(t *testing.T) {
+					otherCache, err := NewCache(cacheType, fs, &Configuration{RemoteStoragePath: tmpRemoteDir, Timeout: time.Second, PackageFormat: format})
+					require.NoError(t, err)
+					tmpDestDir := t.TempDir()
+					require.NoError(t, otherCache.Fetch(ctx, key, tmpDestDir))
+					var content []string
+					require.NoError(t, fs.ListDirTree(tmpDestDir, &content))
+					actualTree, err := fs.ConvertToRelativePath(tmpDestDir, content...)
+					require.NoError(t, err)
+					assert.ElementsMatch(t, expectedTree, actualTree)
+
+					// the most recent package is fetched whatever its format.
+					require.NoError(t, otherCache.Store(ctx, key, tmpSrcDir))
+					time.Sleep(10 * time.Millisecond)
+					if cacheType == CacheMutable {
+						// packages in the previous format are replaced.
+						entries, err := fs.Ls(filesystem.FilePathJoin(fs, tmpRemoteDir, key))
+						require.NoError(t, err)
+						assert.Contains(t, entries, getPackageName(format))
+						for j := range PackageFormats {
+							if PackageFormats[j] != format {
+								assert.NotContains(t, entries, getPackageName(PackageFormats[j]))
+							}
+						}
+					}
+				})
+			}
+		})
+	}
+}